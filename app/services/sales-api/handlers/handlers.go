@@ -9,11 +9,34 @@ import (
 	"os"
 
 	v1CheckGrp "github.com/deliveranceTechSolutions/erp/app/services/sales-api/handlers/debug/checkgrp"
+	"github.com/deliveranceTechSolutions/erp/app/services/sales-api/handlers/oauth/discoverygrp"
+	"github.com/deliveranceTechSolutions/erp/app/services/sales-api/handlers/oauth/tokengrp"
+	v1APIKeyGrp "github.com/deliveranceTechSolutions/erp/app/services/sales-api/handlers/v1/apikeygrp"
+	v1DashboardGrp "github.com/deliveranceTechSolutions/erp/app/services/sales-api/handlers/v1/dashboardgrp"
+	v1MFAGrp "github.com/deliveranceTechSolutions/erp/app/services/sales-api/handlers/v1/mfagrp"
+	v1ProjectGrp "github.com/deliveranceTechSolutions/erp/app/services/sales-api/handlers/v1/projectgrp"
+	v1ReportGrp "github.com/deliveranceTechSolutions/erp/app/services/sales-api/handlers/v1/reportgrp"
+	v1RoleGrp "github.com/deliveranceTechSolutions/erp/app/services/sales-api/handlers/v1/rolegrp"
+	v1SearchGrp "github.com/deliveranceTechSolutions/erp/app/services/sales-api/handlers/v1/searchgrp"
+	v1SubscriptionGrp "github.com/deliveranceTechSolutions/erp/app/services/sales-api/handlers/v1/subscriptiongrp"
 	v1TestGrp "github.com/deliveranceTechSolutions/erp/app/services/sales-api/handlers/v1/testgrp"
 	v1UserGrp "github.com/deliveranceTechSolutions/erp/app/services/sales-api/handlers/v1/usergrp"
+	apikeyCore "github.com/deliveranceTechSolutions/erp/business/core/apikey"
+	dashboardCore "github.com/deliveranceTechSolutions/erp/business/core/dashboard"
+	federationCore "github.com/deliveranceTechSolutions/erp/business/core/federation"
+	mfaCore "github.com/deliveranceTechSolutions/erp/business/core/mfa"
+	oauthCore "github.com/deliveranceTechSolutions/erp/business/core/oauth"
+	projectCore "github.com/deliveranceTechSolutions/erp/business/core/project"
+	reportCore "github.com/deliveranceTechSolutions/erp/business/core/report"
+	roleCore "github.com/deliveranceTechSolutions/erp/business/core/role"
+	searchCore "github.com/deliveranceTechSolutions/erp/business/core/search"
+	sessionCore "github.com/deliveranceTechSolutions/erp/business/core/session"
+	subscriptionCore "github.com/deliveranceTechSolutions/erp/business/core/subscription"
 	userCore "github.com/deliveranceTechSolutions/erp/business/core/user"
 	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/oidc"
 	"github.com/deliveranceTechSolutions/erp/business/web/mid"
+	"github.com/deliveranceTechSolutions/erp/foundation/mail"
 	"github.com/deliveranceTechSolutions/erp/foundation/web"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
@@ -58,10 +81,16 @@ func DebugMux(build string, log *zap.SugaredLogger, db *sqlx.DB) http.Handler {
 
 // APIMuxConfig contains all the mandatory systems required by handlers.
 type APIMuxConfig struct {
-	Shutdown chan os.Signal
-	Log      *zap.SugaredLogger
-	Auth     *auth.Auth
-	DB       *sqlx.DB
+	Shutdown   chan os.Signal
+	Log        *zap.SugaredLogger
+	Auth       *auth.Auth
+	Issuer     string
+	DB         *sqlx.DB
+	Mailer     mail.Mailer
+	User       userCore.Config
+	MFARoles   []string
+	MFAIssuer  string
+	Federation *oidc.Verifier
 }
 
 // APIMux returns a reference to web.App, which is a custome web framework
@@ -82,6 +111,10 @@ func APIMux(cfg APIMuxConfig) *web.App {
 	// Allows you to version the API and maintain client code.
 	v1(app, cfg)
 
+	// Load the OAuth routes, which are fixed by the standard rather than
+	// versioned with the API.
+	oauth(app, cfg)
+
 	return app
 }
 
@@ -101,6 +134,29 @@ func FileMux(cfg APIMuxConfig) *web.App {
 	return fs
 }
 
+// oauth binds the OAuth 2.0 routes.
+func oauth(app *web.App, cfg APIMuxConfig) *web.App {
+	const group = "oauth"
+
+	th := tokengrp.Handlers{
+		OAuth: oauthCore.NewCore(cfg.Log, cfg.DB, cfg.User),
+		Auth:  cfg.Auth,
+	}
+	app.Handle(http.MethodPost, group, "/token", th.Token)
+	app.Handle(http.MethodPost, group, "/introspect", th.Introspect)
+
+	// Publish the keys and discovery document other services verify our
+	// tokens with.
+	dh := discoverygrp.Handlers{
+		Auth:   cfg.Auth,
+		Issuer: cfg.Issuer,
+	}
+	app.Handle(http.MethodGet, "", "/.well-known/jwks.json", dh.JWKS)
+	app.Handle(http.MethodGet, "", "/.well-known/openid-configuration", dh.OpenIDConfiguration)
+
+	return app
+}
+
 // v1 binds all the version 1 routes.
 func v1(app *web.App, cfg APIMuxConfig) *web.App {
 	const version = "v1"
@@ -113,15 +169,116 @@ func v1(app *web.App, cfg APIMuxConfig) *web.App {
 
 	// Register user management and authentication endpoints.
 	ugh := v1UserGrp.Handlers{
-		User: userCore.NewCore(cfg.Log, cfg.DB),
-		Auth: cfg.Auth,
+		User:       userCore.NewCore(cfg.Log, cfg.DB, cfg.User),
+		Session:    sessionCore.NewCore(cfg.Log, cfg.DB, cfg.User.Tokens),
+		Federation: federationCore.NewCore(cfg.Log, cfg.DB, cfg.Federation, cfg.User.Tokens),
+		Auth:       cfg.Auth,
 	}
 	app.Handle(http.MethodGet, version, "/users/token", ugh.Token)
-	app.Handle(http.MethodGet, version, "/users/:page/:rows", ugh.Query, mid.Authenticate(cfg.Auth), mid.Authorize(auth.RoleAdmin))
+	app.Handle(http.MethodPost, version, "/users/token/refresh", ugh.Refresh)
+	app.Handle(http.MethodPost, version, "/users/token/logout", ugh.Logout, mid.Authenticate(cfg.Auth))
+	if cfg.Federation != nil {
+		app.Handle(http.MethodPost, version, "/users/token/exchange", ugh.Exchange)
+	}
+	app.Handle(http.MethodPost, version, "/users/password/forgot", ugh.ForgotPassword)
+	app.Handle(http.MethodPost, version, "/users/password/reset", ugh.ResetPassword)
+	app.Handle(http.MethodPost, version, "/users/verify", ugh.VerifyEmail)
+	app.Handle(http.MethodPost, version, "/users/verify/resend", ugh.RequestVerification)
+	app.Handle(http.MethodDelete, version, "/users/sessions", ugh.RevokeSessions, mid.Authenticate(cfg.Auth))
+	app.Handle(http.MethodDelete, version, "/users/:id/sessions", ugh.RevokeSessions, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermUsersWrite))
+	app.Handle(http.MethodGet, version, "/users/me", ugh.QueryMe, mid.Authenticate(cfg.Auth))
+	app.Handle(http.MethodPut, version, "/users/me", ugh.UpdateMe, mid.Authenticate(cfg.Auth))
+	app.Handle(http.MethodPut, version, "/users/me/password", ugh.ChangePassword, mid.Authenticate(cfg.Auth))
+	app.Handle(http.MethodGet, version, "/users/email/:email", ugh.QueryByEmail, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermUsersRead))
+	app.Handle(http.MethodGet, version, "/users", ugh.Query, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermUsersRead))
 	app.Handle(http.MethodGet, version, "/users/:id", ugh.QueryByID, mid.Authenticate(cfg.Auth))
-	app.Handle(http.MethodPost, version, "/users", ugh.Create, mid.Authenticate(cfg.Auth), mid.Authorize(auth.RoleAdmin))
-	app.Handle(http.MethodPut, version, "/users/:id", ugh.Update, mid.Authenticate(cfg.Auth), mid.Authorize(auth.RoleAdmin))
-	app.Handle(http.MethodDelete, version, "/users/:id", ugh.Delete, mid.Authenticate(cfg.Auth), mid.Authorize(auth.RoleAdmin))
+	app.Handle(http.MethodPost, version, "/users", ugh.Create, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermUsersWrite))
+	app.Handle(http.MethodPut, version, "/users/:id", ugh.Update, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermUsersWrite))
+	app.Handle(http.MethodDelete, version, "/users/:id", ugh.Delete, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermUsersWrite), mid.RequireMFA(cfg.MFARoles...))
+	app.Handle(http.MethodPut, version, "/users/:id/roles", ugh.AssignRoles, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermRolesManage), mid.RequireMFA(cfg.MFARoles...))
+
+	// Register multi-factor authentication endpoints.
+	mgh := v1MFAGrp.Handlers{
+		MFA:    mfaCore.NewCore(cfg.Log, cfg.DB),
+		Issuer: cfg.MFAIssuer,
+	}
+	app.Handle(http.MethodPost, version, "/users/mfa", mgh.Enroll, mid.Authenticate(cfg.Auth))
+	app.Handle(http.MethodPost, version, "/users/mfa/confirm", mgh.Confirm, mid.Authenticate(cfg.Auth))
+	app.Handle(http.MethodDelete, version, "/users/mfa", mgh.Disable, mid.Authenticate(cfg.Auth))
+
+	// Register role management endpoints.
+	rlh := v1RoleGrp.Handlers{
+		Role: roleCore.NewCore(cfg.Log, cfg.DB),
+		Auth: cfg.Auth,
+	}
+	app.Handle(http.MethodGet, version, "/permissions", rlh.Permissions, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermRolesManage))
+	app.Handle(http.MethodGet, version, "/roles", rlh.Query, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermRolesManage))
+	app.Handle(http.MethodGet, version, "/roles/:name", rlh.QueryByName, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermRolesManage))
+	app.Handle(http.MethodPost, version, "/roles", rlh.Create, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermRolesManage), mid.RequireMFA(cfg.MFARoles...))
+	app.Handle(http.MethodPut, version, "/roles/:name", rlh.Update, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermRolesManage), mid.RequireMFA(cfg.MFARoles...))
+	app.Handle(http.MethodDelete, version, "/roles/:name", rlh.Delete, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermRolesManage), mid.RequireMFA(cfg.MFARoles...))
+
+	// Register API key management endpoints.
+	akh := v1APIKeyGrp.Handlers{
+		APIKey: apikeyCore.NewCore(cfg.Log, cfg.DB),
+	}
+	app.Handle(http.MethodGet, version, "/apikeys", akh.Query, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermAPIKeysManage))
+	app.Handle(http.MethodPost, version, "/apikeys", akh.Create, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermAPIKeysManage), mid.RequireMFA(cfg.MFARoles...))
+	app.Handle(http.MethodDelete, version, "/apikeys/:id", akh.Revoke, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermAPIKeysManage))
+
+	// Register sales reporting endpoints.
+	rgh := v1ReportGrp.Handlers{
+		Log:    cfg.Log,
+		Report: reportCore.NewCore(cfg.Log, cfg.DB),
+	}
+	app.Handle(http.MethodGet, version, "/reports", rgh.List, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermReportsRead), mid.RequireScope(auth.ScopeReportsRead))
+	app.Handle(http.MethodGet, version, "/reports/:name", rgh.Query, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermReportsRead), mid.RequireScope(auth.ScopeReportsRead))
+
+	// Register dashboard management endpoints.
+	dgh := v1DashboardGrp.Handlers{
+		Dashboard: dashboardCore.NewCore(cfg.Log, cfg.DB),
+	}
+	app.Handle(http.MethodGet, version, "/dashboards", dgh.Query, mid.Authenticate(cfg.Auth))
+	app.Handle(http.MethodGet, version, "/dashboards/:id", dgh.QueryByID, mid.Authenticate(cfg.Auth))
+	app.Handle(http.MethodGet, version, "/dashboards/:id/data", dgh.Load, mid.Authenticate(cfg.Auth), mid.RequireScope(auth.ScopeReportsRead))
+	app.Handle(http.MethodPost, version, "/dashboards", dgh.Create, mid.Authenticate(cfg.Auth))
+	app.Handle(http.MethodPut, version, "/dashboards/:id", dgh.Update, mid.Authenticate(cfg.Auth))
+	app.Handle(http.MethodPut, version, "/dashboards/:id/shares", dgh.Share, mid.Authenticate(cfg.Auth))
+	app.Handle(http.MethodDelete, version, "/dashboards/:id", dgh.Delete, mid.Authenticate(cfg.Auth))
+
+	// Register scheduled delivery endpoints.
+	sgh := v1SubscriptionGrp.Handlers{
+		Subscription: subscriptionCore.NewCore(cfg.Log, cfg.DB, cfg.Mailer),
+	}
+	app.Handle(http.MethodGet, version, "/subscriptions", sgh.Query, mid.Authenticate(cfg.Auth))
+	app.Handle(http.MethodGet, version, "/subscriptions/:id", sgh.QueryByID, mid.Authenticate(cfg.Auth))
+	app.Handle(http.MethodPost, version, "/subscriptions", sgh.Create, mid.Authenticate(cfg.Auth))
+	app.Handle(http.MethodDelete, version, "/subscriptions/:id", sgh.Delete, mid.Authenticate(cfg.Auth))
+
+	// Register project scheduling endpoints.
+	pgh := v1ProjectGrp.Handlers{
+		Project: projectCore.NewCore(cfg.Log, cfg.DB),
+	}
+	app.Handle(http.MethodGet, version, "/projects", pgh.Query, mid.Authenticate(cfg.Auth), mid.RequireScope(auth.ScopeProjectsRead))
+	app.Handle(http.MethodGet, version, "/projects/:id", pgh.QueryByID, mid.Authenticate(cfg.Auth), mid.RequireScope(auth.ScopeProjectsRead))
+	app.Handle(http.MethodPost, version, "/projects", pgh.Create, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermProjectsWrite), mid.RequireScope(auth.ScopeProjectsWrite))
+	app.Handle(http.MethodPut, version, "/projects/:id", pgh.Update, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermProjectsWrite), mid.RequireScope(auth.ScopeProjectsWrite))
+	app.Handle(http.MethodDelete, version, "/projects/:id", pgh.Delete, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermProjectsWrite), mid.RequireScope(auth.ScopeProjectsWrite))
+	app.Handle(http.MethodGet, version, "/projects/:id/gantt", pgh.Gantt, mid.Authenticate(cfg.Auth), mid.RequireScope(auth.ScopeProjectsRead))
+	app.Handle(http.MethodGet, version, "/projects/:id/tasks", pgh.QueryTasks, mid.Authenticate(cfg.Auth), mid.RequireScope(auth.ScopeProjectsRead))
+	app.Handle(http.MethodPost, version, "/projects/:id/tasks", pgh.CreateTask, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermProjectsWrite), mid.RequireScope(auth.ScopeProjectsWrite))
+	// Task updates are permitted by the core so assignees can report progress.
+	app.Handle(http.MethodPut, version, "/projects/:id/tasks/:task_id", pgh.UpdateTask, mid.Authenticate(cfg.Auth), mid.RequireScope(auth.ScopeProjectsWrite))
+	app.Handle(http.MethodDelete, version, "/projects/:id/tasks/:task_id", pgh.DeleteTask, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermProjectsWrite), mid.RequireScope(auth.ScopeProjectsWrite))
+	app.Handle(http.MethodGet, version, "/projects/:id/dependencies", pgh.QueryDependencies, mid.Authenticate(cfg.Auth), mid.RequireScope(auth.ScopeProjectsRead))
+	app.Handle(http.MethodPost, version, "/projects/:id/dependencies", pgh.CreateDependency, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermProjectsWrite), mid.RequireScope(auth.ScopeProjectsWrite))
+	app.Handle(http.MethodDelete, version, "/projects/:id/dependencies/:task_id/:depends_on_id", pgh.DeleteDependency, mid.Authenticate(cfg.Auth), mid.RequirePermission(auth.PermProjectsWrite), mid.RequireScope(auth.ScopeProjectsWrite))
+
+	// Register search endpoints.
+	shh := v1SearchGrp.Handlers{
+		Search: searchCore.NewCore(cfg.Log, cfg.DB),
+	}
+	app.Handle(http.MethodGet, version, "/search", shh.Query, mid.Authenticate(cfg.Auth))
 
 	return app
 }
@@ -0,0 +1,103 @@
+// Package discoverygrp maintains the group of handlers that publish what
+// other services need to verify our tokens.
+package discoverygrp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/foundation/web"
+)
+
+// maxAge is how long, in seconds, clients may cache the documents. It is
+// kept short so keys added for rotation are picked up well before they are
+// used to sign tokens.
+const maxAge = 300
+
+// Handlers manages the set of discovery endpoints.
+type Handlers struct {
+	Auth   *auth.Auth
+	Issuer string
+}
+
+// configuration is the OpenID Provider Metadata document.
+type configuration struct {
+	Issuer                            string   `json:"issuer"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// JWKS publishes the public keys tokens are signed with as a JSON Web Key
+// Set.
+func (h Handlers) JWKS(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	jwks, err := h.Auth.JWKS()
+	if err != nil {
+		return fmt.Errorf("listing keys: %w", err)
+	}
+
+	return respond(ctx, w, r, jwks)
+}
+
+// OpenIDConfiguration publishes the OpenID discovery document describing
+// where the keys and token endpoint are found.
+func (h Handlers) OpenIDConfiguration(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	issuer := strings.TrimSuffix(h.Issuer, "/")
+
+	algs, err := h.Auth.Algorithms()
+	if err != nil {
+		return fmt.Errorf("listing algorithms: %w", err)
+	}
+
+	cfg := configuration{
+		Issuer:                            issuer,
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post"},
+		GrantTypesSupported:               []string{"password", "client_credentials", "refresh_token"},
+		ResponseTypesSupported:            []string{"token"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algs,
+		ScopesSupported:                   []string{auth.ScopeReportsRead, auth.ScopeProjectsRead, auth.ScopeProjectsWrite},
+		ClaimsSupported:                   []string{"iss", "sub", "exp", "iat", "jti", "tenant", "roles", "client_id", "scope"},
+	}
+
+	return respond(ctx, w, r, cfg)
+}
+
+// respond sends the document with headers that let clients cache it and
+// revalidate it cheaply once it is out of date.
+func respond(ctx context.Context, w http.ResponseWriter, r *http.Request, doc any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("encoding document: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		web.SetStatusCode(ctx, http.StatusNotModified)
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	return web.RespondRaw(ctx, w, data, "application/json", http.StatusOK)
+}
@@ -0,0 +1,233 @@
+// Package tokengrp maintains the group of handlers for the OAuth 2.0 token
+// and introspection endpoints.
+package tokengrp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	oauthCore "github.com/deliveranceTechSolutions/erp/business/core/oauth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/deliveranceTechSolutions/erp/foundation/web"
+)
+
+// Handlers manages the set of OAuth endpoints.
+type Handlers struct {
+	OAuth oauthCore.Core
+	Auth  *auth.Auth
+}
+
+// tokenResponse is the successful response of the token endpoint.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// introspection is the response of the introspection endpoint as described
+// in RFC 7662. Inactive tokens are described by active alone.
+type introspection struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientID  string   `json:"client_id,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	Audience  string   `json:"aud,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	ID        string   `json:"jti,omitempty"`
+	TenantID  string   `json:"tenant,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	AMR       []string `json:"amr,omitempty"`
+}
+
+// errorResponse is the error response of the token endpoint. It has the form
+// OAuth clients expect rather than the form used by the rest of the API.
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// Token issues an access token for the password, client_credentials and
+// refresh_token grants. Parameters are form encoded and the client
+// authenticates with HTTP Basic auth or the client_id and client_secret
+// parameters. Users who enabled multi-factor authentication send a code in
+// the mfa_code parameter with the password grant.
+func (h Handlers) Token(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	// Responses carrying tokens must never be cached.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := r.ParseForm(); err != nil {
+		return respondError(ctx, w, "invalid_request", err.Error(), http.StatusBadRequest)
+	}
+	form := r.PostForm
+
+	clientID, secret, basic := credentials(r)
+	if clientID == "" {
+		return respondError(ctx, w, "invalid_client", "client authentication is required", http.StatusUnauthorized)
+	}
+
+	var (
+		claims  auth.Claims
+		refresh string
+	)
+
+	switch grant := form.Get("grant_type"); grant {
+	case "password":
+		username, password := form.Get("username"), form.Get("password")
+		if username == "" || password == "" {
+			return respondError(ctx, w, "invalid_request", "username and password are required", http.StatusBadRequest)
+		}
+		claims, refresh, err = h.OAuth.Password(ctx, clientID, secret, username, password, form.Get("mfa_code"), form.Get("scope"), web.RemoteIP(r), v.Now)
+
+	case "client_credentials":
+		claims, err = h.OAuth.ClientCredentials(ctx, clientID, secret, form.Get("scope"), v.Now)
+
+	case "refresh_token":
+		token := form.Get("refresh_token")
+		if token == "" {
+			return respondError(ctx, w, "invalid_request", "refresh_token is required", http.StatusBadRequest)
+		}
+		claims, refresh, err = h.OAuth.Refresh(ctx, clientID, secret, token, v.Now)
+
+	case "":
+		return respondError(ctx, w, "invalid_request", "grant_type is required", http.StatusBadRequest)
+
+	default:
+		return respondError(ctx, w, "unsupported_grant_type", fmt.Sprintf("grant type %q is not supported", grant), http.StatusBadRequest)
+	}
+
+	if err != nil {
+		switch cause := validate.Cause(err); cause {
+		case oauthCore.ErrInvalidClient:
+			if basic {
+				w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+			}
+			return respondError(ctx, w, cause.Error(), "client authentication failed", http.StatusUnauthorized)
+		case oauthCore.ErrInvalidGrant, oauthCore.ErrUnauthorizedClient, oauthCore.ErrInvalidScope:
+			return respondError(ctx, w, cause.Error(), err.Error(), http.StatusBadRequest)
+		default:
+			return fmt.Errorf("issuing token: %w", err)
+		}
+	}
+
+	tkn := tokenResponse{
+		TokenType:    "Bearer",
+		ExpiresIn:    claims.ExpiresAt - v.Now.Unix(),
+		RefreshToken: refresh,
+		Scope:        claims.Scope,
+	}
+	tkn.AccessToken, err = h.Auth.GenerateToken(claims)
+	if err != nil {
+		return fmt.Errorf("generating token: %w", err)
+	}
+
+	return web.Respond(ctx, w, tkn, http.StatusOK)
+}
+
+// Introspect tells a client whether an access token is active and, when it
+// is, what the token carries, as described in RFC 7662. It lets services
+// that don't verify our tokens themselves ask about them instead. Clients
+// authenticate as they do at the token endpoint and only learn about the
+// tokens of their own tenant; any other token is reported as inactive.
+func (h Handlers) Introspect(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := r.ParseForm(); err != nil {
+		return respondError(ctx, w, "invalid_request", err.Error(), http.StatusBadRequest)
+	}
+
+	clientID, secret, basic := credentials(r)
+	if clientID == "" {
+		return respondError(ctx, w, "invalid_client", "client authentication is required", http.StatusUnauthorized)
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		return respondError(ctx, w, "invalid_request", "token is required", http.StatusBadRequest)
+	}
+
+	clt, err := h.OAuth.AuthenticateClient(ctx, clientID, secret)
+	if err != nil {
+		if validate.Cause(err) == oauthCore.ErrInvalidClient {
+			if basic {
+				w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+			}
+			return respondError(ctx, w, oauthCore.ErrInvalidClient.Error(), "client authentication failed", http.StatusUnauthorized)
+		}
+		return fmt.Errorf("authenticating client: %w", err)
+	}
+
+	inactive := introspection{Active: false}
+
+	claims, err := h.Auth.ValidateToken(token)
+	if err != nil || claims.TenantID != clt.TenantID {
+		return web.Respond(ctx, w, inactive, http.StatusOK)
+	}
+
+	revoked, err := h.Auth.Revoked(database.WithTenant(ctx, claims.TenantID), claims)
+	if err != nil {
+		return fmt.Errorf("checking revocation: %w", err)
+	}
+	if revoked {
+		return web.Respond(ctx, w, inactive, http.StatusOK)
+	}
+
+	resp := introspection{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "Bearer",
+		ExpiresAt: claims.ExpiresAt,
+		IssuedAt:  claims.IssuedAt,
+		NotBefore: claims.NotBefore,
+		Subject:   claims.Subject,
+		Audience:  claims.Audience,
+		Issuer:    claims.Issuer,
+		ID:        claims.Id,
+		TenantID:  claims.TenantID,
+		Roles:     claims.Roles,
+		AMR:       claims.AMR,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// credentials returns the id and secret the client authenticated with, from
+// HTTP Basic auth or the client_id and client_secret parameters. It reports
+// whether Basic auth was used. The form must already be parsed.
+func credentials(r *http.Request) (clientID string, secret string, basic bool) {
+	clientID, secret, basic = r.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+		return clientID, secret, true
+	}
+
+	return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret"), false
+}
+
+// respondError sends an error response in the form defined for the token
+// endpoint.
+func respondError(ctx context.Context, w http.ResponseWriter, code string, description string, status int) error {
+	resp := errorResponse{
+		Error:            code,
+		ErrorDescription: description,
+	}
+	return web.Respond(ctx, w, resp, status)
+}
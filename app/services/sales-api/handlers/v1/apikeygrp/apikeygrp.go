@@ -0,0 +1,96 @@
+// Package apikeygrp maintains the group of handlers for API key access.
+package apikeygrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	apikeyCore "github.com/deliveranceTechSolutions/erp/business/core/apikey"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/apikey"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/deliveranceTechSolutions/erp/foundation/web"
+)
+
+// Handlers manages the set of API key endpoints.
+type Handlers struct {
+	APIKey apikeyCore.Core
+}
+
+// Query returns the API keys of the tenant. The keys themselves are never
+// returned, only their prefixes.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	keys, err := h.APIKey.Query(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to query for api keys: %w", err)
+	}
+
+	return web.Respond(ctx, w, keys, http.StatusOK)
+}
+
+// Create issues a new API key. The key is only ever shown in this response.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	var nk apikey.NewAPIKey
+	if err := web.Decode(r, &nk); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	key, raw, err := h.APIKey.Create(ctx, claims, nk, v.Now)
+	if err != nil {
+		switch validate.Cause(err) {
+		case database.ErrInvalidID:
+			return validate.NewRequestError(err, http.StatusBadRequest)
+		case database.ErrNotFound:
+			return validate.NewRequestError(err, http.StatusNotFound)
+		case database.ErrForbidden:
+			return validate.NewRequestError(err, http.StatusForbidden)
+		default:
+			return fmt.Errorf("apikey[%+v]: %w", &nk, err)
+		}
+	}
+
+	resp := struct {
+		apikey.APIKey
+		Key string `json:"key"`
+	}{
+		APIKey: key,
+		Key:    raw,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusCreated)
+}
+
+// Revoke stops an API key from being accepted.
+func (h Handlers) Revoke(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	id := web.Param(r, "id")
+	if err := h.APIKey.Revoke(ctx, id, v.Now); err != nil {
+		switch validate.Cause(err) {
+		case database.ErrInvalidID:
+			return validate.NewRequestError(err, http.StatusBadRequest)
+		case database.ErrNotFound:
+			return validate.NewRequestError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("ID[%s]: %w", id, err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
@@ -0,0 +1,180 @@
+// Package dashboardgrp maintains the group of handlers for dashboard access.
+package dashboardgrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	dashboardCore "github.com/deliveranceTechSolutions/erp/business/core/dashboard"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/dashboard"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/deliveranceTechSolutions/erp/foundation/web"
+)
+
+// Handlers manages the set of dashboard endpoints.
+type Handlers struct {
+	Dashboard dashboardCore.Core
+}
+
+// Query returns the dashboards the user owns or that are shared with them.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	dashboards, err := h.Dashboard.Query(ctx, claims)
+	if err != nil {
+		return fmt.Errorf("unable to query for dashboards: %w", err)
+	}
+
+	return web.Respond(ctx, w, dashboards, http.StatusOK)
+}
+
+// QueryByID returns a dashboard by its ID.
+func (h Handlers) QueryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	id := web.Param(r, "id")
+	dsh, err := h.Dashboard.QueryByID(ctx, claims, id)
+	if err != nil {
+		return response(err, fmt.Errorf("ID[%s]: %w", id, err))
+	}
+
+	return web.Respond(ctx, w, dsh, http.StatusOK)
+}
+
+// Load returns a dashboard with the data for every chart on it.
+func (h Handlers) Load(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	id := web.Param(r, "id")
+	view, err := h.Dashboard.Load(ctx, claims, id, v.Now)
+	if err != nil {
+		return response(err, fmt.Errorf("ID[%s]: %w", id, err))
+	}
+
+	return web.Respond(ctx, w, view, http.StatusOK)
+}
+
+// Create adds a new dashboard owned by the user.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	var nd dashboard.NewDashboard
+	if err := web.Decode(r, &nd); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	dsh, err := h.Dashboard.Create(ctx, claims, nd, v.Now)
+	if err != nil {
+		return response(err, fmt.Errorf("dashboard[%+v]: %w", &nd, err))
+	}
+
+	return web.Respond(ctx, w, dsh, http.StatusCreated)
+}
+
+// Update updates a dashboard in the system.
+func (h Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	var upd dashboard.UpdateDashboard
+	if err := web.Decode(r, &upd); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	id := web.Param(r, "id")
+	dsh, err := h.Dashboard.Update(ctx, claims, id, upd, v.Now)
+	if err != nil {
+		return response(err, fmt.Errorf("ID[%s] Dashboard[%+v]: %w", id, &upd, err))
+	}
+
+	return web.Respond(ctx, w, dsh, http.StatusOK)
+}
+
+// Share replaces the users and roles a dashboard is shared with.
+func (h Handlers) Share(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	var sh dashboard.Share
+	if err := web.Decode(r, &sh); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	id := web.Param(r, "id")
+	dsh, err := h.Dashboard.Share(ctx, claims, id, sh, v.Now)
+	if err != nil {
+		return response(err, fmt.Errorf("ID[%s] Share[%+v]: %w", id, &sh, err))
+	}
+
+	return web.Respond(ctx, w, dsh, http.StatusOK)
+}
+
+// Delete removes a dashboard from the system.
+func (h Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	id := web.Param(r, "id")
+	if err := h.Dashboard.Delete(ctx, claims, id); err != nil {
+		return response(err, fmt.Errorf("ID[%s]: %w", id, err))
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// response maps the known store errors to their request errors and returns
+// the fallback for anything else.
+func response(err error, fallback error) error {
+	switch validate.Cause(err) {
+	case database.ErrInvalidID:
+		return validate.NewRequestError(err, http.StatusBadRequest)
+	case database.ErrNotFound:
+		return validate.NewRequestError(err, http.StatusNotFound)
+	case database.ErrForbidden:
+		return validate.NewRequestError(err, http.StatusForbidden)
+	}
+
+	return fallback
+}
@@ -0,0 +1,115 @@
+// Package mfagrp maintains the group of handlers for multi-factor
+// authentication.
+package mfagrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	mfaCore "github.com/deliveranceTechSolutions/erp/business/core/mfa"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/deliveranceTechSolutions/erp/foundation/web"
+)
+
+// Handlers manages the set of MFA endpoints.
+type Handlers struct {
+	MFA    mfaCore.Core
+	Issuer string
+}
+
+// Enroll starts the enrollment of the authenticated user. The response
+// holds the secret and the otpauth URI to add to an authenticator app.
+func (h Handlers) Enroll(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	enr, err := h.MFA.Enroll(ctx, claims, h.Issuer, v.Now)
+	if err != nil {
+		switch validate.Cause(err) {
+		case database.ErrNotFound:
+			return validate.NewRequestError(err, http.StatusNotFound)
+		case mfaCore.ErrEnabled:
+			return validate.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("enrolling: %w", err)
+		}
+	}
+
+	return web.Respond(ctx, w, enr, http.StatusCreated)
+}
+
+// Confirm enables the pending enrollment of the authenticated user with a
+// code from their authenticator app. The recovery codes are only ever shown
+// in this response.
+func (h Handlers) Confirm(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	var req struct {
+		Code string `json:"code" validate:"required"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	codes, err := h.MFA.Confirm(ctx, claims, req.Code, v.Now)
+	if err != nil {
+		switch validate.Cause(err) {
+		case mfaCore.ErrInvalidCode:
+			return validate.NewRequestError(err, http.StatusBadRequest)
+		case mfaCore.ErrNotPending, mfaCore.ErrEnabled:
+			return validate.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("confirming: %w", err)
+		}
+	}
+
+	resp := struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{
+		RecoveryCodes: codes,
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// Disable removes the authenticated user's enrollment. Once enabled it can
+// only be removed with a token issued with multi-factor authentication.
+func (h Handlers) Disable(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	if err := h.MFA.Disable(ctx, claims); err != nil {
+		switch validate.Cause(err) {
+		case mfaCore.ErrMFARequired:
+			return validate.NewRequestError(err, http.StatusForbidden)
+		default:
+			return fmt.Errorf("disabling: %w", err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
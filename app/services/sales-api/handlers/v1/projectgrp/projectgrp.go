@@ -0,0 +1,335 @@
+// Package projectgrp maintains the group of handlers for project, task and
+// schedule access.
+package projectgrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	projectCore "github.com/deliveranceTechSolutions/erp/business/core/project"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/project"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/deliveranceTechSolutions/erp/foundation/web"
+)
+
+// Handlers manages the set of project endpoints.
+type Handlers struct {
+	Project projectCore.Core
+}
+
+// Query returns a page of projects. The projects can be filtered, sorted and
+// paged through the query string, e.g. ?name=install&order_by=-start_date, and
+// the next and prev cursors are passed back as ?after= and ?before=.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ks, err := keyset(r)
+	if err != nil {
+		return validate.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		return validate.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	defaults := []database.OrderBy{
+		{Column: "start_date", Direction: database.ASC},
+		{Column: "name", Direction: database.ASC},
+	}
+	orderBy, err := database.ParseOrderBy(r.URL.Query().Get("order_by"), project.OrderByFields, defaults...)
+	if err != nil {
+		return validate.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	page, err := h.Project.Query(ctx, filter, orderBy, ks)
+	if err != nil {
+		if validate.Cause(err) == database.ErrInvalidCursor {
+			return validate.NewRequestError(err, http.StatusBadRequest)
+		}
+		return fmt.Errorf("unable to query for projects: %w", err)
+	}
+
+	return web.Respond(ctx, w, page, http.StatusOK)
+}
+
+// QueryByID returns a project by its ID.
+func (h Handlers) QueryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	prj, err := h.Project.QueryByID(ctx, id)
+	if err != nil {
+		return response(err, fmt.Errorf("ID[%s]: %w", id, err))
+	}
+
+	return web.Respond(ctx, w, prj, http.StatusOK)
+}
+
+// Create adds a new project to the system.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	var np project.NewProject
+	if err := web.Decode(r, &np); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	prj, err := h.Project.Create(ctx, claims, np, v.Now)
+	if err != nil {
+		return fmt.Errorf("project[%+v]: %w", &np, err)
+	}
+
+	return web.Respond(ctx, w, prj, http.StatusCreated)
+}
+
+// Update updates a project in the system.
+func (h Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var up project.UpdateProject
+	if err := web.Decode(r, &up); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	id := web.Param(r, "id")
+	prj, err := h.Project.Update(ctx, id, up, v.Now)
+	if err != nil {
+		return response(err, fmt.Errorf("ID[%s] Project[%+v]: %w", id, &up, err))
+	}
+
+	return web.Respond(ctx, w, prj, http.StatusOK)
+}
+
+// Delete removes a project from the system.
+func (h Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	if err := h.Project.Delete(ctx, id); err != nil {
+		return response(err, fmt.Errorf("ID[%s]: %w", id, err))
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// =============================================================================
+
+// QueryTasks returns every task in a project.
+func (h Handlers) QueryTasks(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	tasks, err := h.Project.QueryTasks(ctx, id)
+	if err != nil {
+		return response(err, fmt.Errorf("ID[%s]: %w", id, err))
+	}
+
+	return web.Respond(ctx, w, tasks, http.StatusOK)
+}
+
+// CreateTask adds a new task to a project.
+func (h Handlers) CreateTask(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var nt project.NewTask
+	if err := web.Decode(r, &nt); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	id := web.Param(r, "id")
+	tsk, err := h.Project.CreateTask(ctx, id, nt, v.Now)
+	if err != nil {
+		return response(err, fmt.Errorf("ID[%s] Task[%+v]: %w", id, &nt, err))
+	}
+
+	return web.Respond(ctx, w, tsk, http.StatusCreated)
+}
+
+// UpdateTask updates a task in a project. The task's assignee may report
+// progress on it without permission to write projects.
+func (h Handlers) UpdateTask(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	var ut project.UpdateTask
+	if err := web.Decode(r, &ut); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	id := web.Param(r, "id")
+	taskID := web.Param(r, "task_id")
+	tsk, err := h.Project.UpdateTask(ctx, claims, id, taskID, ut, v.Now)
+	if err != nil {
+		return response(err, fmt.Errorf("ID[%s] TaskID[%s] Task[%+v]: %w", id, taskID, &ut, err))
+	}
+
+	return web.Respond(ctx, w, tsk, http.StatusOK)
+}
+
+// DeleteTask removes a task from a project.
+func (h Handlers) DeleteTask(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	taskID := web.Param(r, "task_id")
+	if err := h.Project.DeleteTask(ctx, id, taskID); err != nil {
+		return response(err, fmt.Errorf("ID[%s] TaskID[%s]: %w", id, taskID, err))
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// QueryDependencies returns every dependency between the tasks in a project.
+func (h Handlers) QueryDependencies(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	deps, err := h.Project.QueryDependencies(ctx, id)
+	if err != nil {
+		return response(err, fmt.Errorf("ID[%s]: %w", id, err))
+	}
+
+	return web.Respond(ctx, w, deps, http.StatusOK)
+}
+
+// CreateDependency links two tasks in a project.
+func (h Handlers) CreateDependency(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var nd project.NewDependency
+	if err := web.Decode(r, &nd); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	id := web.Param(r, "id")
+	dep, err := h.Project.CreateDependency(ctx, id, nd, v.Now)
+	if err != nil {
+		return response(err, fmt.Errorf("ID[%s] Dependency[%+v]: %w", id, &nd, err))
+	}
+
+	return web.Respond(ctx, w, dep, http.StatusCreated)
+}
+
+// DeleteDependency removes the link between two tasks in a project.
+func (h Handlers) DeleteDependency(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	taskID := web.Param(r, "task_id")
+	dependsOnID := web.Param(r, "depends_on_id")
+	if err := h.Project.DeleteDependency(ctx, id, taskID, dependsOnID); err != nil {
+		return response(err, fmt.Errorf("ID[%s] TaskID[%s] DependsOnID[%s]: %w", id, taskID, dependsOnID, err))
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Gantt returns the project's tasks scheduled on a gantt chart together with
+// its critical path.
+func (h Handlers) Gantt(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := web.Param(r, "id")
+	gc, err := h.Project.Gantt(ctx, id)
+	if err != nil {
+		return response(err, fmt.Errorf("ID[%s]: %w", id, err))
+	}
+
+	return web.Respond(ctx, w, gc, http.StatusOK)
+}
+
+// Limits on the number of rows returned in a page.
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// keyset reads the page to return from the after or before cursor, the limit
+// and whether to count the total from the query string.
+func keyset(r *http.Request) (database.Keyset, error) {
+	values := r.URL.Query()
+
+	ks := database.Keyset{
+		After:  values.Get("after"),
+		Before: values.Get("before"),
+		Limit:  defaultLimit,
+	}
+
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			return database.Keyset{}, fmt.Errorf("invalid limit format [%s]", v)
+		}
+		ks.Limit = n
+	}
+
+	if v := values.Get("total"); v != "" {
+		total, err := strconv.ParseBool(v)
+		if err != nil {
+			return database.Keyset{}, fmt.Errorf("invalid total format [%s]", v)
+		}
+		ks.Total = total
+	}
+
+	return ks, nil
+}
+
+// parseFilter reads the project filters from the query string. Dates are
+// provided as 2006-01-02.
+func parseFilter(r *http.Request) (project.QueryFilter, error) {
+	values := r.URL.Query()
+
+	var filter project.QueryFilter
+	if name := values.Get("name"); name != "" {
+		filter.Name = &name
+	}
+
+	if v := values.Get("start_after"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return project.QueryFilter{}, fmt.Errorf("invalid start_after format [%s]", v)
+		}
+		filter.StartDateFrom = &t
+	}
+	if v := values.Get("start_before"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return project.QueryFilter{}, fmt.Errorf("invalid start_before format [%s]", v)
+		}
+		filter.StartDateUntil = &t
+	}
+
+	return filter, nil
+}
+
+// response maps the known store errors to their request errors and returns
+// the fallback for anything else.
+func response(err error, fallback error) error {
+	switch validate.Cause(err) {
+	case database.ErrInvalidID:
+		return validate.NewRequestError(err, http.StatusBadRequest)
+	case database.ErrNotFound:
+		return validate.NewRequestError(err, http.StatusNotFound)
+	case database.ErrForbidden:
+		return validate.NewRequestError(err, http.StatusForbidden)
+	case projectCore.ErrCycle:
+		return validate.NewRequestError(err, http.StatusConflict)
+	}
+
+	return fallback
+}
@@ -0,0 +1,176 @@
+// Package reportgrp maintains the group of handlers for report access.
+package reportgrp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	reportCore "github.com/deliveranceTechSolutions/erp/business/core/report"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/deliveranceTechSolutions/erp/foundation/canvas"
+	"github.com/deliveranceTechSolutions/erp/foundation/sheet"
+	"github.com/deliveranceTechSolutions/erp/foundation/web"
+	"go.uber.org/zap"
+)
+
+// Handlers manages the set of report endpoints.
+type Handlers struct {
+	Log    *zap.SugaredLogger
+	Report reportCore.Core
+}
+
+// List returns the names of the reports that can be requested.
+func (h Handlers) List(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, h.Report.Names(), http.StatusOK)
+}
+
+// Query returns the named report loaded into its chart. The time range and
+// grouping are provided as query parameters:
+// ?group=day|week|month&from=2019-01-01&to=2019-02-01&limit=10
+//
+// The response format is chosen by the report name's extension, then the
+// format query parameter and finally the Accept header. Images are drawn
+// with svg or png, e.g. /v1/reports/revenue.svg, and the data is downloaded
+// as a spreadsheet with csv or xlsx, e.g. /v1/reports/revenue?format=xlsx
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	params, err := parseParams(r)
+	if err != nil {
+		return validate.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	name, format, err := parseFormat(r, web.Param(r, "name"))
+	if err != nil {
+		return validate.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	chart, err := h.Report.Report(ctx, name, params, v.Now)
+	if err != nil {
+		if errors.Is(err, reportCore.ErrUnknownReport) {
+			return validate.NewRequestError(err, http.StatusNotFound)
+		}
+		return fmt.Errorf("report[%s]: %w", name, err)
+	}
+
+	switch format {
+	case canvas.FormatSVG, canvas.FormatPNG:
+		var buf bytes.Buffer
+		if err := reportCore.Render(&buf, chart, format); err != nil {
+			return fmt.Errorf("rendering report[%s]: %w", name, err)
+		}
+		return web.RespondRaw(ctx, w, buf.Bytes(), canvas.ContentType(format), http.StatusOK)
+
+	case sheet.FormatCSV, sheet.FormatXLSX:
+		if err := reportCore.CheckExport(chart, format); err != nil {
+			return fmt.Errorf("exporting report[%s]: %w", name, err)
+		}
+
+		export := func(w io.Writer) error {
+			return reportCore.Export(w, name, chart, format)
+		}
+
+		// The status has already been sent so the client can only learn the
+		// export failed by the download being cut short.
+		if err := web.RespondStream(ctx, w, sheet.ContentType(format), name+"."+format, http.StatusOK, export); err != nil {
+			h.Log.Errorw("ERROR", "traceid", v.TraceID, "ERROR", fmt.Errorf("exporting report[%s]: %w", name, err))
+			panic(http.ErrAbortHandler)
+		}
+		return nil
+	}
+
+	return web.Respond(ctx, w, chart, http.StatusOK)
+}
+
+// formats maps the media types a client may accept to a response format.
+var formats = map[string]string{
+	"image/svg+xml": canvas.FormatSVG,
+	"image/png":     canvas.FormatPNG,
+	"text/csv":      sheet.FormatCSV,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": sheet.FormatXLSX,
+}
+
+// parseFormat separates any format extension from the report name and
+// returns the format the response should be written in. An empty format
+// means JSON.
+func parseFormat(r *http.Request, name string) (string, string, error) {
+	switch ext := path.Ext(name); ext {
+	case ".svg", ".png", ".csv", ".xlsx":
+		return strings.TrimSuffix(name, ext), strings.TrimPrefix(ext, "."), nil
+	}
+
+	if format := r.URL.Query().Get("format"); format != "" {
+		switch format {
+		case "json":
+			return name, "", nil
+		case canvas.FormatSVG, canvas.FormatPNG, sheet.FormatCSV, sheet.FormatXLSX:
+			return name, format, nil
+		}
+		return "", "", fmt.Errorf("invalid format [%s]", format)
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		if mediaType == "application/json" {
+			break
+		}
+		if format, exists := formats[mediaType]; exists {
+			return name, format, nil
+		}
+	}
+
+	return name, "", nil
+}
+
+// parseParams reads the report parameters from the query string. Dates may be
+// provided as RFC3339 timestamps or as plain dates.
+func parseParams(r *http.Request) (reportCore.Params, error) {
+	values := r.URL.Query()
+
+	params := reportCore.Params{
+		Grouping: values.Get("group"),
+	}
+
+	var err error
+	if params.From, err = parseTime(values.Get("from")); err != nil {
+		return reportCore.Params{}, fmt.Errorf("invalid from format [%s]", values.Get("from"))
+	}
+	if params.To, err = parseTime(values.Get("to")); err != nil {
+		return reportCore.Params{}, fmt.Errorf("invalid to format [%s]", values.Get("to"))
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		if params.Limit, err = strconv.Atoi(limit); err != nil {
+			return reportCore.Params{}, fmt.Errorf("invalid limit format [%s]", limit)
+		}
+	}
+
+	return params, nil
+}
+
+// parseTime returns the zero time for an empty value so the core can apply
+// its defaults.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,143 @@
+// Package rolegrp maintains the group of handlers for role access.
+package rolegrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	roleCore "github.com/deliveranceTechSolutions/erp/business/core/role"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/role"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/deliveranceTechSolutions/erp/foundation/web"
+)
+
+// Handlers manages the set of role endpoints.
+type Handlers struct {
+	Role roleCore.Core
+	Auth *auth.Auth
+}
+
+// Query returns the roles defined in the tenant.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	roles, err := h.Role.Query(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to query for roles: %w", err)
+	}
+
+	return web.Respond(ctx, w, roles, http.StatusOK)
+}
+
+// QueryByName returns a role by its name.
+func (h Handlers) QueryByName(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	name := web.Param(r, "name")
+	rol, err := h.Role.QueryByName(ctx, name)
+	if err != nil {
+		switch validate.Cause(err) {
+		case database.ErrNotFound:
+			return validate.NewRequestError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("Name[%s]: %w", name, err)
+		}
+	}
+
+	return web.Respond(ctx, w, rol, http.StatusOK)
+}
+
+// Create defines a new role in the tenant.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	var nr role.NewRole
+	if err := web.Decode(r, &nr); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	rol, err := h.Role.Create(ctx, claims, nr, v.Now)
+	if err != nil {
+		switch validate.Cause(err) {
+		case roleCore.ErrBuiltinRole:
+			return validate.NewRequestError(err, http.StatusBadRequest)
+		case database.ErrForbidden:
+			return validate.NewRequestError(err, http.StatusForbidden)
+		default:
+			return fmt.Errorf("role[%+v]: %w", &nr, err)
+		}
+	}
+
+	return web.Respond(ctx, w, rol, http.StatusCreated)
+}
+
+// Update modifies a role. The change applies to the users assigned the role
+// on their next request.
+func (h Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	var ur role.UpdateRole
+	if err := web.Decode(r, &ur); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	name := web.Param(r, "name")
+	rol, err := h.Role.Update(ctx, claims, name, ur, v.Now)
+	if err != nil {
+		switch validate.Cause(err) {
+		case roleCore.ErrBuiltinRole:
+			return validate.NewRequestError(err, http.StatusBadRequest)
+		case database.ErrForbidden:
+			return validate.NewRequestError(err, http.StatusForbidden)
+		case database.ErrNotFound:
+			return validate.NewRequestError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("Name[%s] Role[%+v]: %w", name, &ur, err)
+		}
+	}
+	h.Auth.ForgetPermissions(claims.TenantID)
+
+	return web.Respond(ctx, w, rol, http.StatusOK)
+}
+
+// Delete removes a role, taking it away from every user assigned it.
+func (h Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	name := web.Param(r, "name")
+	if err := h.Role.Delete(ctx, name); err != nil {
+		switch validate.Cause(err) {
+		case roleCore.ErrBuiltinRole:
+			return validate.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("Name[%s]: %w", name, err)
+		}
+	}
+	h.Auth.ForgetPermissions(claims.TenantID)
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Permissions returns every permission roles can be granted.
+func (h Handlers) Permissions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, auth.Permissions, http.StatusOK)
+}
@@ -0,0 +1,73 @@
+// Package searchgrp maintains the group of handlers for search access.
+package searchgrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	searchCore "github.com/deliveranceTechSolutions/erp/business/core/search"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/search"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/deliveranceTechSolutions/erp/foundation/web"
+)
+
+// Limits on the number of results returned.
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// Handlers manages the set of search endpoints.
+type Handlers struct {
+	Search searchCore.Core
+}
+
+// Query returns the records matching ?q= the user is allowed to view, best
+// match first. Results can be narrowed with ?type=product,user and ?limit=.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	values := r.URL.Query()
+
+	sq := search.Query{
+		Text:  strings.TrimSpace(values.Get("q")),
+		Limit: defaultLimit,
+	}
+	for _, v := range values["type"] {
+		for _, typ := range strings.Split(v, ",") {
+			if typ = strings.TrimSpace(typ); typ != "" {
+				sq.Types = append(sq.Types, typ)
+			}
+		}
+	}
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			return validate.NewRequestError(fmt.Errorf("invalid limit format [%s]", v), http.StatusBadRequest)
+		}
+		sq.Limit = n
+	}
+
+	results, err := h.Search.Search(ctx, claims, sq)
+	if err != nil {
+		return fmt.Errorf("search[%+v]: %w", sq, err)
+	}
+
+	page := database.Page[search.Result]{
+		Items: results,
+	}
+	if page.Items == nil {
+		page.Items = []search.Result{}
+	}
+
+	return web.Respond(ctx, w, page, http.StatusOK)
+}
@@ -0,0 +1,108 @@
+// Package subscriptiongrp maintains the group of handlers for scheduled
+// delivery access.
+package subscriptiongrp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	subscriptionCore "github.com/deliveranceTechSolutions/erp/business/core/subscription"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/subscription"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/deliveranceTechSolutions/erp/foundation/web"
+)
+
+// Handlers manages the set of subscription endpoints.
+type Handlers struct {
+	Subscription subscriptionCore.Core
+}
+
+// Query returns the subscriptions the user owns.
+func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	subs, err := h.Subscription.Query(ctx, claims)
+	if err != nil {
+		return fmt.Errorf("unable to query for subscriptions: %w", err)
+	}
+
+	return web.Respond(ctx, w, subs, http.StatusOK)
+}
+
+// QueryByID returns a subscription by its ID.
+func (h Handlers) QueryByID(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	id := web.Param(r, "id")
+	sub, err := h.Subscription.QueryByID(ctx, claims, id)
+	if err != nil {
+		return response(err, fmt.Errorf("ID[%s]: %w", id, err))
+	}
+
+	return web.Respond(ctx, w, sub, http.StatusOK)
+}
+
+// Create subscribes the user to a report or dashboard.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	var ns subscription.NewSubscription
+	if err := web.Decode(r, &ns); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	sub, err := h.Subscription.Create(ctx, claims, ns, v.Now)
+	if err != nil {
+		return response(err, fmt.Errorf("subscription[%+v]: %w", &ns, err))
+	}
+
+	return web.Respond(ctx, w, sub, http.StatusCreated)
+}
+
+// Delete removes a subscription from the system.
+func (h Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	id := web.Param(r, "id")
+	if err := h.Subscription.Delete(ctx, claims, id); err != nil {
+		return response(err, fmt.Errorf("ID[%s]: %w", id, err))
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// response maps the known store errors to their request errors and returns
+// the fallback for anything else.
+func response(err error, fallback error) error {
+	switch validate.Cause(err) {
+	case database.ErrInvalidID:
+		return validate.NewRequestError(err, http.StatusBadRequest)
+	case database.ErrNotFound:
+		return validate.NewRequestError(err, http.StatusNotFound)
+	case database.ErrForbidden:
+		return validate.NewRequestError(err, http.StatusForbidden)
+	}
+
+	return fallback
+}
@@ -7,40 +7,62 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	federationCore "github.com/deliveranceTechSolutions/erp/business/core/federation"
+	mfaCore "github.com/deliveranceTechSolutions/erp/business/core/mfa"
+	sessionCore "github.com/deliveranceTechSolutions/erp/business/core/session"
 	userCore "github.com/deliveranceTechSolutions/erp/business/core/user"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/session"
 	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
 	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
 	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/oidc"
 	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
 	"github.com/deliveranceTechSolutions/erp/foundation/web"
 )
 
+// mfaHeader is the header users who enabled multi-factor authentication send
+// a code from their authenticator app, or a recovery code, in when asking
+// for a token.
+const mfaHeader = "X-MFA-Code"
+
 // Handlers manages the set of user enpoints.
 type Handlers struct {
-	User userCore.Core
-	Auth *auth.Auth
+	User       userCore.Core
+	Session    sessionCore.Core
+	Federation federationCore.Core
+	Auth       *auth.Auth
 }
 
-// Query returns a list of users with paging.
+// Query returns a page of users. The users can be filtered, sorted and paged
+// through the query string, e.g. ?name=bill&role=ADMIN&order_by=-name, and the
+// next and prev cursors are passed back as ?after= and ?before=.
 func (h Handlers) Query(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	page := web.Param(r, "page")
-	pageNumber, err := strconv.Atoi(page)
+	ks, err := keyset(r)
+	if err != nil {
+		return validate.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	filter, err := parseFilter(r)
 	if err != nil {
-		return validate.NewRequestError(fmt.Errorf("invalid page format [%s]", page), http.StatusBadRequest)
+		return validate.NewRequestError(err, http.StatusBadRequest)
 	}
-	rows := web.Param(r, "rows")
-	rowsPerPage, err := strconv.Atoi(rows)
+
+	orderBy, err := database.ParseOrderBy(r.URL.Query().Get("order_by"), user.OrderByFields, database.OrderBy{Column: "user_id", Direction: database.ASC})
 	if err != nil {
-		return validate.NewRequestError(fmt.Errorf("invalid rows format [%s]", rows), http.StatusBadRequest)
+		return validate.NewRequestError(err, http.StatusBadRequest)
 	}
 
-	users, err := h.User.Query(ctx, pageNumber, rowsPerPage)
+	page, err := h.User.Query(ctx, filter, orderBy, ks)
 	if err != nil {
+		if validate.Cause(err) == database.ErrInvalidCursor {
+			return validate.NewRequestError(err, http.StatusBadRequest)
+		}
 		return fmt.Errorf("unable to query for users: %w", err)
 	}
 
-	return web.Respond(ctx, w, users, http.StatusOK)
+	return web.Respond(ctx, w, page, http.StatusOK)
 }
 
 // QueryByID returns a user by its ID.
@@ -68,6 +90,128 @@ func (h Handlers) QueryByID(ctx context.Context, w http.ResponseWriter, r *http.
 	return web.Respond(ctx, w, usr, http.StatusOK)
 }
 
+// QueryByEmail returns a user by their email.
+func (h Handlers) QueryByEmail(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	req := struct {
+		Email string `json:"email" validate:"required,email"`
+	}{
+		Email: web.Param(r, "email"),
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	usr, err := h.User.QueryByEmail(ctx, claims, req.Email)
+	if err != nil {
+		switch validate.Cause(err) {
+		case database.ErrNotFound:
+			return validate.NewRequestError(err, http.StatusNotFound)
+		case database.ErrForbidden:
+			return validate.NewRequestError(err, http.StatusForbidden)
+		default:
+			return fmt.Errorf("Email[%s]: %w", req.Email, err)
+		}
+	}
+
+	return web.Respond(ctx, w, usr, http.StatusOK)
+}
+
+// QueryMe returns the authenticated user.
+func (h Handlers) QueryMe(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	usr, err := h.User.QueryByID(ctx, claims, claims.Subject)
+	if err != nil {
+		switch validate.Cause(err) {
+		case database.ErrInvalidID, database.ErrNotFound:
+			err := errors.New("token is not issued for a user")
+			return validate.NewRequestError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("ID[%s]: %w", claims.Subject, err)
+		}
+	}
+
+	return web.Respond(ctx, w, usr, http.StatusOK)
+}
+
+// UpdateMe changes the name and email of the authenticated user.
+func (h Handlers) UpdateMe(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	var up user.UpdateProfile
+	if err := web.Decode(r, &up); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	if err := h.User.UpdateProfile(ctx, claims, up, v.Now); err != nil {
+		switch validate.Cause(err) {
+		case database.ErrInvalidID, database.ErrNotFound:
+			err := errors.New("token is not issued for a user")
+			return validate.NewRequestError(err, http.StatusNotFound)
+		case userCore.ErrLocked:
+			return validate.NewRequestError(err, http.StatusTooManyRequests)
+		default:
+			return fmt.Errorf("ID[%s] Profile[%+v]: %w", claims.Subject, &up, err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// ChangePassword sets a new password for the authenticated user, who has to
+// give their current one too. Every session of the user is ended, so they
+// sign in again with the new password.
+func (h Handlers) ChangePassword(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	var cp user.ChangePassword
+	if err := web.Decode(r, &cp); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	revoked, err := h.User.ChangePassword(ctx, claims, cp, v.Now)
+	if err != nil {
+		switch validate.Cause(err) {
+		case database.ErrInvalidID, database.ErrNotFound:
+			err := errors.New("token is not issued for a user")
+			return validate.NewRequestError(err, http.StatusNotFound)
+		case userCore.ErrWrongPassword:
+			return validate.NewRequestError(err, http.StatusForbidden)
+		case userCore.ErrLocked:
+			return validate.NewRequestError(err, http.StatusTooManyRequests)
+		default:
+			return fmt.Errorf("ID[%s]: %w", claims.Subject, err)
+		}
+	}
+	h.revoke(revoked)
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
 // Create adds a new user to the system.
 func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	v, err := web.GetValues(ctx)
@@ -75,20 +219,29 @@ func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Req
 		return web.NewShutdownError("web value missing from context")
 	}
 
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
 	var nu user.NewUser
 	if err := web.Decode(r, &nu); err != nil {
 		return fmt.Errorf("unable to decode payload: %w", err)
 	}
 
-	usr, err := h.User.Create(ctx, nu, v.Now)
+	usr, err := h.User.Create(ctx, claims, nu, v.Now)
 	if err != nil {
+		if validate.Cause(err) == database.ErrForbidden {
+			return validate.NewRequestError(err, http.StatusForbidden)
+		}
 		return fmt.Errorf("user[%+v]: %w", &usr, err)
 	}
 
 	return web.Respond(ctx, w, usr, http.StatusCreated)
 }
 
-// Update updates a user in the system.
+// Update updates a user in the system. Setting a password ends every
+// session of the user.
 func (h Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	v, err := web.GetValues(ctx)
 	if err != nil {
@@ -106,7 +259,8 @@ func (h Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Req
 	}
 
 	id := web.Param(r, "id")
-	if err := h.User.Update(ctx, claims, id, upd, v.Now); err != nil {
+	revoked, err := h.User.Update(ctx, claims, id, upd, v.Now)
+	if err != nil {
 		switch validate.Cause(err) {
 		case database.ErrInvalidID:
 			return validate.NewRequestError(err, http.StatusBadRequest)
@@ -118,6 +272,7 @@ func (h Handlers) Update(ctx context.Context, w http.ResponseWriter, r *http.Req
 			return fmt.Errorf("ID[%s] User[%+v]: %w", id, &upd, err)
 		}
 	}
+	h.revoke(revoked)
 
 	return web.Respond(ctx, w, nil, http.StatusNoContent)
 }
@@ -146,7 +301,8 @@ func (h Handlers) Delete(ctx context.Context, w http.ResponseWriter, r *http.Req
 	return web.Respond(ctx, w, nil, http.StatusNoContent)
 }
 
-// Token provides an API token for the authenticated user.
+// Token provides an API token for the authenticated user. Users who enabled
+// multi-factor authentication send a code in the X-MFA-Code header too.
 func (h Handlers) Token(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	v, err := web.GetValues(ctx)
 	if err != nil {
@@ -159,25 +315,430 @@ func (h Handlers) Token(ctx context.Context, w http.ResponseWriter, r *http.Requ
 		return validate.NewRequestError(err, http.StatusUnauthorized)
 	}
 
-	claims, err := h.User.Authenticate(ctx, v.Now, email, pass)
+	claims, err := h.User.Authenticate(ctx, v.Now, email, pass, r.Header.Get(mfaHeader), web.RemoteIP(r))
 	if err != nil {
 		switch validate.Cause(err) {
-		case database.ErrNotFound:
-			return validate.NewRequestError(err, http.StatusNotFound)
-		case database.ErrAuthenticationFailure:
+		case database.ErrNotFound, database.ErrAuthenticationFailure:
+
+			// Unknown emails get the same response as wrong passwords so
+			// the endpoint can't be used to find out who has an account.
+			return validate.NewRequestError(database.ErrAuthenticationFailure, http.StatusUnauthorized)
+		case mfaCore.ErrInvalidCode:
+			return validate.NewRequestError(err, http.StatusUnauthorized)
+		case mfaCore.ErrCodeRequired:
+			w.Header().Set("WWW-Authenticate", mfaHeader)
 			return validate.NewRequestError(err, http.StatusUnauthorized)
+		case userCore.ErrUnverified:
+			return validate.NewRequestError(err, http.StatusForbidden)
+		case userCore.ErrLocked:
+			return validate.NewRequestError(err, http.StatusTooManyRequests)
 		default:
 			return fmt.Errorf("authenticating: %w", err)
 		}
 	}
 
-	var tkn struct {
-		Token string `json:"token"`
+	tkn := tokens{
+		ExpiresIn: claims.ExpiresAt - v.Now.Unix(),
+	}
+	tkn.Token, err = h.Auth.GenerateToken(claims)
+	if err != nil {
+		return fmt.Errorf("generating token: %w", err)
+	}
+
+	tkn.RefreshToken, err = h.Session.Start(ctx, claims, v.Now)
+	if err != nil {
+		return fmt.Errorf("starting session: %w", err)
+	}
+
+	return web.Respond(ctx, w, tkn, http.StatusOK)
+}
+
+// ForgotPassword emails a password reset link to the user with the email.
+// The response is the same whether or not anyone has the email.
+func (h Handlers) ForgotPassword(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	h.User.RequestPasswordReset(ctx, req.Email, v.Now)
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// ResetPassword sets a new password with the token from a password reset
+// email.
+func (h Handlers) ResetPassword(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req struct {
+		Token           string `json:"token" validate:"required"`
+		Password        string `json:"password" validate:"required"`
+		PasswordConfirm string `json:"password_confirm" validate:"eqfield=Password"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	if err := h.User.ResetPassword(ctx, req.Token, req.Password, req.PasswordConfirm, v.Now); err != nil {
+		switch validate.Cause(err) {
+		case userCore.ErrInvalidToken:
+			return validate.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("resetting password: %w", err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// RequestVerification emails a new verification link to the user with the
+// email. The response is the same whether or not anyone has the email.
+func (h Handlers) RequestVerification(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	h.User.RequestVerification(ctx, req.Email, v.Now)
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// VerifyEmail verifies a user's email address with the token from a
+// verification email.
+func (h Handlers) VerifyEmail(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req struct {
+		Token string `json:"token" validate:"required"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	if err := h.User.VerifyEmail(ctx, req.Token, v.Now); err != nil {
+		switch validate.Cause(err) {
+		case userCore.ErrInvalidToken:
+			return validate.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("verifying email: %w", err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// Exchange trades the ID token of a trusted external issuer for an access
+// token and the refresh token to continue the session with. The user is
+// provisioned the first time they sign in this way.
+func (h Handlers) Exchange(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req struct {
+		IDToken string `json:"id_token" validate:"required"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	claims, err := h.Federation.Exchange(ctx, req.IDToken, v.Now)
+	if err != nil {
+		switch validate.Cause(err) {
+		case oidc.ErrUnknownIssuer, oidc.ErrInvalidToken, federationCore.ErrNoEmail:
+			return validate.NewRequestError(err, http.StatusUnauthorized)
+		case federationCore.ErrNoRoles:
+			return validate.NewRequestError(err, http.StatusForbidden)
+		case federationCore.ErrEmailTaken:
+			return validate.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("exchanging: %w", err)
+		}
+	}
+
+	tkn := tokens{
+		ExpiresIn: claims.ExpiresAt - v.Now.Unix(),
 	}
 	tkn.Token, err = h.Auth.GenerateToken(claims)
 	if err != nil {
 		return fmt.Errorf("generating token: %w", err)
 	}
 
+	tkn.RefreshToken, err = h.Session.Start(ctx, claims, v.Now)
+	if err != nil {
+		return fmt.Errorf("starting session: %w", err)
+	}
+
 	return web.Respond(ctx, w, tkn, http.StatusOK)
 }
+
+// Refresh exchanges a refresh token for a new access token and the refresh
+// token to use next time. Each refresh token can only be used once; using
+// one again revokes the session it belongs to.
+func (h Handlers) Refresh(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+	if err := web.Decode(r, &req); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+	if err := validate.Check(req); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	claims, refresh, err := h.Session.Refresh(ctx, req.RefreshToken, "", v.Now)
+	if err != nil {
+		switch validate.Cause(err) {
+		case database.ErrAuthenticationFailure, sessionCore.ErrTokenReused:
+			return validate.NewRequestError(err, http.StatusUnauthorized)
+		default:
+			return fmt.Errorf("refreshing: %w", err)
+		}
+	}
+
+	tkn := tokens{
+		RefreshToken: refresh,
+		ExpiresIn:    claims.ExpiresAt - v.Now.Unix(),
+	}
+	tkn.Token, err = h.Auth.GenerateToken(claims)
+	if err != nil {
+		return fmt.Errorf("generating token: %w", err)
+	}
+
+	return web.Respond(ctx, w, tkn, http.StatusOK)
+}
+
+// Logout revokes the access token used for the request along with the
+// session it was issued for.
+func (h Handlers) Logout(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	revoked, err := h.Session.Logout(ctx, claims, v.Now)
+	if err != nil {
+		switch validate.Cause(err) {
+		case database.ErrInvalidID:
+			return validate.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("logging out: %w", err)
+		}
+	}
+	h.revoke(revoked)
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// AssignRoles replaces the roles of a user.
+func (h Handlers) AssignRoles(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	var body struct {
+		Roles []string `json:"roles"`
+	}
+	if err := web.Decode(r, &body); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	id := web.Param(r, "id")
+	if err := h.User.AssignRoles(ctx, claims, id, body.Roles, v.Now); err != nil {
+		switch validate.Cause(err) {
+		case database.ErrInvalidID:
+			return validate.NewRequestError(err, http.StatusBadRequest)
+		case database.ErrNotFound:
+			return validate.NewRequestError(err, http.StatusNotFound)
+		case database.ErrForbidden:
+			return validate.NewRequestError(err, http.StatusForbidden)
+		default:
+			return fmt.Errorf("ID[%s] Roles[%v]: %w", id, body.Roles, err)
+		}
+	}
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// RevokeSessions revokes every session of a user. Without an id in the path
+// the sessions of the authenticated user are revoked.
+func (h Handlers) RevokeSessions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	claims, err := auth.GetClaims(ctx)
+	if err != nil {
+		return errors.New("claims missing from context")
+	}
+
+	id := web.Param(r, "id")
+	if id == "" {
+		id = claims.Subject
+	}
+
+	revoked, err := h.Session.RevokeAll(ctx, claims, id, v.Now)
+	if err != nil {
+		switch validate.Cause(err) {
+		case database.ErrInvalidID:
+			return validate.NewRequestError(err, http.StatusBadRequest)
+		case database.ErrForbidden:
+			return validate.NewRequestError(err, http.StatusForbidden)
+		default:
+			return fmt.Errorf("ID[%s]: %w", id, err)
+		}
+	}
+	h.revoke(revoked)
+
+	return web.Respond(ctx, w, nil, http.StatusNoContent)
+}
+
+// =============================================================================
+
+// tokens is the response to a sign in or refresh.
+type tokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// revoke stops this instance accepting the revoked access tokens right away
+// rather than once its cache of the revocation list is out of date.
+func (h Handlers) revoke(revoked []session.Revoked) {
+	for _, rv := range revoked {
+		h.Auth.Revoke(rv.JTI, rv.DateExpires)
+	}
+}
+
+// Limits on the number of rows returned in a page.
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// keyset reads the page to return from the after or before cursor, the limit
+// and whether to count the total from the query string.
+func keyset(r *http.Request) (database.Keyset, error) {
+	values := r.URL.Query()
+
+	ks := database.Keyset{
+		After:  values.Get("after"),
+		Before: values.Get("before"),
+		Limit:  defaultLimit,
+	}
+
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			return database.Keyset{}, fmt.Errorf("invalid limit format [%s]", v)
+		}
+		ks.Limit = n
+	}
+
+	if v := values.Get("total"); v != "" {
+		total, err := strconv.ParseBool(v)
+		if err != nil {
+			return database.Keyset{}, fmt.Errorf("invalid total format [%s]", v)
+		}
+		ks.Total = total
+	}
+
+	return ks, nil
+}
+
+// parseFilter reads the user filters from the query string. Dates may be
+// provided as RFC3339 timestamps or as plain 2006-01-02 dates.
+func parseFilter(r *http.Request) (user.QueryFilter, error) {
+	values := r.URL.Query()
+
+	var filter user.QueryFilter
+	if name := values.Get("name"); name != "" {
+		filter.Name = &name
+	}
+	if email := values.Get("email"); email != "" {
+		filter.Email = &email
+	}
+	if role := values.Get("role"); role != "" {
+		filter.Role = &role
+	}
+
+	if v := values.Get("created_after"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return user.QueryFilter{}, fmt.Errorf("invalid created_after format [%s]", v)
+		}
+		filter.StartCreatedDate = &t
+	}
+	if v := values.Get("created_before"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return user.QueryFilter{}, fmt.Errorf("invalid created_before format [%s]", v)
+		}
+		filter.EndCreatedDate = &t
+	}
+
+	return filter, nil
+}
+
+// parseTime parses a RFC3339 timestamp or a date.
+func parseTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
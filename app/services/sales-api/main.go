@@ -6,20 +6,30 @@ import (
 	"expvar" // Calls init function.
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"runtime"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/ardanlabs/conf/v2"
 	"github.com/deliveranceTechSolutions/erp/app/services/sales-api/handlers"
+	apikeyCore "github.com/deliveranceTechSolutions/erp/business/core/apikey"
+	subscriptionCore "github.com/deliveranceTechSolutions/erp/business/core/subscription"
+	userCore "github.com/deliveranceTechSolutions/erp/business/core/user"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/role"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/session"
 	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
 	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/oidc"
+	"github.com/deliveranceTechSolutions/erp/business/sys/password"
 	"github.com/deliveranceTechSolutions/erp/foundation/keystore"
 	"github.com/deliveranceTechSolutions/erp/foundation/logger"
+	"github.com/deliveranceTechSolutions/erp/foundation/mail"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/zipkin"
@@ -81,17 +91,56 @@ func run(log *zap.SugaredLogger) error {
 			ShutdownTimeout time.Duration `conf:"default:20s"`
 		}
 		Auth struct {
-			KeysFolder string `conf:"default:zarf/keys/"`
-			ActiveKID  string `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
+			KeysFolder string        `conf:"default:zarf/keys/"`
+			ActiveKID  string        `conf:"default:54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"`
+			Issuer     string        `conf:"default:http://localhost:3000"`
+			Reload     time.Duration `conf:"default:1m"`
+			RetainKeys time.Duration `conf:"default:1h"`
+			MFARoles   []string      `conf:"default:ADMIN"`
+			MFAIssuer  string        `conf:"default:ERP"`
+			Issuers    string
+			Audience   string        `conf:"default:sales-api"`
+			TTL        time.Duration `conf:"default:1h"`
+			RoleTTL    map[string]time.Duration
+			Leeway     time.Duration `conf:"default:30s"`
 		}
 		DB struct {
-			User         string `conf:"default:postgres"`
-			Password     string `conf:"default:postgres,mask"`
-			Host         string `conf:"default:localhost"`
-			Name         string `conf:"default:postgres"`
-			MaxIdleConns int    `conf:"default:0"`
-			MaxOpenConns int    `conf:"default:0"`
-			DisableTLS   bool   `conf:"default:true"`
+			User             string `conf:"default:postgres"`
+			Password         string `conf:"default:postgres,mask"`
+			Host             string `conf:"default:localhost"`
+			Name             string `conf:"default:postgres"`
+			MaxIdleConns     int    `conf:"default:0"`
+			MaxOpenConns     int    `conf:"default:0"`
+			DisableTLS       bool   `conf:"default:true"`
+			RowLevelSecurity bool   `conf:"default:false"`
+		}
+		Password struct {
+			MinLength    int `conf:"default:8"`
+			BreachedList string
+			Hash         string `conf:"default:argon2id"`
+			ArgonTime    uint32 `conf:"default:3"`
+			ArgonMemory  uint32 `conf:"default:65536"`
+			ArgonThreads uint8  `conf:"default:4"`
+			BcryptCost   int    `conf:"default:10"`
+		}
+		Lockout struct {
+			Attempts   int           `conf:"default:5"`
+			IPAttempts int           `conf:"default:50"`
+			Delay      time.Duration `conf:"default:30s"`
+			MaxDelay   time.Duration `conf:"default:1h"`
+			Window     time.Duration `conf:"default:24h"`
+		}
+		Mail struct {
+			Host     string `conf:"default:localhost"`
+			Port     int    `conf:"default:1025"`
+			Username string
+			Password string `conf:"mask"`
+			From     string `conf:"default:reports@sales-api.local"`
+			LinkURL  string `conf:"default:http://localhost:8080"`
+		}
+		Scheduler struct {
+			Disabled bool          `conf:"default:false"`
+			Interval time.Duration `conf:"default:1m"`
 		}
 		Zipkin struct {
 			ReporterURI string  `conf:"default:http://localhost:9411/api/v2/spans"`
@@ -141,10 +190,41 @@ func run(log *zap.SugaredLogger) error {
 		return fmt.Errorf("reading keys: %w", err)
 	}
 
-	auth, err := auth.New(cfg.Auth.ActiveKID, ks)
+	// The active file in the keys folder, when there is one, names the active
+	// key in place of the configured one.
+	activeKID := cfg.Auth.ActiveKID
+	if kid := ks.ActiveKID(); kid != "" {
+		activeKID = kid
+	}
+
+	// Tokens are issued by and for this service, and only such tokens are
+	// accepted, so a token issued for another service sharing the keys isn't.
+	tokens := auth.Policy{
+		Issuer:   strings.TrimSuffix(cfg.Auth.Issuer, "/"),
+		Audience: cfg.Auth.Audience,
+		TTL:      cfg.Auth.TTL,
+		RoleTTL:  cfg.Auth.RoleTTL,
+		Leeway:   cfg.Auth.Leeway,
+	}
+
+	auth, err := auth.New(activeKID, ks)
 	if err != nil {
 		return fmt.Errorf("constructing auth: %w", err)
 	}
+	auth.SetPolicy(tokens)
+
+	// Users of the external issuers listed in the issuers file, when one is
+	// configured, can sign in with the ID tokens those issuers give them.
+	var federation *oidc.Verifier
+	if cfg.Auth.Issuers != "" {
+		issuers, err := oidc.LoadIssuers(cfg.Auth.Issuers)
+		if err != nil {
+			return err
+		}
+		if federation, err = oidc.NewVerifier(issuers, nil); err != nil {
+			return fmt.Errorf("constructing federation: %w", err)
+		}
+	}
 
 	// =========================================================================
 	// Database Support
@@ -153,13 +233,14 @@ func run(log *zap.SugaredLogger) error {
 	log.Infow("startup", "status", "initializing database support", "host", cfg.DB.Host)
 
 	db, err := database.Open(database.Config{
-		User:         cfg.DB.User,
-		Password:     cfg.DB.Password,
-		Host:         cfg.DB.Host,
-		Name:         cfg.DB.Name,
-		MaxIdleConns: cfg.DB.MaxIdleConns,
-		MaxOpenConns: cfg.DB.MaxOpenConns,
-		DisableTLS:   cfg.DB.DisableTLS,
+		User:             cfg.DB.User,
+		Password:         cfg.DB.Password,
+		Host:             cfg.DB.Host,
+		Name:             cfg.DB.Name,
+		MaxIdleConns:     cfg.DB.MaxIdleConns,
+		MaxOpenConns:     cfg.DB.MaxOpenConns,
+		DisableTLS:       cfg.DB.DisableTLS,
+		RowLevelSecurity: cfg.DB.RowLevelSecurity,
 	})
 	if err != nil {
 		return fmt.Errorf("connecting to db: %w", err)
@@ -169,6 +250,87 @@ func run(log *zap.SugaredLogger) error {
 		db.Close()
 	}()
 
+	// Access tokens are checked against the revocation list kept with the
+	// sessions.
+	auth.SetRevocationLookup(session.NewStore(log, db))
+
+	// The permissions granted by a token's roles are looked up in the roles
+	// its tenant defined.
+	auth.SetPermissionLookup(role.NewStore(log, db))
+
+	// Integrations can authenticate with the API keys issued to them.
+	auth.SetAPIKeyLookup(apikeyCore.NewCore(log, db))
+
+	// =========================================================================
+	// User Policies
+
+	// Passwords found in the breached list, when one is configured, can't be
+	// chosen by users.
+	passwords := password.Policy{
+		MinLength: cfg.Password.MinLength,
+	}
+	if cfg.Password.BreachedList != "" {
+		f, err := os.Open(cfg.Password.BreachedList)
+		if err != nil {
+			return fmt.Errorf("opening breached passwords: %w", err)
+		}
+		err = passwords.LoadBreached(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	// New passwords are hashed with the configured algorithm. Hashes made
+	// with another algorithm or other parameters are replaced as their users
+	// sign in.
+	var hasher password.Hasher
+	switch cfg.Password.Hash {
+	case password.SchemeArgon2id:
+		hasher.Algorithm = password.Argon2id{
+			Time:    cfg.Password.ArgonTime,
+			Memory:  cfg.Password.ArgonMemory,
+			Threads: cfg.Password.ArgonThreads,
+			SaltLen: password.DefaultArgon2id.SaltLen,
+			KeyLen:  password.DefaultArgon2id.KeyLen,
+		}
+	case password.SchemeBcrypt:
+		hasher.Algorithm = password.Bcrypt{Cost: cfg.Password.BcryptCost}
+	default:
+		return fmt.Errorf("unknown password hash algorithm %q", cfg.Password.Hash)
+	}
+
+	users := userCore.Config{
+		Password: passwords,
+		Hasher:   hasher,
+		Tokens:   tokens,
+		Lockout: userCore.Lockout{
+			Attempts:   cfg.Lockout.Attempts,
+			IPAttempts: cfg.Lockout.IPAttempts,
+			Delay:      cfg.Lockout.Delay,
+			MaxDelay:   cfg.Lockout.MaxDelay,
+			Window:     cfg.Lockout.Window,
+		},
+	}
+
+	// =========================================================================
+	// Mail Support
+
+	// The mailer is pointed at a local test SMTP server by default so
+	// deliveries can be inspected during development.
+	mailer := mail.NewSMTP(mail.Config{
+		Host:     cfg.Mail.Host,
+		Port:     cfg.Mail.Port,
+		Username: cfg.Mail.Username,
+		Password: cfg.Mail.Password,
+		From:     cfg.Mail.From,
+	})
+
+	// Users are sent verification and password reset emails linking to the
+	// pages of the web app.
+	users.Mailer = mailer
+	users.LinkURL = cfg.Mail.LinkURL
+
 	// =========================================================================
 	// Start Tracing Support
 
@@ -215,10 +377,16 @@ func run(log *zap.SugaredLogger) error {
 
 	// Construct the mux for the API calls.
 	apiMux := handlers.APIMux(handlers.APIMuxConfig{
-		Shutdown: shutdown,
-		Log:      log,
-		Auth:     auth,
-		DB:       db,
+		Shutdown:   shutdown,
+		Log:        log,
+		Auth:       auth,
+		Issuer:     tokens.Issuer,
+		DB:         db,
+		Mailer:     mailer,
+		User:       users,
+		MFARoles:   cfg.Auth.MFARoles,
+		MFAIssuer:  cfg.Auth.MFAIssuer,
+		Federation: federation,
 	})
 
 	// Construct a server to service the requests against the mux.
@@ -241,7 +409,52 @@ func run(log *zap.SugaredLogger) error {
 		serverErrors <- api.ListenAndServe()
 	}()
 
-	
+	// =========================================================================
+	// Start Scheduler
+
+	// Every instance runs the scheduler. Due subscriptions are claimed in the
+	// database before they are sent so each run is only delivered once.
+	schedCtx, schedCancel := context.WithCancel(context.Background())
+	schedDone := make(chan struct{})
+	defer func() {
+		schedCancel()
+		<-schedDone
+	}()
+
+	go func() {
+		defer close(schedDone)
+		if cfg.Scheduler.Disabled {
+			log.Infow("startup", "status", "scheduler disabled")
+			return
+		}
+
+		log.Infow("startup", "status", "scheduler started", "interval", cfg.Scheduler.Interval)
+		runScheduler(schedCtx, log, subscriptionCore.NewCore(log, db, mailer), cfg.Scheduler.Interval)
+		log.Infow("shutdown", "status", "scheduler stopped")
+	}()
+
+	// =========================================================================
+	// Start Key Reloader
+
+	// The keys folder is read again on every interval and on SIGHUP so keys
+	// can be rotated without a restart.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	keysCtx, keysCancel := context.WithCancel(context.Background())
+	keysDone := make(chan struct{})
+	defer func() {
+		keysCancel()
+		<-keysDone
+	}()
+
+	go func() {
+		defer close(keysDone)
+		log.Infow("startup", "status", "key reloader started", "folder", cfg.Auth.KeysFolder, "interval", cfg.Auth.Reload)
+		runKeyReloader(keysCtx, log, ks, auth, os.DirFS(cfg.Auth.KeysFolder), cfg.Auth.Reload, cfg.Auth.RetainKeys, reload)
+		log.Infow("shutdown", "status", "key reloader stopped")
+	}()
 
 	// =========================================================================
 	// Shutdown
@@ -292,6 +505,64 @@ func fileHandler(w http.ResponseWriter, r *http.Request) {
 
 // =============================================================================
 
+// runScheduler delivers due subscriptions every interval until the context
+// is canceled.
+func runScheduler(ctx context.Context, log *zap.SugaredLogger, core subscriptionCore.Core, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := core.Deliver(ctx, time.Now())
+		if err != nil {
+			log.Errorw("scheduler", "status", "delivery failed", "ERROR", err)
+		}
+		if sent > 0 {
+			log.Infow("scheduler", "status", "subscriptions delivered", "sent", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runKeyReloader reloads the key store from the folder on every interval and
+// whenever a signal is received, switching the active key when the folder
+// names a new one.
+func runKeyReloader(ctx context.Context, log *zap.SugaredLogger, ks *keystore.KeyStore, a *auth.Auth, fsys fs.FS, interval time.Duration, retain time.Duration, reload <-chan os.Signal) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-reload:
+			log.Infow("keys", "status", "reload requested")
+		}
+
+		if err := ks.Reload(fsys, retain); err != nil {
+			log.Errorw("keys", "status", "reload failed", "ERROR", err)
+			continue
+		}
+
+		kid := ks.ActiveKID()
+		if kid == "" || kid == a.ActiveKID() {
+			continue
+		}
+		if err := a.SetActiveKID(kid); err != nil {
+			log.Errorw("keys", "status", "switching active key failed", "kid", kid, "ERROR", err)
+			continue
+		}
+		log.Infow("keys", "status", "active key switched", "kid", kid)
+	}
+}
+
+// =============================================================================
+
 // startTracing configure open telemetery to be used with zipkin.
 func startTracing(serviceName string, reporterURI string, probability float64) (*trace.TracerProvider, error) {
 
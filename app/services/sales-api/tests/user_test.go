@@ -49,7 +49,7 @@ func TestProducts(t *testing.T) {
 	}
 
 	t.Run("getToken200", tests.getToken200)
-	t.Run("getToken200", tests.getToken404)
+	t.Run("getToken401", tests.getToken401)
 }
 
 func (ut *UserTests) getToken200(t *testing.T) {
@@ -82,8 +82,9 @@ func (ut *UserTests) getToken200(t *testing.T) {
 	}
 }
 
-// getToken401 ensures an unknown user can't generate a token.
-func (ut *UserTests) getToken404(t *testing.T) {
+// getToken401 ensures an unknown user can't generate a token, and can't tell
+// they are unknown from a wrong password.
+func (ut *UserTests) getToken401(t *testing.T) {
 	r := httptest.NewRequest(http.MethodGet, "/v1/users/token", nil)
 	w := httptest.NewRecorder()
 
@@ -95,10 +96,21 @@ func (ut *UserTests) getToken404(t *testing.T) {
 		testID := 0
 		t.Logf("\tTest %d:\tWhen fetching a token with an unrecognized email.", testID)
 		{
-			if w.Code != http.StatusNotFound {
-				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 404 for the response : %v", tests.Failed, testID, w.Code)
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a status code of 401 for the response : %v", tests.Failed, testID, w.Code)
 			}
-			t.Logf("\t%s\tTest %d:\tShould receive a status code of 404 for the response.", tests.Success, testID)
+			t.Logf("\t%s\tTest %d:\tShould receive a status code of 401 for the response.", tests.Success, testID)
+
+			wrong := httptest.NewRequest(http.MethodGet, "/v1/users/token", nil)
+			ww := httptest.NewRecorder()
+
+			wrong.SetBasicAuth("user@example.com", "some-password")
+			ut.app.ServeHTTP(ww, wrong)
+
+			if ww.Code != w.Code || ww.Body.String() != w.Body.String() {
+				t.Fatalf("\t%s\tTest %d:\tShould receive the same response as a wrong password : %v %s, %v %s", tests.Failed, testID, w.Code, w.Body, ww.Code, ww.Body)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive the same response as a wrong password.", tests.Success, testID)
 		}
 	}
 }
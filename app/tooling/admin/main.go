@@ -2,6 +2,10 @@ package main
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -10,10 +14,23 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	oauthCore "github.com/deliveranceTechSolutions/erp/business/core/oauth"
+	tenantCore "github.com/deliveranceTechSolutions/erp/business/core/tenant"
+	userCore "github.com/deliveranceTechSolutions/erp/business/core/user"
 	"github.com/deliveranceTechSolutions/erp/business/data/schema"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/client"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/tenant"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
 	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/password"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/deliveranceTechSolutions/erp/foundation/keystore"
+	"github.com/deliveranceTechSolutions/erp/foundation/logger"
 	"github.com/golang-jwt/jwt/v4"
 )
 
@@ -28,25 +45,63 @@ func main() {
 	 *	}
 	 */
 
-	err := migrate()
-	if err != nil {
+	if err := run(os.Args[1:]); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
 
-func seed() error {
-	cfg := database.Config{
-		User:         "postgres",
-		Password:     "postgres",
-		Host:         "localhost",
-		Name:         "postgres",
-		MaxIdleConns: 0,
-		MaxOpenConns: 0,
-		DisableTLS:   true,
+// run executes the command named by the first argument, migrating and seeding
+// the database when none is given.
+//
+//	admin migrate
+//	admin seed
+//	admin tenants list
+//	admin tenants create <name> <admin-name> <admin-email> <admin-password>
+//	admin tenants delete <tenant-id>
+//	admin clients list <tenant-id>
+//	admin clients create <tenant-id> <name> <grants> [scopes]
+//	admin clients delete <client-id>
+//	admin users hashes [argon2id|bcrypt]
+//	admin keys list [folder]
+//	admin keys rotate [folder] [RS256|ES256|ES384|EdDSA]
+//	admin keys activate <kid> [folder]
+func run(args []string) error {
+	if len(args) == 0 {
+		return migrate()
 	}
 
-	db, err := database.Open(cfg)
+	switch args[0] {
+	case "migrate":
+		return migrate()
+	case "seed":
+		return seed()
+	case "tenants":
+		return tenants(args[1:])
+	case "clients":
+		return clients(args[1:])
+	case "users":
+		return users(args[1:])
+	case "keys":
+		return keys(args[1:])
+	}
+
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+// dbConfig is the database every command connects to.
+var dbConfig = database.Config{
+	User:         "postgres",
+	Password:     "postgres",
+	Host:         "localhost",
+	Name:         "postgres",
+	MaxIdleConns: 0,
+	MaxOpenConns: 0,
+	DisableTLS:   true,
+}
+
+func seed() error {
+	db, err := database.Open(dbConfig)
 	if err != nil {
 		return fmt.Errorf("connect database: %w", err)
 	}
@@ -64,17 +119,7 @@ func seed() error {
 }
 
 func migrate() error {
-	cfg := database.Config{
-		User:         "postgres",
-		Password:     "postgres",
-		Host:         "localhost",
-		Name:         "postgres",
-		MaxIdleConns: 0,
-		MaxOpenConns: 0,
-		DisableTLS:   true,
-	}
-
-	db, err := database.Open(cfg)
+	db, err := database.Open(dbConfig)
 	if err != nil {
 		return fmt.Errorf("connect database: %w", err)
 	}
@@ -92,6 +137,325 @@ func migrate() error {
 	return seed()
 }
 
+// tenants provisions, lists and deletes tenants.
+func tenants(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: admin tenants list|create|delete")
+	}
+
+	db, err := database.Open(dbConfig)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	log, err := logger.New("ADMIN")
+	if err != nil {
+		return fmt.Errorf("constructing logger: %w", err)
+	}
+	defer log.Sync()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	core := tenantCore.NewCore(log, db)
+
+	switch args[0] {
+	case "list":
+		tenants, err := core.Query(ctx)
+		if err != nil {
+			return fmt.Errorf("query tenants: %w", err)
+		}
+		for _, tnt := range tenants {
+			fmt.Printf("%s\t%s\n", tnt.ID, tnt.Name)
+		}
+		return nil
+
+	case "create":
+		if len(args) != 5 {
+			return errors.New("usage: admin tenants create <name> <admin-name> <admin-email> <admin-password>")
+		}
+
+		nt := tenant.NewTenant{
+			Name: args[1],
+		}
+		nu := user.NewUser{
+			Name:            args[2],
+			Email:           args[3],
+			Password:        args[4],
+			PasswordConfirm: args[4],
+		}
+
+		tnt, usr, err := core.Provision(ctx, nt, nu, time.Now())
+		if err != nil {
+			return fmt.Errorf("provision tenant: %w", err)
+		}
+
+		fmt.Printf("tenant %s created with admin %s\n", tnt.ID, usr.ID)
+		return nil
+
+	case "delete":
+		if len(args) != 2 {
+			return errors.New("usage: admin tenants delete <tenant-id>")
+		}
+
+		if err := core.Delete(ctx, args[1]); err != nil {
+			return fmt.Errorf("delete tenant: %w", err)
+		}
+
+		fmt.Println("tenant deleted")
+		return nil
+	}
+
+	return fmt.Errorf("unknown tenants command %q", args[0])
+}
+
+// clients registers, lists and deletes the OAuth clients of a tenant. Grants
+// and scopes are comma separated lists.
+func clients(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: admin clients list|create|delete")
+	}
+
+	db, err := database.Open(dbConfig)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	log, err := logger.New("ADMIN")
+	if err != nil {
+		return fmt.Errorf("constructing logger: %w", err)
+	}
+	defer log.Sync()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	core := oauthCore.NewCore(log, db, userCore.Config{})
+
+	switch args[0] {
+	case "list":
+		if len(args) != 2 {
+			return errors.New("usage: admin clients list <tenant-id>")
+		}
+
+		clients, err := core.QueryClients(ctx, args[1])
+		if err != nil {
+			return fmt.Errorf("query clients: %w", err)
+		}
+		for _, clt := range clients {
+			fmt.Printf("%s\t%s\t%s\t%s\n", clt.ID, clt.Name, strings.Join(clt.Grants, ","), strings.Join(clt.Scopes, ","))
+		}
+		return nil
+
+	case "create":
+		if len(args) != 4 && len(args) != 5 {
+			return errors.New("usage: admin clients create <tenant-id> <name> <grants> [scopes]")
+		}
+
+		nc := client.NewClient{
+			Name:   args[2],
+			Grants: strings.Split(args[3], ","),
+		}
+		if len(args) == 5 {
+			nc.Scopes = strings.Split(args[4], ",")
+		}
+
+		clt, secret, err := core.CreateClient(ctx, args[1], nc, time.Now())
+		if err != nil {
+			return fmt.Errorf("create client: %w", err)
+		}
+
+		fmt.Printf("client_id: %s\nclient_secret: %s\n", clt.ID, secret)
+		return nil
+
+	case "delete":
+		if len(args) != 2 {
+			return errors.New("usage: admin clients delete <client-id>")
+		}
+
+		if err := core.DeleteClient(ctx, args[1]); err != nil {
+			return fmt.Errorf("delete client: %w", err)
+		}
+
+		fmt.Println("client deleted")
+		return nil
+	}
+
+	return fmt.Errorf("unknown clients command %q", args[0])
+}
+
+// users reports on the users of every tenant. The hashes command counts the
+// password hashes by scheme and how many are outdated compared to the
+// algorithm new hashes are made with, argon2id unless bcrypt is named. Both
+// are taken with their default parameters.
+func users(args []string) error {
+	if len(args) == 0 || args[0] != "hashes" || len(args) > 2 {
+		return errors.New("usage: admin users hashes [argon2id|bcrypt]")
+	}
+
+	var hasher password.Hasher
+	if len(args) == 2 {
+		switch args[1] {
+		case password.SchemeArgon2id:
+			hasher.Algorithm = password.DefaultArgon2id
+		case password.SchemeBcrypt:
+			hasher.Algorithm = password.DefaultBcrypt
+		default:
+			return fmt.Errorf("unknown password hash algorithm %q", args[1])
+		}
+	}
+
+	db, err := database.Open(dbConfig)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	log, err := logger.New("ADMIN")
+	if err != nil {
+		return fmt.Errorf("constructing logger: %w", err)
+	}
+	defer log.Sync()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	core := userCore.NewCore(log, db, userCore.Config{Hasher: hasher})
+
+	report, err := core.ReportHashes(ctx)
+	if err != nil {
+		return fmt.Errorf("report hashes: %w", err)
+	}
+
+	for _, scheme := range []string{password.SchemeArgon2id, password.SchemeBcrypt, "unknown"} {
+		fmt.Printf("%s\t%d\n", scheme, report.Schemes[scheme])
+	}
+	fmt.Printf("outdated\t%d of %d\n", report.Outdated, report.Total)
+
+	return nil
+}
+
+// keysFolder is the folder the service reads its signing keys from.
+const keysFolder = "zarf/keys/"
+
+// keys lists, generates and activates the signing keys in a keys folder. A
+// rotation generates the next key, which the service picks up and publishes
+// right away, and activates it once services verifying our tokens have had
+// time to fetch it. The previous key is kept until the tokens it signed have
+// expired.
+func keys(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: admin keys list|rotate|activate")
+	}
+
+	folder := func(i int) string {
+		if len(args) > i {
+			return args[i]
+		}
+		return keysFolder
+	}
+
+	switch args[0] {
+	case "list":
+		ks, err := keystore.NewFS(os.DirFS(folder(1)))
+		if err != nil {
+			return fmt.Errorf("reading keys: %w", err)
+		}
+
+		active := ks.ActiveKID()
+		for kid := range ks.PublicKeys() {
+			marker := ""
+			if kid == active {
+				marker = "\tactive"
+			}
+			fmt.Printf("%s%s\n", kid, marker)
+		}
+		return nil
+
+	case "rotate":
+		alg := auth.AlgRS256
+		if len(args) > 2 {
+			alg = args[2]
+		}
+
+		privateBlock, err := genPrivateKey(alg)
+		if err != nil {
+			return err
+		}
+
+		kid := validate.GenerateID()
+		name := filepath.Join(folder(1), kid+".pem")
+		if err := os.WriteFile(name, pem.EncodeToMemory(privateBlock), 0600); err != nil {
+			return fmt.Errorf("writing key: %w", err)
+		}
+
+		fmt.Printf("%s key %s generated\n", alg, kid)
+		fmt.Printf("activate it once it is published: admin keys activate %s\n", kid)
+		return nil
+
+	case "activate":
+		if len(args) < 2 {
+			return errors.New("usage: admin keys activate <kid> [folder]")
+		}
+		kid := args[1]
+
+		if _, err := os.Stat(filepath.Join(folder(2), kid+".pem")); err != nil {
+			return fmt.Errorf("finding key: %w", err)
+		}
+
+		// Write the file aside and rename it so the service never reads a
+		// partially written kid.
+		tmp := filepath.Join(folder(2), keystore.ActiveFile+".tmp")
+		if err := os.WriteFile(tmp, []byte(kid+"\n"), 0644); err != nil {
+			return fmt.Errorf("writing active file: %w", err)
+		}
+		if err := os.Rename(tmp, filepath.Join(folder(2), keystore.ActiveFile)); err != nil {
+			return fmt.Errorf("replacing active file: %w", err)
+		}
+
+		fmt.Printf("key %s activated\n", kid)
+		return nil
+	}
+
+	return fmt.Errorf("unknown keys command %q", args[0])
+}
+
+// genPrivateKey generates a private key for signing tokens with the
+// algorithm. RSA keys are encoded as PKCS #1 like the keys generated before
+// other algorithms were supported and the rest as PKCS #8.
+func genPrivateKey(alg string) (*pem.Block, error) {
+	var privateKey crypto.Signer
+	var err error
+	switch alg {
+	case auth.AlgRS256:
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("generating key: %w", err)
+		}
+		return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, nil
+	case auth.AlgES256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case auth.AlgES384:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case auth.AlgEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("generating key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("encoding key: %w", err)
+	}
+
+	return &pem.Block{Type: "PRIVATE KEY", Bytes: der}, nil
+}
+
 func genToken() error {
 
 	/*
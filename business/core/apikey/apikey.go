@@ -0,0 +1,170 @@
+// Package apikey provides an example of a core business API. API keys let
+// integrations that can't sign in interactively call the API on behalf of a
+// user, limited to the permissions the key was given.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	roleCore "github.com/deliveranceTechSolutions/erp/business/core/role"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/apikey"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// keyPrefix starts every API key so leaked keys are easy to recognize, for
+// example by secret scanners.
+const keyPrefix = "erp_"
+
+// Core manages the set of API's for API key access.
+type Core struct {
+	log    *zap.SugaredLogger
+	apikey apikey.Store
+	user   user.Store
+	role   roleCore.Core
+}
+
+// NewCore constructs a core for API key api access.
+func NewCore(log *zap.SugaredLogger, db *sqlx.DB) Core {
+	return Core{
+		log:    log,
+		apikey: apikey.NewStore(log, db),
+		user:   user.NewStore(log, db),
+		role:   roleCore.NewCore(log, db),
+	}
+}
+
+// Create issues a new API key for a user and returns it along with the key
+// itself, which is only stored hashed and can't be recovered later. Keys
+// can't be given permissions the claims creating them don't have.
+func (c Core) Create(ctx context.Context, claims auth.Claims, nk apikey.NewAPIKey, now time.Time) (apikey.APIKey, string, error) {
+	if err := roleCore.CheckPermissions(nk.Permissions); err != nil {
+		return apikey.APIKey{}, "", err
+	}
+	if !claims.HasPermission(nk.Permissions...) {
+		return apikey.APIKey{}, "", database.ErrForbidden
+	}
+	if nk.DateExpires != nil && !nk.DateExpires.After(now) {
+		return apikey.APIKey{}, "", validate.FieldErrors{{Field: "date_expires", Err: "must be in the future"}}
+	}
+
+	if _, err := c.user.QueryByID(ctx, claims, nk.UserID); err != nil {
+		return apikey.APIKey{}, "", fmt.Errorf("query user: %w", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return apikey.APIKey{}, "", fmt.Errorf("generating key: %w", err)
+	}
+	raw := keyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key, err := c.apikey.Create(ctx, nk, raw[:len(keyPrefix)+6], hash(raw), now)
+	if err != nil {
+		return apikey.APIKey{}, "", fmt.Errorf("create: %w", err)
+	}
+
+	return key, raw, nil
+}
+
+// Query retrieves the API keys of the tenant.
+func (c Core) Query(ctx context.Context) ([]apikey.APIKey, error) {
+	keys, err := c.apikey.Query(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Revoke stops the API key from being accepted.
+func (c Core) Revoke(ctx context.Context, keyID string, now time.Time) error {
+	if err := c.apikey.Revoke(ctx, keyID, now); err != nil {
+		return fmt.Errorf("revoke: %w", err)
+	}
+
+	return nil
+}
+
+// Authenticate returns the claims the API key acts with. They are issued to
+// the key's user and carry the key's permissions, less any the user no
+// longer has. It implements the auth.APIKeyLookup interface.
+func (c Core) Authenticate(ctx context.Context, raw string, now time.Time) (auth.Claims, error) {
+	if !strings.HasPrefix(raw, keyPrefix) {
+		return auth.Claims{}, database.ErrAuthenticationFailure
+	}
+
+	key, err := c.apikey.QueryByHash(database.WithSystem(ctx), hash(raw))
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return auth.Claims{}, database.ErrAuthenticationFailure
+		}
+		return auth.Claims{}, fmt.Errorf("query: %w", err)
+	}
+
+	if key.DateRevoked != nil || (key.DateExpires != nil && !now.Before(*key.DateExpires)) {
+		return auth.Claims{}, database.ErrAuthenticationFailure
+	}
+
+	ctx = database.WithTenant(ctx, key.TenantID)
+	ctx = database.WithUser(ctx, key.UserID, nil)
+
+	self := auth.Claims{StandardClaims: jwt.StandardClaims{Subject: key.UserID}}
+	usr, err := c.user.QueryByID(ctx, self, key.UserID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return auth.Claims{}, database.ErrAuthenticationFailure
+		}
+		return auth.Claims{}, fmt.Errorf("query user: %w", err)
+	}
+
+	held := auth.Claims{Roles: usr.Roles}
+	held.Permissions, err = c.role.Permissions(ctx, usr.Roles)
+	if err != nil {
+		return auth.Claims{}, fmt.Errorf("query permissions: %w", err)
+	}
+
+	var perms []string
+	for _, p := range key.Permissions {
+		if held.HasPermission(p) {
+			perms = append(perms, p)
+		}
+	}
+
+	if err := c.apikey.Use(ctx, key.ID, now); err != nil {
+		c.log.Errorw("apikey", "status", "recording use", "keyID", key.ID, "ERROR", err)
+	}
+
+	claims := auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:  usr.ID,
+			IssuedAt: key.DateCreated.Unix(),
+		},
+		TenantID:    usr.TenantID,
+		Permissions: perms,
+	}
+	if key.DateExpires != nil {
+		claims.ExpiresAt = key.DateExpires.Unix()
+	}
+
+	return claims, nil
+}
+
+// =============================================================================
+
+// hash returns the hash of an API key that is stored in its place.
+func hash(raw string) []byte {
+	sum := sha256.Sum256([]byte(raw))
+	return sum[:]
+}
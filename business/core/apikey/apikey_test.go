@@ -0,0 +1,123 @@
+package apikey_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apikeyCore "github.com/deliveranceTechSolutions/erp/business/core/apikey"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/apikey"
+	"github.com/deliveranceTechSolutions/erp/business/data/tests"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var dbc = tests.DBContainer{
+	Image: "postgres:13-alpine",
+	Port:  "5432",
+	Args:  []string{"-e", "POSTGRES_PASSWORD=postgres"},
+}
+
+func TestAPIKey(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	core := apikeyCore.NewCore(log, db)
+
+	const adminID = "5cf37266-3473-4006-984f-9325122678b7"
+	const userID = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+
+	admin := auth.Claims{
+		StandardClaims: jwt.StandardClaims{Subject: adminID},
+		TenantID:       tests.TenantID,
+		Roles:          []string{auth.RoleAdmin},
+	}
+
+	t.Log("Given the need to let integrations authenticate with API keys.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling an API key.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+			now := time.Now()
+
+			nk := apikey.NewAPIKey{
+				Name:        "Warehouse Scanner",
+				UserID:      adminID,
+				Permissions: []string{auth.PermProjectsWrite},
+			}
+			key, raw, err := core.Create(ctx, admin, nk, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an API key : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create an API key.", tests.Success, testID)
+
+			claims, err := core.Authenticate(context.Background(), raw, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to authenticate with the key : %s.", tests.Failed, testID, err)
+			}
+			if claims.Subject != adminID || claims.TenantID != tests.TenantID || len(claims.Roles) != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould act as the key's user without roles : %+v.", tests.Failed, testID, claims)
+			}
+			if !claims.HasPermission(auth.PermProjectsWrite) || claims.HasPermission(auth.PermUsersRead) {
+				t.Fatalf("\t%s\tTest %d:\tShould be granted only the key's permissions : %v.", tests.Failed, testID, claims.Permissions)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be granted only the key's permissions.", tests.Success, testID)
+
+			keys, err := core.Query(ctx)
+			if err != nil || len(keys) != 1 || keys[0].DateUsed == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould record when the key was used : %v %+v.", tests.Failed, testID, err, keys)
+			}
+			t.Logf("\t%s\tTest %d:\tShould record when the key was used.", tests.Success, testID)
+
+			if err := core.Revoke(ctx, key.ID, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to revoke the key : %s.", tests.Failed, testID, err)
+			}
+			if _, err := core.Authenticate(context.Background(), raw, now); !errors.Is(err, database.ErrAuthenticationFailure) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept a revoked key : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT accept a revoked key.", tests.Success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen an API key outlives what it was granted.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+			now := time.Now()
+
+			user := auth.Claims{
+				StandardClaims: jwt.StandardClaims{Subject: userID},
+				TenantID:       tests.TenantID,
+				Roles:          []string{auth.RoleUser},
+			}
+			nk := apikey.NewAPIKey{Name: "Connector", UserID: userID, Permissions: []string{auth.PermUsersRead}}
+			if _, _, err := core.Create(ctx, user, nk, now); !errors.Is(err, database.ErrForbidden) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to grant a key more than you have : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to grant a key more than you have.", tests.Success, testID)
+
+			expires := now.Add(time.Hour)
+			nk.DateExpires = &expires
+			_, raw, err := core.Create(ctx, admin, nk, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an API key : %s.", tests.Failed, testID, err)
+			}
+
+			claims, err := core.Authenticate(context.Background(), raw, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to authenticate with the key : %s.", tests.Failed, testID, err)
+			}
+			if claims.HasPermission(auth.PermUsersRead) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be granted permissions the user doesn't have : %v.", tests.Failed, testID, claims.Permissions)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be granted permissions the user doesn't have.", tests.Success, testID)
+
+			if _, err := core.Authenticate(context.Background(), raw, expires); !errors.Is(err, database.ErrAuthenticationFailure) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept an expired key : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT accept an expired key.", tests.Success, testID)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Package dashboard provides an example of a core business API. Dashboards are
+// stored per user and loaded together with the data for each of their charts.
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	reportCore "github.com/deliveranceTechSolutions/erp/business/core/report"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/dashboard"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// ErrUnknownSource occurs when a chart names a report that does not exist.
+var ErrUnknownSource = errors.New("chart source is not a known report")
+
+// Core manages the set of API's for dashboard access.
+type Core struct {
+	log       *zap.SugaredLogger
+	dashboard dashboard.Store
+	report    reportCore.Core
+}
+
+// NewCore constructs a core for dashboard api access.
+func NewCore(log *zap.SugaredLogger, db *sqlx.DB) Core {
+	return Core{
+		log:       log,
+		dashboard: dashboard.NewStore(log, db),
+		report:    reportCore.NewCore(log, db),
+	}
+}
+
+// View is a dashboard together with the loaded data for every chart.
+type View struct {
+	dashboard.Dashboard
+	Data []ChartView `json:"data"`
+}
+
+// ChartView is a single chart definition and the chart its source produced.
+type ChartView struct {
+	Name     string             `json:"name"`
+	Position dashboard.Position `json:"position"`
+	Chart    reportCore.Chart   `json:"chart"`
+}
+
+// Create inserts a new dashboard into the database. Charts show report data,
+// so only users permitted to read reports can add them.
+func (c Core) Create(ctx context.Context, claims auth.Claims, nd dashboard.NewDashboard, now time.Time) (dashboard.Dashboard, error) {
+	if err := c.checkSources(claims, nd.Charts); err != nil {
+		return dashboard.Dashboard{}, err
+	}
+
+	dsh, err := c.dashboard.Create(ctx, claims, nd, now)
+	if err != nil {
+		return dashboard.Dashboard{}, fmt.Errorf("create: %w", err)
+	}
+
+	return dsh, nil
+}
+
+// Update replaces a dashboard document in the database. Like Create, only
+// users permitted to read reports can set its charts.
+func (c Core) Update(ctx context.Context, claims auth.Claims, dashboardID string, ud dashboard.UpdateDashboard, now time.Time) (dashboard.Dashboard, error) {
+	if err := c.checkSources(claims, ud.Charts); err != nil {
+		return dashboard.Dashboard{}, err
+	}
+
+	dsh, err := c.dashboard.Update(ctx, claims, dashboardID, ud, now)
+	if err != nil {
+		return dashboard.Dashboard{}, fmt.Errorf("update: %w", err)
+	}
+
+	return dsh, nil
+}
+
+// Share replaces the set of users and roles a dashboard is shared with.
+func (c Core) Share(ctx context.Context, claims auth.Claims, dashboardID string, sh dashboard.Share, now time.Time) (dashboard.Dashboard, error) {
+	dsh, err := c.dashboard.Share(ctx, claims, dashboardID, sh, now)
+	if err != nil {
+		return dashboard.Dashboard{}, fmt.Errorf("share: %w", err)
+	}
+
+	return dsh, nil
+}
+
+// Delete removes a dashboard from the database.
+func (c Core) Delete(ctx context.Context, claims auth.Claims, dashboardID string) error {
+	if err := c.dashboard.Delete(ctx, claims, dashboardID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves the dashboards visible to the claims subject.
+func (c Core) Query(ctx context.Context, claims auth.Claims) ([]dashboard.Dashboard, error) {
+	dashboards, err := c.dashboard.Query(ctx, claims)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return dashboards, nil
+}
+
+// QueryByID gets the specified dashboard from the database.
+func (c Core) QueryByID(ctx context.Context, claims auth.Claims, dashboardID string) (dashboard.Dashboard, error) {
+	dsh, err := c.dashboard.QueryByID(ctx, claims, dashboardID)
+	if err != nil {
+		return dashboard.Dashboard{}, fmt.Errorf("query: %w", err)
+	}
+
+	return dsh, nil
+}
+
+// Load gets the specified dashboard and the data for every chart on it. The
+// data is report data, so a dashboard shared with a user who isn't permitted
+// to read reports can't be loaded by them.
+func (c Core) Load(ctx context.Context, claims auth.Claims, dashboardID string, now time.Time) (View, error) {
+	dsh, err := c.dashboard.QueryByID(ctx, claims, dashboardID)
+	if err != nil {
+		return View{}, fmt.Errorf("query: %w", err)
+	}
+
+	if len(dsh.Charts) > 0 && !claims.HasPermission(auth.PermReportsRead) {
+		return View{}, database.ErrForbidden
+	}
+
+	view := View{
+		Dashboard: dsh,
+		Data:      make([]ChartView, len(dsh.Charts)),
+	}
+
+	for i, ch := range dsh.Charts {
+		chart, err := c.report.Report(ctx, ch.Source, params(ch), now)
+		if err != nil {
+			return View{}, fmt.Errorf("loading chart[%s]: %w", ch.Name, err)
+		}
+
+		view.Data[i] = ChartView{
+			Name:     ch.Name,
+			Position: ch.Position,
+			Chart:    chart,
+		}
+	}
+
+	return view, nil
+}
+
+// =============================================================================
+
+// checkSources verifies the claims can read reports when there are charts,
+// and that every chart names a report that exists.
+func (c Core) checkSources(claims auth.Claims, charts dashboard.Charts) error {
+	if len(charts) > 0 && !claims.HasPermission(auth.PermReportsRead) {
+		return database.ErrForbidden
+	}
+
+	var fields validate.FieldErrors
+	for _, ch := range charts {
+		if !c.report.Exists(ch.Source) {
+			fields = append(fields, validate.FieldError{
+				Field: "source",
+				Err:   fmt.Sprintf("chart[%s] source[%s]: %s", ch.Name, ch.Source, ErrUnknownSource),
+			})
+		}
+	}
+
+	if fields != nil {
+		return fields
+	}
+
+	return nil
+}
+
+// params converts a chart's stored filters into report parameters.
+func params(ch dashboard.Chart) reportCore.Params {
+	p := reportCore.Params{
+		Grouping: ch.Filters.Group,
+		Limit:    ch.Filters.Limit,
+		Chart:    ch.Type,
+	}
+	if ch.Filters.From != nil {
+		p.From = *ch.Filters.From
+	}
+	if ch.Filters.To != nil {
+		p.To = *ch.Filters.To
+	}
+
+	return p
+}
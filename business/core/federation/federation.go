@@ -0,0 +1,218 @@
+// Package federation provides an example of a core business API. Users of
+// trusted external issuers sign in by exchanging the issuer's ID token for
+// one of ours, and are provisioned the first time they do.
+package federation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	roleCore "github.com/deliveranceTechSolutions/erp/business/core/role"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/identity"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/oidc"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Set of error variables for external tokens that can't be exchanged.
+var (
+	ErrNoRoles    = errors.New("no roles granted to the user")
+	ErrNoEmail    = errors.New("token has no email")
+	ErrEmailTaken = errors.New("email belongs to another account")
+)
+
+// Core manages the set of API's for federated sign in.
+type Core struct {
+	log      *zap.SugaredLogger
+	verifier *oidc.Verifier
+	identity identity.Store
+	user     user.Store
+	role     roleCore.Core
+	tokens   auth.Policy
+}
+
+// NewCore constructs a core for federated sign in that trusts the issuers
+// of the verifier. Our tokens are issued under the tokens policy.
+func NewCore(log *zap.SugaredLogger, db *sqlx.DB, verifier *oidc.Verifier, tokens auth.Policy) Core {
+	return Core{
+		log:      log,
+		verifier: verifier,
+		identity: identity.NewStore(log, db),
+		user:     user.NewStore(log, db),
+		role:     roleCore.NewCore(log, db),
+		tokens:   tokens,
+	}
+}
+
+// Exchange verifies the ID token of a trusted issuer and returns the claims
+// for an access token of ours. The user is provisioned into the issuer's
+// tenant the first time they sign in, and their roles are kept in step with
+// the groups the issuer lists on every sign in. The authentication methods
+// the issuer reports are carried over, so multi-factor authentication done
+// at the issuer counts as our own.
+func (c Core) Exchange(ctx context.Context, token string, now time.Time) (auth.Claims, error) {
+	id, err := c.verifier.Verify(ctx, token, now)
+	if err != nil {
+		return auth.Claims{}, fmt.Errorf("verify: %w", err)
+	}
+
+	if len(id.Roles) == 0 {
+		return auth.Claims{}, ErrNoRoles
+	}
+
+	ctx = database.WithTenant(ctx, id.TenantID)
+
+	// The issuer's mapping hands out roles the way an admin would, but only
+	// roles the tenant defines.
+	issuer := auth.Claims{Roles: []string{auth.RoleAdmin}}
+	if err := c.role.CheckAssignable(ctx, issuer, id.Roles); err != nil {
+		return auth.Claims{}, fmt.Errorf("check roles: %w", err)
+	}
+
+	usr, err := c.provision(ctx, id, now)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+
+	claims := auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Id:      validate.GenerateID(),
+			Subject: usr.ID,
+		},
+		TenantID: usr.TenantID,
+		Roles:    usr.Roles,
+		AMR:      id.AMR,
+	}
+
+	return c.tokens.Stamp(claims, now), nil
+}
+
+// provision returns the user linked to the identity with the roles the
+// identity was granted. A user is linked the first time the identity is
+// seen, either a new user or an existing one of the tenant with the email
+// when the issuer verified the email.
+func (c Core) provision(ctx context.Context, id oidc.Identity, now time.Time) (user.User, error) {
+	link, err := c.identity.QueryBySubject(ctx, id.Issuer, id.Subject)
+	switch {
+	case err == nil:
+	case errors.Is(err, database.ErrNotFound):
+		if link, err = c.link(ctx, id, now); err != nil {
+			return user.User{}, err
+		}
+	default:
+		return user.User{}, fmt.Errorf("query identity: %w", err)
+	}
+
+	self := auth.Claims{StandardClaims: jwt.StandardClaims{Subject: link.UserID}}
+	usr, err := c.user.QueryByID(ctx, self, link.UserID)
+	if err != nil {
+		return user.User{}, fmt.Errorf("query user: %w", err)
+	}
+
+	if !equal(usr.Roles, id.Roles) {
+		if err := c.user.Update(ctx, self, usr.ID, user.UpdateUser{Roles: id.Roles}, now); err != nil {
+			return user.User{}, fmt.Errorf("update roles: %w", err)
+		}
+		usr.Roles = id.Roles
+	}
+
+	return usr, nil
+}
+
+// link links the identity to the user with its email, creating the user
+// when there isn't one. Only a verified email is trusted to name an
+// existing user, otherwise anyone able to set the email at the issuer could
+// take the user over.
+func (c Core) link(ctx context.Context, id oidc.Identity, now time.Time) (identity.Identity, error) {
+	if id.Email == "" {
+		return identity.Identity{}, ErrNoEmail
+	}
+
+	usr, err := c.user.QueryByEmailAnyTenant(ctx, id.Email)
+	switch {
+	case err == nil:
+		if !id.EmailVerified || usr.TenantID != id.TenantID {
+			return identity.Identity{}, ErrEmailTaken
+		}
+
+	case errors.Is(err, database.ErrNotFound):
+		if usr, err = c.create(ctx, id, now); err != nil {
+			return identity.Identity{}, err
+		}
+
+	default:
+		return identity.Identity{}, fmt.Errorf("query email: %w", err)
+	}
+
+	link, err := c.identity.Create(ctx, id.Issuer, id.Subject, usr.ID, now)
+	if err != nil {
+		return identity.Identity{}, fmt.Errorf("create identity: %w", err)
+	}
+
+	return link, nil
+}
+
+// create provisions a user for the identity. The user signs in through the
+// issuer, so they are given a random password nobody knows.
+func (c Core) create(ctx context.Context, id oidc.Identity, now time.Time) (user.User, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return user.User{}, fmt.Errorf("generating password: %w", err)
+	}
+	pass := base64.RawURLEncoding.EncodeToString(b)
+
+	name := id.Name
+	if name == "" {
+		name = id.Email
+	}
+
+	nu := user.NewUser{
+		Name:            name,
+		Email:           id.Email,
+		Roles:           id.Roles,
+		Password:        pass,
+		PasswordConfirm: pass,
+	}
+
+	usr, err := c.user.Create(ctx, nu, now)
+	if err != nil {
+		return user.User{}, fmt.Errorf("create user: %w", err)
+	}
+
+	if id.EmailVerified {
+		if err := c.user.Verify(ctx, usr.ID, now); err != nil {
+			return user.User{}, fmt.Errorf("verify user: %w", err)
+		}
+		usr.DateVerified = &now
+	}
+
+	return usr, nil
+}
+
+// equal reports whether the two sets of roles are the same.
+func equal(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	have := make(map[string]bool, len(a))
+	for _, r := range a {
+		have[r] = true
+	}
+	for _, r := range b {
+		if !have[r] {
+			return false
+		}
+	}
+
+	return true
+}
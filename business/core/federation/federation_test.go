@@ -0,0 +1,150 @@
+package federation_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	federationCore "github.com/deliveranceTechSolutions/erp/business/core/federation"
+	"github.com/deliveranceTechSolutions/erp/business/data/tests"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/oidc"
+	"github.com/deliveranceTechSolutions/erp/foundation/keystore"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var dbc = tests.DBContainer{
+	Image: "postgres:13-alpine",
+	Port:  "5432",
+	Args:  []string{"-e", "POSTGRES_PASSWORD=postgres"},
+}
+
+func TestExchange(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	const issuerURL = "https://sso.example.com"
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("creating key: %v", err)
+	}
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksFile, "k1", key)
+
+	verifier, err := oidc.NewVerifier([]oidc.Issuer{
+		{
+			URL:          issuerURL,
+			Audience:     "erp",
+			JWKS:         jwksFile,
+			TenantID:     tests.TenantID,
+			Roles:        map[string][]string{"erp-admins": {auth.RoleAdmin}},
+			DefaultRoles: []string{auth.RoleUser},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("creating verifier: %v", err)
+	}
+
+	core := federationCore.NewCore(log, db, verifier, auth.Policy{})
+
+	t.Log("Given the need to sign in users of a trusted external issuer.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen exchanging the issuer's ID tokens.", testID)
+		{
+			ctx := context.Background()
+			now := time.Now()
+
+			token := func(sub, email string, verified bool, groups ...string) string {
+				claims := jwt.MapClaims{
+					"iss":            issuerURL,
+					"aud":            "erp",
+					"sub":            sub,
+					"email":          email,
+					"email_verified": verified,
+					"name":           "Jane Doe",
+					"groups":         groups,
+					"exp":            now.Add(time.Hour).Unix(),
+				}
+				tkn := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+				tkn.Header["kid"] = "k1"
+				str, err := tkn.SignedString(key)
+				if err != nil {
+					t.Fatalf("signing token: %v", err)
+				}
+				return str
+			}
+
+			claims, err := core.Exchange(ctx, token("jane", "jane@example.com", true), now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to exchange a token for a new user : %s.", tests.Failed, testID, err)
+			}
+			if claims.TenantID != tests.TenantID || !reflect.DeepEqual([]string(claims.Roles), []string{auth.RoleUser}) {
+				t.Fatalf("\t%s\tTest %d:\tShould provision the user into the issuer's tenant : %+v.", tests.Failed, testID, claims)
+			}
+			t.Logf("\t%s\tTest %d:\tShould provision the user into the issuer's tenant.", tests.Success, testID)
+
+			again, err := core.Exchange(ctx, token("jane", "jane@example.com", true, "erp-admins"), now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to exchange a token again : %s.", tests.Failed, testID, err)
+			}
+			if again.Subject != claims.Subject {
+				t.Fatalf("\t%s\tTest %d:\tShould sign in the same user again : %s != %s.", tests.Failed, testID, again.Subject, claims.Subject)
+			}
+			t.Logf("\t%s\tTest %d:\tShould sign in the same user again.", tests.Success, testID)
+
+			if !reflect.DeepEqual([]string(again.Roles), []string{auth.RoleAdmin, auth.RoleUser}) {
+				t.Fatalf("\t%s\tTest %d:\tShould map the user's groups to roles : %v.", tests.Failed, testID, again.Roles)
+			}
+			t.Logf("\t%s\tTest %d:\tShould map the user's groups to roles.", tests.Success, testID)
+
+			const userID = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+
+			if _, err := core.Exchange(ctx, token("gopher", "user@example.com", false), now); !errors.Is(err, federationCore.ErrEmailTaken) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT link an existing user by an unverified email : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT link an existing user by an unverified email.", tests.Success, testID)
+
+			linked, err := core.Exchange(ctx, token("gopher", "user@example.com", true), now)
+			if err != nil || linked.Subject != userID {
+				t.Fatalf("\t%s\tTest %d:\tShould link an existing user by a verified email : %v, %s.", tests.Failed, testID, err, linked.Subject)
+			}
+			t.Logf("\t%s\tTest %d:\tShould link an existing user by a verified email.", tests.Success, testID)
+
+			other := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"iss": "https://evil.example.com", "sub": "jane"})
+			str, _ := other.SignedString(key)
+			if _, err := core.Exchange(ctx, str, now); !errors.Is(err, oidc.ErrUnknownIssuer) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT exchange the tokens of other issuers : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT exchange the tokens of other issuers.", tests.Success, testID)
+		}
+	}
+}
+
+// writeJWKS publishes the public key of the signer to the file.
+func writeJWKS(t *testing.T, path string, kid string, signer crypto.Signer) {
+	a, err := auth.New(kid, keystore.NewMap(map[string]crypto.Signer{kid: signer}))
+	if err != nil {
+		t.Fatalf("creating authenticator: %v", err)
+	}
+	jwks, err := a.JWKS()
+	if err != nil {
+		t.Fatalf("building key set: %v", err)
+	}
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatalf("marshaling key set: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("writing key set: %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+// Package mfa provides an example of a core business API. Users enroll in
+// TOTP multi-factor authentication with an authenticator app and are then
+// asked for a code from it, or one of their recovery codes, to sign in.
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/mfa"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/totp"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Set of error variables for multi-factor authentication.
+var (
+	ErrEnabled      = errors.New("multi-factor authentication is already enabled")
+	ErrNotPending   = errors.New("multi-factor authentication enrollment is not pending")
+	ErrCodeRequired = errors.New("multi-factor authentication code required")
+	ErrInvalidCode  = errors.New("invalid multi-factor authentication code")
+	ErrMFARequired  = errors.New("token must be issued with multi-factor authentication")
+)
+
+// recoveryCodes is the number of recovery codes a user is given. Each can
+// be used once in place of a code from their authenticator app.
+const recoveryCodes = 10
+
+// Enrollment is what a user adds to their authenticator app, either by
+// typing in the secret or by scanning the URI as a QR code.
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// Core manages the set of API's for MFA access.
+type Core struct {
+	log  *zap.SugaredLogger
+	mfa  mfa.Store
+	user user.Store
+}
+
+// NewCore constructs a core for MFA api access.
+func NewCore(log *zap.SugaredLogger, db *sqlx.DB) Core {
+	return Core{
+		log:  log,
+		mfa:  mfa.NewStore(log, db),
+		user: user.NewStore(log, db),
+	}
+}
+
+// Enroll starts the enrollment of the user the claims were issued to. The
+// issuer names the service in their authenticator app. The enrollment
+// stays pending until it is confirmed.
+func (c Core) Enroll(ctx context.Context, claims auth.Claims, issuer string, now time.Time) (Enrollment, error) {
+	usr, err := c.user.QueryByID(ctx, claims, claims.Subject)
+	if err != nil {
+		return Enrollment{}, fmt.Errorf("query user: %w", err)
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	if _, err := c.mfa.Create(ctx, usr.ID, secret, now); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return Enrollment{}, ErrEnabled
+		}
+		return Enrollment{}, fmt.Errorf("create: %w", err)
+	}
+
+	enr := Enrollment{
+		Secret: secret,
+		URI:    totp.URI(issuer, usr.Email, secret),
+	}
+
+	return enr, nil
+}
+
+// Confirm enables the pending enrollment of the user the claims were issued
+// to with a code from their authenticator app. It returns the user's
+// recovery codes, which can't be recovered later.
+func (c Core) Confirm(ctx context.Context, claims auth.Claims, code string, now time.Time) ([]string, error) {
+	m, err := c.mfa.QueryByUser(ctx, claims.Subject)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrNotPending
+		}
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	if m.DateEnabled != nil {
+		return nil, ErrEnabled
+	}
+
+	step, ok := totp.Validate(m.Secret, code, now)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes := make([]string, recoveryCodes)
+	hashes := make([]string, recoveryCodes)
+	for i := range codes {
+		if codes[i], err = newRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = hash(codes[i])
+	}
+
+	if err := c.mfa.Enable(ctx, claims.Subject, hashes, step, now); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil, ErrNotPending
+		}
+		return nil, fmt.Errorf("enable: %w", err)
+	}
+
+	return codes, nil
+}
+
+// Disable removes the enrollment of the user the claims were issued to.
+// Once enabled it can only be removed with a token that was issued with
+// multi-factor authentication.
+func (c Core) Disable(ctx context.Context, claims auth.Claims) error {
+	m, err := c.mfa.QueryByUser(ctx, claims.Subject)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("query: %w", err)
+	}
+
+	if m.DateEnabled != nil && !claims.MFA() {
+		return ErrMFARequired
+	}
+
+	if err := c.mfa.Delete(ctx, claims.Subject); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Verify checks the second factor of a user who signed in with the claims.
+// Users who haven't enabled multi-factor authentication have nothing to
+// check and their claims are returned as they are. Otherwise the code must
+// be one from their authenticator app, which can be used only once, or one
+// of their recovery codes, and the returned claims record it was given.
+func (c Core) Verify(ctx context.Context, claims auth.Claims, code string, now time.Time) (auth.Claims, error) {
+	ctx = database.WithTenant(ctx, claims.TenantID)
+	ctx = database.WithUser(ctx, claims.Subject, claims.Roles)
+
+	m, err := c.mfa.QueryByUser(ctx, claims.Subject)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return claims, nil
+		}
+		return auth.Claims{}, fmt.Errorf("query: %w", err)
+	}
+	if m.DateEnabled == nil {
+		return claims, nil
+	}
+
+	if code == "" {
+		return auth.Claims{}, ErrCodeRequired
+	}
+
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(m.Secret, code, now)
+		if !ok {
+			return auth.Claims{}, ErrInvalidCode
+		}
+		if err := c.mfa.UseStep(ctx, claims.Subject, step); err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return auth.Claims{}, ErrInvalidCode
+			}
+			return auth.Claims{}, fmt.Errorf("use step: %w", err)
+		}
+
+		claims.AMR = append(claims.AMR, auth.AMROTP, auth.AMRMFA)
+		return claims, nil
+	}
+
+	if err := c.mfa.UseRecoveryCode(ctx, claims.Subject, hash(code)); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return auth.Claims{}, ErrInvalidCode
+		}
+		return auth.Claims{}, fmt.Errorf("use recovery code: %w", err)
+	}
+
+	c.log.Infow("mfa", "status", "recovery code used", "userID", claims.Subject)
+
+	claims.AMR = append(claims.AMR, auth.AMRMFA)
+	return claims, nil
+}
+
+// =============================================================================
+
+// newRecoveryCode generates a recovery code that is easy to read and type,
+// like "k7wqm-2xr4d".
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating recovery code: %w", err)
+	}
+
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// hash returns the hash a recovery code is stored as. Codes are compared
+// ignoring case, spaces and dashes.
+func hash(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,102 @@
+package mfa_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mfaCore "github.com/deliveranceTechSolutions/erp/business/core/mfa"
+	"github.com/deliveranceTechSolutions/erp/business/data/tests"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/totp"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var dbc = tests.DBContainer{
+	Image: "postgres:13-alpine",
+	Port:  "5432",
+	Args:  []string{"-e", "POSTGRES_PASSWORD=postgres"},
+}
+
+func TestMFA(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	core := mfaCore.NewCore(log, db)
+
+	const adminID = "5cf37266-3473-4006-984f-9325122678b7"
+
+	claims := auth.Claims{
+		StandardClaims: jwt.StandardClaims{Subject: adminID},
+		TenantID:       tests.TenantID,
+		Roles:          []string{auth.RoleAdmin},
+		AMR:            []string{auth.AMRPassword},
+	}
+
+	t.Log("Given the need to ask users for a second factor.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen enrolling a user in TOTP.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+			now := time.Now()
+
+			if _, err := core.Verify(context.Background(), claims, "", now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT ask users who aren't enrolled for a code : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT ask users who aren't enrolled for a code.", tests.Success, testID)
+
+			enr, err := core.Enroll(ctx, claims, "ERP", now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to enroll : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to enroll.", tests.Success, testID)
+
+			code, err := totp.Code(enr.Secret, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a code : %s.", tests.Failed, testID, err)
+			}
+			recovery, err := core.Confirm(ctx, claims, code, now)
+			if err != nil || len(recovery) == 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to confirm the enrollment : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to confirm the enrollment.", tests.Success, testID)
+
+			if _, err := core.Verify(context.Background(), claims, "", now); !errors.Is(err, mfaCore.ErrCodeRequired) {
+				t.Fatalf("\t%s\tTest %d:\tShould ask for a code once enrolled : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould ask for a code once enrolled.", tests.Success, testID)
+
+			if _, err := core.Verify(context.Background(), claims, code, now); !errors.Is(err, mfaCore.ErrInvalidCode) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept a code twice : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT accept a code twice.", tests.Success, testID)
+
+			later := now.Add(totp.Period)
+			code, _ = totp.Code(enr.Secret, later)
+			mfa, err := core.Verify(context.Background(), claims, code, later)
+			if err != nil || !mfa.MFA() {
+				t.Fatalf("\t%s\tTest %d:\tShould record the second factor in the claims : %v %v.", tests.Failed, testID, err, mfa.AMR)
+			}
+			t.Logf("\t%s\tTest %d:\tShould record the second factor in the claims.", tests.Success, testID)
+
+			if _, err := core.Verify(context.Background(), claims, recovery[0], later); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept a recovery code : %s.", tests.Failed, testID, err)
+			}
+			if _, err := core.Verify(context.Background(), claims, recovery[0], later); !errors.Is(err, mfaCore.ErrInvalidCode) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept a recovery code twice : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept each recovery code once.", tests.Success, testID)
+
+			if err := core.Disable(ctx, claims); !errors.Is(err, mfaCore.ErrMFARequired) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT disable without a second factor : %v.", tests.Failed, testID, err)
+			}
+			if err := core.Disable(ctx, mfa); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould disable with a second factor : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould disable only with a second factor.", tests.Success, testID)
+		}
+	}
+}
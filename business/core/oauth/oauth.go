@@ -0,0 +1,234 @@
+// Package oauth provides an example of a core business API. It issues tokens
+// to registered OAuth clients for the grants and scopes they are allowed.
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	mfaCore "github.com/deliveranceTechSolutions/erp/business/core/mfa"
+	sessionCore "github.com/deliveranceTechSolutions/erp/business/core/session"
+	userCore "github.com/deliveranceTechSolutions/erp/business/core/user"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/client"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Set of error variables for the ways a token request can fail. They match
+// the error codes of the OAuth 2.0 token endpoint.
+var (
+	ErrInvalidClient      = errors.New("invalid_client")
+	ErrInvalidGrant       = errors.New("invalid_grant")
+	ErrUnauthorizedClient = errors.New("unauthorized_client")
+	ErrInvalidScope       = errors.New("invalid_scope")
+)
+
+// Core manages the set of API's for OAuth access.
+type Core struct {
+	log     *zap.SugaredLogger
+	client  client.Store
+	user    userCore.Core
+	session sessionCore.Core
+	tokens  auth.Policy
+}
+
+// NewCore constructs a core for OAuth api access. Users signing in follow
+// the policies of the user configuration, and tokens are issued under its
+// tokens policy.
+func NewCore(log *zap.SugaredLogger, db *sqlx.DB, cfg userCore.Config) Core {
+	return Core{
+		log:     log,
+		client:  client.NewStore(log, db),
+		user:    userCore.NewCore(log, db, cfg),
+		session: sessionCore.NewCore(log, db, cfg.Tokens),
+		tokens:  cfg.Tokens,
+	}
+}
+
+// Password issues a token to the client on behalf of the user signing in
+// with their email and password, and code when they enabled multi-factor
+// authentication, from the address ip. A refresh token is returned as well
+// when the client is allowed to use them.
+func (c Core) Password(ctx context.Context, clientID, secret, email, password, code, scope, ip string, now time.Time) (auth.Claims, string, error) {
+	clt, err := c.authenticate(ctx, clientID, secret, client.GrantPassword)
+	if err != nil {
+		return auth.Claims{}, "", err
+	}
+
+	granted, err := grant(clt, scope)
+	if err != nil {
+		return auth.Claims{}, "", err
+	}
+
+	claims, err := c.user.Authenticate(ctx, now, email, password, code, ip)
+	if err != nil {
+		switch cause := validate.Cause(err); cause {
+		case database.ErrNotFound, database.ErrAuthenticationFailure:
+			return auth.Claims{}, "", ErrInvalidGrant
+		case userCore.ErrLocked, userCore.ErrUnverified, mfaCore.ErrCodeRequired, mfaCore.ErrInvalidCode:
+			return auth.Claims{}, "", fmt.Errorf("%s: %w", cause, ErrInvalidGrant)
+		default:
+			return auth.Claims{}, "", fmt.Errorf("authenticate: %w", err)
+		}
+	}
+
+	// Clients can only sign in the users of their own tenant.
+	if claims.TenantID != clt.TenantID {
+		return auth.Claims{}, "", ErrInvalidGrant
+	}
+
+	claims.ClientID = clt.ID
+	claims.Scope = granted
+
+	// Without a refresh token the access token is still recorded against the
+	// user, so ending their sessions revokes it too.
+	if !clt.Allows(client.GrantRefreshToken) {
+		if err := c.session.Record(ctx, claims, now); err != nil {
+			return auth.Claims{}, "", fmt.Errorf("record session: %w", err)
+		}
+		return claims, "", nil
+	}
+
+	refresh, err := c.session.Start(ctx, claims, now)
+	if err != nil {
+		return auth.Claims{}, "", fmt.Errorf("start session: %w", err)
+	}
+
+	return claims, refresh, nil
+}
+
+// ClientCredentials issues a token to the client on its own behalf. The
+// token's subject is the client and it carries no roles, only scopes.
+func (c Core) ClientCredentials(ctx context.Context, clientID, secret, scope string, now time.Time) (auth.Claims, error) {
+	clt, err := c.authenticate(ctx, clientID, secret, client.GrantClientCredentials)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+
+	granted, err := grant(clt, scope)
+	if err != nil {
+		return auth.Claims{}, err
+	}
+
+	claims := auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Id:      validate.GenerateID(),
+			Subject: clt.ID,
+		},
+		TenantID: clt.TenantID,
+		ClientID: clt.ID,
+		Scope:    granted,
+	}
+
+	return c.tokens.Stamp(claims, now), nil
+}
+
+// Refresh continues a session the client started with the password grant.
+func (c Core) Refresh(ctx context.Context, clientID, secret, refresh string, now time.Time) (auth.Claims, string, error) {
+	clt, err := c.authenticate(ctx, clientID, secret, client.GrantRefreshToken)
+	if err != nil {
+		return auth.Claims{}, "", err
+	}
+
+	claims, next, err := c.session.Refresh(ctx, refresh, clt.ID, now)
+	if err != nil {
+		switch validate.Cause(err) {
+		case database.ErrAuthenticationFailure, sessionCore.ErrTokenReused:
+			return auth.Claims{}, "", ErrInvalidGrant
+		default:
+			return auth.Claims{}, "", fmt.Errorf("refresh: %w", err)
+		}
+	}
+
+	return claims, next, nil
+}
+
+// AuthenticateClient verifies the secret of a client calling an endpoint
+// other than the token endpoint, such as token introspection.
+func (c Core) AuthenticateClient(ctx context.Context, clientID, secret string) (client.Client, error) {
+	clt, err := c.client.Authenticate(ctx, clientID, secret)
+	if err != nil {
+		if validate.Cause(err) == database.ErrAuthenticationFailure {
+			return client.Client{}, ErrInvalidClient
+		}
+		return client.Client{}, fmt.Errorf("authenticate client: %w", err)
+	}
+
+	return clt, nil
+}
+
+// CreateClient registers a new client with the tenant and returns it along
+// with its secret.
+func (c Core) CreateClient(ctx context.Context, tenantID string, nc client.NewClient, now time.Time) (client.Client, string, error) {
+	clt, secret, err := c.client.Create(database.WithTenant(ctx, tenantID), nc, now)
+	if err != nil {
+		return client.Client{}, "", fmt.Errorf("create: %w", err)
+	}
+
+	return clt, secret, nil
+}
+
+// QueryClients retrieves the clients registered with the tenant.
+func (c Core) QueryClients(ctx context.Context, tenantID string) ([]client.Client, error) {
+	clients, err := c.client.Query(database.WithTenant(ctx, tenantID))
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return clients, nil
+}
+
+// DeleteClient removes a client, ending the sessions it started.
+func (c Core) DeleteClient(ctx context.Context, clientID string) error {
+	if err := c.client.Delete(database.WithSystem(ctx), clientID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// authenticate verifies the client's secret and that it can use the grant.
+func (c Core) authenticate(ctx context.Context, clientID, secret, grantType string) (client.Client, error) {
+	clt, err := c.AuthenticateClient(ctx, clientID, secret)
+	if err != nil {
+		return client.Client{}, err
+	}
+
+	if !clt.Allows(grantType) {
+		return client.Client{}, ErrUnauthorizedClient
+	}
+
+	return clt, nil
+}
+
+// grant returns the scopes to issue for the space separated scopes that were
+// requested. Every scope requested must be allowed for the client, and when
+// none are requested every scope it is allowed is issued.
+func grant(clt client.Client, requested string) (string, error) {
+	if strings.TrimSpace(requested) == "" {
+		return strings.Join(clt.Scopes, " "), nil
+	}
+
+	allowed := make(map[string]bool)
+	for _, s := range clt.Scopes {
+		allowed[s] = true
+	}
+
+	scopes := strings.Fields(requested)
+	for _, s := range scopes {
+		if !allowed[s] {
+			return "", fmt.Errorf("%w: %q is not allowed", ErrInvalidScope, s)
+		}
+	}
+
+	return strings.Join(scopes, " "), nil
+}
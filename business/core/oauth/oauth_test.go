@@ -0,0 +1,126 @@
+package oauth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/core/oauth"
+	sessionCore "github.com/deliveranceTechSolutions/erp/business/core/session"
+	userCore "github.com/deliveranceTechSolutions/erp/business/core/user"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/client"
+	"github.com/deliveranceTechSolutions/erp/business/data/tests"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+)
+
+var dbc = tests.DBContainer{
+	Image: "postgres:13-alpine",
+	Port:  "5432",
+	Args:  []string{"-e", "POSTGRES_PASSWORD=postgres"},
+}
+
+func TestToken(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	core := oauth.NewCore(log, db, userCore.Config{})
+	sessions := sessionCore.NewCore(log, db, auth.Policy{})
+
+	t.Log("Given the need to issue tokens to OAuth clients.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a client uses the grants it is allowed.", testID)
+		{
+			ctx := context.Background()
+			now := time.Now()
+
+			nc := client.NewClient{
+				Name:   "Reporting",
+				Grants: []string{client.GrantPassword, client.GrantClientCredentials, client.GrantRefreshToken},
+				Scopes: []string{auth.ScopeReportsRead, auth.ScopeProjectsRead},
+			}
+			clt, secret, err := core.CreateClient(ctx, tests.TenantID, nc, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to register a client : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to register a client.", tests.Success, testID)
+
+			claims, err := core.ClientCredentials(ctx, clt.ID, secret, auth.ScopeProjectsRead, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to use the client credentials grant : %s.", tests.Failed, testID, err)
+			}
+			if claims.Subject != clt.ID || claims.TenantID != tests.TenantID || !claims.HasScope(auth.ScopeProjectsRead) || claims.HasScope(auth.ScopeReportsRead) {
+				t.Fatalf("\t%s\tTest %d:\tShould be issued only the scopes requested : %+v.", tests.Failed, testID, claims)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be issued only the scopes requested.", tests.Success, testID)
+
+			claims, refresh, err := core.Password(ctx, clt.ID, secret, "admin@example.com", "gophers", "", "", "", now)
+			if err != nil || refresh == "" {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to use the password grant : %v.", tests.Failed, testID, err)
+			}
+			if claims.ClientID != clt.ID || !claims.HasScope(auth.ScopeReportsRead, auth.ScopeProjectsRead) {
+				t.Fatalf("\t%s\tTest %d:\tShould be issued every scope allowed : %+v.", tests.Failed, testID, claims)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to use the password grant.", tests.Success, testID)
+
+			refreshed, _, err := core.Refresh(ctx, clt.ID, secret, refresh, now)
+			if err != nil || refreshed.Scope != claims.Scope || refreshed.ClientID != clt.ID {
+				t.Fatalf("\t%s\tTest %d:\tShould keep the scopes when refreshing : %v %+v.", tests.Failed, testID, err, refreshed)
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep the scopes when refreshing.", tests.Success, testID)
+
+			nc.Name = "Kiosk"
+			nc.Grants = []string{client.GrantPassword}
+			kiosk, kioskSecret, err := core.CreateClient(ctx, tests.TenantID, nc, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to register a client : %s.", tests.Failed, testID, err)
+			}
+
+			claims, refresh, err = core.Password(ctx, kiosk.ID, kioskSecret, "user@example.com", "gophers", "", "", "", now)
+			if err != nil || refresh != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould be issued no refresh token without the refresh grant : %v %q.", tests.Failed, testID, err, refresh)
+			}
+
+			tctx := database.WithTenant(ctx, tests.TenantID)
+			revoked, err := sessions.RevokeAll(tctx, claims, claims.Subject, now)
+			if err != nil || len(revoked) != 1 || revoked[0].JTI != claims.Id {
+				t.Fatalf("\t%s\tTest %d:\tShould revoke the access token with the user's sessions : %v %+v.", tests.Failed, testID, err, revoked)
+			}
+			t.Logf("\t%s\tTest %d:\tShould revoke the access token with the user's sessions.", tests.Success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen a client asks for more than it is allowed.", testID)
+		{
+			ctx := context.Background()
+			now := time.Now()
+
+			nc := client.NewClient{
+				Name:   "Integration",
+				Grants: []string{client.GrantClientCredentials},
+				Scopes: []string{auth.ScopeProjectsRead},
+			}
+			clt, secret, err := core.CreateClient(ctx, tests.TenantID, nc, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to register a client : %s.", tests.Failed, testID, err)
+			}
+
+			if _, err := core.ClientCredentials(ctx, clt.ID, "wrong", "", now); !errors.Is(err, oauth.ErrInvalidClient) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept a wrong secret : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT accept a wrong secret.", tests.Success, testID)
+
+			if _, err := core.ClientCredentials(ctx, clt.ID, secret, auth.ScopeProjectsWrite, now); !errors.Is(err, oauth.ErrInvalidScope) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT issue a scope that isn't allowed : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT issue a scope that isn't allowed.", tests.Success, testID)
+
+			if _, _, err := core.Password(ctx, clt.ID, secret, "admin@example.com", "gophers", "", "", "", now); !errors.Is(err, oauth.ErrUnauthorizedClient) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT allow a grant that isn't allowed : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT allow a grant that isn't allowed.", tests.Success, testID)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Package project provides an example of a core business API. Projects are
+// made up of tasks linked by dependencies and are scheduled with the critical
+// path method to produce gantt charts.
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	reportCore "github.com/deliveranceTechSolutions/erp/business/core/report"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/project"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Core manages the set of API's for project access.
+type Core struct {
+	log     *zap.SugaredLogger
+	project project.Store
+}
+
+// NewCore constructs a core for project api access.
+func NewCore(log *zap.SugaredLogger, db *sqlx.DB) Core {
+	return Core{
+		log:     log,
+		project: project.NewStore(log, db),
+	}
+}
+
+// Create inserts a new project into the database.
+func (c Core) Create(ctx context.Context, claims auth.Claims, np project.NewProject, now time.Time) (project.Project, error) {
+	prj, err := c.project.Create(ctx, claims, np, now)
+	if err != nil {
+		return project.Project{}, fmt.Errorf("create: %w", err)
+	}
+
+	return prj, nil
+}
+
+// Update replaces a project document in the database.
+func (c Core) Update(ctx context.Context, projectID string, up project.UpdateProject, now time.Time) (project.Project, error) {
+	prj, err := c.project.Update(ctx, projectID, up, now)
+	if err != nil {
+		return project.Project{}, fmt.Errorf("update: %w", err)
+	}
+
+	return prj, nil
+}
+
+// Delete removes a project from the database.
+func (c Core) Delete(ctx context.Context, projectID string) error {
+	if err := c.project.Delete(ctx, projectID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves a page of existing projects from the database.
+func (c Core) Query(ctx context.Context, filter project.QueryFilter, orderBy []database.OrderBy, ks database.Keyset) (database.Page[project.Project], error) {
+	page, err := c.project.Query(ctx, filter, orderBy, ks)
+	if err != nil {
+		return database.Page[project.Project]{}, fmt.Errorf("query: %w", err)
+	}
+
+	return page, nil
+}
+
+// QueryByID gets the specified project from the database.
+func (c Core) QueryByID(ctx context.Context, projectID string) (project.Project, error) {
+	prj, err := c.project.QueryByID(ctx, projectID)
+	if err != nil {
+		return project.Project{}, fmt.Errorf("query: %w", err)
+	}
+
+	return prj, nil
+}
+
+// =============================================================================
+
+// CreateTask inserts a new task into a project.
+func (c Core) CreateTask(ctx context.Context, projectID string, nt project.NewTask, now time.Time) (project.Task, error) {
+	tsk, err := c.project.CreateTask(ctx, projectID, nt, now)
+	if err != nil {
+		return project.Task{}, fmt.Errorf("create task: %w", err)
+	}
+
+	return tsk, nil
+}
+
+// UpdateTask replaces a task document in the database. Users permitted to
+// write projects may change any field. The assignee of a task may report
+// their progress on it by changing only the percent complete.
+func (c Core) UpdateTask(ctx context.Context, claims auth.Claims, projectID string, taskID string, ut project.UpdateTask, now time.Time) (project.Task, error) {
+	if !claims.HasPermission(auth.PermProjectsWrite) {
+		tsk, err := c.project.QueryTaskByID(ctx, projectID, taskID)
+		if err != nil {
+			return project.Task{}, fmt.Errorf("query task: %w", err)
+		}
+
+		progressOnly := ut.Name == nil && ut.AssigneeID == nil && ut.DurationDays == nil && ut.StartAfter == nil && ut.Milestone == nil
+		if tsk.AssigneeID == nil || *tsk.AssigneeID != claims.Subject || !progressOnly {
+			return project.Task{}, database.ErrForbidden
+		}
+	}
+
+	tsk, err := c.project.UpdateTask(ctx, projectID, taskID, ut, now)
+	if err != nil {
+		return project.Task{}, fmt.Errorf("update task: %w", err)
+	}
+
+	return tsk, nil
+}
+
+// DeleteTask removes a task from a project.
+func (c Core) DeleteTask(ctx context.Context, projectID string, taskID string) error {
+	if err := c.project.DeleteTask(ctx, projectID, taskID); err != nil {
+		return fmt.Errorf("delete task: %w", err)
+	}
+
+	return nil
+}
+
+// QueryTasks retrieves every task in a project.
+func (c Core) QueryTasks(ctx context.Context, projectID string) ([]project.Task, error) {
+	if _, err := c.project.QueryByID(ctx, projectID); err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	tasks, err := c.project.QueryTasks(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("query tasks: %w", err)
+	}
+
+	return tasks, nil
+}
+
+// CreateDependency links two tasks in a project. Links that would make a
+// task depend on itself through other tasks are rejected.
+func (c Core) CreateDependency(ctx context.Context, projectID string, nd project.NewDependency, now time.Time) (project.Dependency, error) {
+
+	// PERFORM PRE BUSINESS OPERATIONS
+
+	prj, tasks, deps, err := c.load(ctx, projectID)
+	if err != nil {
+		return project.Dependency{}, err
+	}
+
+	candidate := project.Dependency{TaskID: nd.TaskID, DependsOnID: nd.DependsOnID, Type: nd.Type, LagDays: nd.LagDays}
+	if _, err := schedule(prj.StartDate, tasks, append(deps, candidate)); err != nil {
+		if errors.Is(err, ErrCycle) {
+			return project.Dependency{}, validate.FieldErrors{{Field: "depends_on_id", Err: err.Error()}}
+		}
+		return project.Dependency{}, err
+	}
+
+	dep, err := c.project.CreateDependency(ctx, projectID, nd, now)
+	if err != nil {
+		return project.Dependency{}, fmt.Errorf("create dependency: %w", err)
+	}
+
+	// PERFORM POST BUSINESS OPERATIONS
+
+	return dep, nil
+}
+
+// DeleteDependency removes the link between two tasks.
+func (c Core) DeleteDependency(ctx context.Context, projectID string, taskID string, dependsOnID string) error {
+	if err := c.project.DeleteDependency(ctx, projectID, taskID, dependsOnID); err != nil {
+		return fmt.Errorf("delete dependency: %w", err)
+	}
+
+	return nil
+}
+
+// QueryDependencies retrieves every dependency in a project.
+func (c Core) QueryDependencies(ctx context.Context, projectID string) ([]project.Dependency, error) {
+	if _, err := c.project.QueryByID(ctx, projectID); err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	deps, err := c.project.QueryDependencies(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("query dependencies: %w", err)
+	}
+
+	return deps, nil
+}
+
+// =============================================================================
+
+// Gantt schedules the project's tasks and returns them as a loaded gantt
+// chart. Tasks are placed at their earliest start from the project start
+// date and marked critical when they have no slack.
+func (c Core) Gantt(ctx context.Context, projectID string) (reportCore.GanttChart, error) {
+	prj, tasks, deps, err := c.load(ctx, projectID)
+	if err != nil {
+		return reportCore.GanttChart{}, err
+	}
+
+	gc := reportCore.GanttChart{
+		Title:        prj.Name,
+		StartDate:    prj.StartDate,
+		EndDate:      prj.StartDate,
+		Table:        []reportCore.GanttTable{},
+		CriticalPath: []string{},
+	}
+	if len(tasks) == 0 {
+		return gc, nil
+	}
+
+	timings, err := schedule(prj.StartDate, tasks, deps)
+	if err != nil {
+		return reportCore.GanttChart{}, fmt.Errorf("scheduling: %w", err)
+	}
+
+	dependsOn := make(map[string][]string)
+	for _, dep := range deps {
+		dependsOn[dep.TaskID] = append(dependsOn[dep.TaskID], dep.DependsOnID)
+	}
+
+	table := make([]reportCore.GanttTable, len(tasks))
+	for i, tsk := range tasks {
+		t := timings[tsk.ID]
+
+		row := reportCore.GanttTable{
+			ID:              tsk.ID,
+			ItemName:        tsk.Name,
+			Duration:        days(tsk.DurationDays),
+			StartDate:       prj.StartDate.AddDate(0, 0, t.earlyStart),
+			EndDate:         prj.StartDate.AddDate(0, 0, t.earlyFinish),
+			PercentComplete: tsk.PercentComplete,
+			Milestone:       tsk.Milestone,
+			Critical:        t.critical(),
+			Slack:           days(t.slack()),
+			DependsOn:       dependsOn[tsk.ID],
+		}
+		if tsk.AssigneeName != nil {
+			row.Assignee = *tsk.AssigneeName
+		}
+		if row.DependsOn == nil {
+			row.DependsOn = []string{}
+		}
+
+		table[i] = row
+	}
+
+	if err := gc.LoadData(table); err != nil {
+		return reportCore.GanttChart{}, err
+	}
+
+	return gc, nil
+}
+
+// load reads a project together with its tasks and dependencies.
+func (c Core) load(ctx context.Context, projectID string) (project.Project, []project.Task, []project.Dependency, error) {
+	prj, err := c.project.QueryByID(ctx, projectID)
+	if err != nil {
+		return project.Project{}, nil, nil, fmt.Errorf("query: %w", err)
+	}
+
+	tasks, err := c.project.QueryTasks(ctx, projectID)
+	if err != nil {
+		return project.Project{}, nil, nil, fmt.Errorf("query tasks: %w", err)
+	}
+
+	deps, err := c.project.QueryDependencies(ctx, projectID)
+	if err != nil {
+		return project.Project{}, nil, nil, fmt.Errorf("query dependencies: %w", err)
+	}
+
+	return prj, tasks, deps, nil
+}
+
+// days formats a number of days for display.
+func days(n int) string {
+	return fmt.Sprintf("%dd", n)
+}
@@ -0,0 +1,178 @@
+package project
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/project"
+)
+
+// ErrCycle occurs when task dependencies loop back on themselves so the
+// tasks cannot be put in order.
+var ErrCycle = errors.New("task dependencies form a cycle")
+
+// timing is when a task can run, in days from the project start. The early
+// dates are the soonest the task can run and the late dates the latest it can
+// run without delaying the end of the project.
+type timing struct {
+	earlyStart  int
+	earlyFinish int
+	lateStart   int
+	lateFinish  int
+}
+
+// slack is how many days the task can slip without delaying the project.
+func (t timing) slack() int {
+	return t.lateStart - t.earlyStart
+}
+
+// critical reports whether the task is on the critical path.
+func (t timing) critical() bool {
+	return t.slack() == 0
+}
+
+// schedule applies the critical path method to the tasks. A forward pass
+// finds the earliest each task can run given its dependencies and start
+// constraint, then a backward pass from the project end finds the latest.
+// Dependencies naming tasks that are not provided are ignored.
+func schedule(start time.Time, tasks []project.Task, deps []project.Dependency) (map[string]timing, error) {
+	byID := make(map[string]project.Task, len(tasks))
+	for _, tsk := range tasks {
+		byID[tsk.ID] = tsk
+	}
+
+	var links []project.Dependency
+	for _, dep := range deps {
+		_, task := byID[dep.TaskID]
+		_, dependsOn := byID[dep.DependsOnID]
+		if task && dependsOn {
+			links = append(links, dep)
+		}
+	}
+
+	order, err := topological(tasks, links)
+	if err != nil {
+		return nil, err
+	}
+
+	predecessors := make(map[string][]project.Dependency)
+	successors := make(map[string][]project.Dependency)
+	for _, dep := range links {
+		predecessors[dep.TaskID] = append(predecessors[dep.TaskID], dep)
+		successors[dep.DependsOnID] = append(successors[dep.DependsOnID], dep)
+	}
+
+	timings := make(map[string]timing, len(tasks))
+
+	// Forward pass.
+	var end int
+	for _, id := range order {
+		tsk := byID[id]
+		duration := tsk.DurationDays
+
+		es := 0
+		if tsk.StartAfter != nil {
+			es = latest(es, int(math.Ceil(tsk.StartAfter.Sub(start).Hours()/24)))
+		}
+		for _, dep := range predecessors[id] {
+			p := timings[dep.DependsOnID]
+			switch dep.Type {
+			case project.StartToStart:
+				es = latest(es, p.earlyStart+dep.LagDays)
+			case project.FinishToFinish:
+				es = latest(es, p.earlyFinish+dep.LagDays-duration)
+			case project.StartToFinish:
+				es = latest(es, p.earlyStart+dep.LagDays-duration)
+			default:
+				es = latest(es, p.earlyFinish+dep.LagDays)
+			}
+		}
+
+		t := timing{earlyStart: es, earlyFinish: es + duration}
+		timings[id] = t
+		end = latest(end, t.earlyFinish)
+	}
+
+	// Backward pass.
+	for i := len(order) - 1; i >= 0; i-- {
+		id := order[i]
+		duration := byID[id].DurationDays
+		t := timings[id]
+
+		lf := end
+		for _, dep := range successors[id] {
+			s := timings[dep.TaskID]
+			switch dep.Type {
+			case project.StartToStart:
+				lf = earliest(lf, s.lateStart-dep.LagDays+duration)
+			case project.FinishToFinish:
+				lf = earliest(lf, s.lateFinish-dep.LagDays)
+			case project.StartToFinish:
+				lf = earliest(lf, s.lateFinish-dep.LagDays+duration)
+			default:
+				lf = earliest(lf, s.lateStart-dep.LagDays)
+			}
+		}
+
+		t.lateFinish = lf
+		t.lateStart = lf - duration
+		timings[id] = t
+	}
+
+	return timings, nil
+}
+
+// topological orders the tasks so every task comes after the tasks it
+// depends on. Tasks without a fixed order keep the order they were provided.
+func topological(tasks []project.Task, links []project.Dependency) ([]string, error) {
+	indegree := make(map[string]int, len(tasks))
+	next := make(map[string][]string)
+	for _, dep := range links {
+		indegree[dep.TaskID]++
+		next[dep.DependsOnID] = append(next[dep.DependsOnID], dep.TaskID)
+	}
+
+	var queue []string
+	for _, tsk := range tasks {
+		if indegree[tsk.ID] == 0 {
+			queue = append(queue, tsk.ID)
+		}
+	}
+
+	order := make([]string, 0, len(tasks))
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		order = append(order, id)
+
+		for _, succ := range next[id] {
+			indegree[succ]--
+			if indegree[succ] == 0 {
+				queue = append(queue, succ)
+			}
+		}
+	}
+
+	if len(order) != len(tasks) {
+		return nil, ErrCycle
+	}
+
+	return order, nil
+}
+
+// latest returns the later of two day offsets.
+func latest(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// earliest returns the earlier of two day offsets.
+func earliest(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,112 @@
+package project
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/project"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestSchedule(t *testing.T) {
+	start := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Log("Given the need to schedule project tasks by their critical path.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen two branches join into a milestone.", testID)
+		{
+			// design(3) -> build(2) ---\
+			//          \-> order(5) ---> install(0)
+			tasks := []project.Task{
+				{ID: "design", DurationDays: 3},
+				{ID: "build", DurationDays: 2},
+				{ID: "order", DurationDays: 5},
+				{ID: "install", Milestone: true},
+			}
+			deps := []project.Dependency{
+				{TaskID: "build", DependsOnID: "design", Type: project.FinishToStart},
+				{TaskID: "order", DependsOnID: "design", Type: project.FinishToStart},
+				{TaskID: "install", DependsOnID: "build", Type: project.FinishToStart},
+				{TaskID: "install", DependsOnID: "order", Type: project.FinishToStart},
+			}
+
+			timings, err := schedule(start, tasks, deps)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to schedule the tasks: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to schedule the tasks.", success, testID)
+
+			exp := map[string]timing{
+				"design":  {earlyStart: 0, earlyFinish: 3, lateStart: 0, lateFinish: 3},
+				"build":   {earlyStart: 3, earlyFinish: 5, lateStart: 6, lateFinish: 8},
+				"order":   {earlyStart: 3, earlyFinish: 8, lateStart: 3, lateFinish: 8},
+				"install": {earlyStart: 8, earlyFinish: 8, lateStart: 8, lateFinish: 8},
+			}
+			for id, want := range exp {
+				if got := timings[id]; got != want {
+					t.Logf("\t\tTest %d:\texp: %+v", testID, want)
+					t.Logf("\t\tTest %d:\tgot: %+v", testID, got)
+					t.Fatalf("\t%s\tTest %d:\tShould place task %q at its early and late dates.", failed, testID, id)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould place every task at its early and late dates.", success, testID)
+
+			for id, critical := range map[string]bool{"design": true, "build": false, "order": true, "install": true} {
+				if timings[id].critical() != critical {
+					t.Fatalf("\t%s\tTest %d:\tShould mark %q critical=%v.", failed, testID, id, critical)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould mark only the zero slack tasks critical.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen tasks overlap with start and finish links.", testID)
+		{
+			after := start.AddDate(0, 0, 2)
+			tasks := []project.Task{
+				{ID: "dig", DurationDays: 4, StartAfter: &after},
+				{ID: "pour", DurationDays: 3},
+				{ID: "inspect", DurationDays: 1},
+			}
+			deps := []project.Dependency{
+				{TaskID: "pour", DependsOnID: "dig", Type: project.StartToStart, LagDays: 2},
+				{TaskID: "inspect", DependsOnID: "pour", Type: project.FinishToFinish, LagDays: 1},
+			}
+
+			timings, err := schedule(start, tasks, deps)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to schedule the tasks: %v", failed, testID, err)
+			}
+
+			exp := map[string]int{"dig": 2, "pour": 4, "inspect": 7}
+			for id, es := range exp {
+				if got := timings[id].earlyStart; got != es {
+					t.Fatalf("\t%s\tTest %d:\tShould start %q on day %d, got %d.", failed, testID, id, es, got)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould honor start constraints, lags and link types.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen dependencies form a cycle.", testID)
+		{
+			tasks := []project.Task{{ID: "a", DurationDays: 1}, {ID: "b", DurationDays: 1}}
+			deps := []project.Dependency{
+				{TaskID: "a", DependsOnID: "b"},
+				{TaskID: "b", DependsOnID: "a"},
+			}
+
+			if _, err := schedule(start, tasks, deps); !errors.Is(err, ErrCycle) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to schedule the tasks: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to schedule the tasks.", success, testID)
+		}
+	}
+}
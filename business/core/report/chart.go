@@ -2,48 +2,167 @@ package report
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"sort"
 	"time"
+
+	"github.com/deliveranceTechSolutions/erp/foundation/canvas"
+)
+
+// Set of chart types that can be constructed by name.
+const (
+	TypeLine    = "line"
+	TypeBar     = "bar"
+	TypePie     = "pie"
+	TypeScatter = "scatter"
+	TypeBubble  = "bubble"
 )
 
-type report interface {
-	LoadData() error
-	Render() error
+type drawable interface {
+	LoadData(series []Series) error
+	Render(cv canvas.Canvas) error
+	chart() *Chart
 }
 
+// Chart holds the data and axes shared by every chart type.
 type Chart struct {
-	Data 		any
-	Title 		string
-	X			Axis
-	Y			Axis
-	IsLoaded	bool
+	Type     string   `json:"type"`
+	Data     []Series `json:"data"`
+	Title    string   `json:"title"`
+	X        Axis     `json:"x"`
+	Y        Axis     `json:"y"`
+	IsLoaded bool     `json:"-"`
 }
 
+// Axis describes one axis of a chart. Frequency is the number of points
+// plotted along the axis and Magnitude is the largest value it must show.
 type Axis struct {
-	Frequency 	int
-	Magnitude	int
-	Name		string
-}
+	Frequency int    `json:"frequency"`
+	Magnitude int    `json:"magnitude"`
+	Name      string `json:"name"`
+}
+
+// Series is a named set of points plotted together on a chart.
+type Series struct {
+	Name   string  `json:"name"`
+	Points []Point `json:"points"`
+}
+
+// Point is a single labeled value within a series. Time is set when the label
+// names a period so exports can write it as a real date.
+type Point struct {
+	Label string     `json:"label"`
+	Value float64    `json:"value"`
+	Time  *time.Time `json:"time,omitempty"`
+}
+
+// LoadData stores the series on the chart and sizes both axes to fit them.
+func (c *Chart) LoadData(series []Series) error {
+	var frequency int
+	var magnitude float64
+	for _, s := range series {
+		if len(s.Points) > frequency {
+			frequency = len(s.Points)
+		}
+		for _, p := range s.Points {
+			if p.Value > magnitude {
+				magnitude = p.Value
+			}
+		}
+	}
 
-func (c *Chart) LoadData() {
-	data := []int{}
+	c.Data = series
+	c.X.Frequency = frequency
+	c.Y.Frequency = frequency
+	c.Y.Magnitude = int(math.Ceil(magnitude))
+	c.IsLoaded = true
 
-	c.Data = data
+	return nil
 }
 
+// CanHaveView reports whether the chart can be shown on a dashboard.
 func (c *Chart) CanHaveView() bool {
 	return true
 }
 
+func (c *Chart) chart() *Chart {
+	return c
+}
+
+// newChart constructs an empty chart of the specified type.
+func newChart(chartType string) (drawable, error) {
+	switch chartType {
+	case TypeLine:
+		return &LineChart{Chart{Type: chartType}}, nil
+	case TypeBar:
+		return &BarChart{Chart{Type: chartType}}, nil
+	case TypePie:
+		return &PieChart{Chart{Type: chartType}}, nil
+	case TypeScatter:
+		return &ScatterXYChart{Chart{Type: chartType}}, nil
+	case TypeBubble:
+		return &BubbleChart{Chart{Type: chartType}}, nil
+	}
+
+	return nil, fmt.Errorf("chart type[%s]: %w", chartType, ErrUnknownChart)
+}
+
+// GanttChart lays a schedule of items out against time. CriticalPath lists
+// the IDs of the items that cannot slip without delaying the end date.
 type GanttChart struct {
-	Table  []*GanttTable
-	loaded bool
+	Title        string       `json:"title"`
+	StartDate    time.Time    `json:"start_date"`
+	EndDate      time.Time    `json:"end_date"`
+	Table        []GanttTable `json:"table"`
+	CriticalPath []string     `json:"critical_path"`
+	IsLoaded     bool         `json:"-"`
 }
 
+// GanttTable is a single scheduled item on a gantt chart.
 type GanttTable struct {
-	ItemName  string
-	Duration  string
-	StartDate time.Time
-	EndDate   time.Time
+	ID              string    `json:"id"`
+	ItemName        string    `json:"item_name"`
+	Assignee        string    `json:"assignee,omitempty"`
+	Duration        string    `json:"duration"`
+	StartDate       time.Time `json:"start_date"`
+	EndDate         time.Time `json:"end_date"`
+	PercentComplete int       `json:"percent_complete"`
+	Milestone       bool      `json:"milestone"`
+	Critical        bool      `json:"critical"`
+	Slack           string    `json:"slack"`
+	DependsOn       []string  `json:"depends_on"`
+}
+
+// LoadData stores the items on the chart ordered by when they start and
+// spans the chart from the earliest start to the latest end.
+func (gc *GanttChart) LoadData(table []GanttTable) error {
+	if len(table) == 0 {
+		return errors.New("GanttChart requires at least one item")
+	}
+
+	sort.SliceStable(table, func(i, j int) bool {
+		return table[i].StartDate.Before(table[j].StartDate)
+	})
+
+	gc.Table = table
+	gc.StartDate = table[0].StartDate
+	gc.EndDate = table[0].EndDate
+	for _, row := range table {
+		if row.EndDate.After(gc.EndDate) {
+			gc.EndDate = row.EndDate
+		}
+	}
+
+	gc.CriticalPath = []string{}
+	for _, row := range table {
+		if row.Critical {
+			gc.CriticalPath = append(gc.CriticalPath, row.ID)
+		}
+	}
+	gc.IsLoaded = true
+
+	return nil
 }
 
 type HistogramChart struct {
@@ -58,18 +177,14 @@ type LineChart struct {
 	Chart
 }
 
-func (lc *LineChart) LoadData() error {
-	databaseData := []int{}
-	lc.Data = databaseData
-
-	return nil
-}
-
-func (lc *LineChart) Render() error {
+// Render draws the chart onto the canvas.
+func (lc *LineChart) Render(cv canvas.Canvas) error {
 	if !lc.IsLoaded {
 		return errors.New("LineChart render error")
 	}
 
+	lc.drawLines(cv)
+
 	return nil
 }
 
@@ -79,19 +194,14 @@ type BarChart struct {
 	Chart
 }
 
-func (bc *BarChart) LoadData() error {
-	databaseData := []int{}
-	bc.Data = databaseData
-
-	return nil
-
-}
-
-func (bc *BarChart) Render() error {
+// Render draws the chart onto the canvas.
+func (bc *BarChart) Render(cv canvas.Canvas) error {
 	if !bc.IsLoaded {
 		return errors.New("BarChart render error")
 	}
 
+	bc.drawBars(cv)
+
 	return nil
 }
 
@@ -101,18 +211,23 @@ type PieChart struct {
 	Chart
 }
 
-func (pc *PieChart) LoadData() error {
-	databaseData := []int{}
-	pc.Data = databaseData
+// LoadData stores a single series on the chart. A pie only has one ring.
+func (pc *PieChart) LoadData(series []Series) error {
+	if len(series) != 1 {
+		return fmt.Errorf("PieChart requires exactly one series, got %d", len(series))
+	}
 
-	return nil
+	return pc.Chart.LoadData(series)
 }
 
-func (pc *PieChart) Render() error {
+// Render draws the chart onto the canvas.
+func (pc *PieChart) Render(cv canvas.Canvas) error {
 	if !pc.IsLoaded {
 		return errors.New("PieChart render error")
 	}
 
+	pc.drawPie(cv)
+
 	return nil
 }
 
@@ -121,18 +236,15 @@ func (pc *PieChart) Render() error {
 type ScatterXYChart struct {
 	Chart
 }
-func (sc *ScatterXYChart) LoadData() error {
-	databaseData := []int{}
-	sc.Data = databaseData
-
-	return nil
-}
 
-func (sc *ScatterXYChart) Render() error {
+// Render draws the chart onto the canvas.
+func (sc *ScatterXYChart) Render(cv canvas.Canvas) error {
 	if !sc.IsLoaded {
 		return errors.New("ScatterXYChart render error")
 	}
 
+	sc.drawMarkers(cv, false)
+
 	return nil
 }
 
@@ -142,18 +254,14 @@ type BubbleChart struct {
 	Chart
 }
 
-func (bc *ScatterXYChart) LoadData() error {
-	databaseData := []int{}
-	sc.Data = databaseData
-
-	return nil
-}
-
-func (sc *ScatterXYChart) Render() error {
-	if !sc.IsLoaded {
-		return errors.New("ScatterXYChart render error")
+// Render draws the chart onto the canvas.
+func (bc *BubbleChart) Render(cv canvas.Canvas) error {
+	if !bc.IsLoaded {
+		return errors.New("BubbleChart render error")
 	}
 
+	bc.drawMarkers(cv, true)
+
 	return nil
 }
 
@@ -0,0 +1,98 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/deliveranceTechSolutions/erp/foundation/sheet"
+)
+
+// Export writes the chart's data as a sheet in the specified format. The
+// first column holds the point labels and each series gets its own column.
+// Rows are written as they are built so the output streams to w.
+func Export(w io.Writer, name string, ch Chart, format string) error {
+	if err := CheckExport(ch, format); err != nil {
+		return err
+	}
+
+	sw, err := sheet.New(w, format, name)
+	if err != nil {
+		return err
+	}
+
+	label := ch.X.Name
+	if label == "" {
+		label = "Label"
+	}
+
+	header := []string{label}
+	for _, s := range ch.Data {
+		header = append(header, s.Name)
+	}
+	if err := sw.WriteHeader(header); err != nil {
+		return fmt.Errorf("writing header: %w", err)
+	}
+
+	// Series may not share every label so rows follow the order labels are
+	// first seen across all series.
+	type row struct {
+		point  Point
+		values map[int]float64
+	}
+	var order []string
+	rows := make(map[string]*row)
+	for i, s := range ch.Data {
+		for _, pt := range s.Points {
+			r, exists := rows[pt.Label]
+			if !exists {
+				r = &row{point: pt, values: make(map[int]float64)}
+				rows[pt.Label] = r
+				order = append(order, pt.Label)
+			}
+			r.values[i] = pt.Value
+		}
+	}
+
+	for _, label := range order {
+		r := rows[label]
+
+		cells := make([]sheet.Cell, 0, len(header))
+		switch {
+		case r.point.Time != nil:
+			cells = append(cells, sheet.Date(*r.point.Time))
+		default:
+			cells = append(cells, sheet.String(r.point.Label))
+		}
+
+		for i := range ch.Data {
+			v, exists := r.values[i]
+			if !exists {
+				cells = append(cells, sheet.Empty())
+				continue
+			}
+			cells = append(cells, sheet.Number(v))
+		}
+
+		if err := sw.WriteRow(cells); err != nil {
+			return fmt.Errorf("writing row[%s]: %w", label, err)
+		}
+	}
+
+	return sw.Close()
+}
+
+// CheckExport reports whether the chart can be exported in the specified
+// format. It lets an error be returned before any of the export is written.
+func CheckExport(ch Chart, format string) error {
+	if !ch.IsLoaded {
+		return errors.New("chart has no data loaded")
+	}
+
+	switch format {
+	case sheet.FormatCSV, sheet.FormatXLSX:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported sheet format[%s]", format)
+}
@@ -0,0 +1,351 @@
+package report
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/deliveranceTechSolutions/erp/foundation/canvas"
+)
+
+// Dimensions of a rendered chart in pixels.
+const (
+	RenderWidth  = 640
+	RenderHeight = 400
+)
+
+// Spacing around the plot area in pixels.
+const (
+	marginTop    = 48
+	marginBottom = 56
+	marginLeft   = 64
+	marginRight  = 24
+	legendWidth  = 150
+	titleSize    = 16
+	labelSize    = 11
+	yTicks       = 5
+	maxLabelLen  = 14
+)
+
+// Colors used for the chart furniture.
+var (
+	inkColor  = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	axisColor = color.RGBA{0x88, 0x88, 0x88, 0xff}
+	gridColor = color.RGBA{0xe5, 0xe5, 0xe5, 0xff}
+)
+
+// palette holds the colors assigned to each series in order.
+var palette = []color.RGBA{
+	{0x1f, 0x77, 0xb4, 0xff},
+	{0xff, 0x7f, 0x0e, 0xff},
+	{0x2c, 0xa0, 0x2c, 0xff},
+	{0xd6, 0x27, 0x28, 0xff},
+	{0x94, 0x67, 0xbd, 0xff},
+	{0x8c, 0x56, 0x4b, 0xff},
+	{0xe3, 0x77, 0xc2, 0xff},
+	{0x17, 0xbe, 0xcf, 0xff},
+}
+
+// Render draws a loaded chart and writes it to w in the specified format.
+// The supported formats are canvas.FormatSVG and canvas.FormatPNG.
+func Render(w io.Writer, ch Chart, format string) error {
+	r, err := newChart(ch.Type)
+	if err != nil {
+		return err
+	}
+	*r.chart() = ch
+
+	cv, err := canvas.New(format, RenderWidth, RenderHeight)
+	if err != nil {
+		return err
+	}
+
+	if err := r.Render(cv); err != nil {
+		return err
+	}
+
+	return cv.Encode(w)
+}
+
+// =============================================================================
+
+// plot describes the area of the canvas data is drawn in and the scale of the
+// y axis.
+type plot struct {
+	left   float64
+	top    float64
+	right  float64
+	bottom float64
+	max    float64
+	bands  int
+}
+
+// y converts a value into a vertical position within the plot.
+func (p plot) y(v float64) float64 {
+	return p.bottom - (v/p.max)*(p.bottom-p.top)
+}
+
+// band returns the width of the slot reserved for each point on the x axis.
+func (p plot) band() float64 {
+	return (p.right - p.left) / float64(p.bands)
+}
+
+// x returns the horizontal center of the slot for point i.
+func (p plot) x(i int) float64 {
+	return p.left + (float64(i)+0.5)*p.band()
+}
+
+// frame draws the title, axes, grid lines, tick labels and legend shared by
+// the cartesian charts and returns the area the data should be drawn in.
+func (c *Chart) frame(cv canvas.Canvas) plot {
+	width, height := cv.Size()
+	cv.Rect(0, 0, float64(width), float64(height), color.White)
+	cv.Text(canvas.Point{X: float64(width) / 2, Y: marginTop / 2}, c.Title, titleSize, inkColor, canvas.AnchorMiddle)
+
+	right := float64(width - marginRight)
+	if len(c.Data) > 1 {
+		right -= legendWidth
+		c.legend(cv, right+marginRight, seriesNames(c.Data))
+	}
+
+	max, step := niceScale(maxValue(c.Data), yTicks)
+	p := plot{
+		left:   marginLeft,
+		top:    marginTop,
+		right:  right,
+		bottom: float64(height - marginBottom),
+		max:    max,
+		bands:  c.X.Frequency,
+	}
+	if p.bands == 0 {
+		p.bands = 1
+	}
+
+	// Horizontal grid lines with their tick labels.
+	for v := 0.0; v <= max+step/2; v += step {
+		y := p.y(v)
+		cv.Line(canvas.Point{X: p.left, Y: y}, canvas.Point{X: p.right, Y: y}, gridColor, 1)
+		cv.Text(canvas.Point{X: p.left - 6, Y: y + labelSize/3}, formatValue(v), labelSize, inkColor, canvas.AnchorEnd)
+	}
+
+	// The axes themselves.
+	cv.Line(canvas.Point{X: p.left, Y: p.top}, canvas.Point{X: p.left, Y: p.bottom}, axisColor, 1)
+	cv.Line(canvas.Point{X: p.left, Y: p.bottom}, canvas.Point{X: p.right, Y: p.bottom}, axisColor, 1)
+
+	// Label the x axis ticks, skipping labels when they would overlap.
+	labels := pointLabels(c.Data)
+	every := int(math.Ceil(float64(len(labels)) * canvas.TextWidth(strings.Repeat("0", maxLabelLen/2), labelSize) / (p.right - p.left)))
+	if every < 1 {
+		every = 1
+	}
+	for i, label := range labels {
+		x := p.x(i)
+		cv.Line(canvas.Point{X: x, Y: p.bottom}, canvas.Point{X: x, Y: p.bottom + 4}, axisColor, 1)
+		if i%every == 0 {
+			cv.Text(canvas.Point{X: x, Y: p.bottom + 6 + labelSize}, truncate(label), labelSize, inkColor, canvas.AnchorMiddle)
+		}
+	}
+
+	// Name the axes.
+	cv.Text(canvas.Point{X: (p.left + p.right) / 2, Y: float64(height) - 12}, c.X.Name, labelSize, inkColor, canvas.AnchorMiddle)
+	cv.Text(canvas.Point{X: 8, Y: p.top - 12}, c.Y.Name, labelSize, inkColor, canvas.AnchorStart)
+
+	return p
+}
+
+// legend draws a color swatch and name for each entry starting at x.
+func (c *Chart) legend(cv canvas.Canvas, x float64, names []string) {
+	for i, name := range names {
+		y := marginTop + float64(i)*(labelSize+8)
+		cv.Rect(x, y, labelSize, labelSize, palette[i%len(palette)])
+		cv.Text(canvas.Point{X: x + labelSize + 6, Y: y + labelSize - 1}, name, labelSize, inkColor, canvas.AnchorStart)
+	}
+}
+
+// drawLines plots each series as a line with a marker on every point.
+func (c *Chart) drawLines(cv canvas.Canvas) {
+	p := c.frame(cv)
+
+	for i, s := range c.Data {
+		stroke := palette[i%len(palette)]
+
+		points := make([]canvas.Point, len(s.Points))
+		for j, pt := range s.Points {
+			points[j] = canvas.Point{X: p.x(j), Y: p.y(pt.Value)}
+		}
+
+		cv.Polyline(points, stroke, 2)
+		for _, pt := range points {
+			cv.Circle(pt, 3, stroke)
+		}
+	}
+}
+
+// drawBars plots the series side by side within each slot on the x axis.
+func (c *Chart) drawBars(cv canvas.Canvas) {
+	p := c.frame(cv)
+	if len(c.Data) == 0 {
+		return
+	}
+
+	group := p.band() * 0.8
+	width := group / float64(len(c.Data))
+
+	for i, s := range c.Data {
+		fill := palette[i%len(palette)]
+		for j, pt := range s.Points {
+			x := p.x(j) - group/2 + float64(i)*width
+			y := p.y(pt.Value)
+			cv.Rect(x, y, width, p.bottom-y, fill)
+		}
+	}
+}
+
+// drawMarkers plots every point as a circle. When sized is true the area of
+// each circle is proportional to its value.
+func (c *Chart) drawMarkers(cv canvas.Canvas, sized bool) {
+	p := c.frame(cv)
+	maxRadius := math.Min(p.band()/2, 30)
+
+	for i, s := range c.Data {
+		fill := palette[i%len(palette)]
+		for j, pt := range s.Points {
+			radius := 4.0
+			if sized && p.max > 0 {
+				radius = math.Max(2, maxRadius*math.Sqrt(pt.Value/p.max))
+			}
+			cv.Circle(canvas.Point{X: p.x(j), Y: p.y(pt.Value)}, radius, fill)
+		}
+	}
+}
+
+// drawPie plots the points of the first series as slices of a circle with a
+// legend showing each share.
+func (c *Chart) drawPie(cv canvas.Canvas) {
+	width, height := cv.Size()
+	cv.Rect(0, 0, float64(width), float64(height), color.White)
+	cv.Text(canvas.Point{X: float64(width) / 2, Y: marginTop / 2}, c.Title, titleSize, inkColor, canvas.AnchorMiddle)
+
+	if len(c.Data) == 0 {
+		return
+	}
+	points := c.Data[0].Points
+
+	var total float64
+	for _, pt := range points {
+		total += math.Max(pt.Value, 0)
+	}
+
+	area := float64(width - legendWidth - marginRight)
+	center := canvas.Point{X: area / 2, Y: float64(marginTop+height) / 2}
+	radius := math.Min(area, float64(height-marginTop))/2 - 16
+
+	names := make([]string, len(points))
+	start := 0.0
+	for i, pt := range points {
+		share := 0.0
+		if total > 0 {
+			share = math.Max(pt.Value, 0) / total
+		}
+
+		end := start + share*2*math.Pi
+		cv.Wedge(center, radius, start, end, palette[i%len(palette)])
+		start = end
+
+		names[i] = fmt.Sprintf("%s %s%%", truncate(pt.Label), formatValue(math.Round(share*1000)/10))
+	}
+
+	c.legend(cv, area, names)
+}
+
+// =============================================================================
+
+// niceScale rounds max up to a value that divides evenly into ticks steps of
+// 1, 2 or 5 times a power of ten.
+func niceScale(max float64, ticks int) (float64, float64) {
+	if max <= 0 {
+		max = 1
+	}
+
+	rough := max / float64(ticks)
+	magnitude := math.Pow(10, math.Floor(math.Log10(rough)))
+
+	var step float64
+	switch norm := rough / magnitude; {
+	case norm <= 1:
+		step = magnitude
+	case norm <= 2:
+		step = 2 * magnitude
+	case norm <= 5:
+		step = 5 * magnitude
+	default:
+		step = 10 * magnitude
+	}
+
+	return math.Ceil(max/step) * step, step
+}
+
+// maxValue returns the largest value across every series.
+func maxValue(series []Series) float64 {
+	var max float64
+	for _, s := range series {
+		for _, pt := range s.Points {
+			if pt.Value > max {
+				max = pt.Value
+			}
+		}
+	}
+	return max
+}
+
+// seriesNames returns the name of each series shortened to fit the legend.
+func seriesNames(series []Series) []string {
+	names := make([]string, len(series))
+	for i, s := range series {
+		names[i] = truncate(s.Name)
+	}
+	return names
+}
+
+// pointLabels returns the labels of the longest series.
+func pointLabels(series []Series) []string {
+	var labels []string
+	for _, s := range series {
+		if len(s.Points) > len(labels) {
+			labels = make([]string, len(s.Points))
+			for i, pt := range s.Points {
+				labels[i] = pt.Label
+			}
+		}
+	}
+	return labels
+}
+
+// formatValue formats a tick or share value without trailing zeros and with
+// a suffix for thousands and millions.
+func formatValue(v float64) string {
+	switch abs := math.Abs(v); {
+	case abs >= 1e6:
+		return trimFloat(v/1e6) + "M"
+	case abs >= 1e4:
+		return trimFloat(v/1e3) + "k"
+	}
+	return trimFloat(v)
+}
+
+// trimFloat formats v with at most one decimal place.
+func trimFloat(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+}
+
+// truncate shortens a label so it fits under its tick.
+func truncate(label string) string {
+	runes := []rune(label)
+	if len(runes) <= maxLabelLen {
+		return label
+	}
+	return string(runes[:maxLabelLen-2]) + ".."
+}
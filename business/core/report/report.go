@@ -2,86 +2,295 @@
 package report
 
 import (
-	"reflect"
-	"sync"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
 
-	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/report"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
 
-// Core manages the set of API's for user access.
+// Set of error variables for report operations.
+var (
+	ErrUnknownReport = errors.New("report not found")
+	ErrUnknownChart  = errors.New("chart type not found")
+)
+
+// DefaultRange is the window of time reported on when no range is provided.
+const DefaultRange = 30 * 24 * time.Hour
+
+// DefaultLimit is the number of rows returned by top-N reports when no limit
+// is provided.
+const DefaultLimit = 10
+
+// Params defines the time range, grouping and limit used to build a report.
+// Chart overrides the type of chart the report is drawn with.
+type Params struct {
+	Grouping string
+	From     time.Time
+	To       time.Time
+	Limit    int
+	Chart    string
+}
+
+// Core manages the set of API's for report access.
 type Core struct {
-	log  *zap.SugaredLogger
-	user user.Store
-	dash Dashboard
+	log    *zap.SugaredLogger
+	report report.Store
 }
 
-// NewCore constructs a core for user api access.
+// NewCore constructs a core for report api access.
 func NewCore(log *zap.SugaredLogger, db *sqlx.DB) Core {
 	return Core{
-		log:  log,
-		user: user.NewStore(log, db),
-		dash: generateDashboard(),
+		log:    log,
+		report: report.NewStore(log, db),
 	}
 }
 
-// Interface being implemented in chart.go
-//
-// type report interface {
-// 	LoadData()
-// 	CanHaveView() bool
-// }
-
-// Dashboard creates a map which stores new charts
-type Dashboard struct {
-	mu     sync.Mutex
-	charts map[string]report
+// Names returns the sorted set of reports that can be requested.
+func (c Core) Names() []string {
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
 }
 
-// generateDashboard creates a personal mutex for each user's dash
-func generateDashboard() Dashboard {
-	var mu sync.Mutex
-	return Dashboard{
-		mu:     mu,
-		charts: make(map[string]report),
+// Exists reports whether the named report can be requested.
+func (c Core) Exists(name string) bool {
+	_, exists := definitions[name]
+	return exists
+}
+
+// Report queries the data behind the named report and returns it loaded into
+// the chart the report is drawn with.
+func (c Core) Report(ctx context.Context, name string, p Params, now time.Time) (Chart, error) {
+	def, exists := definitions[name]
+	if !exists {
+		return Chart{}, fmt.Errorf("report[%s]: %w", name, ErrUnknownReport)
+	}
+
+	p = p.withDefaults(now)
+
+	series, err := def.load(ctx, c.report, p)
+	if err != nil {
+		return Chart{}, fmt.Errorf("loading report[%s]: %w", name, err)
+	}
+
+	chartType := def.chart
+	if p.Chart != "" {
+		chartType = p.Chart
+	}
+
+	r, err := newChart(chartType)
+	if err != nil {
+		return Chart{}, err
 	}
+
+	ch := r.chart()
+	ch.Title = def.title
+	ch.X.Name = def.x
+	ch.Y.Name = def.y
+	if def.x == "" {
+		ch.X.Name = p.Grouping
+	}
+
+	if err := r.LoadData(series); err != nil {
+		return Chart{}, fmt.Errorf("loading chart[%s]: %w", name, err)
+	}
+
+	return *ch, nil
 }
 
-// CreateNew is a chart constructor
-func (d *Dashboard) CreateNew(name string, chartType interface{}) Chart {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	
-	d.charts[name] = selectChart(chartType)
+// withDefaults fills in any parameter that was not provided.
+func (p Params) withDefaults(now time.Time) Params {
+	if p.Grouping == "" {
+		p.Grouping = report.GroupDay
+	}
+	if p.To.IsZero() {
+		p.To = now
+	}
+	if p.From.IsZero() {
+		p.From = p.To.Add(-DefaultRange)
+	}
+	if p.Limit <= 0 {
+		p.Limit = DefaultLimit
+	}
 
-	return Chart{}
+	return p
 }
 
-func selectChart[CT collection](chartType CT) CT {
-	var chart CT	
+// filter converts the parameters into the store's filter.
+func (p Params) filter() report.Filter {
+	return report.Filter{
+		Grouping: p.Grouping,
+		From:     p.From,
+		To:       p.To,
+	}
+}
 
-	return chart
+// =============================================================================
+
+// definition describes how a named report is queried and which chart it is
+// drawn with. An empty x axis name means the axis is named by the grouping.
+type definition struct {
+	chart string
+	title string
+	x     string
+	y     string
+	load  func(ctx context.Context, s report.Store, p Params) ([]Series, error)
 }
 
-type collection interface {
-	GanttChart 			|
-	GanttTable 			|
-	HistogramChart 		|
-	ColumnChart 		|
-	LineChart 			|
-	BarChart 			|
-	PieChart 			|
-	ScatterXYChart 		|
-	BubbleChart 		|
-	StockChart 			|
-	SurfaceChart 		|
-	RadarChart 			|
-	TreemapChart 		|
-	SunburstChart 		|
-	BoxandWhiskerChart 	|
-	WaterfallChart 		|
-	FunnelChart 		|
-	ComboChart 			|
-	MapChart
+// definitions holds the set of reports that can be requested by name.
+var definitions = map[string]definition{
+	"revenue": {
+		chart: TypeLine,
+		title: "Revenue",
+		y:     "revenue",
+		load: periodSeries(func(pd report.Period) (string, float64) {
+			return "Revenue", float64(pd.Revenue)
+		}),
+	},
+	"units": {
+		chart: TypeLine,
+		title: "Units Sold",
+		y:     "units",
+		load: periodSeries(func(pd report.Period) (string, float64) {
+			return "Units", float64(pd.Units)
+		}),
+	},
+	"orders": {
+		chart: TypeLine,
+		title: "Orders",
+		y:     "orders",
+		load: periodSeries(func(pd report.Period) (string, float64) {
+			return "Orders", float64(pd.Orders)
+		}),
+	},
+	"average-order-value": {
+		chart: TypeLine,
+		title: "Average Order Value",
+		y:     "average",
+		load: periodSeries(func(pd report.Period) (string, float64) {
+			return "Average", pd.Average
+		}),
+	},
+	"revenue-by-product": {
+		chart: TypeBar,
+		title: "Revenue by Product",
+		x:     "product",
+		y:     "revenue",
+		load: func(ctx context.Context, s report.Store, p Params) ([]Series, error) {
+			totals, err := s.QueryByProduct(ctx, p.filter())
+			if err != nil {
+				return nil, err
+			}
+			return productSeries("Revenue", totals, func(t report.ProductTotal) int { return t.Revenue }), nil
+		},
+	},
+	"units-by-product": {
+		chart: TypeBar,
+		title: "Units by Product",
+		x:     "product",
+		y:     "units",
+		load: func(ctx context.Context, s report.Store, p Params) ([]Series, error) {
+			totals, err := s.QueryByProduct(ctx, p.filter())
+			if err != nil {
+				return nil, err
+			}
+			return productSeries("Units", totals, func(t report.ProductTotal) int { return t.Units }), nil
+		},
+	},
+	"product-share": {
+		chart: TypePie,
+		title: "Revenue Share by Product",
+		x:     "product",
+		y:     "revenue",
+		load: func(ctx context.Context, s report.Store, p Params) ([]Series, error) {
+			totals, err := s.QueryByProduct(ctx, p.filter())
+			if err != nil {
+				return nil, err
+			}
+			return productSeries("Revenue", totals, func(t report.ProductTotal) int { return t.Revenue }), nil
+		},
+	},
+	"top-sellers": {
+		chart: TypeBar,
+		title: "Top Sellers",
+		x:     "product",
+		y:     "units",
+		load: func(ctx context.Context, s report.Store, p Params) ([]Series, error) {
+			totals, err := s.QueryTopProducts(ctx, p.filter(), p.Limit)
+			if err != nil {
+				return nil, err
+			}
+			return productSeries("Units", totals, func(t report.ProductTotal) int { return t.Units }), nil
+		},
+	},
+	"revenue-by-user": {
+		chart: TypeBar,
+		title: "Revenue by User",
+		x:     "user",
+		y:     "revenue",
+		load: func(ctx context.Context, s report.Store, p Params) ([]Series, error) {
+			totals, err := s.QueryByUser(ctx, p.filter())
+			if err != nil {
+				return nil, err
+			}
+
+			revenue := Series{Name: "Revenue", Points: make([]Point, len(totals))}
+			units := Series{Name: "Units", Points: make([]Point, len(totals))}
+			for i, t := range totals {
+				revenue.Points[i] = Point{Label: t.Name, Value: float64(t.Revenue)}
+				units.Points[i] = Point{Label: t.Name, Value: float64(t.Units)}
+			}
+			return []Series{revenue, units}, nil
+		},
+	},
+}
+
+// periodSeries constructs a loader that plots one value per bucket of time.
+func periodSeries(value func(pd report.Period) (string, float64)) func(context.Context, report.Store, Params) ([]Series, error) {
+	f := func(ctx context.Context, s report.Store, p Params) ([]Series, error) {
+		periods, err := s.QueryByPeriod(ctx, p.filter())
+		if err != nil {
+			return nil, err
+		}
+
+		var series Series
+		series.Points = make([]Point, len(periods))
+		for i, pd := range periods {
+			name, v := value(pd)
+			series.Name = name
+			start := pd.Start
+			series.Points[i] = Point{Label: periodLabel(start, p.Grouping), Value: v, Time: &start}
+		}
+
+		return []Series{series}, nil
+	}
+
+	return f
+}
+
+// productSeries plots one value per product.
+func productSeries(name string, totals []report.ProductTotal, value func(t report.ProductTotal) int) []Series {
+	series := Series{Name: name, Points: make([]Point, len(totals))}
+	for i, t := range totals {
+		series.Points[i] = Point{Label: t.Name, Value: float64(value(t))}
+	}
+
+	return []Series{series}
+}
+
+// periodLabel formats the start of a bucket of time for display.
+func periodLabel(t time.Time, grouping string) string {
+	if grouping == report.GroupMonth {
+		return t.Format("2006-01")
+	}
+	return t.Format("2006-01-02")
 }
@@ -1,2 +1,334 @@
 // Package report provides an example of a core business API.
-package report
\ No newline at end of file
+package report
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/foundation/canvas"
+	"github.com/deliveranceTechSolutions/erp/foundation/sheet"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestLoadData(t *testing.T) {
+	t.Log("Given the need to load report data into charts.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen loading two series into a line chart.", testID)
+		{
+			r, err := newChart(TypeLine)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to construct a line chart: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to construct a line chart.", success, testID)
+
+			series := []Series{
+				{Name: "Revenue", Points: []Point{{Label: "2019-01-01", Value: 100}, {Label: "2019-01-02", Value: 250.5}}},
+				{Name: "Units", Points: []Point{{Label: "2019-01-01", Value: 2}}},
+			}
+			if err := r.LoadData(series); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to load the series: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to load the series.", success, testID)
+
+			ch := r.chart()
+			if exp, got := 2, ch.X.Frequency; exp != got {
+				t.Logf("\t\tTest %d:\texp: %d", testID, exp)
+				t.Logf("\t\tTest %d:\tgot: %d", testID, got)
+				t.Fatalf("\t%s\tTest %d:\tShould size the x axis to the longest series.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould size the x axis to the longest series.", success, testID)
+
+			if exp, got := 251, ch.Y.Magnitude; exp != got {
+				t.Logf("\t\tTest %d:\texp: %d", testID, exp)
+				t.Logf("\t\tTest %d:\tgot: %d", testID, got)
+				t.Fatalf("\t%s\tTest %d:\tShould size the y axis to the largest value.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould size the y axis to the largest value.", success, testID)
+
+			if err := r.Render(canvas.NewSVG(RenderWidth, RenderHeight)); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to render a loaded chart: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to render a loaded chart.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen loading two series into a pie chart.", testID)
+		{
+			r, err := newChart(TypePie)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to construct a pie chart: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to construct a pie chart.", success, testID)
+
+			if err := r.LoadData(make([]Series, 2)); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to load more than one series.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to load more than one series.", success, testID)
+		}
+	}
+}
+
+func TestParams(t *testing.T) {
+	t.Log("Given the need to default report parameters.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen no parameters are provided.", testID)
+		{
+			now := time.Date(2019, time.March, 24, 0, 0, 0, 0, time.UTC)
+			p := Params{}.withDefaults(now)
+
+			if !p.To.Equal(now) {
+				t.Fatalf("\t%s\tTest %d:\tShould end the range now: %v", failed, testID, p.To)
+			}
+			t.Logf("\t%s\tTest %d:\tShould end the range now.", success, testID)
+
+			if exp := now.Add(-DefaultRange); !p.From.Equal(exp) {
+				t.Fatalf("\t%s\tTest %d:\tShould start the range %v earlier: %v", failed, testID, DefaultRange, p.From)
+			}
+			t.Logf("\t%s\tTest %d:\tShould start the range %v earlier.", success, testID, DefaultRange)
+
+			if p.Grouping != "day" || p.Limit != DefaultLimit {
+				t.Fatalf("\t%s\tTest %d:\tShould group by day with the default limit: %+v", failed, testID, p)
+			}
+			t.Logf("\t%s\tTest %d:\tShould group by day with the default limit.", success, testID)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	t.Log("Given the need to draw loaded charts as images.")
+	{
+		series := []Series{
+			{Name: "Revenue", Points: []Point{{Label: "Comic Books", Value: 350}, {Label: "McDonalds Toys", Value: 225}}},
+		}
+
+		for testID, chartType := range []string{TypeLine, TypeBar, TypePie, TypeScatter, TypeBubble} {
+			t.Logf("\tTest %d:\tWhen rendering a %s chart.", testID, chartType)
+			{
+				r, err := newChart(chartType)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to construct the chart: %v", failed, testID, err)
+				}
+				r.chart().Title = "Revenue & Units"
+				if err := r.LoadData(series); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to load the series: %v", failed, testID, err)
+				}
+
+				var svg bytes.Buffer
+				if err := Render(&svg, *r.chart(), canvas.FormatSVG); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to render SVG: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to render SVG.", success, testID)
+
+				if !strings.HasPrefix(svg.String(), "<svg") || !strings.Contains(svg.String(), "Revenue &amp; Units") {
+					t.Fatalf("\t%s\tTest %d:\tShould produce an SVG document with an escaped title:\n%s", failed, testID, svg.String())
+				}
+				t.Logf("\t%s\tTest %d:\tShould produce an SVG document with an escaped title.", success, testID)
+
+				var img bytes.Buffer
+				if err := Render(&img, *r.chart(), canvas.FormatPNG); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to render PNG: %v", failed, testID, err)
+				}
+
+				decoded, err := png.Decode(&img)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould produce a valid PNG: %v", failed, testID, err)
+				}
+				if b := decoded.Bounds(); b.Dx() != RenderWidth || b.Dy() != RenderHeight {
+					t.Fatalf("\t%s\tTest %d:\tShould produce a %dx%d PNG: %v", failed, testID, RenderWidth, RenderHeight, b)
+				}
+				t.Logf("\t%s\tTest %d:\tShould produce a valid PNG.", success, testID)
+			}
+		}
+
+		testID := 5
+		t.Logf("\tTest %d:\tWhen rendering a chart that was never loaded.", testID)
+		{
+			if err := Render(&bytes.Buffer{}, Chart{Type: TypeLine}, canvas.FormatSVG); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to render the chart.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to render the chart.", success, testID)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	t.Log("Given the need to download report data as a spreadsheet.")
+	{
+		day := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+		series := []Series{
+			{Name: "Revenue", Points: []Point{{Label: "2019-01-01", Value: 100, Time: &day}, {Label: "2019-01-02", Value: 250.5}}},
+			{Name: "Units", Points: []Point{{Label: "2019-01-01", Value: 2, Time: &day}}},
+		}
+
+		r, err := newChart(TypeLine)
+		if err != nil {
+			t.Fatalf("\t%s\tShould be able to construct a line chart: %v", failed, err)
+		}
+		r.chart().X.Name = "Period"
+		if err := r.LoadData(series); err != nil {
+			t.Fatalf("\t%s\tShould be able to load the series: %v", failed, err)
+		}
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen exporting a chart as CSV.", testID)
+		{
+			var buf bytes.Buffer
+			if err := Export(&buf, "revenue", *r.chart(), sheet.FormatCSV); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to export CSV: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to export CSV.", success, testID)
+
+			exp := "Period,Revenue,Units\n2019-01-01T00:00:00Z,100,2\n2019-01-02,250.5,\n"
+			if got := buf.String(); exp != got {
+				t.Logf("\t\tTest %d:\texp: %q", testID, exp)
+				t.Logf("\t\tTest %d:\tgot: %q", testID, got)
+				t.Fatalf("\t%s\tTest %d:\tShould write a header and one row per label.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould write a header and one row per label.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen exporting a chart as XLSX.", testID)
+		{
+			var buf bytes.Buffer
+			if err := Export(&buf, "revenue", *r.chart(), sheet.FormatXLSX); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to export XLSX: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to export XLSX.", success, testID)
+
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould produce a valid workbook archive: %v", failed, testID, err)
+			}
+
+			var worksheet string
+			for _, f := range zr.File {
+				if f.Name != "xl/worksheets/sheet1.xml" {
+					continue
+				}
+				rc, err := f.Open()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to open the worksheet: %v", failed, testID, err)
+				}
+				data, err := io.ReadAll(rc)
+				rc.Close()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to read the worksheet: %v", failed, testID, err)
+				}
+				worksheet = string(data)
+			}
+			if err := xml.Unmarshal([]byte(worksheet), new(any)); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould produce a well formed worksheet: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould produce a well formed worksheet.", success, testID)
+
+			for _, cell := range []string{`<c r="A2" s="1"><v>43466</v></c>`, `<c r="B3" s="0"><v>250.5</v></c>`} {
+				if !strings.Contains(worksheet, cell) {
+					t.Fatalf("\t%s\tTest %d:\tShould write typed cells, missing %s:\n%s", failed, testID, cell, worksheet)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould write typed cells.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen exporting labels that look like formulas.", testID)
+		{
+			r, err := newChart(TypeBar)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to construct a bar chart: %v", failed, testID, err)
+			}
+			hostile := []Series{{Name: "@Revenue", Points: []Point{{Label: "=HYPERLINK(\"http://evil\")", Value: 1}, {Label: "-1+1", Value: 2}}}}
+			if err := r.LoadData(hostile); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to load the series: %v", failed, testID, err)
+			}
+
+			var buf bytes.Buffer
+			if err := Export(&buf, "products", *r.chart(), sheet.FormatCSV); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to export CSV: %v", failed, testID, err)
+			}
+
+			exp := "Label,'@Revenue\n\"'=HYPERLINK(\"\"http://evil\"\")\",1\n'-1+1,2\n"
+			if got := buf.String(); exp != got {
+				t.Logf("\t\tTest %d:\texp: %q", testID, exp)
+				t.Logf("\t\tTest %d:\tgot: %q", testID, got)
+				t.Fatalf("\t%s\tTest %d:\tShould quote text that would run as a formula.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould quote text that would run as a formula.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen exporting labels that look like formulas as XLSX.", testID)
+		{
+			r, err := newChart(TypeBar)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to construct a bar chart: %v", failed, testID, err)
+			}
+			if err := r.LoadData([]Series{{Name: "Amount", Points: []Point{{Label: "-5", Value: 1}}}}); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to load the series: %v", failed, testID, err)
+			}
+
+			var buf bytes.Buffer
+			if err := Export(&buf, "products", *r.chart(), sheet.FormatXLSX); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to export XLSX: %v", failed, testID, err)
+			}
+
+			cells, err := readCells(buf.Bytes())
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to read the worksheet: %v", failed, testID, err)
+			}
+			if got := cells["A2"]; got != "-5" {
+				t.Fatalf("\t%s\tTest %d:\tShould read back the text as it was written, got %q.", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould read back the text as it was written.", success, testID)
+		}
+	}
+}
+
+// readCells returns the text of the inline string cells in the worksheet of
+// an XLSX workbook by their reference.
+func readCells(workbook []byte) (map[string]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(workbook), int64(len(workbook)))
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := zr.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var worksheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref  string `xml:"r,attr"`
+				Text string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.NewDecoder(rc).Decode(&worksheet); err != nil {
+		return nil, err
+	}
+
+	cells := make(map[string]string)
+	for _, row := range worksheet.Rows {
+		for _, cell := range row.Cells {
+			cells[cell.Ref] = cell.Text
+		}
+	}
+
+	return cells, nil
+}
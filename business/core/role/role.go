@@ -0,0 +1,241 @@
+// Package role provides an example of a core business API. Tenants define
+// roles as sets of permissions and assign them to their users alongside the
+// built in ADMIN and USER roles.
+package role
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/role"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Set of error variables for the ways a role can be misused.
+var (
+	ErrBuiltinRole       = errors.New("built in roles can't be changed")
+	ErrUnknownRole       = errors.New("role is not defined")
+	ErrUnknownPermission = errors.New("permission is not known")
+)
+
+// builtin holds the roles every tenant has. ADMIN is granted every
+// permission and USER is granted none beyond a user's own records.
+var builtin = map[string]bool{
+	auth.RoleAdmin: true,
+	auth.RoleUser:  true,
+}
+
+// Core manages the set of API's for role access.
+type Core struct {
+	log  *zap.SugaredLogger
+	role role.Store
+}
+
+// NewCore constructs a core for role api access.
+func NewCore(log *zap.SugaredLogger, db *sqlx.DB) Core {
+	return Core{
+		log:  log,
+		role: role.NewStore(log, db),
+	}
+}
+
+// Create defines a new role in the tenant. The role can only grant
+// permissions the claims have themselves.
+func (c Core) Create(ctx context.Context, claims auth.Claims, nr role.NewRole, now time.Time) (role.Role, error) {
+	if builtin[nr.Name] {
+		return role.Role{}, ErrBuiltinRole
+	}
+	if err := CheckPermissions(nr.Permissions); err != nil {
+		return role.Role{}, err
+	}
+	if !claims.HasPermission(nr.Permissions...) {
+		return role.Role{}, database.ErrForbidden
+	}
+
+	rol, err := c.role.Create(ctx, nr, now)
+	if err != nil {
+		return role.Role{}, fmt.Errorf("create: %w", err)
+	}
+
+	return rol, nil
+}
+
+// Update modifies a role. The change applies to every user assigned the
+// role. The role can only be given permissions the claims have themselves.
+func (c Core) Update(ctx context.Context, claims auth.Claims, name string, ur role.UpdateRole, now time.Time) (role.Role, error) {
+	if builtin[name] {
+		return role.Role{}, ErrBuiltinRole
+	}
+	if err := CheckPermissions(ur.Permissions); err != nil {
+		return role.Role{}, err
+	}
+	if !claims.HasPermission(ur.Permissions...) {
+		return role.Role{}, database.ErrForbidden
+	}
+
+	rol, err := c.role.Update(ctx, name, ur, now)
+	if err != nil {
+		return role.Role{}, fmt.Errorf("update: %w", err)
+	}
+
+	return rol, nil
+}
+
+// Delete removes a role, taking it away from every user assigned it.
+func (c Core) Delete(ctx context.Context, name string) error {
+	if builtin[name] {
+		return ErrBuiltinRole
+	}
+
+	if err := c.role.Delete(ctx, name); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves the roles defined in the tenant.
+func (c Core) Query(ctx context.Context) ([]role.Role, error) {
+	roles, err := c.role.Query(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return roles, nil
+}
+
+// QueryByName gets the specified role.
+func (c Core) QueryByName(ctx context.Context, name string) (role.Role, error) {
+	rol, err := c.role.QueryByName(ctx, name)
+	if err != nil {
+		return role.Role{}, fmt.Errorf("query: %w", err)
+	}
+
+	return rol, nil
+}
+
+// Permissions returns every permission granted by the roles.
+func (c Core) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	perms, err := c.role.Permissions(ctx, roles)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return perms, nil
+}
+
+// CheckAssignable verifies the claims can assign the roles to a user. Every
+// role must be defined in the tenant. Users can always hand out roles they
+// hold themselves. Other roles need the permission to manage roles, and even
+// then only roles granting nothing beyond the permissions the claims have, so
+// no one can grant more than they have. ADMIN is only assignable by ADMINs.
+func (c Core) CheckAssignable(ctx context.Context, claims auth.Claims, roles []string) error {
+	held := make(map[string]bool)
+	for _, r := range claims.Roles {
+		held[r] = true
+	}
+
+	var unheld []string
+	for _, r := range roles {
+		if !held[r] {
+			unheld = append(unheld, r)
+		}
+	}
+	if len(unheld) > 0 && !claims.HasPermission(auth.PermRolesManage) {
+		return database.ErrForbidden
+	}
+
+	grants, err := c.grants(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range roles {
+		if _, exists := grants[r]; !exists {
+			return validate.FieldErrors{{Field: "roles", Err: fmt.Sprintf("role[%s]: %s", r, ErrUnknownRole)}}
+		}
+	}
+
+	for _, r := range unheld {
+		if r == auth.RoleAdmin || !claims.HasPermission(grants[r]...) {
+			return database.ErrForbidden
+		}
+	}
+
+	return nil
+}
+
+// CheckManageable verifies the claims can change or remove a user holding
+// the roles. Users can always change themselves. Anyone else must hold every
+// role the user has, or at least every permission those roles grant, so no
+// one can take over a user with more than they have. Users holding ADMIN are
+// only manageable by ADMINs.
+func (c Core) CheckManageable(ctx context.Context, claims auth.Claims, userID string, roles []string) error {
+	if claims.Subject == userID {
+		return nil
+	}
+
+	held := make(map[string]bool)
+	for _, r := range claims.Roles {
+		held[r] = true
+	}
+
+	grants, err := c.grants(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range roles {
+		if held[r] {
+			continue
+		}
+		if r == auth.RoleAdmin || !claims.HasPermission(grants[r]...) {
+			return database.ErrForbidden
+		}
+	}
+
+	return nil
+}
+
+// CheckPermissions verifies every permission is one the service checks.
+func CheckPermissions(perms []string) error {
+	known := make(map[string]bool)
+	for _, p := range auth.Permissions {
+		known[p] = true
+	}
+
+	for _, p := range perms {
+		if !known[p] {
+			return validate.FieldErrors{{Field: "permissions", Err: fmt.Sprintf("permission[%s]: %s", p, ErrUnknownPermission)}}
+		}
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// grants returns the permissions granted by every role defined in the tenant,
+// including the built in ones.
+func (c Core) grants(ctx context.Context) (map[string][]string, error) {
+	defined, err := c.role.Query(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	grants := make(map[string][]string)
+	for name := range builtin {
+		grants[name] = nil
+	}
+	for _, rol := range defined {
+		grants[rol.Name] = rol.Permissions
+	}
+
+	return grants, nil
+}
@@ -0,0 +1,184 @@
+package role_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	roleCore "github.com/deliveranceTechSolutions/erp/business/core/role"
+	userCore "github.com/deliveranceTechSolutions/erp/business/core/user"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/role"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/data/tests"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var dbc = tests.DBContainer{
+	Image: "postgres:13-alpine",
+	Port:  "5432",
+	Args:  []string{"-e", "POSTGRES_PASSWORD=postgres"},
+}
+
+func TestRole(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	core := roleCore.NewCore(log, db)
+	users := userCore.NewCore(log, db, userCore.Config{})
+
+	const adminID = "5cf37266-3473-4006-984f-9325122678b7"
+	const userID = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+
+	claims := func(subject string, roles ...string) auth.Claims {
+		return auth.Claims{
+			StandardClaims: jwt.StandardClaims{Subject: subject},
+			TenantID:       tests.TenantID,
+			Roles:          roles,
+		}
+	}
+
+	t.Log("Given the need to define roles as sets of permissions.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a role.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+			now := time.Now()
+
+			nr := role.NewRole{
+				Name:        "AUDITOR",
+				Description: "Reads users and reports",
+				Permissions: []string{auth.PermUsersRead, auth.PermReportsRead},
+			}
+			if _, err := core.Create(ctx, claims(adminID, auth.RoleAdmin), nr, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a role : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create a role.", tests.Success, testID)
+
+			if _, err := core.Create(ctx, claims(adminID, auth.RoleAdmin), role.NewRole{Name: auth.RoleAdmin, Permissions: []string{auth.PermUsersRead}}, now); !errors.Is(err, roleCore.ErrBuiltinRole) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to redefine a built in role : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to redefine a built in role.", tests.Success, testID)
+
+			if _, err := core.Create(ctx, claims(adminID, auth.RoleAdmin), role.NewRole{Name: "CLERK", Permissions: []string{"ledger:post"}}, now); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to grant an unknown permission.", tests.Failed, testID)
+			} else if _, ok := validate.Cause(err).(validate.FieldErrors); !ok {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to grant an unknown permission : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to grant an unknown permission.", tests.Success, testID)
+
+			perms, err := core.Permissions(ctx, []string{auth.RoleUser, "AUDITOR"})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to look up permissions : %s.", tests.Failed, testID, err)
+			}
+			if len(perms) != 2 || perms[0] != auth.PermReportsRead || perms[1] != auth.PermUsersRead {
+				t.Fatalf("\t%s\tTest %d:\tShould be granted the role's permissions : %v.", tests.Failed, testID, perms)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be granted the role's permissions.", tests.Success, testID)
+
+			upd := role.UpdateRole{Permissions: []string{auth.PermUsersRead}}
+			if _, err := core.Update(ctx, claims(adminID, auth.RoleAdmin), "AUDITOR", upd, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to update a role : %s.", tests.Failed, testID, err)
+			}
+			perms, err = core.Permissions(ctx, []string{"AUDITOR"})
+			if err != nil || len(perms) != 1 || perms[0] != auth.PermUsersRead {
+				t.Fatalf("\t%s\tTest %d:\tShould see the updated permissions : %v %v.", tests.Failed, testID, err, perms)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to update a role.", tests.Success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen assigning roles to users.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+			now := time.Now()
+
+			err := users.AssignRoles(ctx, claims(userID, auth.RoleUser), userID, []string{auth.RoleUser, "AUDITOR"}, now)
+			if !errors.Is(err, database.ErrForbidden) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to grant yourself a role you don't hold : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to grant yourself a role you don't hold.", tests.Success, testID)
+
+			err = users.AssignRoles(ctx, claims(adminID, auth.RoleAdmin), userID, []string{auth.RoleUser, "UNDEFINED"}, now)
+			if _, ok := validate.Cause(err).(validate.FieldErrors); !ok {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to assign an undefined role : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to assign an undefined role.", tests.Success, testID)
+
+			if err := users.AssignRoles(ctx, claims(adminID, auth.RoleAdmin), userID, []string{auth.RoleUser, "AUDITOR"}, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to assign a role : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to assign a role.", tests.Success, testID)
+
+			manager := claims(adminID, "MANAGER")
+			manager.Permissions = []string{auth.PermRolesManage, auth.PermUsersRead}
+
+			if err := core.CheckAssignable(ctx, manager, []string{auth.RoleAdmin}); !errors.Is(err, database.ErrForbidden) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to assign ADMIN without being one : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to assign ADMIN without being one.", tests.Success, testID)
+
+			if err := core.CheckAssignable(ctx, manager, []string{"AUDITOR"}); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to assign a role granting permissions you have : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to assign a role granting permissions you have.", tests.Success, testID)
+
+			upd := role.UpdateRole{Permissions: []string{auth.PermUsersRead, auth.PermUsersWrite}}
+			if _, err := core.Update(ctx, manager, "AUDITOR", upd, now); !errors.Is(err, database.ErrForbidden) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to grant a permission you don't have : %v.", tests.Failed, testID, err)
+			}
+			if _, err := core.Update(ctx, claims(adminID, auth.RoleAdmin), "AUDITOR", upd, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to update a role : %s.", tests.Failed, testID, err)
+			}
+			if err := core.CheckAssignable(ctx, manager, []string{"AUDITOR"}); !errors.Is(err, database.ErrForbidden) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to assign a role granting permissions you don't have : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to grant a permission you don't have.", tests.Success, testID)
+
+			if err := core.Delete(ctx, "AUDITOR"); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to delete a role : %s.", tests.Failed, testID, err)
+			}
+
+			usr, err := users.QueryByID(ctx, claims(adminID, auth.RoleAdmin), userID)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to retrieve the user : %s.", tests.Failed, testID, err)
+			}
+			if len(usr.Roles) != 1 || usr.Roles[0] != auth.RoleUser {
+				t.Fatalf("\t%s\tTest %d:\tShould take a deleted role away from its users : %v.", tests.Failed, testID, usr.Roles)
+			}
+			t.Logf("\t%s\tTest %d:\tShould take a deleted role away from its users.", tests.Success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen changing users who hold more than you.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+			now := time.Now()
+
+			manager := claims(userID, "MANAGER")
+			manager.Permissions = []string{auth.PermUsersRead, auth.PermUsersWrite}
+
+			pass := "gophers2"
+			upd := user.UpdateUser{Password: &pass, PasswordConfirm: &pass}
+			if _, err := users.Update(ctx, manager, adminID, upd, now); !errors.Is(err, database.ErrForbidden) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to set the password of an ADMIN : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to set the password of an ADMIN.", tests.Success, testID)
+
+			if err := users.Delete(ctx, manager, adminID); !errors.Is(err, database.ErrForbidden) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to delete an ADMIN : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to delete an ADMIN.", tests.Success, testID)
+
+			name := "Manager"
+			if _, err := users.Update(ctx, manager, userID, user.UpdateUser{Name: &name}, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to change yourself : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to change yourself.", tests.Success, testID)
+		}
+	}
+}
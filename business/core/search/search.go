@@ -0,0 +1,44 @@
+// Package search provides an example of a core business API. Staff search
+// users, products, projects and dashboards by partial name or email from a
+// single box.
+package search
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/search"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Core manages the set of API's for search access.
+type Core struct {
+	log    *zap.SugaredLogger
+	search search.Store
+}
+
+// NewCore constructs a core for search api access.
+func NewCore(log *zap.SugaredLogger, db *sqlx.DB) Core {
+	return Core{
+		log:    log,
+		search: search.NewStore(log, db),
+	}
+}
+
+// Search finds the records matching the query that the claims are allowed to
+// view.
+func (c Core) Search(ctx context.Context, claims auth.Claims, sq search.Query) ([]search.Result, error) {
+
+	// PERFORM PRE BUSINESS OPERATIONS
+
+	results, err := c.search.Search(ctx, claims, sq)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+
+	// PERFORM POST BUSINESS OPERATIONS
+
+	return results, nil
+}
@@ -0,0 +1,235 @@
+// Package session provides an example of a core business API. A session is
+// started when a user signs in and continued with refresh tokens, each used
+// once and replaced, until it expires or is revoked.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/session"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// ErrTokenReused occurs when a refresh token that was already used is used
+// again. Only one of the two parties holding it can be the user, so the
+// whole session is revoked.
+var ErrTokenReused = errors.New("refresh token reused")
+
+// refreshTTL is how long a refresh token can be used for. Each refresh
+// issues a new token so an active session never expires.
+const refreshTTL = 30 * 24 * time.Hour
+
+// Core manages the set of API's for session access.
+type Core struct {
+	log     *zap.SugaredLogger
+	session session.Store
+	user    user.Store
+	tokens  auth.Policy
+}
+
+// NewCore constructs a core for session api access. Refreshed access tokens
+// are issued under the tokens policy.
+func NewCore(log *zap.SugaredLogger, db *sqlx.DB, tokens auth.Policy) Core {
+	return Core{
+		log:     log,
+		session: session.NewStore(log, db),
+		user:    user.NewStore(log, db),
+		tokens:  tokens,
+	}
+}
+
+// Start begins a session for the user the claims were issued to and returns
+// its first refresh token.
+func (c Core) Start(ctx context.Context, claims auth.Claims, now time.Time) (string, error) {
+	ctx = database.WithTenant(ctx, claims.TenantID)
+	ctx = database.WithUser(ctx, claims.Subject, claims.Roles)
+
+	token, err := c.issue(ctx, claims, validate.GenerateID(), now)
+	if err != nil {
+		return "", fmt.Errorf("issue: %w", err)
+	}
+
+	return token, nil
+}
+
+// Record keeps track of an access token the claims are for that was issued
+// without a refresh token, so it is revoked along with the user's sessions.
+// The record can't be used to refresh the token.
+func (c Core) Record(ctx context.Context, claims auth.Claims, now time.Time) error {
+	ctx = database.WithTenant(ctx, claims.TenantID)
+	ctx = database.WithUser(ctx, claims.Subject, claims.Roles)
+
+	expires := time.Unix(claims.ExpiresAt, 0)
+	nrt := session.NewRefreshToken{
+		UserID:        claims.Subject,
+		FamilyID:      validate.GenerateID(),
+		ClientID:      claims.ClientID,
+		Scope:         claims.Scope,
+		AMR:           claims.AMR,
+		AccessID:      claims.Id,
+		AccessExpires: expires,
+		DateExpires:   expires,
+	}
+
+	if _, err := c.session.Create(ctx, nrt, now); err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	return nil
+}
+
+// Refresh uses the refresh token to continue its session. A session started
+// by an OAuth client can only be continued by that client, which is named by
+// clientID. It returns the claims for a new access token along with the
+// refresh token to use next.
+func (c Core) Refresh(ctx context.Context, token string, clientID string, now time.Time) (auth.Claims, string, error) {
+	rt, err := c.session.QueryByHash(database.WithSystem(ctx), hash(token))
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return auth.Claims{}, "", database.ErrAuthenticationFailure
+		}
+		return auth.Claims{}, "", fmt.Errorf("query: %w", err)
+	}
+
+	ctx = database.WithTenant(ctx, rt.TenantID)
+	ctx = database.WithUser(ctx, rt.UserID, nil)
+
+	var boundTo string
+	if rt.ClientID != nil {
+		boundTo = *rt.ClientID
+	}
+
+	switch {
+	case boundTo != clientID, rt.DateRevoked != nil, !now.Before(rt.DateExpires):
+		return auth.Claims{}, "", database.ErrAuthenticationFailure
+	case rt.DateUsed != nil:
+		return auth.Claims{}, "", c.reused(ctx, rt, now)
+	}
+
+	if _, err := c.session.Use(ctx, rt.ID, now); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return auth.Claims{}, "", c.reused(ctx, rt, now)
+		}
+		return auth.Claims{}, "", fmt.Errorf("use: %w", err)
+	}
+
+	self := auth.Claims{StandardClaims: jwt.StandardClaims{Subject: rt.UserID}}
+	usr, err := c.user.QueryByID(ctx, self, rt.UserID)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return auth.Claims{}, "", database.ErrAuthenticationFailure
+		}
+		return auth.Claims{}, "", fmt.Errorf("query user: %w", err)
+	}
+
+	claims := auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Id:      validate.GenerateID(),
+			Subject: usr.ID,
+		},
+		TenantID: usr.TenantID,
+		Roles:    usr.Roles,
+		ClientID: clientID,
+		Scope:    rt.Scope,
+		AMR:      rt.AMR,
+	}
+	claims = c.tokens.Stamp(claims, now)
+
+	next, err := c.issue(database.WithUser(ctx, usr.ID, usr.Roles), claims, rt.FamilyID, now)
+	if err != nil {
+		return auth.Claims{}, "", fmt.Errorf("issue: %w", err)
+	}
+
+	return claims, next, nil
+}
+
+// Logout ends the session the access token the claims were read from was
+// issued for. It returns the access tokens that were revoked.
+func (c Core) Logout(ctx context.Context, claims auth.Claims, now time.Time) ([]session.Revoked, error) {
+	if claims.Id == "" {
+		return nil, database.ErrInvalidID
+	}
+
+	revoked, err := c.session.RevokeAccess(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0), now)
+	if err != nil {
+		return nil, fmt.Errorf("revoke: %w", err)
+	}
+
+	return revoked, nil
+}
+
+// RevokeAll ends every session of the user. Users can end their own sessions
+// and users permitted to write users can end anyone's. It returns the access
+// tokens that were revoked.
+func (c Core) RevokeAll(ctx context.Context, claims auth.Claims, userID string, now time.Time) ([]session.Revoked, error) {
+	if !claims.HasPermission(auth.PermUsersWrite) && claims.Subject != userID {
+		return nil, database.ErrForbidden
+	}
+
+	revoked, err := c.session.RevokeUser(ctx, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("revoke: %w", err)
+	}
+
+	return revoked, nil
+}
+
+// =============================================================================
+
+// issue creates a refresh token in the session for the access token the
+// claims are for.
+func (c Core) issue(ctx context.Context, claims auth.Claims, familyID string, now time.Time) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generating token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	nrt := session.NewRefreshToken{
+		UserID:        claims.Subject,
+		FamilyID:      familyID,
+		ClientID:      claims.ClientID,
+		Scope:         claims.Scope,
+		AMR:           claims.AMR,
+		TokenHash:     hash(token),
+		AccessID:      claims.Id,
+		AccessExpires: time.Unix(claims.ExpiresAt, 0),
+		DateExpires:   now.Add(refreshTTL),
+	}
+
+	if _, err := c.session.Create(ctx, nrt, now); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// reused revokes the session the refresh token belongs to and reports the
+// reuse.
+func (c Core) reused(ctx context.Context, rt session.RefreshToken, now time.Time) error {
+	c.log.Infow("session", "status", "refresh token reused", "userID", rt.UserID, "familyID", rt.FamilyID)
+
+	if _, err := c.session.RevokeFamily(ctx, rt.FamilyID, now); err != nil {
+		return fmt.Errorf("revoke: %w", err)
+	}
+
+	return ErrTokenReused
+}
+
+// hash returns the hash of a refresh token that is stored in its place.
+func hash(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -0,0 +1,114 @@
+package session_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/core/session"
+	sessionStore "github.com/deliveranceTechSolutions/erp/business/data/store/session"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/data/tests"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+)
+
+var dbc = tests.DBContainer{
+	Image: "postgres:13-alpine",
+	Port:  "5432",
+	Args:  []string{"-e", "POSTGRES_PASSWORD=postgres"},
+}
+
+func TestSession(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	tokens := auth.Policy{TTL: time.Hour}
+	core := session.NewCore(log, db, tokens)
+	store := sessionStore.NewStore(log, db)
+	userStore := user.NewStore(log, db)
+
+	t.Log("Given the need to continue and end sessions.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen refreshing a session.", testID)
+		{
+			ctx := context.Background()
+			now := time.Now()
+
+			claims, err := userStore.Authenticate(ctx, now, "admin@example.com", "gophers")
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to authenticate : %s.", tests.Failed, testID, err)
+			}
+			claims = tokens.Stamp(claims, now)
+
+			first, err := core.Start(ctx, claims, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to start a session : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to start a session.", tests.Success, testID)
+
+			refreshed, second, err := core.Refresh(ctx, first, "", now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to refresh the session : %s.", tests.Failed, testID, err)
+			}
+			if refreshed.Subject != claims.Subject || refreshed.Id == claims.Id || second == first {
+				t.Fatalf("\t%s\tTest %d:\tShould get a new access and refresh token : %+v.", tests.Failed, testID, refreshed)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get a new access and refresh token.", tests.Success, testID)
+
+			if _, _, err := core.Refresh(ctx, first, "", now); !errors.Is(err, session.ErrTokenReused) {
+				t.Fatalf("\t%s\tTest %d:\tShould detect a refresh token being reused : %v.", tests.Failed, testID, err)
+			}
+			if _, _, err := core.Refresh(ctx, second, "", now); !errors.Is(err, database.ErrAuthenticationFailure) {
+				t.Fatalf("\t%s\tTest %d:\tShould revoke the session when a token is reused : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould revoke the session when a token is reused.", tests.Success, testID)
+
+			tctx := database.WithTenant(ctx, tests.TenantID)
+			for _, jti := range []string{claims.Id, refreshed.Id} {
+				revoked, err := store.Revoked(tctx, jti)
+				if err != nil || !revoked {
+					t.Fatalf("\t%s\tTest %d:\tShould revoke the access tokens of the session : %v.", tests.Failed, testID, err)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould revoke the access tokens of the session.", tests.Success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen logging out.", testID)
+		{
+			ctx := context.Background()
+			now := time.Now()
+
+			claims, err := userStore.Authenticate(ctx, now, "admin@example.com", "gophers")
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to authenticate : %s.", tests.Failed, testID, err)
+			}
+			claims = tokens.Stamp(claims, now)
+
+			token, err := core.Start(ctx, claims, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to start a session : %s.", tests.Failed, testID, err)
+			}
+
+			tctx := database.WithTenant(ctx, claims.TenantID)
+			revoked, err := core.Logout(tctx, claims, now)
+			if err != nil || len(revoked) != 1 || revoked[0].JTI != claims.Id {
+				t.Fatalf("\t%s\tTest %d:\tShould revoke the access token : %v %+v.", tests.Failed, testID, err, revoked)
+			}
+			t.Logf("\t%s\tTest %d:\tShould revoke the access token.", tests.Success, testID)
+
+			if _, _, err := core.Refresh(ctx, token, "", now); !errors.Is(err, database.ErrAuthenticationFailure) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to refresh the session : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to refresh the session.", tests.Success, testID)
+
+			if _, err := core.RevokeAll(tctx, claims, claims.Subject, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to revoke every session : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to revoke every session.", tests.Success, testID)
+		}
+	}
+}
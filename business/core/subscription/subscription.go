@@ -0,0 +1,330 @@
+// Package subscription provides an example of a core business API. Users
+// subscribe to a report or dashboard and receive it by email on a cron
+// schedule.
+package subscription
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	dashboardCore "github.com/deliveranceTechSolutions/erp/business/core/dashboard"
+	reportCore "github.com/deliveranceTechSolutions/erp/business/core/report"
+	roleCore "github.com/deliveranceTechSolutions/erp/business/core/role"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/subscription"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/deliveranceTechSolutions/erp/foundation/canvas"
+	"github.com/deliveranceTechSolutions/erp/foundation/cron"
+	"github.com/deliveranceTechSolutions/erp/foundation/mail"
+	"github.com/deliveranceTechSolutions/erp/foundation/sheet"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// ErrUnknownSource occurs when a subscription names a report that does not
+// exist.
+var ErrUnknownSource = errors.New("subscription source is not a known report")
+
+// Failed deliveries are retried after retryBase, doubling the delay after
+// every further failure, until maxRetries retries have failed.
+const (
+	retryBase  = time.Minute
+	maxRetries = 5
+)
+
+// Core manages the set of API's for subscription access.
+type Core struct {
+	log          *zap.SugaredLogger
+	subscription subscription.Store
+	user         user.Store
+	report       reportCore.Core
+	dashboard    dashboardCore.Core
+	role         roleCore.Core
+	mailer       mail.Mailer
+}
+
+// NewCore constructs a core for subscription api access. The mailer is used
+// to deliver subscriptions when they are due.
+func NewCore(log *zap.SugaredLogger, db *sqlx.DB, mailer mail.Mailer) Core {
+	return Core{
+		log:          log,
+		subscription: subscription.NewStore(log, db),
+		user:         user.NewStore(log, db),
+		report:       reportCore.NewCore(log, db),
+		dashboard:    dashboardCore.NewCore(log, db),
+		role:         roleCore.NewCore(log, db),
+		mailer:       mailer,
+	}
+}
+
+// Create inserts a new subscription into the database. Reports and the
+// report data on dashboards are only available to users permitted to read
+// reports, and dashboards must be visible to the subscriber.
+func (c Core) Create(ctx context.Context, claims auth.Claims, ns subscription.NewSubscription, now time.Time) (subscription.Subscription, error) {
+
+	// PERFORM PRE BUSINESS OPERATIONS
+
+	if err := validate.Check(ns); err != nil {
+		return subscription.Subscription{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	schedule, err := cron.Parse(ns.Schedule)
+	if err != nil {
+		return subscription.Subscription{}, validate.FieldErrors{{Field: "schedule", Err: err.Error()}}
+	}
+
+	next := schedule.Next(now.UTC())
+	if next.IsZero() {
+		return subscription.Subscription{}, validate.FieldErrors{{Field: "schedule", Err: "schedule never runs"}}
+	}
+
+	switch ns.SourceType {
+	case subscription.SourceReport:
+		if !c.report.Exists(ns.Source) {
+			return subscription.Subscription{}, validate.FieldErrors{{Field: "source", Err: fmt.Sprintf("source[%s]: %s", ns.Source, ErrUnknownSource)}}
+		}
+		if !claims.HasPermission(auth.PermReportsRead) {
+			return subscription.Subscription{}, database.ErrForbidden
+		}
+
+	case subscription.SourceDashboard:
+		if _, err := c.dashboard.QueryByID(ctx, claims, ns.Source); err != nil {
+			return subscription.Subscription{}, fmt.Errorf("source[%s]: %w", ns.Source, err)
+		}
+		if !claims.HasPermission(auth.PermReportsRead) {
+			return subscription.Subscription{}, database.ErrForbidden
+		}
+	}
+
+	sub, err := c.subscription.Create(ctx, claims, ns, next, now)
+	if err != nil {
+		return subscription.Subscription{}, fmt.Errorf("create: %w", err)
+	}
+
+	// PERFORM POST BUSINESS OPERATIONS
+
+	return sub, nil
+}
+
+// Delete removes a subscription from the database.
+func (c Core) Delete(ctx context.Context, claims auth.Claims, subscriptionID string) error {
+	if err := c.subscription.Delete(ctx, claims, subscriptionID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves the subscriptions owned by the claims subject.
+func (c Core) Query(ctx context.Context, claims auth.Claims) ([]subscription.Subscription, error) {
+	subs, err := c.subscription.Query(ctx, claims)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return subs, nil
+}
+
+// QueryByID gets the specified subscription from the database.
+func (c Core) QueryByID(ctx context.Context, claims auth.Claims, subscriptionID string) (subscription.Subscription, error) {
+	sub, err := c.subscription.QueryByID(ctx, claims, subscriptionID)
+	if err != nil {
+		return subscription.Subscription{}, fmt.Errorf("query: %w", err)
+	}
+
+	return sub, nil
+}
+
+// Deliver sends every subscription that is due at now and returns how many
+// were sent. Each due run is claimed before it is sent so when several
+// instances deliver at the same time a run is only ever sent by one of them.
+// Runs missed while no instance was running are sent once and the schedule
+// then continues from now. A failed delivery is retried with an increasing
+// delay, up to maxRetries times or until its next scheduled run if that comes
+// first. A subscription that can't be claimed or recorded is logged and
+// skipped so the rest are still delivered. Only failing to find the due
+// subscriptions fails the whole run.
+func (c Core) Deliver(ctx context.Context, now time.Time) (int, error) {
+	now = now.UTC()
+
+	// The scheduler works through every tenant's subscriptions and delivers
+	// each one scoped to the tenant it belongs to.
+	ctx = database.WithSystem(ctx)
+
+	due, err := c.subscription.QueryDue(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("query due: %w", err)
+	}
+
+	var sent int
+	for _, sub := range due {
+		schedule, err := cron.Parse(sub.Schedule)
+		if err != nil {
+			c.log.Errorw("subscription", "status", "invalid schedule", "subscriptionID", sub.ID, "ERROR", err)
+			continue
+		}
+
+		claimed, err := c.subscription.Claim(ctx, sub, schedule.Next(now), now)
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				continue
+			}
+			c.log.Errorw("subscription", "status", "claiming", "subscriptionID", sub.ID, "ERROR", err)
+			continue
+		}
+
+		result := c.send(database.WithTenant(ctx, claimed.TenantID), claimed, now)
+		if result != nil {
+			c.log.Errorw("subscription", "status", "delivery failed", "subscriptionID", claimed.ID, "ERROR", result)
+		} else {
+			sent++
+		}
+
+		recorded, err := c.subscription.RecordResult(ctx, claimed.ID, result, now)
+		if err != nil {
+			c.log.Errorw("subscription", "status", "recording result", "subscriptionID", claimed.ID, "ERROR", err)
+			continue
+		}
+
+		if result != nil && recorded.Failures <= maxRetries {
+			if err := c.subscription.Retry(ctx, claimed.ID, now.Add(retryDelay(recorded.Failures))); err != nil {
+				c.log.Errorw("subscription", "status", "scheduling retry", "subscriptionID", claimed.ID, "ERROR", err)
+			}
+		}
+	}
+
+	return sent, nil
+}
+
+// =============================================================================
+
+// send builds the subscription's attachments as its owner would see them and
+// emails them to the owner.
+func (c Core) send(ctx context.Context, sub subscription.Subscription, now time.Time) error {
+	claims := auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Subject: sub.UserID,
+		},
+		TenantID: sub.TenantID,
+	}
+
+	// Queries are made on behalf of the subscriber, first to learn their
+	// roles and then with them.
+	ctx = database.WithUser(ctx, sub.UserID, nil)
+	usr, err := c.user.QueryByID(ctx, claims, sub.UserID)
+	if err != nil {
+		return fmt.Errorf("query user: %w", err)
+	}
+	claims.Roles = usr.Roles
+	ctx = database.WithUser(ctx, usr.ID, usr.Roles)
+
+	claims.Permissions, err = c.role.Permissions(ctx, usr.Roles)
+	if err != nil {
+		return fmt.Errorf("query permissions: %w", err)
+	}
+	ctx = database.WithPermissions(ctx, claims.Permissions)
+
+	var attachments []mail.Attachment
+	switch sub.SourceType {
+	case subscription.SourceReport:
+		if !claims.HasPermission(auth.PermReportsRead) {
+			return database.ErrForbidden
+		}
+
+		chart, err := c.report.Report(ctx, sub.Source, reportCore.Params{}, now)
+		if err != nil {
+			return fmt.Errorf("report[%s]: %w", sub.Source, err)
+		}
+
+		a, err := attachment(sub.Source, chart, sub.Format)
+		if err != nil {
+			return err
+		}
+		attachments = append(attachments, a)
+
+	case subscription.SourceDashboard:
+		view, err := c.dashboard.Load(ctx, claims, sub.Source, now)
+		if err != nil {
+			return fmt.Errorf("dashboard[%s]: %w", sub.Source, err)
+		}
+
+		for _, cv := range view.Data {
+			a, err := attachment(view.Name+"-"+cv.Name, cv.Chart, sub.Format)
+			if err != nil {
+				return err
+			}
+			attachments = append(attachments, a)
+		}
+
+	default:
+		return fmt.Errorf("unknown source type[%s]", sub.SourceType)
+	}
+
+	msg := mail.Message{
+		To:          []string{usr.Email},
+		Subject:     sub.Name,
+		Body:        fmt.Sprintf("Your scheduled delivery of %q for %s is attached.\n", sub.Name, now.Format("2006-01-02")),
+		Attachments: attachments,
+	}
+
+	if err := c.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("sending: %w", err)
+	}
+
+	return nil
+}
+
+// retryDelay returns how long to wait before retrying a delivery that has
+// failed the given number of times in a row.
+func retryDelay(failures int) time.Duration {
+	return retryBase << (failures - 1)
+}
+
+// attachment writes the chart as a file in the subscription's format.
+func attachment(name string, chart reportCore.Chart, format string) (mail.Attachment, error) {
+	var buf bytes.Buffer
+	var contentType string
+
+	switch format {
+	case subscription.FormatCSV:
+		if err := reportCore.Export(&buf, name, chart, sheet.FormatCSV); err != nil {
+			return mail.Attachment{}, fmt.Errorf("exporting chart[%s]: %w", name, err)
+		}
+		contentType = sheet.ContentType(sheet.FormatCSV)
+
+	case subscription.FormatSVG:
+		if err := reportCore.Render(&buf, chart, canvas.FormatSVG); err != nil {
+			return mail.Attachment{}, fmt.Errorf("rendering chart[%s]: %w", name, err)
+		}
+		contentType = canvas.ContentType(canvas.FormatSVG)
+
+	default:
+		return mail.Attachment{}, fmt.Errorf("unknown format[%s]", format)
+	}
+
+	a := mail.Attachment{
+		Name:        filename(name) + "." + format,
+		ContentType: contentType,
+		Data:        buf.Bytes(),
+	}
+
+	return a, nil
+}
+
+// filename replaces the characters in name that do not belong in a file name.
+func filename(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+}
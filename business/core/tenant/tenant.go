@@ -0,0 +1,78 @@
+// Package tenant provides an example of a core business API. Tenants are
+// provisioned with their first admin so they can be signed in to right away.
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/tenant"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Core manages the set of API's for tenant access.
+type Core struct {
+	log    *zap.SugaredLogger
+	tenant tenant.Store
+	user   user.Store
+}
+
+// NewCore constructs a core for tenant api access.
+func NewCore(log *zap.SugaredLogger, db *sqlx.DB) Core {
+	return Core{
+		log:    log,
+		tenant: tenant.NewStore(log, db),
+		user:   user.NewStore(log, db),
+	}
+}
+
+// Provision creates a new tenant along with its first admin user. The admin
+// is provisioned by an operator who knows them, so their email address is
+// taken as verified.
+func (c Core) Provision(ctx context.Context, nt tenant.NewTenant, admin user.NewUser, now time.Time) (tenant.Tenant, user.User, error) {
+	admin.Roles = []string{auth.RoleAdmin, auth.RoleUser}
+
+	tnt, err := c.tenant.Create(database.WithSystem(ctx), nt, now)
+	if err != nil {
+		return tenant.Tenant{}, user.User{}, fmt.Errorf("create: %w", err)
+	}
+
+	usr, err := c.user.Create(database.WithTenant(ctx, tnt.ID), admin, now)
+	if err != nil {
+		if err := c.tenant.Delete(database.WithSystem(ctx), tnt.ID); err != nil {
+			c.log.Errorw("tenant", "status", "rollback failed", "tenantID", tnt.ID, "ERROR", err)
+		}
+		return tenant.Tenant{}, user.User{}, fmt.Errorf("create admin: %w", err)
+	}
+
+	if err := c.user.Verify(database.WithTenant(ctx, tnt.ID), usr.ID, now); err != nil {
+		return tenant.Tenant{}, user.User{}, fmt.Errorf("verify admin: %w", err)
+	}
+	usr.DateVerified = &now
+
+	return tnt, usr, nil
+}
+
+// Delete removes a tenant along with every record that belongs to it.
+func (c Core) Delete(ctx context.Context, tenantID string) error {
+	if err := c.tenant.Delete(database.WithSystem(ctx), tenantID); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
+
+// Query retrieves every tenant.
+func (c Core) Query(ctx context.Context) ([]tenant.Tenant, error) {
+	tenants, err := c.tenant.Query(database.WithSystem(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return tenants, nil
+}
@@ -0,0 +1,267 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/emailtoken"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/deliveranceTechSolutions/erp/foundation/mail"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrInvalidToken is returned when an emailed token is unknown, expired or
+// was already used.
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// These are how long emailed tokens can be used for. The emails tell users
+// so, keep them in step.
+const (
+	verifyTTL = 48 * time.Hour
+	resetTTL  = time.Hour
+)
+
+// backgroundTimeout is how long emails requested in the background have to
+// be sent.
+const backgroundTimeout = time.Minute
+
+// RequestPasswordReset emails the user with the email a link to reset their
+// password. Nothing is sent for unknown emails. The lookup and the email are
+// done in the background so callers can't tell the difference, not even by
+// how long the request takes, and the request can't be used to find out who
+// has an account.
+func (c Core) RequestPasswordReset(ctx context.Context, email string, now time.Time) {
+	c.background("sending reset email", func(ctx context.Context) error {
+		usr, err := c.user.QueryByEmailAnyTenant(ctx, email)
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return nil
+			}
+			return fmt.Errorf("query: %w", err)
+		}
+
+		if err := c.email(scoped(ctx, usr), usr, emailtoken.PurposeReset, now); err != nil {
+			return fmt.Errorf("email: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// ResetPassword sets a new password for the user the reset token was
+// emailed to. The token can only be used once. Since the user proved they
+// own their address it is verified as well, and their sessions and failed
+// sign ins are cleared.
+func (c Core) ResetPassword(ctx context.Context, token, password, confirm string, now time.Time) error {
+	et, err := c.token.QueryByHash(ctx, hash(token), emailtoken.PurposeReset, now)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("query: %w", err)
+	}
+
+	self := auth.Claims{
+		StandardClaims: jwt.StandardClaims{Subject: et.UserID},
+		TenantID:       et.TenantID,
+	}
+	ctx = database.WithTenant(ctx, et.TenantID)
+	ctx = database.WithUser(ctx, et.UserID, nil)
+
+	usr, err := c.user.QueryByID(ctx, self, et.UserID)
+	if err != nil {
+		return fmt.Errorf("query user: %w", err)
+	}
+
+	if password != confirm {
+		return validate.FieldErrors{{Field: "password_confirm", Err: "must match password"}}
+	}
+	if err := c.cfg.Password.Check("password", password, usr.Email); err != nil {
+		return err
+	}
+
+	if err := c.token.Use(ctx, et.TokenHash, now); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("use: %w", err)
+	}
+
+	uu := user.UpdateUser{
+		Password:        &password,
+		PasswordConfirm: &confirm,
+	}
+	if err := c.user.Update(ctx, self, usr.ID, uu, now); err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	if err := c.user.Verify(ctx, usr.ID, now); err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+
+	if _, err := c.session.RevokeUser(ctx, usr.ID, now); err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+
+	if c.cfg.Lockout.Attempts > 0 {
+		for _, key := range []string{accountKey(usr.Email), userKey(usr.ID)} {
+			if err := c.lockout.Reset(ctx, key); err != nil {
+				return fmt.Errorf("lockout: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// RequestVerification emails the user with the email a new link to verify
+// their address. Like password resets, it is done in the background so
+// nothing tells callers whether the email belongs to anyone, or whether it
+// was already verified.
+func (c Core) RequestVerification(ctx context.Context, email string, now time.Time) {
+	c.background("sending verification email", func(ctx context.Context) error {
+		usr, err := c.user.QueryByEmailAnyTenant(ctx, email)
+		if err != nil {
+			if errors.Is(err, database.ErrNotFound) {
+				return nil
+			}
+			return fmt.Errorf("query: %w", err)
+		}
+
+		if usr.DateVerified != nil {
+			return nil
+		}
+
+		if err := c.email(scoped(ctx, usr), usr, emailtoken.PurposeVerify, now); err != nil {
+			return fmt.Errorf("email: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Wait blocks until the emails requested in the background have been sent.
+func (c Core) Wait() {
+	c.pending.Wait()
+}
+
+// VerifyEmail records that the user the verification token was emailed to
+// owns their address. The token can only be used once.
+func (c Core) VerifyEmail(ctx context.Context, token string, now time.Time) error {
+	et, err := c.token.QueryByHash(ctx, hash(token), emailtoken.PurposeVerify, now)
+	if err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("query: %w", err)
+	}
+
+	ctx = database.WithTenant(ctx, et.TenantID)
+	ctx = database.WithUser(ctx, et.UserID, nil)
+
+	if err := c.token.Use(ctx, et.TokenHash, now); err != nil {
+		if errors.Is(err, database.ErrNotFound) {
+			return ErrInvalidToken
+		}
+		return fmt.Errorf("use: %w", err)
+	}
+
+	if err := c.user.Verify(ctx, et.UserID, now); err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// background runs fn apart from the request, which may be over before fn
+// is, with a time limit of its own. Failures are logged since there is no
+// one left to return them to.
+func (c Core) background(status string, fn func(ctx context.Context) error) {
+	c.pending.Add(1)
+
+	go func() {
+		defer c.pending.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), backgroundTimeout)
+		defer cancel()
+
+		if err := fn(ctx); err != nil {
+			c.log.Errorw("user", "status", status, "ERROR", err)
+		}
+	}()
+}
+
+// email issues a token for the purpose to the user and sends it to them.
+func (c Core) email(ctx context.Context, usr user.User, purpose string, now time.Time) error {
+	if c.cfg.Mailer == nil {
+		return nil
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("generating token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	var msg mail.Message
+	switch purpose {
+	case emailtoken.PurposeVerify:
+		msg = mail.Message{
+			Subject: "Verify your email address",
+			Body: fmt.Sprintf("Hi %s,\n\nUse this link to verify your email address. It expires in 48 hours.\n\n%s\n",
+				usr.Name, c.link("verify-email", token)),
+		}
+		if _, err := c.token.Create(ctx, usr.ID, purpose, hash(token), now.Add(verifyTTL), now); err != nil {
+			return fmt.Errorf("create token: %w", err)
+		}
+
+	case emailtoken.PurposeReset:
+		msg = mail.Message{
+			Subject: "Reset your password",
+			Body: fmt.Sprintf("Hi %s,\n\nUse this link to reset your password. It expires in an hour and can only be used once.\n\n%s\n\nIf you didn't ask to reset your password you can ignore this email.\n",
+				usr.Name, c.link("reset-password", token)),
+		}
+		if _, err := c.token.Create(ctx, usr.ID, purpose, hash(token), now.Add(resetTTL), now); err != nil {
+			return fmt.Errorf("create token: %w", err)
+		}
+
+	default:
+		return fmt.Errorf("unknown purpose[%s]", purpose)
+	}
+
+	msg.To = []string{usr.Email}
+	if err := c.cfg.Mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+
+	return nil
+}
+
+// link returns the address of the page the token is used on.
+func (c Core) link(page, token string) string {
+	return fmt.Sprintf("%s/%s?token=%s", strings.TrimRight(c.cfg.LinkURL, "/"), page, token)
+}
+
+// scoped returns the context scoped to the tenant of the user, acting as
+// them.
+func scoped(ctx context.Context, usr user.User) context.Context {
+	ctx = database.WithTenant(ctx, usr.TenantID)
+	return database.WithUser(ctx, usr.ID, usr.Roles)
+}
+
+// hash returns the hash an emailed token is stored as.
+func hash(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -0,0 +1,120 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/session"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+)
+
+// ErrWrongPassword is returned when a user changing their password doesn't
+// give their current one.
+var ErrWrongPassword = errors.New("current password is incorrect")
+
+// UpdateProfile changes the name and email of the user the claims belong to.
+// A new email has to be verified before the user can sign in with it. The
+// email can't be changed while the account is locked out, so the lock can't
+// be shed by moving to another address.
+func (c Core) UpdateProfile(ctx context.Context, claims auth.Claims, up user.UpdateProfile, now time.Time) error {
+	if err := validate.Check(up); err != nil {
+		return fmt.Errorf("validating data: %w", err)
+	}
+
+	if up.Email != nil && c.cfg.Lockout.Attempts > 0 {
+		usr, err := c.user.QueryByID(ctx, claims, claims.Subject)
+		if err != nil {
+			return fmt.Errorf("query: %w", err)
+		}
+
+		until, err := c.lockout.LockedUntil(ctx, []string{accountKey(usr.Email), userKey(usr.ID)}, now)
+		if err != nil {
+			return fmt.Errorf("lockout: %w", err)
+		}
+		if !until.IsZero() {
+			return ErrLocked
+		}
+	}
+
+	uu := user.UpdateUser{
+		Name:  up.Name,
+		Email: up.Email,
+	}
+	if _, err := c.Update(ctx, claims, claims.Subject, uu, now); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ChangePassword sets a new password for the user the claims belong to once
+// they give their current one, so a stolen token alone can't take the
+// account over. Wrong passwords count against the account like failed sign
+// ins do. Every session of the user is ended and the access tokens revoked
+// are returned, so anyone who learned the old password is signed out.
+func (c Core) ChangePassword(ctx context.Context, claims auth.Claims, cp user.ChangePassword, now time.Time) ([]session.Revoked, error) {
+	if err := validate.Check(cp); err != nil {
+		return nil, fmt.Errorf("validating data: %w", err)
+	}
+
+	usr, err := c.user.QueryByID(ctx, claims, claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	// Failures count against the account like failed sign ins do, and
+	// against the user too so changing email doesn't start the count over.
+	keys := c.lockoutKeys(usr.Email, "")
+	if c.cfg.Lockout.Attempts > 0 {
+		keys[userKey(usr.ID)] = c.cfg.Lockout.Attempts
+
+		until, err := c.lockout.LockedUntil(ctx, []string{accountKey(usr.Email), userKey(usr.ID)}, now)
+		if err != nil {
+			return nil, fmt.Errorf("lockout: %w", err)
+		}
+		if !until.IsZero() {
+			return nil, ErrLocked
+		}
+	}
+
+	if _, err := c.user.Authenticate(ctx, now, usr.Email, cp.CurrentPassword); err != nil {
+		if validate.Cause(err) != database.ErrAuthenticationFailure {
+			return nil, fmt.Errorf("authenticate: %w", err)
+		}
+		if err := c.fail(ctx, keys, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrWrongPassword
+	}
+	if c.cfg.Lockout.Attempts > 0 {
+		for _, key := range []string{accountKey(usr.Email), userKey(usr.ID)} {
+			if err := c.lockout.Reset(ctx, key); err != nil {
+				return nil, fmt.Errorf("lockout: %w", err)
+			}
+		}
+	}
+
+	if err := c.cfg.Password.Check("password", cp.Password, usr.Email); err != nil {
+		return nil, err
+	}
+
+	uu := user.UpdateUser{
+		Password:        &cp.Password,
+		PasswordConfirm: &cp.PasswordConfirm,
+	}
+	if err := c.user.Update(ctx, claims, usr.ID, uu, now); err != nil {
+		return nil, fmt.Errorf("update: %w", err)
+	}
+
+	revoked, err := c.session.RevokeUser(ctx, usr.ID, now)
+	if err != nil {
+		return nil, fmt.Errorf("revoke sessions: %w", err)
+	}
+
+	return revoked, nil
+}
@@ -5,34 +5,101 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	mfaCore "github.com/deliveranceTechSolutions/erp/business/core/mfa"
+	roleCore "github.com/deliveranceTechSolutions/erp/business/core/role"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/emailtoken"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/lockout"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/session"
 	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
 	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/password"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/deliveranceTechSolutions/erp/foundation/mail"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
 )
 
+// Set of error variables for the ways a sign in can be refused.
+var (
+	ErrLocked     = errors.New("too many failed sign ins, try again later")
+	ErrUnverified = errors.New("email address not verified")
+)
+
+// Lockout describes when failed sign ins lock an account or an address out.
+// After Attempts failures in a row for an account, or IPAttempts for an
+// address, sign ins are refused for Delay. The delay doubles with every
+// further failure up to MaxDelay. Failures are forgotten after Window
+// without one. A zero number of attempts turns that kind of lockout off.
+type Lockout struct {
+	Attempts   int
+	IPAttempts int
+	Delay      time.Duration
+	MaxDelay   time.Duration
+	Window     time.Duration
+}
+
+// Config holds the policies the core applies to users. Passwords are hashed
+// with the Hasher. The tokens users sign in for are issued under Tokens.
+// Verification and password reset emails are sent with the Mailer and link
+// to pages under LinkURL. Without a Mailer no emails are sent.
+type Config struct {
+	Password password.Policy
+	Hasher   password.Hasher
+	Lockout  Lockout
+	Tokens   auth.Policy
+	Mailer   mail.Mailer
+	LinkURL  string
+}
+
 // Core manages the set of API's for user access.
 type Core struct {
-	log  *zap.SugaredLogger
-	user user.Store
+	log     *zap.SugaredLogger
+	user    user.Store
+	role    roleCore.Core
+	lockout lockout.Store
+	token   emailtoken.Store
+	session session.Store
+	mfa     mfaCore.Core
+	cfg     Config
+	pending *sync.WaitGroup
 }
 
 // NewCore constructs a core for user api access.
-func NewCore(log *zap.SugaredLogger, db *sqlx.DB) Core {
+func NewCore(log *zap.SugaredLogger, db *sqlx.DB, cfg Config) Core {
 	return Core{
-		log:  log,
-		user: user.NewStore(log, db),
+		log:     log,
+		user:    user.NewStore(log, db).WithHasher(cfg.Hasher),
+		role:    roleCore.NewCore(log, db),
+		lockout: lockout.NewStore(log, db),
+		token:   emailtoken.NewStore(log, db),
+		session: session.NewStore(log, db),
+		mfa:     mfaCore.NewCore(log, db),
+		cfg:     cfg,
+		pending: new(sync.WaitGroup),
 	}
 }
 
-// Create inserts a new user into the database.
-func (c Core) Create(ctx context.Context, nu user.NewUser, now time.Time) (user.User, error) {
+// Create inserts a new user into the database. The claims must be able to
+// assign the user's roles.
+func (c Core) Create(ctx context.Context, claims auth.Claims, nu user.NewUser, now time.Time) (user.User, error) {
 
 	// PERFORM PRE BUSINESS OPERATIONS
 
+	if err := c.cfg.Password.Check("password", nu.Password, nu.Email); err != nil {
+		return user.User{}, err
+	}
+
+	if err := c.role.CheckAssignable(ctx, claims, nu.Roles); err != nil {
+		return user.User{}, err
+	}
+
 	usr, err := c.user.Create(ctx, nu, now)
 	if err != nil {
 		return user.User{}, fmt.Errorf("create: %w", err)
@@ -40,28 +107,103 @@ func (c Core) Create(ctx context.Context, nu user.NewUser, now time.Time) (user.
 
 	// PERFORM POST BUSINESS OPERATIONS
 
+	// The user can ask for the email again, so failing to send it doesn't
+	// fail the creation.
+	if err := c.email(ctx, usr, emailtoken.PurposeVerify, now); err != nil {
+		c.log.Errorw("user", "status", "sending verification email", "userID", usr.ID, "ERROR", err)
+	}
+
 	return usr, nil
 }
 
-// Update replaces a user document in the database.
-func (c Core) Update(ctx context.Context, claims auth.Claims, userID string, uu user.UpdateUser, now time.Time) error {
+// Update replaces a user document in the database. The claims must be able
+// to manage the user as it is, and to assign the new roles when they are
+// changed. A new password must meet the password policy and ends every
+// session of the user, like changing it themselves does. It returns the
+// access tokens that were revoked. A changed email has to be verified again
+// before the user can sign in with it.
+func (c Core) Update(ctx context.Context, claims auth.Claims, userID string, uu user.UpdateUser, now time.Time) ([]session.Revoked, error) {
 
 	// PERFORM PRE BUSINESS OPERATIONS
 
+	usr, err := c.user.QueryByID(ctx, claims, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	if err := c.role.CheckManageable(ctx, claims, userID, usr.Roles); err != nil {
+		return nil, err
+	}
+	emailChanged := uu.Email != nil && !strings.EqualFold(*uu.Email, usr.Email)
+	if uu.Email != nil {
+		usr.Email = *uu.Email
+	}
+
+	if uu.Password != nil {
+		if err := c.cfg.Password.Check("password", *uu.Password, usr.Email); err != nil {
+			return nil, err
+		}
+	}
+
+	if uu.Roles != nil {
+		if err := c.role.CheckAssignable(ctx, claims, uu.Roles); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := c.user.Update(ctx, claims, userID, uu, now); err != nil {
-		return fmt.Errorf("udpate: %w", err)
+		return nil, fmt.Errorf("udpate: %w", err)
 	}
 
 	// PERFORM POST BUSINESS OPERATIONS
 
+	// A new email is no longer verified, so the user is sent a link to
+	// verify it. They can ask for the email again, so failing to send it
+	// doesn't fail the update.
+	if emailChanged {
+		if err := c.email(ctx, usr, emailtoken.PurposeVerify, now); err != nil {
+			c.log.Errorw("user", "status", "sending verification email", "userID", usr.ID, "ERROR", err)
+		}
+	}
+
+	if uu.Password == nil {
+		return nil, nil
+	}
+
+	revoked, err := c.session.RevokeUser(ctx, userID, now)
+	if err != nil {
+		return nil, fmt.Errorf("revoke sessions: %w", err)
+	}
+
+	return revoked, nil
+}
+
+// AssignRoles replaces the roles of a user.
+func (c Core) AssignRoles(ctx context.Context, claims auth.Claims, userID string, roles []string, now time.Time) error {
+	if roles == nil {
+		roles = []string{}
+	}
+
+	if _, err := c.Update(ctx, claims, userID, user.UpdateUser{Roles: roles}, now); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// Delete removes a user from the database.
+// Delete removes a user from the database. The claims must be able to
+// manage the user.
 func (c Core) Delete(ctx context.Context, claims auth.Claims, userID string) error {
 
 	// PERFORM PRE BUSINESS OPERATIONS
 
+	usr, err := c.user.QueryByID(ctx, claims, userID)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	if err := c.role.CheckManageable(ctx, claims, userID, usr.Roles); err != nil {
+		return err
+	}
+
 	if err := c.user.Delete(ctx, claims, userID); err != nil {
 		return fmt.Errorf("delete: %w", err)
 	}
@@ -71,19 +213,19 @@ func (c Core) Delete(ctx context.Context, claims auth.Claims, userID string) err
 	return nil
 }
 
-// Query retrieves a list of existing users from the database.
-func (c Core) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]user.User, error) {
+// Query retrieves a page of existing users from the database.
+func (c Core) Query(ctx context.Context, filter user.QueryFilter, orderBy []database.OrderBy, ks database.Keyset) (database.Page[user.User], error) {
 
 	// PERFORM PRE BUSINESS OPERATIONS
 
-	users, err := c.user.Query(ctx, pageNumber, rowsPerPage)
+	page, err := c.user.Query(ctx, filter, orderBy, ks)
 	if err != nil {
-		return nil, fmt.Errorf("query: %w", err)
+		return database.Page[user.User]{}, fmt.Errorf("query: %w", err)
 	}
 
 	// PERFORM POST BUSINESS OPERATIONS
 
-	return users, nil
+	return page, nil
 }
 
 // QueryByID gets the specified user from the database.
@@ -106,7 +248,7 @@ func (c Core) QueryByEmail(ctx context.Context, claims auth.Claims, email string
 
 	// PERFORM PRE BUSINESS OPERATIONS
 
-	usr, err := c.user.QueryByID(ctx, claims, email)
+	usr, err := c.user.QueryByEmail(ctx, claims, email)
 	if err != nil {
 		return user.User{}, fmt.Errorf("query: %w", err)
 	}
@@ -116,19 +258,165 @@ func (c Core) QueryByEmail(ctx context.Context, claims auth.Claims, email string
 	return usr, nil
 }
 
+// HashReport counts the password hashes of every user by the scheme they
+// were made with. Outdated hashes were made with another scheme or other
+// parameters than the hasher makes new ones with, and are replaced the next
+// time their user signs in. Hashes of no known scheme count as unknown.
+type HashReport struct {
+	Total    int
+	Schemes  map[string]int
+	Outdated int
+}
+
+// ReportHashes counts the password hashes of the users of every tenant.
+func (c Core) ReportHashes(ctx context.Context) (HashReport, error) {
+	hashes, err := c.user.QueryPasswordHashes(ctx)
+	if err != nil {
+		return HashReport{}, fmt.Errorf("query: %w", err)
+	}
+
+	report := HashReport{
+		Total:   len(hashes),
+		Schemes: make(map[string]int),
+	}
+	for _, hash := range hashes {
+		scheme := password.Scheme(hash)
+		if scheme == "" {
+			scheme = "unknown"
+		}
+		report.Schemes[scheme]++
+
+		if c.cfg.Hasher.Outdated(hash) {
+			report.Outdated++
+		}
+	}
+
+	return report, nil
+}
+
 // Authenticate finds a user by their email and verifies their password. On
 // success it returns a Claims User representing this user. The claims can be
-// used to generate a token for future authentication.
-func (c Core) Authenticate(ctx context.Context, now time.Time, email, password string) (auth.Claims, error) {
+// used to generate a token for future authentication. Users must have
+// verified their email address, and users who enabled multi-factor
+// authentication must give a code as well. Failed attempts are
+// counted against the account and the address, ip, they come from and lock
+// them out once there are too many.
+func (c Core) Authenticate(ctx context.Context, now time.Time, email, password, code, ip string) (auth.Claims, error) {
 
 	// PERFORM PRE BUSINESS OPERATIONS
 
+	keys := c.lockoutKeys(email, ip)
+	if len(keys) > 0 {
+		names := make([]string, 0, len(keys))
+		for key := range keys {
+			names = append(names, key)
+		}
+
+		until, err := c.lockout.LockedUntil(ctx, names, now)
+		if err != nil {
+			return auth.Claims{}, fmt.Errorf("lockout: %w", err)
+		}
+		if !until.IsZero() {
+			return auth.Claims{}, ErrLocked
+		}
+	}
+
 	claims, err := c.user.Authenticate(ctx, now, email, password)
 	if err != nil {
+		switch validate.Cause(err) {
+		case database.ErrNotFound, database.ErrAuthenticationFailure:
+
+			// Unknown emails count too, so guessing at accounts is limited
+			// the same way guessing at passwords is.
+			if err := c.fail(ctx, keys, now); err != nil {
+				return auth.Claims{}, err
+			}
+		}
 		return auth.Claims{}, fmt.Errorf("query: %w", err)
 	}
 
+	usr, err := c.user.QueryByEmailAnyTenant(ctx, email)
+	if err != nil {
+		return auth.Claims{}, fmt.Errorf("query: %w", err)
+	}
+	if usr.DateVerified == nil {
+		return auth.Claims{}, ErrUnverified
+	}
+
+	claims, err = c.mfa.Verify(ctx, claims, code, now)
+	if err != nil {
+		if errors.Is(err, mfaCore.ErrInvalidCode) {
+			if err := c.fail(ctx, keys, now); err != nil {
+				return auth.Claims{}, err
+			}
+		}
+		return auth.Claims{}, fmt.Errorf("verify: %w", err)
+	}
+
 	// PERFORM POST BUSINESS OPERATIONS
 
-	return claims, nil
+	if c.cfg.Lockout.Attempts > 0 {
+		if err := c.lockout.Reset(ctx, accountKey(email)); err != nil {
+			return auth.Claims{}, fmt.Errorf("lockout: %w", err)
+		}
+	}
+
+	return c.cfg.Tokens.Stamp(claims, now), nil
+}
+
+// =============================================================================
+
+// lockoutKeys returns the keys failures are tracked under for a sign in by
+// the email from the address. Only the kinds of lockout in use are tracked.
+func (c Core) lockoutKeys(email, ip string) map[string]int {
+	keys := make(map[string]int)
+	if c.cfg.Lockout.Attempts > 0 {
+		keys[accountKey(email)] = c.cfg.Lockout.Attempts
+	}
+	if c.cfg.Lockout.IPAttempts > 0 && ip != "" {
+		keys["ip:"+ip] = c.cfg.Lockout.IPAttempts
+	}
+	return keys
+}
+
+// fail records a failed sign in under each of the keys and locks out the
+// ones that reached their number of attempts. The lock lasts the configured
+// delay, doubled for every failure past the number of attempts.
+func (c Core) fail(ctx context.Context, keys map[string]int, now time.Time) error {
+	for key, attempts := range keys {
+		f, err := c.lockout.Fail(ctx, key, c.cfg.Lockout.Window, now)
+		if err != nil {
+			return fmt.Errorf("lockout: %w", err)
+		}
+
+		if f.Failures < attempts {
+			continue
+		}
+
+		delay := c.cfg.Lockout.Delay
+		for i := attempts; i < f.Failures && delay < c.cfg.Lockout.MaxDelay; i++ {
+			delay *= 2
+		}
+		if c.cfg.Lockout.MaxDelay > 0 && delay > c.cfg.Lockout.MaxDelay {
+			delay = c.cfg.Lockout.MaxDelay
+		}
+
+		if err := c.lockout.Lock(ctx, key, now.Add(delay)); err != nil {
+			return fmt.Errorf("lockout: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// accountKey returns the key failures are tracked under for the email.
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+// userKey returns the key failed password confirmations of a signed in user
+// are counted under. Unlike the account key it stays the same when the user
+// changes their email.
+func userKey(userID string) string {
+	return "user:" + userID
 }
@@ -1,4 +1,318 @@
-// Package user provides an example of a core business API. Right now these
-// calls are just wrapping the data/store layer. But at some point you will
-// want auditing or something that isn't specific to the data/store layer.
-package user
\ No newline at end of file
+package user_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	userCore "github.com/deliveranceTechSolutions/erp/business/core/user"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/data/tests"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/foundation/mail"
+	"github.com/deliveranceTechSolutions/erp/foundation/mail/mailtest"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var dbc = tests.DBContainer{
+	Image: "postgres:13-alpine",
+	Port:  "5432",
+	Args:  []string{"-e", "POSTGRES_PASSWORD=postgres"},
+}
+
+func TestLockout(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	cfg := userCore.Config{
+		Lockout: userCore.Lockout{
+			Attempts:   3,
+			IPAttempts: 5,
+			Delay:      time.Minute,
+			MaxDelay:   time.Hour,
+			Window:     24 * time.Hour,
+		},
+	}
+	core := userCore.NewCore(log, db, cfg)
+
+	t.Log("Given the need to lock out repeated failed sign ins.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen signing in to an account with the wrong password.", testID)
+		{
+			ctx := context.Background()
+			now := time.Now()
+
+			for i := 0; i < cfg.Lockout.Attempts; i++ {
+				if _, err := core.Authenticate(ctx, now, "admin@example.com", "wrong", "", "10.0.0.1"); !errors.Is(err, database.ErrAuthenticationFailure) {
+					t.Fatalf("\t%s\tTest %d:\tShould fail to sign in with the wrong password : %v.", tests.Failed, testID, err)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould fail to sign in with the wrong password.", tests.Success, testID)
+
+			if _, err := core.Authenticate(ctx, now, "admin@example.com", "gophers", "", "10.0.0.2"); !errors.Is(err, userCore.ErrLocked) {
+				t.Fatalf("\t%s\tTest %d:\tShould lock the account out from any address : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould lock the account out from any address.", tests.Success, testID)
+
+			later := now.Add(cfg.Lockout.Delay + time.Second)
+			if _, err := core.Authenticate(ctx, later, "admin@example.com", "wrong", "", "10.0.0.2"); !errors.Is(err, database.ErrAuthenticationFailure) {
+				t.Fatalf("\t%s\tTest %d:\tShould try the password once the delay passed : %v.", tests.Failed, testID, err)
+			}
+			if _, err := core.Authenticate(ctx, later.Add(cfg.Lockout.Delay+time.Second), "admin@example.com", "gophers", "", "10.0.0.2"); !errors.Is(err, userCore.ErrLocked) {
+				t.Fatalf("\t%s\tTest %d:\tShould double the delay after another failure : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould double the delay after another failure.", tests.Success, testID)
+
+			later = later.Add(2*cfg.Lockout.Delay + time.Second)
+			if _, err := core.Authenticate(ctx, later, "admin@example.com", "gophers", "", "10.0.0.2"); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould sign in once the lock expired : %v.", tests.Failed, testID, err)
+			}
+			if _, err := core.Authenticate(ctx, later, "admin@example.com", "wrong", "", "10.0.0.2"); !errors.Is(err, database.ErrAuthenticationFailure) {
+				t.Fatalf("\t%s\tTest %d:\tShould forget the failures after signing in : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould forget the failures after signing in.", tests.Success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen guessing at accounts from one address.", testID)
+		{
+			ctx := context.Background()
+			now := time.Now()
+
+			for i := 0; i < cfg.Lockout.IPAttempts; i++ {
+				if _, err := core.Authenticate(ctx, now, fmt.Sprintf("nobody%d@example.com", i), "wrong", "", "10.0.0.3"); !errors.Is(err, database.ErrNotFound) {
+					t.Fatalf("\t%s\tTest %d:\tShould NOT find unknown accounts : %v.", tests.Failed, testID, err)
+				}
+			}
+
+			if _, err := core.Authenticate(ctx, now, "user@example.com", "gophers", "", "10.0.0.3"); !errors.Is(err, userCore.ErrLocked) {
+				t.Fatalf("\t%s\tTest %d:\tShould lock the address out : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould lock the address out.", tests.Success, testID)
+
+			if _, err := core.Authenticate(ctx, now, "user@example.com", "gophers", "", "10.0.0.4"); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould sign in from other addresses : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould sign in from other addresses.", tests.Success, testID)
+		}
+	}
+}
+
+func TestEmail(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	srv, err := mailtest.NewServer()
+	if err != nil {
+		t.Fatalf("starting smtp server: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	cfg := userCore.Config{
+		Mailer:  mail.NewSMTP(mail.Config{Host: srv.Host, Port: srv.Port, From: "noreply@sales-api.local"}),
+		LinkURL: "http://localhost:8080/",
+		Lockout: userCore.Lockout{Attempts: 2, Delay: time.Hour, Window: time.Hour},
+	}
+	core := userCore.NewCore(log, db, cfg)
+
+	admin := auth.Claims{
+		StandardClaims: jwt.StandardClaims{Subject: "5cf37266-3473-4006-984f-9325122678b7"},
+		TenantID:       tests.TenantID,
+		Roles:          []string{auth.RoleAdmin},
+	}
+
+	// token returns the token linked to in the last email sent.
+	token := func() string {
+		msgs := srv.Messages()
+		if len(msgs) == 0 {
+			t.Fatal("no email was sent")
+		}
+		text, err := msgs[len(msgs)-1].Text()
+		if err != nil {
+			t.Fatalf("reading email: %v", err)
+		}
+		_, after, found := strings.Cut(text, "?token=")
+		if !found {
+			t.Fatalf("email has no link: %s", text)
+		}
+		return strings.Fields(after)[0]
+	}
+
+	t.Log("Given the need for users to prove they own their email address.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a new user verifies their address and resets their password.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+			now := time.Now()
+
+			nu := user.NewUser{
+				Name:            "Jill Kennedy",
+				Email:           "jill@example.com",
+				Roles:           []string{auth.RoleUser},
+				Password:        "gophers",
+				PasswordConfirm: "gophers",
+			}
+			jill, err := core.Create(ctx, admin, nu, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create the user : %s.", tests.Failed, testID, err)
+			}
+			if msgs := srv.Messages(); len(msgs) != 1 || msgs[0].To[0] != "jill@example.com" {
+				t.Fatalf("\t%s\tTest %d:\tShould email the user a verification link.", tests.Failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould email the user a verification link.", tests.Success, testID)
+
+			if _, err := core.Authenticate(context.Background(), now, "jill@example.com", "gophers", "", ""); !errors.Is(err, userCore.ErrUnverified) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT sign in before verifying : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT sign in before verifying.", tests.Success, testID)
+
+			verify := token()
+			if err := core.VerifyEmail(context.Background(), verify, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to verify the address : %s.", tests.Failed, testID, err)
+			}
+			if err := core.VerifyEmail(context.Background(), verify, now); !errors.Is(err, userCore.ErrInvalidToken) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT use a token twice : %v.", tests.Failed, testID, err)
+			}
+			if _, err := core.Authenticate(context.Background(), now, "jill@example.com", "gophers", "", ""); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould sign in once verified : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould sign in once verified.", tests.Success, testID)
+
+			core.RequestPasswordReset(context.Background(), "nobody@example.com", now)
+			core.Wait()
+			if len(srv.Messages()) != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould quietly ignore unknown emails.", tests.Failed, testID)
+			}
+			core.RequestPasswordReset(context.Background(), "jill@example.com", now)
+			core.Wait()
+			if len(srv.Messages()) != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to request a reset.", tests.Failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould email a reset link only to known users.", tests.Success, testID)
+
+			reset := token()
+			if err := core.ResetPassword(context.Background(), reset, "rabbit hole", "rabbit hole", now.Add(2*time.Hour)); !errors.Is(err, userCore.ErrInvalidToken) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept an expired token : %v.", tests.Failed, testID, err)
+			}
+			if err := core.ResetPassword(context.Background(), reset, "rabbit hole", "rabbit hole", now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to reset the password : %s.", tests.Failed, testID, err)
+			}
+			if err := core.ResetPassword(context.Background(), reset, "rabbit hole", "rabbit hole", now); !errors.Is(err, userCore.ErrInvalidToken) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT use a token twice : %v.", tests.Failed, testID, err)
+			}
+			if _, err := core.Authenticate(context.Background(), now, "jill@example.com", "rabbit hole", "", ""); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould sign in with the new password : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould sign in with the new password.", tests.Success, testID)
+
+			self := auth.Claims{
+				StandardClaims: jwt.StandardClaims{Subject: jill.ID},
+				TenantID:       tests.TenantID,
+				Roles:          jill.Roles,
+			}
+			wrong := user.ChangePassword{CurrentPassword: "not it", Password: "tuna salad", PasswordConfirm: "tuna salad"}
+			for i := 0; i < 2; i++ {
+				if _, err := core.ChangePassword(ctx, self, wrong, now); !errors.Is(err, userCore.ErrWrongPassword) {
+					t.Fatalf("\t%s\tTest %d:\tShould NOT change the password without the current one : %v.", tests.Failed, testID, err)
+				}
+			}
+			if _, err := core.ChangePassword(ctx, self, wrong, now); !errors.Is(err, userCore.ErrLocked) {
+				t.Fatalf("\t%s\tTest %d:\tShould be locked out after too many wrong passwords : %v.", tests.Failed, testID, err)
+			}
+
+			core.RequestPasswordReset(context.Background(), "jill@example.com", now)
+			core.Wait()
+			if err := core.ResetPassword(context.Background(), token(), "looking glass", "looking glass", now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to reset the password : %s.", tests.Failed, testID, err)
+			}
+
+			cp := user.ChangePassword{CurrentPassword: "looking glass", Password: "tuna salad", PasswordConfirm: "tuna salad"}
+			if _, err := core.ChangePassword(ctx, self, cp, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to change the password after a reset : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould clear every lockout of the user on a reset.", tests.Success, testID)
+		}
+	}
+}
+
+func TestSelf(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	core := userCore.NewCore(log, db, userCore.Config{})
+
+	t.Log("Given the need for users to manage their own account.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a user changes their profile and password.", testID)
+		{
+			const userID = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+			now := time.Now()
+			claims := auth.Claims{
+				StandardClaims: jwt.StandardClaims{Subject: userID},
+				TenantID:       tests.TenantID,
+				Roles:          []string{auth.RoleUser},
+			}
+
+			usr, err := core.QueryByEmail(ctx, claims, "user@example.com")
+			if err != nil || usr.ID != userID {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to look themselves up by email : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to look themselves up by email.", tests.Success, testID)
+
+			if _, err := core.QueryByEmail(ctx, claims, "admin@example.com"); !errors.Is(err, database.ErrForbidden) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to look others up by email : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to look others up by email.", tests.Success, testID)
+
+			name := "Jacob Walker"
+			if err := core.UpdateProfile(ctx, claims, user.UpdateProfile{Name: &name}, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to update their profile : %v.", tests.Failed, testID, err)
+			}
+			if usr, err := core.QueryByID(ctx, claims, userID); err != nil || usr.Name != name {
+				t.Fatalf("\t%s\tTest %d:\tShould see the profile updated : %v, %q.", tests.Failed, testID, err, usr.Name)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to update their profile.", tests.Success, testID)
+
+			wrong := user.ChangePassword{
+				CurrentPassword: "not gophers",
+				Password:        "tuna salad sandwich",
+				PasswordConfirm: "tuna salad sandwich",
+			}
+			if _, err := core.ChangePassword(ctx, claims, wrong, now); !errors.Is(err, userCore.ErrWrongPassword) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT change the password without the current one : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT change the password without the current one.", tests.Success, testID)
+
+			cp := wrong
+			cp.CurrentPassword = "gophers"
+			if _, err := core.ChangePassword(ctx, claims, cp, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to change the password : %v.", tests.Failed, testID, err)
+			}
+			if _, err := core.Authenticate(ctx, now, "user@example.com", cp.Password, "", ""); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould sign in with the new password : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould sign in with the new password.", tests.Success, testID)
+
+			email := "jacob@example.com"
+			if err := core.UpdateProfile(ctx, claims, user.UpdateProfile{Email: &email}, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to change their email : %v.", tests.Failed, testID, err)
+			}
+			if usr, err := core.QueryByID(ctx, claims, userID); err != nil || usr.Email != email || usr.DateVerified != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould have the new email unverified : %v, %+v.", tests.Failed, testID, err, usr)
+			}
+			if _, err := core.Authenticate(ctx, now, email, cp.Password, "", ""); !errors.Is(err, userCore.ErrUnverified) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT sign in before verifying the new email : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould have to verify a changed email.", tests.Success, testID)
+		}
+	}
+}
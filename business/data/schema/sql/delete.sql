@@ -1,3 +1,19 @@
+DELETE FROM external_identities;
+DELETE FROM email_tokens;
+DELETE FROM user_mfa;
+DELETE FROM login_failures;
+DELETE FROM api_keys;
+DELETE FROM roles;
+DELETE FROM revoked_tokens;
+DELETE FROM refresh_tokens;
+DELETE FROM oauth_clients;
+DELETE FROM search_documents;
+DELETE FROM task_dependencies;
+DELETE FROM tasks;
+DELETE FROM projects;
+DELETE FROM subscriptions;
+DELETE FROM dashboards;
 DELETE FROM sales;
 DELETE FROM products;
-DELETE FROM users;
\ No newline at end of file
+DELETE FROM users;
+DELETE FROM tenants;
\ No newline at end of file
@@ -40,4 +40,633 @@ CREATE TABLE sales (
 	PRIMARY KEY (sale_id),
 	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
 	FOREIGN KEY (product_id) REFERENCES products(product_id) ON DELETE CASCADE
-);
\ No newline at end of file
+);
+
+-- Version: 1.4
+-- Description: Create table dashboards
+CREATE TABLE dashboards (
+	dashboard_id UUID,
+	user_id      UUID,
+	name         TEXT,
+	layout       JSONB,
+	charts       JSONB,
+	shared_users TEXT[],
+	shared_roles TEXT[],
+	date_created TIMESTAMP,
+	date_updated TIMESTAMP,
+
+	PRIMARY KEY (dashboard_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+-- Version: 1.5
+-- Description: Create table subscriptions
+CREATE TABLE subscriptions (
+	subscription_id UUID,
+	user_id         UUID,
+	name            TEXT,
+	source_type     TEXT,
+	source          TEXT,
+	format          TEXT,
+	schedule        TEXT,
+	next_run        TIMESTAMP,
+	last_run        TIMESTAMP,
+	last_error      TEXT,
+	date_created    TIMESTAMP,
+	date_updated    TIMESTAMP,
+
+	PRIMARY KEY (subscription_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX subscriptions_next_run_idx ON subscriptions (next_run);
+
+-- Version: 1.6
+-- Description: Create tables for projects, tasks and task dependencies
+CREATE TABLE projects (
+	project_id   UUID,
+	user_id      UUID,
+	name         TEXT,
+	description  TEXT,
+	start_date   TIMESTAMP,
+	date_created TIMESTAMP,
+	date_updated TIMESTAMP,
+
+	PRIMARY KEY (project_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE TABLE tasks (
+	task_id          UUID,
+	project_id       UUID,
+	name             TEXT,
+	assignee_id      UUID,
+	duration_days    INT,
+	start_after      TIMESTAMP,
+	milestone        BOOLEAN,
+	percent_complete INT,
+	date_created     TIMESTAMP,
+	date_updated     TIMESTAMP,
+
+	PRIMARY KEY (task_id),
+	FOREIGN KEY (project_id) REFERENCES projects(project_id) ON DELETE CASCADE,
+	FOREIGN KEY (assignee_id) REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE TABLE task_dependencies (
+	project_id    UUID,
+	task_id       UUID,
+	depends_on_id UUID,
+	type          TEXT,
+	lag_days      INT,
+	date_created  TIMESTAMP,
+
+	PRIMARY KEY (task_id, depends_on_id),
+	FOREIGN KEY (project_id) REFERENCES projects(project_id) ON DELETE CASCADE,
+	FOREIGN KEY (task_id) REFERENCES tasks(task_id) ON DELETE CASCADE,
+	FOREIGN KEY (depends_on_id) REFERENCES tasks(task_id) ON DELETE CASCADE
+);
+
+-- Version: 1.7
+-- Description: Create search index maintained by triggers
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE search_documents (
+	type         TEXT,
+	id           UUID,
+	title        TEXT,
+	body         TEXT,
+	owner_id     UUID,
+	restricted   BOOLEAN,
+	shared_users TEXT[],
+	shared_roles TEXT[],
+	document     TSVECTOR,
+	date_updated TIMESTAMP,
+
+	PRIMARY KEY (type, id)
+);
+
+CREATE INDEX search_documents_document_idx ON search_documents USING GIN (document);
+CREATE INDEX search_documents_title_idx ON search_documents USING GIN (title gin_trgm_ops);
+
+CREATE FUNCTION search_upsert(doc_type TEXT, doc_id UUID, doc_title TEXT, doc_body TEXT, doc_owner UUID, doc_restricted BOOLEAN, doc_users TEXT[], doc_roles TEXT[], doc_updated TIMESTAMP) RETURNS VOID AS $$
+BEGIN
+	INSERT INTO search_documents (type, id, title, body, owner_id, restricted, shared_users, shared_roles, document, date_updated)
+	VALUES (
+		doc_type, doc_id, COALESCE(doc_title, ''), COALESCE(doc_body, ''), doc_owner, doc_restricted,
+		COALESCE(doc_users, '{}'), COALESCE(doc_roles, '{}'),
+		setweight(to_tsvector('simple', COALESCE(doc_title, '')), 'A') || setweight(to_tsvector('simple', COALESCE(doc_body, '')), 'B'),
+		doc_updated
+	)
+	ON CONFLICT (type, id) DO UPDATE SET
+		title        = EXCLUDED.title,
+		body         = EXCLUDED.body,
+		owner_id     = EXCLUDED.owner_id,
+		restricted   = EXCLUDED.restricted,
+		shared_users = EXCLUDED.shared_users,
+		shared_roles = EXCLUDED.shared_roles,
+		document     = EXCLUDED.document,
+		date_updated = EXCLUDED.date_updated;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION search_users() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		DELETE FROM search_documents WHERE type = 'user' AND id = OLD.user_id;
+		RETURN OLD;
+	END IF;
+	PERFORM search_upsert('user', NEW.user_id, NEW.name, NEW.email, NEW.user_id, TRUE, NULL, NULL, NEW.date_updated);
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION search_products() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		DELETE FROM search_documents WHERE type = 'product' AND id = OLD.product_id;
+		RETURN OLD;
+	END IF;
+	PERFORM search_upsert('product', NEW.product_id, NEW.name, NULL, NEW.user_id, FALSE, NULL, NULL, NEW.date_updated);
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION search_projects() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		DELETE FROM search_documents WHERE type = 'project' AND id = OLD.project_id;
+		RETURN OLD;
+	END IF;
+	PERFORM search_upsert('project', NEW.project_id, NEW.name, NEW.description, NEW.user_id, FALSE, NULL, NULL, NEW.date_updated);
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION search_dashboards() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		DELETE FROM search_documents WHERE type = 'dashboard' AND id = OLD.dashboard_id;
+		RETURN OLD;
+	END IF;
+	PERFORM search_upsert('dashboard', NEW.dashboard_id, NEW.name, NULL, NEW.user_id, TRUE, NEW.shared_users, NEW.shared_roles, NEW.date_updated);
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_search AFTER INSERT OR UPDATE OR DELETE ON users FOR EACH ROW EXECUTE FUNCTION search_users();
+CREATE TRIGGER products_search AFTER INSERT OR UPDATE OR DELETE ON products FOR EACH ROW EXECUTE FUNCTION search_products();
+CREATE TRIGGER projects_search AFTER INSERT OR UPDATE OR DELETE ON projects FOR EACH ROW EXECUTE FUNCTION search_projects();
+CREATE TRIGGER dashboards_search AFTER INSERT OR UPDATE OR DELETE ON dashboards FOR EACH ROW EXECUTE FUNCTION search_dashboards();
+
+SELECT search_upsert('user', user_id, name, email, user_id, TRUE, NULL, NULL, date_updated) FROM users;
+SELECT search_upsert('product', product_id, name, NULL, user_id, FALSE, NULL, NULL, date_updated) FROM products;
+SELECT search_upsert('project', project_id, name, description, user_id, FALSE, NULL, NULL, date_updated) FROM projects;
+SELECT search_upsert('dashboard', dashboard_id, name, NULL, user_id, TRUE, shared_users, shared_roles, date_updated) FROM dashboards;
+
+-- Version: 1.8
+-- Description: Create tenants and isolate every business table by tenant
+CREATE TABLE tenants (
+	tenant_id    UUID,
+	name         TEXT UNIQUE,
+	date_created TIMESTAMP,
+	date_updated TIMESTAMP,
+
+	PRIMARY KEY (tenant_id)
+);
+
+INSERT INTO tenants (tenant_id, name, date_created, date_updated) VALUES
+	('0a4dc3f4-43c4-4c0b-9a0f-6e3b1a0c9d7e', 'Default', '2019-03-24 00:00:00', '2019-03-24 00:00:00');
+
+CREATE FUNCTION current_tenant() RETURNS UUID AS $$
+	SELECT CAST(NULLIF(current_setting('app.tenant_id', TRUE), '') AS UUID)
+$$ LANGUAGE SQL STABLE;
+
+ALTER TABLE users ADD COLUMN tenant_id UUID REFERENCES tenants(tenant_id) ON DELETE CASCADE;
+ALTER TABLE products ADD COLUMN tenant_id UUID REFERENCES tenants(tenant_id) ON DELETE CASCADE;
+ALTER TABLE sales ADD COLUMN tenant_id UUID REFERENCES tenants(tenant_id) ON DELETE CASCADE;
+ALTER TABLE dashboards ADD COLUMN tenant_id UUID REFERENCES tenants(tenant_id) ON DELETE CASCADE;
+ALTER TABLE subscriptions ADD COLUMN tenant_id UUID REFERENCES tenants(tenant_id) ON DELETE CASCADE;
+ALTER TABLE projects ADD COLUMN tenant_id UUID REFERENCES tenants(tenant_id) ON DELETE CASCADE;
+ALTER TABLE tasks ADD COLUMN tenant_id UUID REFERENCES tenants(tenant_id) ON DELETE CASCADE;
+ALTER TABLE task_dependencies ADD COLUMN tenant_id UUID REFERENCES tenants(tenant_id) ON DELETE CASCADE;
+ALTER TABLE search_documents ADD COLUMN tenant_id UUID REFERENCES tenants(tenant_id) ON DELETE CASCADE;
+
+UPDATE users SET tenant_id = '0a4dc3f4-43c4-4c0b-9a0f-6e3b1a0c9d7e';
+UPDATE products SET tenant_id = '0a4dc3f4-43c4-4c0b-9a0f-6e3b1a0c9d7e';
+UPDATE sales SET tenant_id = '0a4dc3f4-43c4-4c0b-9a0f-6e3b1a0c9d7e';
+UPDATE dashboards SET tenant_id = '0a4dc3f4-43c4-4c0b-9a0f-6e3b1a0c9d7e';
+UPDATE subscriptions SET tenant_id = '0a4dc3f4-43c4-4c0b-9a0f-6e3b1a0c9d7e';
+UPDATE projects SET tenant_id = '0a4dc3f4-43c4-4c0b-9a0f-6e3b1a0c9d7e';
+UPDATE tasks SET tenant_id = '0a4dc3f4-43c4-4c0b-9a0f-6e3b1a0c9d7e';
+UPDATE task_dependencies SET tenant_id = '0a4dc3f4-43c4-4c0b-9a0f-6e3b1a0c9d7e';
+UPDATE search_documents SET tenant_id = '0a4dc3f4-43c4-4c0b-9a0f-6e3b1a0c9d7e';
+
+ALTER TABLE users ALTER COLUMN tenant_id SET NOT NULL, ALTER COLUMN tenant_id SET DEFAULT current_tenant();
+ALTER TABLE products ALTER COLUMN tenant_id SET NOT NULL, ALTER COLUMN tenant_id SET DEFAULT current_tenant();
+ALTER TABLE sales ALTER COLUMN tenant_id SET NOT NULL, ALTER COLUMN tenant_id SET DEFAULT current_tenant();
+ALTER TABLE dashboards ALTER COLUMN tenant_id SET NOT NULL, ALTER COLUMN tenant_id SET DEFAULT current_tenant();
+ALTER TABLE subscriptions ALTER COLUMN tenant_id SET NOT NULL, ALTER COLUMN tenant_id SET DEFAULT current_tenant();
+ALTER TABLE projects ALTER COLUMN tenant_id SET NOT NULL, ALTER COLUMN tenant_id SET DEFAULT current_tenant();
+ALTER TABLE tasks ALTER COLUMN tenant_id SET NOT NULL, ALTER COLUMN tenant_id SET DEFAULT current_tenant();
+ALTER TABLE task_dependencies ALTER COLUMN tenant_id SET NOT NULL, ALTER COLUMN tenant_id SET DEFAULT current_tenant();
+ALTER TABLE search_documents ALTER COLUMN tenant_id SET NOT NULL, ALTER COLUMN tenant_id SET DEFAULT current_tenant();
+
+DROP FUNCTION search_upsert(TEXT, UUID, TEXT, TEXT, UUID, BOOLEAN, TEXT[], TEXT[], TIMESTAMP);
+
+CREATE FUNCTION search_upsert(doc_tenant UUID, doc_type TEXT, doc_id UUID, doc_title TEXT, doc_body TEXT, doc_owner UUID, doc_restricted BOOLEAN, doc_users TEXT[], doc_roles TEXT[], doc_updated TIMESTAMP) RETURNS VOID AS $$
+BEGIN
+	INSERT INTO search_documents (tenant_id, type, id, title, body, owner_id, restricted, shared_users, shared_roles, document, date_updated)
+	VALUES (
+		doc_tenant, doc_type, doc_id, COALESCE(doc_title, ''), COALESCE(doc_body, ''), doc_owner, doc_restricted,
+		COALESCE(doc_users, '{}'), COALESCE(doc_roles, '{}'),
+		setweight(to_tsvector('simple', COALESCE(doc_title, '')), 'A') || setweight(to_tsvector('simple', COALESCE(doc_body, '')), 'B'),
+		doc_updated
+	)
+	ON CONFLICT (type, id) DO UPDATE SET
+		tenant_id    = EXCLUDED.tenant_id,
+		title        = EXCLUDED.title,
+		body         = EXCLUDED.body,
+		owner_id     = EXCLUDED.owner_id,
+		restricted   = EXCLUDED.restricted,
+		shared_users = EXCLUDED.shared_users,
+		shared_roles = EXCLUDED.shared_roles,
+		document     = EXCLUDED.document,
+		date_updated = EXCLUDED.date_updated;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION search_users() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		DELETE FROM search_documents WHERE type = 'user' AND id = OLD.user_id;
+		RETURN OLD;
+	END IF;
+	PERFORM search_upsert(NEW.tenant_id, 'user', NEW.user_id, NEW.name, NEW.email, NEW.user_id, TRUE, NULL, NULL, NEW.date_updated);
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION search_products() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		DELETE FROM search_documents WHERE type = 'product' AND id = OLD.product_id;
+		RETURN OLD;
+	END IF;
+	PERFORM search_upsert(NEW.tenant_id, 'product', NEW.product_id, NEW.name, NULL, NEW.user_id, FALSE, NULL, NULL, NEW.date_updated);
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION search_projects() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		DELETE FROM search_documents WHERE type = 'project' AND id = OLD.project_id;
+		RETURN OLD;
+	END IF;
+	PERFORM search_upsert(NEW.tenant_id, 'project', NEW.project_id, NEW.name, NEW.description, NEW.user_id, FALSE, NULL, NULL, NEW.date_updated);
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION search_dashboards() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		DELETE FROM search_documents WHERE type = 'dashboard' AND id = OLD.dashboard_id;
+		RETURN OLD;
+	END IF;
+	PERFORM search_upsert(NEW.tenant_id, 'dashboard', NEW.dashboard_id, NEW.name, NULL, NEW.user_id, TRUE, NEW.shared_users, NEW.shared_roles, NEW.date_updated);
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'erp_tenant') THEN
+		CREATE ROLE erp_tenant NOLOGIN;
+	END IF;
+END;
+$$;
+
+GRANT erp_tenant TO CURRENT_USER;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO erp_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO erp_tenant;
+
+ALTER TABLE tenants ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE products ENABLE ROW LEVEL SECURITY;
+ALTER TABLE sales ENABLE ROW LEVEL SECURITY;
+ALTER TABLE dashboards ENABLE ROW LEVEL SECURITY;
+ALTER TABLE subscriptions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE projects ENABLE ROW LEVEL SECURITY;
+ALTER TABLE tasks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE task_dependencies ENABLE ROW LEVEL SECURITY;
+ALTER TABLE search_documents ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON tenants USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+CREATE POLICY tenant_isolation ON users USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+CREATE POLICY tenant_isolation ON products USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+CREATE POLICY tenant_isolation ON sales USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+CREATE POLICY tenant_isolation ON dashboards USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+CREATE POLICY tenant_isolation ON subscriptions USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+CREATE POLICY tenant_isolation ON projects USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+CREATE POLICY tenant_isolation ON tasks USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+CREATE POLICY tenant_isolation ON task_dependencies USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+CREATE POLICY tenant_isolation ON search_documents USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+
+-- Version: 1.9
+-- Description: Enforce ownership and roles from the request claims with row level security
+CREATE FUNCTION claims_enforced() RETURNS BOOLEAN AS $$
+	SELECT COALESCE(current_setting('app.row_level_security', TRUE), '') = 'on'
+$$ LANGUAGE SQL STABLE;
+
+CREATE FUNCTION claims_user() RETURNS UUID AS $$
+	SELECT CAST(NULLIF(current_setting('app.user_id', TRUE), '') AS UUID)
+$$ LANGUAGE SQL STABLE;
+
+CREATE FUNCTION claims_roles() RETURNS TEXT[] AS $$
+	SELECT COALESCE(string_to_array(NULLIF(current_setting('app.roles', TRUE), ''), ','), '{}')
+$$ LANGUAGE SQL STABLE;
+
+CREATE FUNCTION claims_admin() RETURNS BOOLEAN AS $$
+	SELECT 'ADMIN' = ANY(claims_roles())
+$$ LANGUAGE SQL STABLE;
+
+CREATE POLICY claims_owner ON users AS RESTRICTIVE
+	USING (NOT claims_enforced() OR claims_admin() OR user_id = claims_user())
+	WITH CHECK (NOT claims_enforced() OR claims_admin() OR user_id = claims_user());
+
+CREATE POLICY claims_view ON dashboards AS RESTRICTIVE FOR SELECT
+	USING (NOT claims_enforced() OR claims_admin() OR user_id = claims_user() OR CAST(claims_user() AS TEXT) = ANY(shared_users) OR shared_roles && claims_roles());
+CREATE POLICY claims_create ON dashboards AS RESTRICTIVE FOR INSERT
+	WITH CHECK (NOT claims_enforced() OR claims_admin() OR user_id = claims_user());
+CREATE POLICY claims_modify ON dashboards AS RESTRICTIVE FOR UPDATE
+	USING (NOT claims_enforced() OR claims_admin() OR user_id = claims_user())
+	WITH CHECK (NOT claims_enforced() OR claims_admin() OR user_id = claims_user());
+CREATE POLICY claims_delete ON dashboards AS RESTRICTIVE FOR DELETE
+	USING (NOT claims_enforced() OR claims_admin() OR user_id = claims_user());
+
+CREATE POLICY claims_owner ON subscriptions AS RESTRICTIVE
+	USING (NOT claims_enforced() OR claims_admin() OR user_id = claims_user())
+	WITH CHECK (NOT claims_enforced() OR claims_admin() OR user_id = claims_user());
+
+CREATE POLICY claims_view ON search_documents AS RESTRICTIVE FOR SELECT
+	USING (NOT claims_enforced() OR NOT restricted OR claims_admin() OR owner_id = claims_user() OR CAST(claims_user() AS TEXT) = ANY(shared_users) OR shared_roles && claims_roles());
+
+-- Version: 2.0
+-- Description: Create refresh tokens and the access token revocation list
+CREATE TABLE refresh_tokens (
+	token_id       UUID,
+	tenant_id      UUID NOT NULL DEFAULT current_tenant() REFERENCES tenants(tenant_id) ON DELETE CASCADE,
+	user_id        UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	family_id      UUID NOT NULL,
+	token_hash     BYTEA UNIQUE NOT NULL,
+	access_id      UUID NOT NULL,
+	access_expires TIMESTAMP NOT NULL,
+	date_created   TIMESTAMP NOT NULL,
+	date_expires   TIMESTAMP NOT NULL,
+	date_used      TIMESTAMP,
+	date_revoked   TIMESTAMP,
+
+	PRIMARY KEY (token_id)
+);
+
+CREATE INDEX refresh_tokens_family_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_idx ON refresh_tokens (user_id);
+CREATE INDEX refresh_tokens_access_idx ON refresh_tokens (access_id);
+
+CREATE TABLE revoked_tokens (
+	jti          TEXT,
+	tenant_id    UUID NOT NULL DEFAULT current_tenant() REFERENCES tenants(tenant_id) ON DELETE CASCADE,
+	date_expires TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (jti)
+);
+
+ALTER TABLE refresh_tokens ENABLE ROW LEVEL SECURITY;
+ALTER TABLE revoked_tokens ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON refresh_tokens USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+CREATE POLICY tenant_isolation ON revoked_tokens USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+
+CREATE POLICY claims_owner ON refresh_tokens AS RESTRICTIVE
+	USING (NOT claims_enforced() OR claims_admin() OR user_id = claims_user())
+	WITH CHECK (NOT claims_enforced() OR claims_admin() OR user_id = claims_user());
+
+-- Version: 2.1
+-- Description: Create OAuth clients and bind refresh tokens to them
+CREATE TABLE oauth_clients (
+	client_id    UUID,
+	tenant_id    UUID NOT NULL DEFAULT current_tenant() REFERENCES tenants(tenant_id) ON DELETE CASCADE,
+	name         TEXT NOT NULL,
+	secret_hash  TEXT NOT NULL,
+	grants       TEXT[] NOT NULL,
+	scopes       TEXT[] NOT NULL,
+	date_created TIMESTAMP,
+	date_updated TIMESTAMP,
+
+	PRIMARY KEY (client_id)
+);
+
+ALTER TABLE refresh_tokens
+	ADD COLUMN client_id UUID REFERENCES oauth_clients(client_id) ON DELETE CASCADE,
+	ADD COLUMN scope     TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE oauth_clients ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON oauth_clients USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+
+-- Version: 2.2
+-- Description: Create roles defined by tenants as sets of permissions
+CREATE TABLE roles (
+	tenant_id    UUID NOT NULL DEFAULT current_tenant() REFERENCES tenants(tenant_id) ON DELETE CASCADE,
+	name         TEXT NOT NULL,
+	description  TEXT NOT NULL DEFAULT '',
+	permissions  TEXT[] NOT NULL,
+	date_created TIMESTAMP,
+	date_updated TIMESTAMP,
+
+	PRIMARY KEY (tenant_id, name)
+);
+
+ALTER TABLE roles ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON roles USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+
+-- Version: 2.3
+-- Description: Create API keys for machine to machine integrations
+CREATE TABLE api_keys (
+	key_id       UUID,
+	tenant_id    UUID NOT NULL DEFAULT current_tenant() REFERENCES tenants(tenant_id) ON DELETE CASCADE,
+	user_id      UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	name         TEXT NOT NULL,
+	prefix       TEXT NOT NULL,
+	key_hash     BYTEA UNIQUE NOT NULL,
+	permissions  TEXT[] NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+	date_expires TIMESTAMP,
+	date_used    TIMESTAMP,
+	date_revoked TIMESTAMP,
+
+	PRIMARY KEY (key_id)
+);
+
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON api_keys USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+
+-- Version: 2.4
+-- Description: Track failed sign ins per account and address to lock them out
+CREATE TABLE login_failures (
+	key          TEXT,
+	failures     INT NOT NULL,
+	date_last    TIMESTAMP NOT NULL,
+	date_locked  TIMESTAMP,
+
+	PRIMARY KEY (key)
+);
+
+-- Sign ins happen before a tenant is known, so only the system can see the
+-- failures. No policy means no tenant can.
+ALTER TABLE login_failures ENABLE ROW LEVEL SECURITY;
+
+-- Version: 2.5
+-- Description: Add TOTP multi-factor authentication
+CREATE TABLE user_mfa (
+	user_id        UUID,
+	tenant_id      UUID NOT NULL DEFAULT current_tenant() REFERENCES tenants(tenant_id) ON DELETE CASCADE,
+	secret         TEXT NOT NULL,
+	recovery_codes TEXT[] NOT NULL DEFAULT '{}',
+	last_step      BIGINT NOT NULL DEFAULT 0,
+	date_created   TIMESTAMP NOT NULL,
+	date_enabled   TIMESTAMP,
+
+	PRIMARY KEY (user_id),
+	FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+ALTER TABLE user_mfa ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON user_mfa USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+
+-- Refreshed tokens keep the methods the session was started with.
+ALTER TABLE refresh_tokens ADD COLUMN amr TEXT[] NOT NULL DEFAULT '{}';
+
+-- Version: 2.6
+-- Description: Add email verification and password reset tokens
+ALTER TABLE users ADD COLUMN date_verified TIMESTAMP;
+
+-- Accounts created before addresses were verified keep signing in.
+UPDATE users SET date_verified = date_created;
+
+CREATE TABLE email_tokens (
+	token_hash   BYTEA,
+	tenant_id    UUID NOT NULL DEFAULT current_tenant() REFERENCES tenants(tenant_id) ON DELETE CASCADE,
+	user_id      UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	purpose      TEXT NOT NULL,
+	date_created TIMESTAMP NOT NULL,
+	date_expires TIMESTAMP NOT NULL,
+	date_used    TIMESTAMP,
+
+	PRIMARY KEY (token_hash)
+);
+
+ALTER TABLE email_tokens ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON email_tokens USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+
+-- Version: 2.7
+-- Description: Link users to the accounts of trusted external issuers
+CREATE TABLE external_identities (
+	issuer       TEXT,
+	subject      TEXT,
+	tenant_id    UUID NOT NULL DEFAULT current_tenant() REFERENCES tenants(tenant_id) ON DELETE CASCADE,
+	user_id      UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	date_created TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (issuer, subject)
+);
+
+ALTER TABLE external_identities ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY tenant_isolation ON external_identities USING (tenant_id = current_tenant()) WITH CHECK (tenant_id = current_tenant());
+
+-- Version: 2.8
+-- Description: Count the failed deliveries of subscriptions so they can be retried
+ALTER TABLE subscriptions ADD COLUMN failures INT NOT NULL DEFAULT 0;
+
+-- Version: 2.9
+-- Description: Record access tokens issued without a refresh token so they can be revoked
+ALTER TABLE refresh_tokens ALTER COLUMN token_hash DROP NOT NULL;
+
+-- Version: 3.0
+-- Description: Enforce permissions from the request claims with row level security
+-- Rows are checked against the permissions the service checks for them. ADMIN
+-- is granted every permission, as it is in the service. Dashboards and
+-- subscriptions keep checking claims_admin() since the service lets ADMIN
+-- rather than a permission reach the ones of other users. The other tables,
+-- such as roles, clients and keys, are left to tenant isolation and the
+-- checks the service makes.
+CREATE FUNCTION claims_permissions() RETURNS TEXT[] AS $$
+	SELECT COALESCE(string_to_array(NULLIF(current_setting('app.permissions', TRUE), ''), ','), '{}')
+$$ LANGUAGE SQL STABLE;
+
+CREATE FUNCTION claims_permitted(permission TEXT) RETURNS BOOLEAN AS $$
+	SELECT NOT claims_enforced() OR claims_admin() OR permission = ANY(claims_permissions())
+$$ LANGUAGE SQL STABLE;
+
+DROP POLICY claims_owner ON users;
+
+CREATE POLICY claims_view ON users AS RESTRICTIVE FOR SELECT
+	USING (claims_permitted('users:read') OR user_id = claims_user());
+CREATE POLICY claims_create ON users AS RESTRICTIVE FOR INSERT
+	WITH CHECK (claims_permitted('users:write'));
+CREATE POLICY claims_modify ON users AS RESTRICTIVE FOR UPDATE
+	USING (claims_permitted('users:write') OR user_id = claims_user())
+	WITH CHECK (claims_permitted('users:write') OR user_id = claims_user());
+CREATE POLICY claims_delete ON users AS RESTRICTIVE FOR DELETE
+	USING (claims_permitted('users:write') OR user_id = claims_user());
+
+DROP POLICY claims_owner ON refresh_tokens;
+
+CREATE POLICY claims_owner ON refresh_tokens AS RESTRICTIVE
+	USING (claims_permitted('users:write') OR user_id = claims_user())
+	WITH CHECK (claims_permitted('users:write') OR user_id = claims_user());
+
+DROP POLICY claims_view ON search_documents;
+
+CREATE POLICY claims_view ON search_documents AS RESTRICTIVE FOR SELECT
+	USING (NOT claims_enforced() OR NOT restricted OR owner_id = claims_user() OR CAST(claims_user() AS TEXT) = ANY(shared_users) OR shared_roles && claims_roles() OR
+		CASE WHEN type = 'user' THEN claims_permitted('users:read') ELSE claims_admin() END);
+
+-- Products and sales are only read by the service, for reports.
+CREATE POLICY claims_view ON products AS RESTRICTIVE FOR SELECT
+	USING (claims_permitted('reports:read'));
+CREATE POLICY claims_view ON sales AS RESTRICTIVE FOR SELECT
+	USING (claims_permitted('reports:read'));
+
+-- Projects can be read by every user of the tenant. The assignee of a task
+-- may update it to report their progress.
+CREATE POLICY claims_create ON projects AS RESTRICTIVE FOR INSERT
+	WITH CHECK (claims_permitted('projects:write'));
+CREATE POLICY claims_modify ON projects AS RESTRICTIVE FOR UPDATE
+	USING (claims_permitted('projects:write'));
+CREATE POLICY claims_delete ON projects AS RESTRICTIVE FOR DELETE
+	USING (claims_permitted('projects:write'));
+
+CREATE POLICY claims_create ON tasks AS RESTRICTIVE FOR INSERT
+	WITH CHECK (claims_permitted('projects:write'));
+CREATE POLICY claims_modify ON tasks AS RESTRICTIVE FOR UPDATE
+	USING (claims_permitted('projects:write') OR assignee_id = claims_user());
+CREATE POLICY claims_delete ON tasks AS RESTRICTIVE FOR DELETE
+	USING (claims_permitted('projects:write'));
+
+CREATE POLICY claims_create ON task_dependencies AS RESTRICTIVE FOR INSERT
+	WITH CHECK (claims_permitted('projects:write'));
+CREATE POLICY claims_delete ON task_dependencies AS RESTRICTIVE FOR DELETE
+	USING (claims_permitted('projects:write'));
+
+-- Version: 3.1
+-- Description: Only find products in search with permission to read reports
+CREATE POLICY claims_products ON search_documents AS RESTRICTIVE FOR SELECT
+	USING (type <> 'product' OR claims_permitted('reports:read'));
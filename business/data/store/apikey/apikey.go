@@ -0,0 +1,174 @@
+// Package apikey contains API key related CRUD functionality.
+package apikey
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// usedInterval is how often the date a key was last used is recorded, so a
+// busy integration doesn't write to the database on every request.
+const usedInterval = time.Minute
+
+// Store manages the set of API's for API key access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs an API key store for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new API key into the database. The prefix and hash are
+// derived from the key by the caller, the key itself is never stored.
+func (s Store) Create(ctx context.Context, nk NewAPIKey, prefix string, hash []byte, now time.Time) (APIKey, error) {
+	if err := validate.Check(nk); err != nil {
+		return APIKey{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	key := APIKey{
+		ID:          validate.GenerateID(),
+		UserID:      nk.UserID,
+		Name:        nk.Name,
+		Prefix:      prefix,
+		KeyHash:     hash,
+		Permissions: nk.Permissions,
+		DateCreated: now,
+		DateExpires: nk.DateExpires,
+	}
+
+	const q = `
+	INSERT INTO api_keys
+		(key_id, user_id, name, prefix, key_hash, permissions, date_created, date_expires)
+	VALUES
+		(:key_id, :user_id, :name, :prefix, :key_hash, :permissions, :date_created, :date_expires)
+	RETURNING
+		tenant_id`
+
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, key, &key); err != nil {
+		return APIKey{}, fmt.Errorf("inserting api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Revoke stops the key from being accepted.
+func (s Store) Revoke(ctx context.Context, keyID string, now time.Time) error {
+	if err := validate.CheckID(keyID); err != nil {
+		return database.ErrInvalidID
+	}
+
+	data := struct {
+		KeyID string    `db:"key_id"`
+		Now   time.Time `db:"now"`
+	}{
+		KeyID: keyID,
+		Now:   now,
+	}
+
+	const q = `
+	UPDATE
+		api_keys
+	SET
+		date_revoked = :now
+	WHERE
+		key_id = :key_id AND
+		date_revoked IS NULL
+	RETURNING
+		key_id`
+
+	var key APIKey
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &key); err != nil {
+		if err == database.ErrNotFound {
+			return database.ErrNotFound
+		}
+		return fmt.Errorf("revoking keyID[%s]: %w", keyID, err)
+	}
+
+	return nil
+}
+
+// Query retrieves the API keys of the tenant the context is scoped to.
+func (s Store) Query(ctx context.Context) ([]APIKey, error) {
+	const q = `
+	SELECT
+		*
+	FROM
+		api_keys
+	ORDER BY
+		date_created, key_id`
+
+	var keys []APIKey
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, struct{}{}, &keys); err != nil {
+		return nil, fmt.Errorf("selecting api keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// QueryByHash gets the API key with the hash from the database. The tenant
+// isn't known until the key is found, so this is run with a system context.
+func (s Store) QueryByHash(ctx context.Context, hash []byte) (APIKey, error) {
+	data := struct {
+		KeyHash []byte `db:"key_hash"`
+	}{
+		KeyHash: hash,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		api_keys
+	WHERE
+		key_hash = :key_hash`
+
+	var key APIKey
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &key); err != nil {
+		if err == database.ErrNotFound {
+			return APIKey{}, database.ErrNotFound
+		}
+		return APIKey{}, fmt.Errorf("selecting api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Use records that the key was used, at most once per usedInterval.
+func (s Store) Use(ctx context.Context, keyID string, now time.Time) error {
+	data := struct {
+		KeyID string    `db:"key_id"`
+		Now   time.Time `db:"now"`
+		Since time.Time `db:"since"`
+	}{
+		KeyID: keyID,
+		Now:   now,
+		Since: now.Add(-usedInterval),
+	}
+
+	const q = `
+	UPDATE
+		api_keys
+	SET
+		date_used = :now
+	WHERE
+		key_id = :key_id AND
+		(date_used IS NULL OR date_used < :since)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("using keyID[%s]: %w", keyID, err)
+	}
+
+	return nil
+}
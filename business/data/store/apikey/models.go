@@ -0,0 +1,34 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKey lets an integration that can't sign in interactively, such as a
+// warehouse scanner, call the API on behalf of a user. It is limited to its
+// own set of permissions. Only a hash of the key is stored and the prefix is
+// kept so people can tell their keys apart.
+type APIKey struct {
+	ID          string         `db:"key_id" json:"id"`
+	TenantID    string         `db:"tenant_id" json:"-"`
+	UserID      string         `db:"user_id" json:"user_id"`
+	Name        string         `db:"name" json:"name"`
+	Prefix      string         `db:"prefix" json:"prefix"`
+	KeyHash     []byte         `db:"key_hash" json:"-"`
+	Permissions pq.StringArray `db:"permissions" json:"permissions"`
+	DateCreated time.Time      `db:"date_created" json:"date_created"`
+	DateExpires *time.Time     `db:"date_expires" json:"date_expires,omitempty"`
+	DateUsed    *time.Time     `db:"date_used" json:"date_used,omitempty"`
+	DateRevoked *time.Time     `db:"date_revoked" json:"date_revoked,omitempty"`
+}
+
+// NewAPIKey contains information needed to create a new APIKey. Keys
+// without an expiry date are valid until they are revoked.
+type NewAPIKey struct {
+	Name        string     `json:"name" validate:"required"`
+	UserID      string     `json:"user_id" validate:"required,uuid"`
+	Permissions []string   `json:"permissions" validate:"required,dive,required"`
+	DateExpires *time.Time `json:"date_expires"`
+}
@@ -0,0 +1,156 @@
+// Package client contains OAuth client related CRUD functionality.
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Store manages the set of API's for client access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs a client store for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create registers a new client with the tenant the context is scoped to. It
+// returns the client's secret, which is only stored hashed and can't be
+// recovered later.
+func (s Store) Create(ctx context.Context, nc NewClient, now time.Time) (Client, string, error) {
+	if err := validate.Check(nc); err != nil {
+		return Client{}, "", fmt.Errorf("validating data: %w", err)
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return Client{}, "", fmt.Errorf("generating secret: %w", err)
+	}
+	secret := base64.RawURLEncoding.EncodeToString(raw)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return Client{}, "", fmt.Errorf("generating secret hash: %w", err)
+	}
+
+	clt := Client{
+		ID:          validate.GenerateID(),
+		Name:        nc.Name,
+		SecretHash:  hash,
+		Grants:      nc.Grants,
+		Scopes:      nc.Scopes,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+	if clt.Scopes == nil {
+		clt.Scopes = []string{}
+	}
+
+	const q = `
+	INSERT INTO oauth_clients
+		(client_id, name, secret_hash, grants, scopes, date_created, date_updated)
+	VALUES
+		(:client_id, :name, :secret_hash, :grants, :scopes, :date_created, :date_updated)
+	RETURNING
+		tenant_id`
+
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, clt, &clt); err != nil {
+		return Client{}, "", fmt.Errorf("inserting client: %w", err)
+	}
+
+	return clt, secret, nil
+}
+
+// Delete removes a client. The sessions it started are ended with it.
+func (s Store) Delete(ctx context.Context, clientID string) error {
+	if err := validate.CheckID(clientID); err != nil {
+		return database.ErrInvalidID
+	}
+
+	data := struct {
+		ClientID string `db:"client_id"`
+	}{
+		ClientID: clientID,
+	}
+
+	const q = `
+	DELETE FROM
+		oauth_clients
+	WHERE
+		client_id = :client_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("deleting clientID[%s]: %w", clientID, err)
+	}
+
+	return nil
+}
+
+// Query retrieves the clients the context can reach.
+func (s Store) Query(ctx context.Context) ([]Client, error) {
+	const q = `
+	SELECT
+		*
+	FROM
+		oauth_clients
+	ORDER BY
+		name, client_id`
+
+	var clients []Client
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, struct{}{}, &clients); err != nil {
+		return nil, fmt.Errorf("selecting clients: %w", err)
+	}
+
+	return clients, nil
+}
+
+// Authenticate finds the client and verifies its secret. The client's tenant
+// isn't known until it is found, so the lookup reaches every tenant.
+func (s Store) Authenticate(ctx context.Context, clientID, secret string) (Client, error) {
+	if err := validate.CheckID(clientID); err != nil {
+		return Client{}, database.ErrAuthenticationFailure
+	}
+
+	data := struct {
+		ClientID string `db:"client_id"`
+	}{
+		ClientID: clientID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		oauth_clients
+	WHERE
+		client_id = :client_id`
+
+	var clt Client
+	if err := database.NamedQueryStruct(database.WithSystem(ctx), s.log, s.db, q, data, &clt); err != nil {
+		if err == database.ErrNotFound {
+			return Client{}, database.ErrAuthenticationFailure
+		}
+		return Client{}, fmt.Errorf("selecting client[%q]: %w", clientID, err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword(clt.SecretHash, []byte(secret)); err != nil {
+		return Client{}, database.ErrAuthenticationFailure
+	}
+
+	return clt, nil
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Set of grants a client can be allowed to use.
+const (
+	GrantPassword          = "password"
+	GrantClientCredentials = "client_credentials"
+	GrantRefreshToken      = "refresh_token"
+)
+
+// Client is an OAuth client, such as a third party integration, registered
+// with a tenant. It can only use the grants it is allowed and be issued the
+// scopes it is allowed.
+type Client struct {
+	ID          string         `db:"client_id" json:"id"`
+	TenantID    string         `db:"tenant_id" json:"-"`
+	Name        string         `db:"name" json:"name"`
+	SecretHash  []byte         `db:"secret_hash" json:"-"`
+	Grants      pq.StringArray `db:"grants" json:"grants"`
+	Scopes      pq.StringArray `db:"scopes" json:"scopes"`
+	DateCreated time.Time      `db:"date_created" json:"date_created"`
+	DateUpdated time.Time      `db:"date_updated" json:"date_updated"`
+}
+
+// Allows reports whether the client can use the grant.
+func (c Client) Allows(grant string) bool {
+	for _, g := range c.Grants {
+		if g == grant {
+			return true
+		}
+	}
+	return false
+}
+
+// NewClient contains information needed to register a new Client.
+type NewClient struct {
+	Name   string   `json:"name" validate:"required"`
+	Grants []string `json:"grants" validate:"required,dive,oneof=password client_credentials refresh_token"`
+	Scopes []string `json:"scopes" validate:"dive,required"`
+}
@@ -0,0 +1,281 @@
+// Package dashboard contains dashboard related CRUD functionality.
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of API's for dashboard access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs a dashboard store for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new dashboard owned by the claims subject into the database.
+func (s Store) Create(ctx context.Context, claims auth.Claims, nd NewDashboard, now time.Time) (Dashboard, error) {
+	if err := validate.Check(nd); err != nil {
+		return Dashboard{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	dsh := Dashboard{
+		ID:          validate.GenerateID(),
+		UserID:      claims.Subject,
+		Name:        nd.Name,
+		Layout:      nd.Layout,
+		Charts:      nd.Charts,
+		SharedUsers: pq.StringArray{},
+		SharedRoles: pq.StringArray{},
+		DateCreated: now,
+		DateUpdated: now,
+	}
+	if dsh.Charts == nil {
+		dsh.Charts = Charts{}
+	}
+
+	const q = `
+	INSERT INTO dashboards
+		(dashboard_id, user_id, name, layout, charts, shared_users, shared_roles, date_created, date_updated)
+	VALUES
+		(:dashboard_id, :user_id, :name, :layout, :charts, :shared_users, :shared_roles, :date_created, :date_updated)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, dsh); err != nil {
+		return Dashboard{}, fmt.Errorf("inserting dashboard: %w", err)
+	}
+
+	return dsh, nil
+}
+
+// Update replaces a dashboard document in the database.
+func (s Store) Update(ctx context.Context, claims auth.Claims, dashboardID string, ud UpdateDashboard, now time.Time) (Dashboard, error) {
+	if err := validate.Check(ud); err != nil {
+		return Dashboard{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	dsh, err := s.queryOwned(ctx, claims, dashboardID)
+	if err != nil {
+		return Dashboard{}, fmt.Errorf("updating dashboard dashboardID[%s]: %w", dashboardID, err)
+	}
+
+	if ud.Name != nil {
+		dsh.Name = *ud.Name
+	}
+	if ud.Layout != nil {
+		dsh.Layout = *ud.Layout
+	}
+	if ud.Charts != nil {
+		dsh.Charts = ud.Charts
+	}
+	dsh.DateUpdated = now
+
+	const q = `
+	UPDATE
+		dashboards
+	SET
+		"name" = :name,
+		"layout" = :layout,
+		"charts" = :charts,
+		"date_updated" = :date_updated
+	WHERE
+		dashboard_id = :dashboard_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, dsh); err != nil {
+		return Dashboard{}, fmt.Errorf("updating dashboardID[%s]: %w", dashboardID, err)
+	}
+
+	return dsh, nil
+}
+
+// Share replaces the set of users and roles the dashboard is shared with.
+func (s Store) Share(ctx context.Context, claims auth.Claims, dashboardID string, sh Share, now time.Time) (Dashboard, error) {
+	if err := validate.Check(sh); err != nil {
+		return Dashboard{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	dsh, err := s.queryOwned(ctx, claims, dashboardID)
+	if err != nil {
+		return Dashboard{}, fmt.Errorf("sharing dashboard dashboardID[%s]: %w", dashboardID, err)
+	}
+
+	dsh.SharedUsers = pq.StringArray(sh.Users)
+	dsh.SharedRoles = pq.StringArray(sh.Roles)
+	if dsh.SharedUsers == nil {
+		dsh.SharedUsers = pq.StringArray{}
+	}
+	if dsh.SharedRoles == nil {
+		dsh.SharedRoles = pq.StringArray{}
+	}
+	dsh.DateUpdated = now
+
+	const q = `
+	UPDATE
+		dashboards
+	SET
+		"shared_users" = :shared_users,
+		"shared_roles" = :shared_roles,
+		"date_updated" = :date_updated
+	WHERE
+		dashboard_id = :dashboard_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, dsh); err != nil {
+		return Dashboard{}, fmt.Errorf("sharing dashboardID[%s]: %w", dashboardID, err)
+	}
+
+	return dsh, nil
+}
+
+// Delete removes a dashboard from the database.
+func (s Store) Delete(ctx context.Context, claims auth.Claims, dashboardID string) error {
+	if _, err := s.queryOwned(ctx, claims, dashboardID); err != nil {
+		return fmt.Errorf("deleting dashboard dashboardID[%s]: %w", dashboardID, err)
+	}
+
+	data := struct {
+		DashboardID string `db:"dashboard_id"`
+	}{
+		DashboardID: dashboardID,
+	}
+
+	const q = `
+	DELETE FROM
+		dashboards
+	WHERE
+		dashboard_id = :dashboard_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("deleting dashboardID[%s]: %w", dashboardID, err)
+	}
+
+	return nil
+}
+
+// Query retrieves the dashboards the claims subject owns or that have been
+// shared with them directly or through one of their roles.
+func (s Store) Query(ctx context.Context, claims auth.Claims) ([]Dashboard, error) {
+	data := struct {
+		UserID string         `db:"user_id"`
+		Roles  pq.StringArray `db:"roles"`
+	}{
+		UserID: claims.Subject,
+		Roles:  claims.Roles,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		dashboards
+	WHERE
+		CAST(user_id AS TEXT) = :user_id OR
+		:user_id = ANY(shared_users) OR
+		shared_roles && :roles
+	ORDER BY
+		name, dashboard_id`
+
+	var dashboards []Dashboard
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &dashboards); err != nil {
+		return nil, fmt.Errorf("selecting dashboards: %w", err)
+	}
+
+	return dashboards, nil
+}
+
+// QueryByID gets the specified dashboard from the database. The dashboard is
+// only returned if the claims subject is allowed to view it.
+func (s Store) QueryByID(ctx context.Context, claims auth.Claims, dashboardID string) (Dashboard, error) {
+	dsh, err := s.queryByID(ctx, dashboardID)
+	if err != nil {
+		return Dashboard{}, err
+	}
+
+	if !CanView(claims, dsh) {
+		return Dashboard{}, database.ErrForbidden
+	}
+
+	return dsh, nil
+}
+
+// CanView reports whether the claims allow the dashboard to be viewed. Admins
+// and the owner can always view a dashboard, other users need it shared with
+// them or one of their roles.
+func CanView(claims auth.Claims, dsh Dashboard) bool {
+	if CanModify(claims, dsh) {
+		return true
+	}
+	for _, userID := range dsh.SharedUsers {
+		if userID == claims.Subject {
+			return true
+		}
+	}
+	return claims.Authorized(dsh.SharedRoles...)
+}
+
+// CanModify reports whether the claims allow the dashboard to be changed.
+// Only admins and the owner can change a dashboard.
+func CanModify(claims auth.Claims, dsh Dashboard) bool {
+	return claims.Authorized(auth.RoleAdmin) || claims.Subject == dsh.UserID
+}
+
+// =============================================================================
+
+// queryOwned gets the specified dashboard and verifies the claims allow it to
+// be changed.
+func (s Store) queryOwned(ctx context.Context, claims auth.Claims, dashboardID string) (Dashboard, error) {
+	dsh, err := s.queryByID(ctx, dashboardID)
+	if err != nil {
+		return Dashboard{}, err
+	}
+
+	if !CanModify(claims, dsh) {
+		return Dashboard{}, database.ErrForbidden
+	}
+
+	return dsh, nil
+}
+
+// queryByID gets the specified dashboard without checking access.
+func (s Store) queryByID(ctx context.Context, dashboardID string) (Dashboard, error) {
+	if err := validate.CheckID(dashboardID); err != nil {
+		return Dashboard{}, database.ErrInvalidID
+	}
+
+	data := struct {
+		DashboardID string `db:"dashboard_id"`
+	}{
+		DashboardID: dashboardID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		dashboards
+	WHERE
+		dashboard_id = :dashboard_id`
+
+	var dsh Dashboard
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &dsh); err != nil {
+		if err == database.ErrNotFound {
+			return Dashboard{}, database.ErrNotFound
+		}
+		return Dashboard{}, fmt.Errorf("selecting dashboardID[%q]: %w", dashboardID, err)
+	}
+
+	return dsh, nil
+}
@@ -0,0 +1,217 @@
+package dashboard_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/dashboard"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/data/tests"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var dbc = tests.DBContainer{
+	Image: "postgres:13-alpine",
+	Port:  "5432",
+	Args:  []string{"-e", "POSTGRES_PASSWORD=postgres"},
+}
+
+// Seeded users the dashboards are created and shared between.
+const (
+	adminID = "5cf37266-3473-4006-984f-9325122678b7"
+	userID  = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+)
+
+func TestDashboard(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	store := dashboard.NewStore(log, db)
+
+	t.Log("Given the need to work with Dashboard records.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a single Dashboard.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+			now := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+			admin := claims(adminID, auth.RoleAdmin)
+			user := claims(userID, auth.RoleUser)
+
+			nd := dashboard.NewDashboard{
+				Name:   "Sales",
+				Layout: dashboard.Layout{Columns: 12, RowHeight: 40},
+				Charts: dashboard.Charts{
+					{
+						Name:     "Revenue",
+						Type:     "line",
+						Source:   "revenue",
+						Filters:  dashboard.Filters{Group: "week"},
+						Position: dashboard.Position{Width: 6, Height: 4},
+					},
+				},
+			}
+
+			dsh, err := store.Create(ctx, admin, nd, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create dashboard : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create dashboard.", tests.Success, testID)
+
+			saved, err := store.QueryByID(ctx, admin, dsh.ID)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to retrieve dashboard by ID: %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to retrieve dashboard by ID.", tests.Success, testID)
+
+			if len(saved.Charts) != 1 || saved.Charts[0].Filters.Group != "week" || saved.Layout.Columns != 12 {
+				t.Fatalf("\t%s\tTest %d:\tShould get back the same layout and charts: %+v", tests.Failed, testID, saved)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get back the same layout and charts.", tests.Success, testID)
+
+			if _, err := store.QueryByID(ctx, user, dsh.ID); !errors.Is(err, database.ErrForbidden) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to view an unshared dashboard : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to view an unshared dashboard.", tests.Success, testID)
+
+			if _, err := store.Share(ctx, admin, dsh.ID, dashboard.Share{Roles: []string{auth.RoleUser}}, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to share dashboard : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to share dashboard.", tests.Success, testID)
+
+			visible, err := store.Query(ctx, user)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to query visible dashboards : %s.", tests.Failed, testID, err)
+			}
+			if len(visible) != 1 || visible[0].ID != dsh.ID {
+				t.Fatalf("\t%s\tTest %d:\tShould see the dashboard shared with their role : %+v.", tests.Failed, testID, visible)
+			}
+			t.Logf("\t%s\tTest %d:\tShould see the dashboard shared with their role.", tests.Success, testID)
+
+			upd := dashboard.UpdateDashboard{Name: tests.StringPointer("Renamed")}
+			if _, err := store.Update(ctx, user, dsh.ID, upd, now); !errors.Is(err, database.ErrForbidden) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to update a shared dashboard : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to update a shared dashboard.", tests.Success, testID)
+
+			if err := store.Delete(ctx, admin, dsh.ID); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to delete dashboard : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to delete dashboard.", tests.Success, testID)
+
+			if _, err := store.QueryByID(ctx, admin, dsh.ID); !errors.Is(err, database.ErrNotFound) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to retrieve dashboard : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to retrieve dashboard.", tests.Success, testID)
+		}
+	}
+}
+
+func TestRowLevelSecurity(t *testing.T) {
+	rls := dbc
+	rls.RowLevelSecurity = true
+
+	log, db, teardown := tests.NewUnit(t, rls)
+	t.Cleanup(teardown)
+
+	store := dashboard.NewStore(log, db)
+
+	t.Log("Given the need to enforce the claims in the database.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the claims checked in Go don't match the user.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+			now := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+			admin := claims(adminID, auth.RoleAdmin)
+			adminCtx := database.WithUser(ctx, adminID, []string{auth.RoleAdmin})
+			userCtx := database.WithUser(ctx, userID, []string{auth.RoleUser})
+
+			dsh, err := store.Create(adminCtx, admin, dashboard.NewDashboard{Name: "Private"}, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create dashboard : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create dashboard.", tests.Success, testID)
+
+			// The user's queries are made with the admin's claims so only the
+			// policies stand in the way.
+			if _, err := store.QueryByID(userCtx, admin, dsh.ID); !errors.Is(err, database.ErrNotFound) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to see an unshared dashboard : %v.", tests.Failed, testID, err)
+			}
+			visible, err := store.Query(userCtx, admin)
+			if err != nil || len(visible) != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to list an unshared dashboard : %v %+v.", tests.Failed, testID, err, visible)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to see an unshared dashboard.", tests.Success, testID)
+
+			if _, err := store.Share(adminCtx, admin, dsh.ID, dashboard.Share{Users: []string{userID}}, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to share dashboard : %s.", tests.Failed, testID, err)
+			}
+			if _, err := store.QueryByID(userCtx, admin, dsh.ID); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to see a shared dashboard : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to see a shared dashboard.", tests.Success, testID)
+
+			upd := dashboard.UpdateDashboard{Name: tests.StringPointer("Renamed")}
+			if _, err := store.Update(userCtx, admin, dsh.ID, upd, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to run the update : %s.", tests.Failed, testID, err)
+			}
+			saved, err := store.QueryByID(adminCtx, admin, dsh.ID)
+			if err != nil || saved.Name != "Private" {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to change a shared dashboard : %v %q.", tests.Failed, testID, err, saved.Name)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to change a shared dashboard.", tests.Success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the permissions checked in Go don't match the user.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+			userCtx := database.WithUser(ctx, userID, []string{"AUDITOR"})
+
+			// The queries are made with the admin's claims so only the
+			// policies stand in the way.
+			users := user.NewStore(log, db)
+			admin := claims(adminID, auth.RoleAdmin)
+
+			if _, err := users.QueryByID(userCtx, admin, adminID); !errors.Is(err, database.ErrNotFound) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to see another user without users:read : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to see another user without users:read.", tests.Success, testID)
+
+			readCtx := database.WithPermissions(userCtx, []string{auth.PermUsersRead})
+			if _, err := users.QueryByID(readCtx, admin, adminID); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to see another user with users:read : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to see another user with users:read.", tests.Success, testID)
+
+			upd := user.UpdateUser{Name: tests.StringPointer("Renamed")}
+			if err := users.Update(readCtx, admin, adminID, upd, time.Now()); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to run the update : %s.", tests.Failed, testID, err)
+			}
+			saved, err := users.QueryByID(readCtx, admin, adminID)
+			if err != nil || saved.Name == "Renamed" {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to change another user without users:write : %v %q.", tests.Failed, testID, err, saved.Name)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to change another user without users:write.", tests.Success, testID)
+		}
+	}
+}
+
+func claims(subject string, roles ...string) auth.Claims {
+	return auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    "service project",
+			Subject:   subject,
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			IssuedAt:  time.Now().UTC().Unix(),
+		},
+		Roles: roles,
+	}
+}
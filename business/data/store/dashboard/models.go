@@ -0,0 +1,121 @@
+package dashboard
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Dashboard represents a user's saved layout of charts.
+type Dashboard struct {
+	ID          string         `db:"dashboard_id" json:"id"`
+	TenantID    string         `db:"tenant_id" json:"-"`
+	UserID      string         `db:"user_id" json:"user_id"`
+	Name        string         `db:"name" json:"name"`
+	Layout      Layout         `db:"layout" json:"layout"`
+	Charts      Charts         `db:"charts" json:"charts"`
+	SharedUsers pq.StringArray `db:"shared_users" json:"shared_users"`
+	SharedRoles pq.StringArray `db:"shared_roles" json:"shared_roles"`
+	DateCreated time.Time      `db:"date_created" json:"date_created"`
+	DateUpdated time.Time      `db:"date_updated" json:"date_updated"`
+}
+
+// Layout describes the grid the dashboard's charts are positioned on.
+type Layout struct {
+	Columns   int `json:"columns" validate:"gte=0"`
+	RowHeight int `json:"row_height" validate:"gte=0"`
+}
+
+// Value implements the driver.Valuer interface so the layout is stored as JSON.
+func (l Layout) Value() (driver.Value, error) {
+	return json.Marshal(l)
+}
+
+// Scan implements the sql.Scanner interface so the layout is read from JSON.
+func (l *Layout) Scan(src any) error {
+	return scanJSON(src, l)
+}
+
+// Chart defines a single chart placed on a dashboard. Source names the report
+// that provides the chart's data.
+type Chart struct {
+	Name     string   `json:"name" validate:"required"`
+	Type     string   `json:"type" validate:"required,oneof=line bar pie scatter bubble"`
+	Source   string   `json:"source" validate:"required"`
+	Filters  Filters  `json:"filters"`
+	Position Position `json:"position"`
+}
+
+// Filters narrows the data a chart's report is built from. Every field is
+// optional and the report's defaults apply to any that are missing.
+type Filters struct {
+	Group string     `json:"group,omitempty" validate:"omitempty,oneof=day week month"`
+	From  *time.Time `json:"from,omitempty"`
+	To    *time.Time `json:"to,omitempty"`
+	Limit int        `json:"limit,omitempty" validate:"gte=0"`
+}
+
+// Position places a chart on the dashboard's grid.
+type Position struct {
+	X      int `json:"x" validate:"gte=0"`
+	Y      int `json:"y" validate:"gte=0"`
+	Width  int `json:"width" validate:"gte=0"`
+	Height int `json:"height" validate:"gte=0"`
+}
+
+// Charts is the collection of charts on a dashboard.
+type Charts []Chart
+
+// Value implements the driver.Valuer interface so the charts are stored as JSON.
+func (c Charts) Value() (driver.Value, error) {
+	if c == nil {
+		c = Charts{}
+	}
+	return json.Marshal(c)
+}
+
+// Scan implements the sql.Scanner interface so the charts are read from JSON.
+func (c *Charts) Scan(src any) error {
+	return scanJSON(src, c)
+}
+
+// NewDashboard contains information needed to create a new Dashboard.
+type NewDashboard struct {
+	Name   string `json:"name" validate:"required"`
+	Layout Layout `json:"layout"`
+	Charts Charts `json:"charts" validate:"dive"`
+}
+
+// UpdateDashboard defines what information may be provided to modify an
+// existing Dashboard. All fields are optional so clients can send just the
+// fields they want changed.
+type UpdateDashboard struct {
+	Name   *string `json:"name" validate:"omitempty,min=1"`
+	Layout *Layout `json:"layout"`
+	Charts Charts  `json:"charts" validate:"omitempty,dive"`
+}
+
+// Share defines the set of users and roles a dashboard is shared with. It
+// replaces any previous sharing.
+type Share struct {
+	Users []string `json:"users" validate:"dive,uuid"`
+	Roles []string `json:"roles"`
+}
+
+// =============================================================================
+
+// scanJSON unmarshals a JSONB column into dest.
+func scanJSON(src any, dest any) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	}
+	return errors.New("unsupported type for JSON column")
+}
@@ -0,0 +1,132 @@
+// Package emailtoken contains emailed token related CRUD functionality.
+package emailtoken
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of API's for emailed token access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs an emailed token store for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create stores the hash of a token issued to the user for the purpose.
+// Tokens issued to the user for the same purpose before it that weren't
+// used are dropped, so only the latest one works.
+func (s Store) Create(ctx context.Context, userID string, purpose string, hash []byte, expires time.Time, now time.Time) (EmailToken, error) {
+	et := EmailToken{
+		TokenHash:   hash,
+		UserID:      userID,
+		Purpose:     purpose,
+		DateCreated: now,
+		DateExpires: expires,
+	}
+
+	const q = `
+	WITH dropped AS (
+		DELETE FROM
+			email_tokens
+		WHERE
+			user_id = :user_id AND
+			purpose = :purpose AND
+			date_used IS NULL
+	)
+	INSERT INTO email_tokens
+		(token_hash, user_id, purpose, date_created, date_expires)
+	VALUES
+		(:token_hash, :user_id, :purpose, :date_created, :date_expires)
+	RETURNING
+		tenant_id`
+
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, et, &et); err != nil {
+		return EmailToken{}, fmt.Errorf("inserting email token: %w", err)
+	}
+
+	return et, nil
+}
+
+// QueryByHash gets the token with the hash that was issued for the purpose
+// and can still be used. The tenant isn't known until the token is found,
+// so this is run with a system context.
+func (s Store) QueryByHash(ctx context.Context, hash []byte, purpose string, now time.Time) (EmailToken, error) {
+	data := struct {
+		TokenHash []byte    `db:"token_hash"`
+		Purpose   string    `db:"purpose"`
+		Now       time.Time `db:"now"`
+	}{
+		TokenHash: hash,
+		Purpose:   purpose,
+		Now:       now,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		email_tokens
+	WHERE
+		token_hash = :token_hash AND
+		purpose = :purpose AND
+		date_used IS NULL AND
+		date_expires > :now`
+
+	var et EmailToken
+	if err := database.NamedQueryStruct(database.WithSystem(ctx), s.log, s.db, q, data, &et); err != nil {
+		if err == database.ErrNotFound {
+			return EmailToken{}, database.ErrNotFound
+		}
+		return EmailToken{}, fmt.Errorf("selecting email token: %w", err)
+	}
+
+	return et, nil
+}
+
+// Use marks the token with the hash as used. It returns database.ErrNotFound
+// when the token was used already, so racing requests can't both use it.
+func (s Store) Use(ctx context.Context, hash []byte, now time.Time) error {
+	data := struct {
+		TokenHash []byte    `db:"token_hash"`
+		Now       time.Time `db:"now"`
+	}{
+		TokenHash: hash,
+		Now:       now,
+	}
+
+	const q = `
+	UPDATE
+		email_tokens
+	SET
+		date_used = :now
+	WHERE
+		token_hash = :token_hash AND
+		date_used IS NULL
+	RETURNING
+		user_id`
+
+	var result struct {
+		UserID string `db:"user_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &result); err != nil {
+		if err == database.ErrNotFound {
+			return database.ErrNotFound
+		}
+		return fmt.Errorf("using email token: %w", err)
+	}
+
+	return nil
+}
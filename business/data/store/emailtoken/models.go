@@ -0,0 +1,25 @@
+package emailtoken
+
+import (
+	"time"
+)
+
+// These are the purposes a token can be emailed for. A token can only be
+// used for the purpose it was issued for.
+const (
+	PurposeVerify = "verify"
+	PurposeReset  = "reset"
+)
+
+// EmailToken is a single use token emailed to a user to prove they own
+// their address. Only a hash of the token is stored so a leaked table can't
+// be used to take over accounts.
+type EmailToken struct {
+	TokenHash   []byte     `db:"token_hash"`
+	TenantID    string     `db:"tenant_id"`
+	UserID      string     `db:"user_id"`
+	Purpose     string     `db:"purpose"`
+	DateCreated time.Time  `db:"date_created"`
+	DateExpires time.Time  `db:"date_expires"`
+	DateUsed    *time.Time `db:"date_used"`
+}
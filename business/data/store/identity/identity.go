@@ -0,0 +1,80 @@
+// Package identity contains external identity related CRUD functionality.
+package identity
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of API's for external identity access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs an external identity store for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create links the user to the account the issuer gave the subject.
+func (s Store) Create(ctx context.Context, issuer, subject, userID string, now time.Time) (Identity, error) {
+	id := Identity{
+		Issuer:      issuer,
+		Subject:     subject,
+		UserID:      userID,
+		DateCreated: now,
+	}
+
+	const q = `
+	INSERT INTO external_identities
+		(issuer, subject, user_id, date_created)
+	VALUES
+		(:issuer, :subject, :user_id, :date_created)
+	RETURNING
+		tenant_id`
+
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, id, &id); err != nil {
+		return Identity{}, fmt.Errorf("inserting identity: %w", err)
+	}
+
+	return id, nil
+}
+
+// QueryBySubject gets the link to the account the issuer gave the subject.
+func (s Store) QueryBySubject(ctx context.Context, issuer, subject string) (Identity, error) {
+	data := struct {
+		Issuer  string `db:"issuer"`
+		Subject string `db:"subject"`
+	}{
+		Issuer:  issuer,
+		Subject: subject,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		external_identities
+	WHERE
+		issuer = :issuer AND
+		subject = :subject`
+
+	var id Identity
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &id); err != nil {
+		if err == database.ErrNotFound {
+			return Identity{}, database.ErrNotFound
+		}
+		return Identity{}, fmt.Errorf("selecting issuer[%s] subject[%s]: %w", issuer, subject, err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,15 @@
+package identity
+
+import (
+	"time"
+)
+
+// Identity links a user to their account at a trusted external issuer. The
+// account is named by the issuer and the subject the issuer gave it.
+type Identity struct {
+	Issuer      string    `db:"issuer"`
+	Subject     string    `db:"subject"`
+	TenantID    string    `db:"tenant_id"`
+	UserID      string    `db:"user_id"`
+	DateCreated time.Time `db:"date_created"`
+}
@@ -0,0 +1,140 @@
+// Package lockout contains failed sign in tracking related CRUD
+// functionality. The failures are kept in the database so every instance of
+// the service sees them.
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of API's for lockout access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs a lockout store for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// LockedUntil returns the latest time any of the keys is locked until, or
+// the zero time if none of them is locked.
+func (s Store) LockedUntil(ctx context.Context, keys []string, now time.Time) (time.Time, error) {
+	data := struct {
+		Keys pq.StringArray `db:"keys"`
+		Now  time.Time      `db:"now"`
+	}{
+		Keys: keys,
+		Now:  now,
+	}
+
+	const q = `
+	SELECT
+		MAX(date_locked) AS date_locked
+	FROM
+		login_failures
+	WHERE
+		key = ANY(CAST(:keys AS TEXT[])) AND
+		date_locked > :now`
+
+	var result struct {
+		DateLocked *time.Time `db:"date_locked"`
+	}
+	if err := database.NamedQueryStruct(database.WithSystem(ctx), s.log, s.db, q, data, &result); err != nil {
+		return time.Time{}, fmt.Errorf("selecting lockouts: %w", err)
+	}
+
+	if result.DateLocked == nil {
+		return time.Time{}, nil
+	}
+	return *result.DateLocked, nil
+}
+
+// Fail records a failed sign in for the key and returns the number of
+// failures in a row. Failures older than the window are forgotten.
+func (s Store) Fail(ctx context.Context, key string, window time.Duration, now time.Time) (Failure, error) {
+	data := struct {
+		Key   string    `db:"key"`
+		Now   time.Time `db:"now"`
+		Since time.Time `db:"since"`
+	}{
+		Key:   key,
+		Now:   now,
+		Since: now.Add(-window),
+	}
+
+	const q = `
+	INSERT INTO login_failures
+		(key, failures, date_last)
+	VALUES
+		(:key, 1, :now)
+	ON CONFLICT (key) DO UPDATE SET
+		failures = CASE WHEN login_failures.date_last < :since THEN 1 ELSE login_failures.failures + 1 END,
+		date_last = :now
+	RETURNING
+		*`
+
+	var f Failure
+	if err := database.NamedQueryStruct(database.WithSystem(ctx), s.log, s.db, q, data, &f); err != nil {
+		return Failure{}, fmt.Errorf("recording failure[%s]: %w", key, err)
+	}
+
+	return f, nil
+}
+
+// Lock refuses sign ins for the key until the time given.
+func (s Store) Lock(ctx context.Context, key string, until time.Time) error {
+	data := struct {
+		Key   string    `db:"key"`
+		Until time.Time `db:"until"`
+	}{
+		Key:   key,
+		Until: until,
+	}
+
+	const q = `
+	UPDATE
+		login_failures
+	SET
+		date_locked = :until
+	WHERE
+		key = :key`
+
+	if err := database.NamedExecContext(database.WithSystem(ctx), s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("locking[%s]: %w", key, err)
+	}
+
+	return nil
+}
+
+// Reset forgets the failures of the key after a successful sign in.
+func (s Store) Reset(ctx context.Context, key string) error {
+	data := struct {
+		Key string `db:"key"`
+	}{
+		Key: key,
+	}
+
+	const q = `
+	DELETE FROM
+		login_failures
+	WHERE
+		key = :key`
+
+	if err := database.NamedExecContext(database.WithSystem(ctx), s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("resetting[%s]: %w", key, err)
+	}
+
+	return nil
+}
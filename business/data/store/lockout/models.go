@@ -0,0 +1,15 @@
+package lockout
+
+import (
+	"time"
+)
+
+// Failure counts the failed sign ins for a key, which names an account or an
+// address, since the last success. Sign ins for the key are refused until
+// DateLocked once it is set.
+type Failure struct {
+	Key        string     `db:"key"`
+	Failures   int        `db:"failures"`
+	DateLast   time.Time  `db:"date_last"`
+	DateLocked *time.Time `db:"date_locked"`
+}
@@ -0,0 +1,223 @@
+// Package mfa contains multi-factor authentication related CRUD
+// functionality.
+package mfa
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of API's for MFA access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs a MFA store for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create starts the enrollment of the user with the secret, replacing an
+// enrollment that is still pending. It returns database.ErrNotFound when
+// the user's enrollment was already confirmed.
+func (s Store) Create(ctx context.Context, userID string, secret string, now time.Time) (MFA, error) {
+	m := MFA{
+		UserID:        userID,
+		Secret:        secret,
+		RecoveryCodes: []string{},
+		DateCreated:   now,
+	}
+
+	const q = `
+	INSERT INTO user_mfa
+		(user_id, secret, recovery_codes, date_created)
+	VALUES
+		(:user_id, :secret, :recovery_codes, :date_created)
+	ON CONFLICT (user_id) DO UPDATE SET
+		secret = EXCLUDED.secret,
+		date_created = EXCLUDED.date_created
+	WHERE
+		user_mfa.date_enabled IS NULL
+	RETURNING
+		*`
+
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, m, &m); err != nil {
+		if err == database.ErrNotFound {
+			return MFA{}, database.ErrNotFound
+		}
+		return MFA{}, fmt.Errorf("inserting mfa: %w", err)
+	}
+
+	return m, nil
+}
+
+// Enable confirms the enrollment of the user, storing the hashes of their
+// recovery codes and the time step of the code they confirmed it with.
+func (s Store) Enable(ctx context.Context, userID string, recoveryCodes []string, step int64, now time.Time) error {
+	data := struct {
+		UserID        string         `db:"user_id"`
+		RecoveryCodes pq.StringArray `db:"recovery_codes"`
+		Step          int64          `db:"step"`
+		Now           time.Time      `db:"now"`
+	}{
+		UserID:        userID,
+		RecoveryCodes: recoveryCodes,
+		Step:          step,
+		Now:           now,
+	}
+
+	const q = `
+	UPDATE
+		user_mfa
+	SET
+		recovery_codes = :recovery_codes,
+		last_step = :step,
+		date_enabled = :now
+	WHERE
+		user_id = :user_id AND
+		date_enabled IS NULL
+	RETURNING
+		user_id`
+
+	var result struct {
+		UserID string `db:"user_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &result); err != nil {
+		if err == database.ErrNotFound {
+			return database.ErrNotFound
+		}
+		return fmt.Errorf("enabling mfa userID[%s]: %w", userID, err)
+	}
+
+	return nil
+}
+
+// UseStep records a code of the time step was used. It returns
+// database.ErrNotFound when a code of the step, or a later one, was already
+// used.
+func (s Store) UseStep(ctx context.Context, userID string, step int64) error {
+	data := struct {
+		UserID string `db:"user_id"`
+		Step   int64  `db:"step"`
+	}{
+		UserID: userID,
+		Step:   step,
+	}
+
+	const q = `
+	UPDATE
+		user_mfa
+	SET
+		last_step = :step
+	WHERE
+		user_id = :user_id AND
+		last_step < :step
+	RETURNING
+		user_id`
+
+	var result struct {
+		UserID string `db:"user_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &result); err != nil {
+		if err == database.ErrNotFound {
+			return database.ErrNotFound
+		}
+		return fmt.Errorf("using step userID[%s]: %w", userID, err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode removes the recovery code with the hash so it can't be
+// used again. It returns database.ErrNotFound when the user has no such
+// code.
+func (s Store) UseRecoveryCode(ctx context.Context, userID string, hash string) error {
+	data := struct {
+		UserID string `db:"user_id"`
+		Hash   string `db:"hash"`
+	}{
+		UserID: userID,
+		Hash:   hash,
+	}
+
+	const q = `
+	UPDATE
+		user_mfa
+	SET
+		recovery_codes = array_remove(recovery_codes, :hash)
+	WHERE
+		user_id = :user_id AND
+		:hash = ANY(recovery_codes)
+	RETURNING
+		user_id`
+
+	var result struct {
+		UserID string `db:"user_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &result); err != nil {
+		if err == database.ErrNotFound {
+			return database.ErrNotFound
+		}
+		return fmt.Errorf("using recovery code userID[%s]: %w", userID, err)
+	}
+
+	return nil
+}
+
+// Delete removes the enrollment of the user.
+func (s Store) Delete(ctx context.Context, userID string) error {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `
+	DELETE FROM
+		user_mfa
+	WHERE
+		user_id = :user_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("deleting mfa userID[%s]: %w", userID, err)
+	}
+
+	return nil
+}
+
+// QueryByUser gets the enrollment of the user from the database.
+func (s Store) QueryByUser(ctx context.Context, userID string) (MFA, error) {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: userID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		user_mfa
+	WHERE
+		user_id = :user_id`
+
+	var m MFA
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &m); err != nil {
+		if err == database.ErrNotFound {
+			return MFA{}, database.ErrNotFound
+		}
+		return MFA{}, fmt.Errorf("selecting mfa userID[%s]: %w", userID, err)
+	}
+
+	return m, nil
+}
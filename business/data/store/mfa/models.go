@@ -0,0 +1,21 @@
+package mfa
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// MFA is a user's enrollment in TOTP multi-factor authentication. It is
+// pending until the user confirms it with a code from their authenticator
+// app. Only hashes of the recovery codes are stored, and LastStep is the
+// time step of the last code used so no code can be used twice.
+type MFA struct {
+	UserID        string         `db:"user_id"`
+	TenantID      string         `db:"tenant_id"`
+	Secret        string         `db:"secret"`
+	RecoveryCodes pq.StringArray `db:"recovery_codes"`
+	LastStep      int64          `db:"last_step"`
+	DateCreated   time.Time      `db:"date_created"`
+	DateEnabled   *time.Time     `db:"date_enabled"`
+}
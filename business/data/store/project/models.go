@@ -0,0 +1,119 @@
+package project
+
+import (
+	"time"
+)
+
+// Set of dependency types between two tasks. The first letter is what the
+// predecessor must reach and the second what the successor is held to.
+const (
+	FinishToStart  = "FS"
+	StartToStart   = "SS"
+	FinishToFinish = "FF"
+	StartToFinish  = "SF"
+)
+
+// Project is a body of work made up of scheduled tasks.
+type Project struct {
+	ID          string    `db:"project_id" json:"id"`
+	TenantID    string    `db:"tenant_id" json:"-"`
+	UserID      *string   `db:"user_id" json:"user_id,omitempty"`
+	Name        string    `db:"name" json:"name"`
+	Description string    `db:"description" json:"description"`
+	StartDate   time.Time `db:"start_date" json:"start_date"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+	DateUpdated time.Time `db:"date_updated" json:"date_updated"`
+}
+
+// NewProject contains information needed to create a new Project.
+type NewProject struct {
+	Name        string    `json:"name" validate:"required"`
+	Description string    `json:"description"`
+	StartDate   time.Time `json:"start_date" validate:"required"`
+}
+
+// UpdateProject defines what information may be provided to modify an
+// existing Project. All fields are optional so clients can send just the
+// fields they want changed.
+type UpdateProject struct {
+	Name        *string    `json:"name"`
+	Description *string    `json:"description"`
+	StartDate   *time.Time `json:"start_date"`
+}
+
+// QueryFilter holds the available fields a query of projects can be filtered
+// on. Every field is optional and only the provided ones narrow the results.
+type QueryFilter struct {
+	Name           *string    `json:"name" validate:"omitempty,min=1"`
+	StartDateFrom  *time.Time `json:"start_after"`
+	StartDateUntil *time.Time `json:"start_before"`
+}
+
+// OrderByFields maps the fields projects can be sorted by to their columns.
+var OrderByFields = map[string]string{
+	"id":           "project_id",
+	"name":         "name",
+	"start_date":   "start_date",
+	"date_created": "date_created",
+}
+
+// Task is a unit of work within a project. Duration is in days and a
+// milestone is a task with no duration that marks a point in the schedule.
+type Task struct {
+	ID              string     `db:"task_id" json:"id"`
+	TenantID        string     `db:"tenant_id" json:"-"`
+	ProjectID       string     `db:"project_id" json:"project_id"`
+	Name            string     `db:"name" json:"name"`
+	AssigneeID      *string    `db:"assignee_id" json:"assignee_id,omitempty"`
+	AssigneeName    *string    `db:"assignee_name" json:"assignee_name,omitempty"`
+	DurationDays    int        `db:"duration_days" json:"duration_days"`
+	StartAfter      *time.Time `db:"start_after" json:"start_after,omitempty"`
+	Milestone       bool       `db:"milestone" json:"milestone"`
+	PercentComplete int        `db:"percent_complete" json:"percent_complete"`
+	DateCreated     time.Time  `db:"date_created" json:"date_created"`
+	DateUpdated     time.Time  `db:"date_updated" json:"date_updated"`
+}
+
+// NewTask contains information needed to create a new Task. StartAfter keeps
+// the task from being scheduled before that date.
+type NewTask struct {
+	Name            string     `json:"name" validate:"required"`
+	AssigneeID      *string    `json:"assignee_id" validate:"omitempty,uuid"`
+	DurationDays    int        `json:"duration_days" validate:"gte=0"`
+	StartAfter      *time.Time `json:"start_after"`
+	Milestone       bool       `json:"milestone"`
+	PercentComplete int        `json:"percent_complete" validate:"gte=0,lte=100"`
+}
+
+// UpdateTask defines what information may be provided to modify an existing
+// Task. All fields are optional so clients can send just the fields they
+// want changed.
+type UpdateTask struct {
+	Name            *string    `json:"name"`
+	AssigneeID      *string    `json:"assignee_id" validate:"omitempty,uuid"`
+	DurationDays    *int       `json:"duration_days" validate:"omitempty,gte=0"`
+	StartAfter      *time.Time `json:"start_after"`
+	Milestone       *bool      `json:"milestone"`
+	PercentComplete *int       `json:"percent_complete" validate:"omitempty,gte=0,lte=100"`
+}
+
+// Dependency links a task to a task it depends on within the same project.
+// Lag is the number of days between the two, negative values overlap them.
+type Dependency struct {
+	ProjectID   string    `db:"project_id" json:"project_id"`
+	TenantID    string    `db:"tenant_id" json:"-"`
+	TaskID      string    `db:"task_id" json:"task_id"`
+	DependsOnID string    `db:"depends_on_id" json:"depends_on_id"`
+	Type        string    `db:"type" json:"type"`
+	LagDays     int       `db:"lag_days" json:"lag_days"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+}
+
+// NewDependency contains information needed to create a new Dependency. The
+// type defaults to finish to start.
+type NewDependency struct {
+	TaskID      string `json:"task_id" validate:"required,uuid"`
+	DependsOnID string `json:"depends_on_id" validate:"required,uuid,nefield=TaskID"`
+	Type        string `json:"type" validate:"omitempty,oneof=FS SS FF SF"`
+	LagDays     int    `json:"lag_days"`
+}
@@ -0,0 +1,235 @@
+// Package project contains project, task and task dependency related CRUD
+// functionality.
+package project
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of API's for project access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs a project store for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new project into the database. The claims subject is
+// recorded as the project's owner.
+func (s Store) Create(ctx context.Context, claims auth.Claims, np NewProject, now time.Time) (Project, error) {
+	if err := validate.Check(np); err != nil {
+		return Project{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	owner := claims.Subject
+	prj := Project{
+		ID:          validate.GenerateID(),
+		UserID:      &owner,
+		Name:        np.Name,
+		Description: np.Description,
+		StartDate:   np.StartDate,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	const q = `
+	INSERT INTO projects
+		(project_id, user_id, name, description, start_date, date_created, date_updated)
+	VALUES
+		(:project_id, :user_id, :name, :description, :start_date, :date_created, :date_updated)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, prj); err != nil {
+		return Project{}, fmt.Errorf("inserting project: %w", err)
+	}
+
+	return prj, nil
+}
+
+// Update replaces a project document in the database.
+func (s Store) Update(ctx context.Context, projectID string, up UpdateProject, now time.Time) (Project, error) {
+	if err := validate.Check(up); err != nil {
+		return Project{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	prj, err := s.QueryByID(ctx, projectID)
+	if err != nil {
+		return Project{}, fmt.Errorf("updating project projectID[%s]: %w", projectID, err)
+	}
+
+	if up.Name != nil {
+		prj.Name = *up.Name
+	}
+	if up.Description != nil {
+		prj.Description = *up.Description
+	}
+	if up.StartDate != nil {
+		prj.StartDate = *up.StartDate
+	}
+	prj.DateUpdated = now
+
+	const q = `
+	UPDATE
+		projects
+	SET
+		"name" = :name,
+		"description" = :description,
+		"start_date" = :start_date,
+		"date_updated" = :date_updated
+	WHERE
+		project_id = :project_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, prj); err != nil {
+		return Project{}, fmt.Errorf("updating projectID[%s]: %w", projectID, err)
+	}
+
+	return prj, nil
+}
+
+// Delete removes a project and everything scheduled in it from the database.
+func (s Store) Delete(ctx context.Context, projectID string) error {
+	if err := validate.CheckID(projectID); err != nil {
+		return database.ErrInvalidID
+	}
+
+	data := struct {
+		ProjectID string `db:"project_id"`
+	}{
+		ProjectID: projectID,
+	}
+
+	const q = `
+	DELETE FROM
+		projects
+	WHERE
+		project_id = :project_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("deleting projectID[%s]: %w", projectID, err)
+	}
+
+	return nil
+}
+
+// Query retrieves a page of existing projects from the database.
+func (s Store) Query(ctx context.Context, filter QueryFilter, orderBy []database.OrderBy, ks database.Keyset) (database.Page[Project], error) {
+	if err := validate.Check(filter); err != nil {
+		return database.Page[Project]{}, fmt.Errorf("validating filter: %w", err)
+	}
+
+	b := database.NewBuilder()
+	if filter.Name != nil {
+		b.Contains("name", *filter.Name)
+	}
+	if filter.StartDateFrom != nil {
+		b.GreaterOrEqual("start_date", *filter.StartDateFrom)
+	}
+	if filter.StartDateUntil != nil {
+		b.Less("start_date", *filter.StartDateUntil)
+	}
+	b.Seek(orderBy, "project_id", ks)
+
+	const base = `
+	SELECT
+		*
+	FROM
+		projects`
+
+	q, data, err := b.Build(base)
+	if err != nil {
+		return database.Page[Project]{}, fmt.Errorf("building query: %w", err)
+	}
+
+	var projects []Project
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &projects); err != nil {
+		return database.Page[Project]{}, fmt.Errorf("selecting projects: %w", err)
+	}
+
+	page, err := database.NewPage(b, projects, keysetValue)
+	if err != nil {
+		return database.Page[Project]{}, err
+	}
+
+	if ks.Total {
+		const base = `
+		SELECT
+			count(*) AS count
+		FROM
+			projects`
+
+		q, data, err := b.BuildCount(base)
+		if err != nil {
+			return database.Page[Project]{}, fmt.Errorf("building count: %w", err)
+		}
+
+		var result struct {
+			Count int `db:"count"`
+		}
+		if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &result); err != nil {
+			return database.Page[Project]{}, fmt.Errorf("counting projects: %w", err)
+		}
+		page.Total = &result.Count
+	}
+
+	return page, nil
+}
+
+// QueryByID gets the specified project from the database.
+func (s Store) QueryByID(ctx context.Context, projectID string) (Project, error) {
+	if err := validate.CheckID(projectID); err != nil {
+		return Project{}, database.ErrInvalidID
+	}
+
+	data := struct {
+		ProjectID string `db:"project_id"`
+	}{
+		ProjectID: projectID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		projects
+	WHERE
+		project_id = :project_id`
+
+	var prj Project
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &prj); err != nil {
+		if err == database.ErrNotFound {
+			return Project{}, database.ErrNotFound
+		}
+		return Project{}, fmt.Errorf("selecting projectID[%q]: %w", projectID, err)
+	}
+
+	return prj, nil
+}
+
+// keysetValue returns the project's value for a column projects can be
+// sorted by.
+func keysetValue(prj Project, column string) any {
+	switch column {
+	case "project_id":
+		return prj.ID
+	case "name":
+		return prj.Name
+	case "start_date":
+		return prj.StartDate
+	case "date_created":
+		return prj.DateCreated
+	}
+	return nil
+}
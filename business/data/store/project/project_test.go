@@ -0,0 +1,120 @@
+package project_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/project"
+	"github.com/deliveranceTechSolutions/erp/business/data/tests"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var dbc = tests.DBContainer{
+	Image: "postgres:13-alpine",
+	Port:  "5432",
+	Args:  []string{"-e", "POSTGRES_PASSWORD=postgres"},
+}
+
+// Seeded users the projects are owned by and assigned to.
+const (
+	adminID = "5cf37266-3473-4006-984f-9325122678b7"
+	userID  = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+)
+
+func TestProject(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	store := project.NewStore(log, db)
+
+	t.Log("Given the need to work with Project records.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a single Project with tasks.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+			now := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+			np := project.NewProject{
+				Name:      "Warehouse Install",
+				StartDate: now,
+			}
+
+			prj, err := store.Create(ctx, claims(adminID, auth.RoleAdmin), np, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create project : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create project.", tests.Success, testID)
+
+			assignee := userID
+			design, err := store.CreateTask(ctx, prj.ID, project.NewTask{Name: "Design", DurationDays: 3, AssigneeID: &assignee}, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create task : %s.", tests.Failed, testID, err)
+			}
+			install, err := store.CreateTask(ctx, prj.ID, project.NewTask{Name: "Install", DurationDays: 5}, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create task : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create tasks.", tests.Success, testID)
+
+			nd := project.NewDependency{TaskID: install.ID, DependsOnID: design.ID}
+			dep, err := store.CreateDependency(ctx, prj.ID, nd, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create dependency : %s.", tests.Failed, testID, err)
+			}
+			if dep.Type != project.FinishToStart {
+				t.Fatalf("\t%s\tTest %d:\tShould default to finish to start : %s.", tests.Failed, testID, dep.Type)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create dependency.", tests.Success, testID)
+
+			tasks, err := store.QueryTasks(ctx, prj.ID)
+			if err != nil || len(tasks) != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to query tasks : %v %+v.", tests.Failed, testID, err, tasks)
+			}
+			if tasks[0].AssigneeName == nil || *tasks[0].AssigneeName == "" {
+				t.Fatalf("\t%s\tTest %d:\tShould get back the assignee's name : %+v.", tests.Failed, testID, tasks[0])
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to query tasks with assignees.", tests.Success, testID)
+
+			done := 100
+			upd, err := store.UpdateTask(ctx, prj.ID, design.ID, project.UpdateTask{PercentComplete: &done}, now)
+			if err != nil || upd.PercentComplete != 100 {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to update task : %v %+v.", tests.Failed, testID, err, upd)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to update task.", tests.Success, testID)
+
+			if err := store.DeleteTask(ctx, prj.ID, design.ID); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to delete task : %s.", tests.Failed, testID, err)
+			}
+			deps, err := store.QueryDependencies(ctx, prj.ID)
+			if err != nil || len(deps) != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould remove the task's dependencies : %v %+v.", tests.Failed, testID, err, deps)
+			}
+			t.Logf("\t%s\tTest %d:\tShould remove the task's dependencies with it.", tests.Success, testID)
+
+			if err := store.Delete(ctx, prj.ID); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to delete project : %s.", tests.Failed, testID, err)
+			}
+			if _, err := store.QueryTaskByID(ctx, prj.ID, install.ID); !errors.Is(err, database.ErrNotFound) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to retrieve tasks of a deleted project : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to delete project and its tasks.", tests.Success, testID)
+		}
+	}
+}
+
+func claims(subject string, roles ...string) auth.Claims {
+	return auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    "service project",
+			Subject:   subject,
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			IssuedAt:  time.Now().UTC().Unix(),
+		},
+		Roles: roles,
+	}
+}
@@ -0,0 +1,309 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+)
+
+// CreateTask inserts a new task into the specified project.
+func (s Store) CreateTask(ctx context.Context, projectID string, nt NewTask, now time.Time) (Task, error) {
+	if err := validate.Check(nt); err != nil {
+		return Task{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	if _, err := s.QueryByID(ctx, projectID); err != nil {
+		return Task{}, fmt.Errorf("creating task projectID[%s]: %w", projectID, err)
+	}
+
+	tsk := Task{
+		ID:              validate.GenerateID(),
+		ProjectID:       projectID,
+		Name:            nt.Name,
+		AssigneeID:      nt.AssigneeID,
+		DurationDays:    nt.DurationDays,
+		StartAfter:      nt.StartAfter,
+		Milestone:       nt.Milestone,
+		PercentComplete: nt.PercentComplete,
+		DateCreated:     now,
+		DateUpdated:     now,
+	}
+	if tsk.Milestone {
+		tsk.DurationDays = 0
+	}
+
+	const q = `
+	INSERT INTO tasks
+		(task_id, project_id, name, assignee_id, duration_days, start_after, milestone, percent_complete, date_created, date_updated)
+	VALUES
+		(:task_id, :project_id, :name, :assignee_id, :duration_days, :start_after, :milestone, :percent_complete, :date_created, :date_updated)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, tsk); err != nil {
+		return Task{}, fmt.Errorf("inserting task: %w", err)
+	}
+
+	return tsk, nil
+}
+
+// UpdateTask replaces a task document in the database.
+func (s Store) UpdateTask(ctx context.Context, projectID string, taskID string, ut UpdateTask, now time.Time) (Task, error) {
+	if err := validate.Check(ut); err != nil {
+		return Task{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	tsk, err := s.QueryTaskByID(ctx, projectID, taskID)
+	if err != nil {
+		return Task{}, fmt.Errorf("updating task taskID[%s]: %w", taskID, err)
+	}
+
+	if ut.Name != nil {
+		tsk.Name = *ut.Name
+	}
+	if ut.AssigneeID != nil {
+		tsk.AssigneeID = ut.AssigneeID
+	}
+	if ut.DurationDays != nil {
+		tsk.DurationDays = *ut.DurationDays
+	}
+	if ut.StartAfter != nil {
+		tsk.StartAfter = ut.StartAfter
+	}
+	if ut.Milestone != nil {
+		tsk.Milestone = *ut.Milestone
+	}
+	if ut.PercentComplete != nil {
+		tsk.PercentComplete = *ut.PercentComplete
+	}
+	if tsk.Milestone {
+		tsk.DurationDays = 0
+	}
+	tsk.DateUpdated = now
+
+	const q = `
+	UPDATE
+		tasks
+	SET
+		"name" = :name,
+		"assignee_id" = :assignee_id,
+		"duration_days" = :duration_days,
+		"start_after" = :start_after,
+		"milestone" = :milestone,
+		"percent_complete" = :percent_complete,
+		"date_updated" = :date_updated
+	WHERE
+		task_id = :task_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, tsk); err != nil {
+		return Task{}, fmt.Errorf("updating taskID[%s]: %w", taskID, err)
+	}
+
+	// Read the task back so the assignee's name reflects any change.
+	return s.QueryTaskByID(ctx, projectID, taskID)
+}
+
+// DeleteTask removes a task and its dependencies from the database.
+func (s Store) DeleteTask(ctx context.Context, projectID string, taskID string) error {
+	if _, err := s.QueryTaskByID(ctx, projectID, taskID); err != nil {
+		return fmt.Errorf("deleting task taskID[%s]: %w", taskID, err)
+	}
+
+	data := struct {
+		TaskID string `db:"task_id"`
+	}{
+		TaskID: taskID,
+	}
+
+	const q = `
+	DELETE FROM
+		tasks
+	WHERE
+		task_id = :task_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("deleting taskID[%s]: %w", taskID, err)
+	}
+
+	return nil
+}
+
+// QueryTasks retrieves every task in the specified project.
+func (s Store) QueryTasks(ctx context.Context, projectID string) ([]Task, error) {
+	if err := validate.CheckID(projectID); err != nil {
+		return nil, database.ErrInvalidID
+	}
+
+	data := struct {
+		ProjectID string `db:"project_id"`
+	}{
+		ProjectID: projectID,
+	}
+
+	const q = `
+	SELECT
+		t.*,
+		u.name AS assignee_name
+	FROM
+		tasks AS t
+	LEFT JOIN
+		users AS u ON u.user_id = t.assignee_id
+	WHERE
+		t.project_id = :project_id
+	ORDER BY
+		t.date_created, t.task_id`
+
+	var tasks []Task
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &tasks); err != nil {
+		return nil, fmt.Errorf("selecting tasks projectID[%s]: %w", projectID, err)
+	}
+
+	return tasks, nil
+}
+
+// QueryTaskByID gets the specified task from the specified project.
+func (s Store) QueryTaskByID(ctx context.Context, projectID string, taskID string) (Task, error) {
+	if err := validate.CheckID(projectID); err != nil {
+		return Task{}, database.ErrInvalidID
+	}
+	if err := validate.CheckID(taskID); err != nil {
+		return Task{}, database.ErrInvalidID
+	}
+
+	data := struct {
+		ProjectID string `db:"project_id"`
+		TaskID    string `db:"task_id"`
+	}{
+		ProjectID: projectID,
+		TaskID:    taskID,
+	}
+
+	const q = `
+	SELECT
+		t.*,
+		u.name AS assignee_name
+	FROM
+		tasks AS t
+	LEFT JOIN
+		users AS u ON u.user_id = t.assignee_id
+	WHERE
+		t.task_id = :task_id AND
+		t.project_id = :project_id`
+
+	var tsk Task
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &tsk); err != nil {
+		if err == database.ErrNotFound {
+			return Task{}, database.ErrNotFound
+		}
+		return Task{}, fmt.Errorf("selecting taskID[%q]: %w", taskID, err)
+	}
+
+	return tsk, nil
+}
+
+// =============================================================================
+
+// CreateDependency links two tasks in the specified project. Adding a link
+// that already exists replaces its type and lag.
+func (s Store) CreateDependency(ctx context.Context, projectID string, nd NewDependency, now time.Time) (Dependency, error) {
+	if err := validate.Check(nd); err != nil {
+		return Dependency{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	for _, taskID := range []string{nd.TaskID, nd.DependsOnID} {
+		if _, err := s.QueryTaskByID(ctx, projectID, taskID); err != nil {
+			return Dependency{}, fmt.Errorf("creating dependency taskID[%s]: %w", taskID, err)
+		}
+	}
+
+	dep := Dependency{
+		ProjectID:   projectID,
+		TaskID:      nd.TaskID,
+		DependsOnID: nd.DependsOnID,
+		Type:        nd.Type,
+		LagDays:     nd.LagDays,
+		DateCreated: now,
+	}
+	if dep.Type == "" {
+		dep.Type = FinishToStart
+	}
+
+	const q = `
+	INSERT INTO task_dependencies
+		(project_id, task_id, depends_on_id, type, lag_days, date_created)
+	VALUES
+		(:project_id, :task_id, :depends_on_id, :type, :lag_days, :date_created)
+	ON CONFLICT (task_id, depends_on_id) DO UPDATE SET
+		"type" = EXCLUDED.type,
+		"lag_days" = EXCLUDED.lag_days`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, dep); err != nil {
+		return Dependency{}, fmt.Errorf("inserting dependency: %w", err)
+	}
+
+	return dep, nil
+}
+
+// DeleteDependency removes the link between two tasks.
+func (s Store) DeleteDependency(ctx context.Context, projectID string, taskID string, dependsOnID string) error {
+	for _, id := range []string{projectID, taskID, dependsOnID} {
+		if err := validate.CheckID(id); err != nil {
+			return database.ErrInvalidID
+		}
+	}
+
+	data := struct {
+		ProjectID   string `db:"project_id"`
+		TaskID      string `db:"task_id"`
+		DependsOnID string `db:"depends_on_id"`
+	}{
+		ProjectID:   projectID,
+		TaskID:      taskID,
+		DependsOnID: dependsOnID,
+	}
+
+	const q = `
+	DELETE FROM
+		task_dependencies
+	WHERE
+		project_id = :project_id AND
+		task_id = :task_id AND
+		depends_on_id = :depends_on_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("deleting dependency taskID[%s] dependsOnID[%s]: %w", taskID, dependsOnID, err)
+	}
+
+	return nil
+}
+
+// QueryDependencies retrieves every dependency in the specified project.
+func (s Store) QueryDependencies(ctx context.Context, projectID string) ([]Dependency, error) {
+	if err := validate.CheckID(projectID); err != nil {
+		return nil, database.ErrInvalidID
+	}
+
+	data := struct {
+		ProjectID string `db:"project_id"`
+	}{
+		ProjectID: projectID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		task_dependencies
+	WHERE
+		project_id = :project_id
+	ORDER BY
+		task_id, depends_on_id`
+
+	var deps []Dependency
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &deps); err != nil {
+		return nil, fmt.Errorf("selecting dependencies projectID[%s]: %w", projectID, err)
+	}
+
+	return deps, nil
+}
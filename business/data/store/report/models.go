@@ -0,0 +1,44 @@
+package report
+
+import (
+	"time"
+)
+
+// Set of groupings supported when bucketing sales over time.
+const (
+	GroupDay   = "day"
+	GroupWeek  = "week"
+	GroupMonth = "month"
+)
+
+// Filter defines the time range and grouping applied to an aggregate query.
+type Filter struct {
+	Grouping string    `validate:"oneof=day week month"`
+	From     time.Time `validate:"required"`
+	To       time.Time `validate:"required,gtfield=From"`
+}
+
+// Period represents the sales totals for a single bucket of time.
+type Period struct {
+	Start   time.Time `db:"period" json:"period"`
+	Revenue int       `db:"revenue" json:"revenue"`
+	Units   int       `db:"units" json:"units"`
+	Orders  int       `db:"orders" json:"orders"`
+	Average float64   `db:"average" json:"average"`
+}
+
+// ProductTotal represents the sales totals for a single product.
+type ProductTotal struct {
+	ProductID string `db:"product_id" json:"product_id"`
+	Name      string `db:"name" json:"name"`
+	Revenue   int    `db:"revenue" json:"revenue"`
+	Units     int    `db:"units" json:"units"`
+}
+
+// UserTotal represents the sales totals for a single user.
+type UserTotal struct {
+	UserID  string `db:"user_id" json:"user_id"`
+	Name    string `db:"name" json:"name"`
+	Revenue int    `db:"revenue" json:"revenue"`
+	Units   int    `db:"units" json:"units"`
+}
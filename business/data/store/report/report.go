@@ -0,0 +1,172 @@
+// Package report contains the aggregate queries used for sales reporting.
+package report
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of API's for report access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs a report store for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// QueryByPeriod retrieves the revenue, units, order count and average order
+// value for each bucket of time in the filter's range.
+func (s Store) QueryByPeriod(ctx context.Context, f Filter) ([]Period, error) {
+	if err := validate.Check(f); err != nil {
+		return nil, fmt.Errorf("validating filter: %w", err)
+	}
+
+	data := struct {
+		Grouping string    `db:"grouping"`
+		From     time.Time `db:"from"`
+		To       time.Time `db:"to"`
+	}{
+		Grouping: f.Grouping,
+		From:     f.From,
+		To:       f.To,
+	}
+
+	// The grouping is bound once and referenced by position in the GROUP BY
+	// and ORDER BY so postgres sees a single expression.
+	const q = `
+	SELECT
+		date_trunc(:grouping, s.date_created)   AS period,
+		COALESCE(SUM(s.paid), 0)                AS revenue,
+		COALESCE(SUM(s.quantity), 0)            AS units,
+		COUNT(*)                                AS orders,
+		CAST(COALESCE(AVG(s.paid), 0) AS FLOAT) AS average
+	FROM
+		sales AS s
+	WHERE
+		s.date_created >= :from AND s.date_created < :to
+	GROUP BY
+		1
+	ORDER BY
+		1`
+
+	var periods []Period
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &periods); err != nil {
+		return nil, fmt.Errorf("selecting periods: %w", err)
+	}
+
+	return periods, nil
+}
+
+// QueryByProduct retrieves the revenue and units sold for every product that
+// sold within the filter's range, ordered by revenue.
+func (s Store) QueryByProduct(ctx context.Context, f Filter) ([]ProductTotal, error) {
+	return s.queryProducts(ctx, f, "revenue", 0)
+}
+
+// QueryTopProducts retrieves the limit best selling products by units within
+// the filter's range.
+func (s Store) QueryTopProducts(ctx context.Context, f Filter, limit int) ([]ProductTotal, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit[%d] must be positive", limit)
+	}
+	return s.queryProducts(ctx, f, "units", limit)
+}
+
+// QueryByUser retrieves the revenue and units sold attributed to each user
+// within the filter's range, ordered by revenue.
+func (s Store) QueryByUser(ctx context.Context, f Filter) ([]UserTotal, error) {
+	if err := validate.Check(f); err != nil {
+		return nil, fmt.Errorf("validating filter: %w", err)
+	}
+
+	data := struct {
+		From time.Time `db:"from"`
+		To   time.Time `db:"to"`
+	}{
+		From: f.From,
+		To:   f.To,
+	}
+
+	const q = `
+	SELECT
+		u.user_id,
+		u.name,
+		COALESCE(SUM(s.paid), 0)     AS revenue,
+		COALESCE(SUM(s.quantity), 0) AS units
+	FROM
+		sales AS s
+	JOIN
+		users AS u ON u.user_id = s.user_id
+	WHERE
+		s.date_created >= :from AND s.date_created < :to
+	GROUP BY
+		u.user_id, u.name
+	ORDER BY
+		revenue DESC, u.name`
+
+	var totals []UserTotal
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &totals); err != nil {
+		return nil, fmt.Errorf("selecting user totals: %w", err)
+	}
+
+	return totals, nil
+}
+
+// queryProducts runs the product aggregate ordered by the specified column.
+// The orderBy value is never taken from user input.
+func (s Store) queryProducts(ctx context.Context, f Filter, orderBy string, limit int) ([]ProductTotal, error) {
+	if err := validate.Check(f); err != nil {
+		return nil, fmt.Errorf("validating filter: %w", err)
+	}
+
+	data := struct {
+		From  time.Time `db:"from"`
+		To    time.Time `db:"to"`
+		Limit any       `db:"limit"`
+	}{
+		From: f.From,
+		To:   f.To,
+	}
+
+	// A NULL limit tells postgres to return every row.
+	if limit > 0 {
+		data.Limit = limit
+	}
+
+	q := `
+	SELECT
+		p.product_id,
+		p.name,
+		COALESCE(SUM(s.paid), 0)     AS revenue,
+		COALESCE(SUM(s.quantity), 0) AS units
+	FROM
+		sales AS s
+	JOIN
+		products AS p ON p.product_id = s.product_id
+	WHERE
+		s.date_created >= :from AND s.date_created < :to
+	GROUP BY
+		p.product_id, p.name
+	ORDER BY
+		` + orderBy + ` DESC, p.name
+	LIMIT :limit`
+
+	var totals []ProductTotal
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &totals); err != nil {
+		return nil, fmt.Errorf("selecting product totals: %w", err)
+	}
+
+	return totals, nil
+}
@@ -0,0 +1,151 @@
+package report_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/report"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/tenant"
+	"github.com/deliveranceTechSolutions/erp/business/data/tests"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+)
+
+var dbc = tests.DBContainer{
+	Image: "postgres:13-alpine",
+	Port:  "5432",
+	Args:  []string{"-e", "POSTGRES_PASSWORD=postgres"},
+}
+
+// Seeded user and product the sales are made against.
+const (
+	userID   = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+	comicsID = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+)
+
+func TestReport(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	store := report.NewStore(log, db)
+
+	// The seeded sales were all made on the first day of 2019.
+	day := report.Filter{
+		Grouping: report.GroupDay,
+		From:     time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2019, time.January, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	t.Log("Given the need to aggregate sales for reports.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen aggregating the seeded sales.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+
+			periods, err := store.QueryByPeriod(ctx, day)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to query by period : %s.", tests.Failed, testID, err)
+			}
+			if len(periods) != 1 || periods[0].Revenue != 575 || periods[0].Units != 10 || periods[0].Orders != 3 {
+				t.Fatalf("\t%s\tTest %d:\tShould total the sales of the day : %+v.", tests.Failed, testID, periods)
+			}
+			t.Logf("\t%s\tTest %d:\tShould total the sales of the day.", tests.Success, testID)
+
+			products, err := store.QueryByProduct(ctx, day)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to query by product : %s.", tests.Failed, testID, err)
+			}
+			if len(products) != 2 || products[0].ProductID != comicsID || products[0].Revenue != 350 || products[1].Revenue != 225 {
+				t.Fatalf("\t%s\tTest %d:\tShould total each product by revenue : %+v.", tests.Failed, testID, products)
+			}
+			t.Logf("\t%s\tTest %d:\tShould total each product by revenue.", tests.Success, testID)
+
+			top, err := store.QueryTopProducts(ctx, day, 1)
+			if err != nil || len(top) != 1 || top[0].ProductID != comicsID || top[0].Units != 7 {
+				t.Fatalf("\t%s\tTest %d:\tShould find the best selling product : %v %+v.", tests.Failed, testID, err, top)
+			}
+			t.Logf("\t%s\tTest %d:\tShould find the best selling product.", tests.Success, testID)
+
+			if _, err := store.QueryTopProducts(ctx, day, 0); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept a limit of zero.", tests.Failed, testID)
+			}
+			backwards := report.Filter{Grouping: report.GroupDay, From: day.To, To: day.From}
+			if _, err := store.QueryByPeriod(ctx, backwards); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept a range that ends before it starts.", tests.Failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT accept an invalid filter.", tests.Success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen sales are made and changed.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+
+			const saleID = "5b3a1a64-6a3e-4e56-8f5e-3c9f1c0d7a11"
+			const insert = `
+			INSERT INTO sales (tenant_id, sale_id, user_id, product_id, quantity, paid, date_created)
+			VALUES ($1, $2, $3, $4, 1, 40, '2019-01-01 12:00:00')`
+			if _, err := db.ExecContext(ctx, insert, tests.TenantID, saleID, userID, comicsID); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a sale : %s.", tests.Failed, testID, err)
+			}
+
+			users, err := store.QueryByUser(ctx, day)
+			if err != nil || len(users) != 1 || users[0].UserID != userID || users[0].Revenue != 40 {
+				t.Fatalf("\t%s\tTest %d:\tShould attribute the sale to its user : %v %+v.", tests.Failed, testID, err, users)
+			}
+			t.Logf("\t%s\tTest %d:\tShould attribute the sale to its user.", tests.Success, testID)
+
+			if _, err := db.ExecContext(ctx, `UPDATE sales SET paid = 60, quantity = 2 WHERE sale_id = $1`, saleID); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to update the sale : %s.", tests.Failed, testID, err)
+			}
+
+			periods, err := store.QueryByPeriod(ctx, day)
+			if err != nil || len(periods) != 1 || periods[0].Revenue != 635 || periods[0].Units != 12 || periods[0].Orders != 4 {
+				t.Fatalf("\t%s\tTest %d:\tShould total the changed sale : %v %+v.", tests.Failed, testID, err, periods)
+			}
+			users, err = store.QueryByUser(ctx, day)
+			if err != nil || len(users) != 1 || users[0].Revenue != 60 || users[0].Units != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould total the changed sale by user : %v %+v.", tests.Failed, testID, err, users)
+			}
+			t.Logf("\t%s\tTest %d:\tShould total the changed sale.", tests.Success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen another tenant has sales of its own.", testID)
+		{
+			ctx := context.Background()
+			now := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+			tnt, err := tenant.NewStore(log, db).Create(database.WithSystem(ctx), tenant.NewTenant{Name: "Acme"}, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create tenant : %s.", tests.Failed, testID, err)
+			}
+
+			const productID = "0e2f6a4c-8d1b-4f7a-9c3e-5a6b7c8d9e0f"
+			if _, err := db.ExecContext(ctx, `
+			INSERT INTO products (tenant_id, product_id, name, cost, quantity, date_created, date_updated)
+			VALUES ($1, $2, 'Rocket Skates', 500, 10, $3, $3)`, tnt.ID, productID, now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a product in the tenant : %s.", tests.Failed, testID, err)
+			}
+			if _, err := db.ExecContext(ctx, `
+			INSERT INTO sales (tenant_id, sale_id, product_id, quantity, paid, date_created)
+			VALUES ($1, '1c8e2b7d-3f4a-4b5c-8d6e-7f8a9b0c1d2e', $2, 1, 500, '2019-01-01 09:00:00')`, tnt.ID, productID); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a sale in the tenant : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create sales in the tenant.", tests.Success, testID)
+
+			products, err := store.QueryByProduct(database.WithTenant(ctx, tnt.ID), day)
+			if err != nil || len(products) != 1 || products[0].ProductID != productID || products[0].Revenue != 500 {
+				t.Fatalf("\t%s\tTest %d:\tShould only total the tenant's own sales : %v %+v.", tests.Failed, testID, err, products)
+			}
+			t.Logf("\t%s\tTest %d:\tShould only total the tenant's own sales.", tests.Success, testID)
+
+			products, err = store.QueryByProduct(database.WithTenant(ctx, tests.TenantID), day)
+			if err != nil || len(products) != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT total another tenant's sales : %v %+v.", tests.Failed, testID, err, products)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT total another tenant's sales.", tests.Success, testID)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package role
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Role is a named set of permissions defined by a tenant. Users are granted
+// the permissions of every role they are assigned.
+type Role struct {
+	TenantID    string         `db:"tenant_id" json:"-"`
+	Name        string         `db:"name" json:"name"`
+	Description string         `db:"description" json:"description"`
+	Permissions pq.StringArray `db:"permissions" json:"permissions"`
+	DateCreated time.Time      `db:"date_created" json:"date_created"`
+	DateUpdated time.Time      `db:"date_updated" json:"date_updated"`
+}
+
+// NewRole contains information needed to define a new Role. Names are upper
+// case like the built in ADMIN and USER roles.
+type NewRole struct {
+	Name        string   `json:"name" validate:"required,uppercase,excludesall= ,"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" validate:"required,dive,required"`
+}
+
+// UpdateRole defines what information may be provided to modify an existing
+// Role. All fields are optional so clients can send just the fields they want
+// changed.
+type UpdateRole struct {
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions" validate:"omitempty,dive,required"`
+}
@@ -0,0 +1,206 @@
+// Package role contains role related CRUD functionality.
+package role
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of API's for role access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs a role store for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create defines a new role in the tenant the context is scoped to.
+func (s Store) Create(ctx context.Context, nr NewRole, now time.Time) (Role, error) {
+	if err := validate.Check(nr); err != nil {
+		return Role{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	rol := Role{
+		Name:        nr.Name,
+		Description: nr.Description,
+		Permissions: nr.Permissions,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	const q = `
+	INSERT INTO roles
+		(name, description, permissions, date_created, date_updated)
+	VALUES
+		(:name, :description, :permissions, :date_created, :date_updated)
+	RETURNING
+		tenant_id`
+
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, rol, &rol); err != nil {
+		return Role{}, fmt.Errorf("inserting role: %w", err)
+	}
+
+	return rol, nil
+}
+
+// Update modifies the description and permissions of a role.
+func (s Store) Update(ctx context.Context, name string, ur UpdateRole, now time.Time) (Role, error) {
+	if err := validate.Check(ur); err != nil {
+		return Role{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	rol, err := s.QueryByName(ctx, name)
+	if err != nil {
+		return Role{}, fmt.Errorf("updating role[%s]: %w", name, err)
+	}
+
+	if ur.Description != nil {
+		rol.Description = *ur.Description
+	}
+	if ur.Permissions != nil {
+		rol.Permissions = ur.Permissions
+	}
+	rol.DateUpdated = now
+
+	const q = `
+	UPDATE
+		roles
+	SET
+		"description" = :description,
+		"permissions" = :permissions,
+		"date_updated" = :date_updated
+	WHERE
+		name = :name`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, rol); err != nil {
+		return Role{}, fmt.Errorf("updating role[%s]: %w", name, err)
+	}
+
+	return rol, nil
+}
+
+// Delete removes a role and takes it away from every user it was assigned
+// to, so a role created later with the same name doesn't grant them anything.
+func (s Store) Delete(ctx context.Context, name string) error {
+	data := struct {
+		Name string `db:"name"`
+	}{
+		Name: name,
+	}
+
+	const q = `
+	WITH deleted AS (
+		DELETE FROM
+			roles
+		WHERE
+			name = :name
+		RETURNING
+			name
+	)
+	UPDATE
+		users
+	SET
+		roles = array_remove(roles, deleted.name)
+	FROM
+		deleted
+	WHERE
+		deleted.name = ANY(users.roles)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("deleting role[%s]: %w", name, err)
+	}
+
+	return nil
+}
+
+// Query retrieves the roles defined in the tenant the context is scoped to.
+func (s Store) Query(ctx context.Context) ([]Role, error) {
+	const q = `
+	SELECT
+		*
+	FROM
+		roles
+	ORDER BY
+		name`
+
+	var roles []Role
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, struct{}{}, &roles); err != nil {
+		return nil, fmt.Errorf("selecting roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+// QueryByName gets the specified role from the database.
+func (s Store) QueryByName(ctx context.Context, name string) (Role, error) {
+	data := struct {
+		Name string `db:"name"`
+	}{
+		Name: name,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		roles
+	WHERE
+		name = :name`
+
+	var rol Role
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &rol); err != nil {
+		if err == database.ErrNotFound {
+			return Role{}, database.ErrNotFound
+		}
+		return Role{}, fmt.Errorf("selecting role[%q]: %w", name, err)
+	}
+
+	return rol, nil
+}
+
+// Permissions returns every permission granted by the roles. It implements
+// the auth.PermissionLookup interface.
+func (s Store) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	data := struct {
+		Roles pq.StringArray `db:"roles"`
+	}{
+		Roles: roles,
+	}
+
+	const q = `
+	SELECT DISTINCT
+		unnest(permissions) AS permission
+	FROM
+		roles
+	WHERE
+		name = ANY(CAST(:roles AS TEXT[]))
+	ORDER BY
+		permission`
+
+	var rows []struct {
+		Permission string `db:"permission"`
+	}
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &rows); err != nil {
+		return nil, fmt.Errorf("selecting permissions: %w", err)
+	}
+
+	perms := make([]string, len(rows))
+	for i, row := range rows {
+		perms[i] = row.Permission
+	}
+
+	return perms, nil
+}
@@ -0,0 +1,27 @@
+package search
+
+// Set of record types that can be searched.
+const (
+	TypeUser      = "user"
+	TypeProduct   = "product"
+	TypeProject   = "project"
+	TypeDashboard = "dashboard"
+)
+
+// Result is a single record matching a search. Title is the record's name and
+// Body the secondary text it was matched on, such as a user's email.
+type Result struct {
+	Type  string  `db:"type" json:"type"`
+	ID    string  `db:"id" json:"id"`
+	Title string  `db:"title" json:"title"`
+	Body  string  `db:"body" json:"body,omitempty"`
+	Rank  float64 `db:"rank" json:"rank"`
+}
+
+// Query describes a search. Only records of the listed types are returned,
+// or of every type when none are listed.
+type Query struct {
+	Text  string   `json:"q" validate:"required"`
+	Types []string `json:"type" validate:"dive,oneof=user product project dashboard"`
+	Limit int      `json:"limit" validate:"gte=1,lte=100"`
+}
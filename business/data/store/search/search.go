@@ -0,0 +1,117 @@
+// Package search contains full text search across records functionality.
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of API's for search access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs a search store for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Search finds the records matching the query that the claims are allowed to
+// view, best match first. Every word matches as a prefix, so "gop sto" finds
+// "Gopher Store", and titles that are close to the text still match so typos
+// are forgiven.
+//
+// The search_documents table is kept up to date by triggers on the searched
+// tables. Restricted records follow the same rules as their QueryByID: users
+// are visible to those permitted to read users, dashboards to admins, and the
+// owner can always view their records. Other users need them shared with
+// them or one of their roles. Products are only read for reports, so they
+// are only found by those permitted to read reports.
+func (s Store) Search(ctx context.Context, claims auth.Claims, sq Query) ([]Result, error) {
+	if err := validate.Check(sq); err != nil {
+		return nil, fmt.Errorf("validating data: %w", err)
+	}
+
+	tsquery := prefixQuery(sq.Text)
+	if tsquery == "" {
+		return nil, nil
+	}
+
+	data := struct {
+		TSQuery     string         `db:"tsquery"`
+		Text        string         `db:"text"`
+		Admin       bool           `db:"admin"`
+		UsersRead   bool           `db:"users_read"`
+		ReportsRead bool           `db:"reports_read"`
+		Subject     string         `db:"subject"`
+		Roles       pq.StringArray `db:"roles"`
+		Types       pq.StringArray `db:"types"`
+		Limit       int            `db:"limit"`
+	}{
+		TSQuery:     tsquery,
+		Text:        sq.Text,
+		Admin:       claims.Authorized(auth.RoleAdmin),
+		UsersRead:   claims.HasPermission(auth.PermUsersRead),
+		ReportsRead: claims.HasPermission(auth.PermReportsRead),
+		Subject:     claims.Subject,
+		Roles:       claims.Roles,
+		Types:       sq.Types,
+		Limit:       sq.Limit,
+	}
+	if data.Roles == nil {
+		data.Roles = pq.StringArray{}
+	}
+	if data.Types == nil {
+		data.Types = pq.StringArray{}
+	}
+
+	const q = `
+	SELECT
+		type, id, title, body,
+		ts_rank(document, to_tsquery('simple', :tsquery)) + word_similarity(:text, title) AS rank
+	FROM
+		search_documents
+	WHERE
+		(document @@ to_tsquery('simple', :tsquery) OR :text <% title) AND
+		(NOT restricted OR (type = 'user' AND :users_read) OR (type <> 'user' AND :admin) OR CAST(owner_id AS TEXT) = :subject OR :subject = ANY(shared_users) OR shared_roles && CAST(:roles AS TEXT[])) AND
+		(type <> 'product' OR :reports_read) AND
+		(CARDINALITY(CAST(:types AS TEXT[])) = 0 OR type = ANY(CAST(:types AS TEXT[])))
+	ORDER BY
+		rank DESC, title
+	FETCH FIRST :limit ROWS ONLY`
+
+	var results []Result
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &results); err != nil {
+		return nil, fmt.Errorf("searching[%q]: %w", sq.Text, err)
+	}
+
+	return results, nil
+}
+
+// prefixQuery turns free text into a tsquery matching every word as a
+// prefix, e.g. "gopher sto" becomes "gopher:* & sto:*". Anything other than
+// a letter or digit separates words so the text can't add tsquery operators.
+func prefixQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+
+	return strings.Join(words, " & ")
+}
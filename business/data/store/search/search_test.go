@@ -0,0 +1,95 @@
+package search_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/search"
+	"github.com/deliveranceTechSolutions/erp/business/data/tests"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var dbc = tests.DBContainer{
+	Image: "postgres:13-alpine",
+	Port:  "5432",
+	Args:  []string{"-e", "POSTGRES_PASSWORD=postgres"},
+}
+
+// Seeded users and products the searches find.
+const (
+	adminID  = "5cf37266-3473-4006-984f-9325122678b7"
+	userID   = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+	comicsID = "a2b0639f-2cc6-44b8-b97b-15d69dbb511e"
+)
+
+func TestSearch(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	store := search.NewStore(log, db)
+
+	t.Log("Given the need to search across records.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen searching the seeded records.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+
+			results, err := store.Search(ctx, claims(adminID, auth.RoleAdmin), search.Query{Text: "gopher", Limit: 10})
+			if err != nil || len(results) != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould find both users as an admin : %v %+v.", tests.Failed, testID, err, results)
+			}
+			t.Logf("\t%s\tTest %d:\tShould find both users as an admin.", tests.Success, testID)
+
+			results, err = store.Search(ctx, claims(userID, auth.RoleUser), search.Query{Text: "gopher", Limit: 10})
+			if err != nil || len(results) != 1 || results[0].ID != userID {
+				t.Fatalf("\t%s\tTest %d:\tShould only find themself as a user : %v %+v.", tests.Failed, testID, err, results)
+			}
+			t.Logf("\t%s\tTest %d:\tShould only find themself as a user.", tests.Success, testID)
+
+			reader := claims(userID, "AUDITOR")
+			reader.Permissions = []string{auth.PermUsersRead}
+			results, err = store.Search(ctx, reader, search.Query{Text: "gopher", Limit: 10})
+			if err != nil || len(results) != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould find both users when permitted to read users : %v %+v.", tests.Failed, testID, err, results)
+			}
+			t.Logf("\t%s\tTest %d:\tShould find both users when permitted to read users.", tests.Success, testID)
+
+			results, err = store.Search(ctx, claims(userID, auth.RoleUser), search.Query{Text: "comic", Types: []string{search.TypeProduct}, Limit: 10})
+			if err != nil || len(results) != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT find products without being permitted to read reports : %v %+v.", tests.Failed, testID, err, results)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT find products without being permitted to read reports.", tests.Success, testID)
+
+			analyst := claims(userID, "ANALYST")
+			analyst.Permissions = []string{auth.PermReportsRead}
+
+			results, err = store.Search(ctx, analyst, search.Query{Text: "com bo", Types: []string{search.TypeProduct}, Limit: 10})
+			if err != nil || len(results) != 1 || results[0].ID != comicsID {
+				t.Fatalf("\t%s\tTest %d:\tShould match words by prefix : %v %+v.", tests.Failed, testID, err, results)
+			}
+			t.Logf("\t%s\tTest %d:\tShould match words by prefix.", tests.Success, testID)
+
+			results, err = store.Search(ctx, analyst, search.Query{Text: "comic boks", Limit: 10})
+			if err != nil || len(results) == 0 || results[0].ID != comicsID {
+				t.Fatalf("\t%s\tTest %d:\tShould forgive typos : %v %+v.", tests.Failed, testID, err, results)
+			}
+			t.Logf("\t%s\tTest %d:\tShould forgive typos.", tests.Success, testID)
+		}
+	}
+}
+
+func claims(subject string, roles ...string) auth.Claims {
+	return auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    "service project",
+			Subject:   subject,
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			IssuedAt:  time.Now().UTC().Unix(),
+		},
+		Roles: roles,
+	}
+}
@@ -0,0 +1,51 @@
+package session
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RefreshToken is one link in a session's chain of refresh tokens. Each time
+// a refresh token is used it is replaced by a new one in the same family,
+// along with the access token issued with it. Only a hash of the token is
+// stored so a leaked table can't be used to sign in.
+type RefreshToken struct {
+	ID            string         `db:"token_id"`
+	TenantID      string         `db:"tenant_id"`
+	UserID        string         `db:"user_id"`
+	FamilyID      string         `db:"family_id"`
+	ClientID      *string        `db:"client_id"`
+	Scope         string         `db:"scope"`
+	AMR           pq.StringArray `db:"amr"`
+	TokenHash     []byte         `db:"token_hash"`
+	AccessID      string         `db:"access_id"`
+	AccessExpires time.Time      `db:"access_expires"`
+	DateCreated   time.Time      `db:"date_created"`
+	DateExpires   time.Time      `db:"date_expires"`
+	DateUsed      *time.Time     `db:"date_used"`
+	DateRevoked   *time.Time     `db:"date_revoked"`
+}
+
+// NewRefreshToken contains information needed to store a refresh token.
+// ClientID and Scope are set when the session was started by an OAuth client.
+// AMR lists the methods the user authenticated with to start the session.
+// TokenHash is left empty to record an access token issued without a refresh
+// token, so it can be revoked like any other but never refreshed.
+type NewRefreshToken struct {
+	UserID        string `validate:"required,uuid"`
+	FamilyID      string `validate:"required,uuid"`
+	ClientID      string `validate:"omitempty,uuid"`
+	Scope         string
+	AMR           []string
+	TokenHash     []byte
+	AccessID      string    `validate:"required,uuid"`
+	AccessExpires time.Time `validate:"required"`
+	DateExpires   time.Time `validate:"required"`
+}
+
+// Revoked is an access token that was revoked before it expired.
+type Revoked struct {
+	JTI         string    `db:"jti"`
+	DateExpires time.Time `db:"date_expires"`
+}
@@ -0,0 +1,225 @@
+// Package session contains refresh token and access token revocation related
+// CRUD functionality.
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of API's for session access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs a session store for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new refresh token into the database.
+func (s Store) Create(ctx context.Context, nrt NewRefreshToken, now time.Time) (RefreshToken, error) {
+	if err := validate.Check(nrt); err != nil {
+		return RefreshToken{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	rt := RefreshToken{
+		ID:            validate.GenerateID(),
+		UserID:        nrt.UserID,
+		FamilyID:      nrt.FamilyID,
+		Scope:         nrt.Scope,
+		AMR:           nrt.AMR,
+		TokenHash:     nrt.TokenHash,
+		AccessID:      nrt.AccessID,
+		AccessExpires: nrt.AccessExpires,
+		DateCreated:   now,
+		DateExpires:   nrt.DateExpires,
+	}
+	if nrt.ClientID != "" {
+		rt.ClientID = &nrt.ClientID
+	}
+	if rt.AMR == nil {
+		rt.AMR = []string{}
+	}
+
+	const q = `
+	INSERT INTO refresh_tokens
+		(token_id, user_id, family_id, client_id, scope, amr, token_hash, access_id, access_expires, date_created, date_expires)
+	VALUES
+		(:token_id, :user_id, :family_id, :client_id, :scope, :amr, :token_hash, :access_id, :access_expires, :date_created, :date_expires)
+	RETURNING
+		tenant_id`
+
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, rt, &rt); err != nil {
+		return RefreshToken{}, fmt.Errorf("inserting refresh token: %w", err)
+	}
+
+	return rt, nil
+}
+
+// QueryByHash gets the refresh token with the hash from the database. The
+// tenant isn't known until the token is found, so this is run with a system
+// context.
+func (s Store) QueryByHash(ctx context.Context, hash []byte) (RefreshToken, error) {
+	data := struct {
+		TokenHash []byte `db:"token_hash"`
+	}{
+		TokenHash: hash,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		refresh_tokens
+	WHERE
+		token_hash = :token_hash`
+
+	var rt RefreshToken
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &rt); err != nil {
+		if err == database.ErrNotFound {
+			return RefreshToken{}, database.ErrNotFound
+		}
+		return RefreshToken{}, fmt.Errorf("selecting refresh token: %w", err)
+	}
+
+	return rt, nil
+}
+
+// Use marks the refresh token as used so it can't be used again. It returns
+// ErrNotFound if the token was already used or revoked, which makes using a
+// token safe when two requests race to use it.
+func (s Store) Use(ctx context.Context, tokenID string, now time.Time) (RefreshToken, error) {
+	data := struct {
+		TokenID string    `db:"token_id"`
+		Now     time.Time `db:"now"`
+	}{
+		TokenID: tokenID,
+		Now:     now,
+	}
+
+	const q = `
+	UPDATE
+		refresh_tokens
+	SET
+		date_used = :now
+	WHERE
+		token_id = :token_id AND
+		date_used IS NULL AND
+		date_revoked IS NULL
+	RETURNING
+		*`
+
+	var rt RefreshToken
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &rt); err != nil {
+		if err == database.ErrNotFound {
+			return RefreshToken{}, database.ErrNotFound
+		}
+		return RefreshToken{}, fmt.Errorf("using refresh token[%s]: %w", tokenID, err)
+	}
+
+	return rt, nil
+}
+
+// RevokeFamily revokes every refresh token of a session along with the
+// access tokens issued with them that haven't expired.
+func (s Store) RevokeFamily(ctx context.Context, familyID string, now time.Time) ([]Revoked, error) {
+	return s.revoke(ctx, "family_id = :family_id", revocation{FamilyID: familyID, Now: now})
+}
+
+// RevokeAccess revokes the access token along with the session it was issued
+// for. Expires is when the access token expires.
+func (s Store) RevokeAccess(ctx context.Context, jti string, expires time.Time, now time.Time) ([]Revoked, error) {
+	where := "family_id IN (SELECT family_id FROM refresh_tokens WHERE CAST(access_id AS TEXT) = :jti_family)"
+	return s.revoke(ctx, where, revocation{JTIFamily: jti, JTI: jti, Expires: expires, Now: now})
+}
+
+// RevokeUser revokes every session of the user.
+func (s Store) RevokeUser(ctx context.Context, userID string, now time.Time) ([]Revoked, error) {
+	if err := validate.CheckID(userID); err != nil {
+		return nil, database.ErrInvalidID
+	}
+
+	return s.revoke(ctx, "user_id = :user_id", revocation{UserID: userID, Now: now})
+}
+
+// Revoked reports whether the access token was revoked.
+func (s Store) Revoked(ctx context.Context, jti string) (bool, error) {
+	data := struct {
+		JTI string `db:"jti"`
+	}{
+		JTI: jti,
+	}
+
+	const q = `
+	SELECT
+		EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = :jti) AS revoked`
+
+	var result struct {
+		Revoked bool `db:"revoked"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &result); err != nil {
+		return false, fmt.Errorf("selecting revoked token[%s]: %w", jti, err)
+	}
+
+	return result.Revoked, nil
+}
+
+// =============================================================================
+
+// revocation holds the values a revoke query can be run with.
+type revocation struct {
+	UserID    string    `db:"user_id"`
+	FamilyID  string    `db:"family_id"`
+	JTIFamily string    `db:"jti_family"`
+	JTI       string    `db:"jti"`
+	Expires   time.Time `db:"expires"`
+	Now       time.Time `db:"now"`
+}
+
+// revoke revokes the refresh tokens matching the condition and adds the
+// access tokens issued with them, along with the access token named by JTI
+// if set, to the revocation list. It returns the access tokens added.
+func (s Store) revoke(ctx context.Context, where string, data revocation) ([]Revoked, error) {
+	q := `
+	WITH sessions AS (
+		UPDATE
+			refresh_tokens
+		SET
+			date_revoked = :now
+		WHERE
+			date_revoked IS NULL AND ` + where + `
+		RETURNING
+			access_id, access_expires
+	), access AS (
+		SELECT CAST(access_id AS TEXT) AS jti, access_expires AS date_expires FROM sessions WHERE access_expires > :now
+		UNION
+		SELECT :jti, :expires WHERE :jti <> ''
+	)
+	INSERT INTO revoked_tokens
+		(jti, date_expires)
+	SELECT
+		jti, date_expires
+	FROM
+		access
+	ON CONFLICT (jti) DO NOTHING
+	RETURNING
+		jti, date_expires`
+
+	var revoked []Revoked
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &revoked); err != nil {
+		return nil, fmt.Errorf("revoking tokens: %w", err)
+	}
+
+	return revoked, nil
+}
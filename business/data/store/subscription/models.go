@@ -0,0 +1,47 @@
+package subscription
+
+import (
+	"time"
+)
+
+// Set of sources a subscription can deliver.
+const (
+	SourceReport    = "report"
+	SourceDashboard = "dashboard"
+)
+
+// Set of formats a subscription can be delivered in.
+const (
+	FormatCSV = "csv"
+	FormatSVG = "svg"
+)
+
+// Subscription is a user's request to receive a report or dashboard by email
+// on a schedule.
+type Subscription struct {
+	ID          string     `db:"subscription_id" json:"id"`
+	TenantID    string     `db:"tenant_id" json:"-"`
+	UserID      string     `db:"user_id" json:"user_id"`
+	Name        string     `db:"name" json:"name"`
+	SourceType  string     `db:"source_type" json:"source_type"`
+	Source      string     `db:"source" json:"source"`
+	Format      string     `db:"format" json:"format"`
+	Schedule    string     `db:"schedule" json:"schedule"`
+	NextRun     time.Time  `db:"next_run" json:"next_run"`
+	LastRun     *time.Time `db:"last_run" json:"last_run,omitempty"`
+	LastError   *string    `db:"last_error" json:"last_error,omitempty"`
+	Failures    int        `db:"failures" json:"failures"`
+	DateCreated time.Time  `db:"date_created" json:"date_created"`
+	DateUpdated time.Time  `db:"date_updated" json:"date_updated"`
+}
+
+// NewSubscription contains information needed to create a new Subscription.
+// Source is a report name or a dashboard ID depending on SourceType and
+// Schedule is a cron expression such as "0 8 * * MON" or "@daily".
+type NewSubscription struct {
+	Name       string `json:"name" validate:"required"`
+	SourceType string `json:"source_type" validate:"required,oneof=report dashboard"`
+	Source     string `json:"source" validate:"required"`
+	Format     string `json:"format" validate:"required,oneof=csv svg"`
+	Schedule   string `json:"schedule" validate:"required"`
+}
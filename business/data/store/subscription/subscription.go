@@ -0,0 +1,284 @@
+// Package subscription contains scheduled delivery related CRUD functionality.
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of API's for subscription access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs a subscription store for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new subscription owned by the claims subject into the
+// database. The first delivery happens at nextRun.
+func (s Store) Create(ctx context.Context, claims auth.Claims, ns NewSubscription, nextRun time.Time, now time.Time) (Subscription, error) {
+	if err := validate.Check(ns); err != nil {
+		return Subscription{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	sub := Subscription{
+		ID:          validate.GenerateID(),
+		UserID:      claims.Subject,
+		Name:        ns.Name,
+		SourceType:  ns.SourceType,
+		Source:      ns.Source,
+		Format:      ns.Format,
+		Schedule:    ns.Schedule,
+		NextRun:     nextRun,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	const q = `
+	INSERT INTO subscriptions
+		(subscription_id, user_id, name, source_type, source, format, schedule, next_run, date_created, date_updated)
+	VALUES
+		(:subscription_id, :user_id, :name, :source_type, :source, :format, :schedule, :next_run, :date_created, :date_updated)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, sub); err != nil {
+		return Subscription{}, fmt.Errorf("inserting subscription: %w", err)
+	}
+
+	return sub, nil
+}
+
+// Delete removes a subscription from the database.
+func (s Store) Delete(ctx context.Context, claims auth.Claims, subscriptionID string) error {
+	if _, err := s.QueryByID(ctx, claims, subscriptionID); err != nil {
+		return fmt.Errorf("deleting subscription subscriptionID[%s]: %w", subscriptionID, err)
+	}
+
+	data := struct {
+		SubscriptionID string `db:"subscription_id"`
+	}{
+		SubscriptionID: subscriptionID,
+	}
+
+	const q = `
+	DELETE FROM
+		subscriptions
+	WHERE
+		subscription_id = :subscription_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("deleting subscriptionID[%s]: %w", subscriptionID, err)
+	}
+
+	return nil
+}
+
+// Query retrieves the subscriptions owned by the claims subject.
+func (s Store) Query(ctx context.Context, claims auth.Claims) ([]Subscription, error) {
+	data := struct {
+		UserID string `db:"user_id"`
+	}{
+		UserID: claims.Subject,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		subscriptions
+	WHERE
+		user_id = :user_id
+	ORDER BY
+		name, subscription_id`
+
+	var subs []Subscription
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &subs); err != nil {
+		return nil, fmt.Errorf("selecting subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+// QueryByID gets the specified subscription from the database. Only admins
+// and the owner can access a subscription.
+func (s Store) QueryByID(ctx context.Context, claims auth.Claims, subscriptionID string) (Subscription, error) {
+	if err := validate.CheckID(subscriptionID); err != nil {
+		return Subscription{}, database.ErrInvalidID
+	}
+
+	data := struct {
+		SubscriptionID string `db:"subscription_id"`
+	}{
+		SubscriptionID: subscriptionID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		subscriptions
+	WHERE
+		subscription_id = :subscription_id`
+
+	var sub Subscription
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &sub); err != nil {
+		if err == database.ErrNotFound {
+			return Subscription{}, database.ErrNotFound
+		}
+		return Subscription{}, fmt.Errorf("selecting subscriptionID[%q]: %w", subscriptionID, err)
+	}
+
+	if !claims.Authorized(auth.RoleAdmin) && claims.Subject != sub.UserID {
+		return Subscription{}, database.ErrForbidden
+	}
+
+	return sub, nil
+}
+
+// QueryDue retrieves the subscriptions whose next run is at or before now.
+func (s Store) QueryDue(ctx context.Context, now time.Time) ([]Subscription, error) {
+	data := struct {
+		Now time.Time `db:"now"`
+	}{
+		Now: now,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		subscriptions
+	WHERE
+		next_run <= :now
+	ORDER BY
+		next_run`
+
+	var subs []Subscription
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &subs); err != nil {
+		return nil, fmt.Errorf("selecting due subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+// Claim moves a due subscription on to its next run. The update only
+// succeeds if the subscription is still scheduled for the run that was read,
+// so when several instances race for the same run exactly one of them gets
+// the subscription back and the rest get database.ErrNotFound.
+func (s Store) Claim(ctx context.Context, sub Subscription, nextRun time.Time, now time.Time) (Subscription, error) {
+	data := struct {
+		SubscriptionID string    `db:"subscription_id"`
+		Scheduled      time.Time `db:"scheduled"`
+		NextRun        time.Time `db:"next_run"`
+		LastRun        time.Time `db:"last_run"`
+	}{
+		SubscriptionID: sub.ID,
+		Scheduled:      sub.NextRun,
+		NextRun:        nextRun,
+		LastRun:        now,
+	}
+
+	const q = `
+	UPDATE
+		subscriptions
+	SET
+		"next_run" = :next_run,
+		"last_run" = :last_run
+	WHERE
+		subscription_id = :subscription_id AND
+		next_run = :scheduled
+	RETURNING
+		*`
+
+	var claimed Subscription
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &claimed); err != nil {
+		if err == database.ErrNotFound {
+			return Subscription{}, database.ErrNotFound
+		}
+		return Subscription{}, fmt.Errorf("claiming subscriptionID[%s]: %w", sub.ID, err)
+	}
+
+	return claimed, nil
+}
+
+// RecordResult saves the outcome of the last delivery and returns the
+// subscription as it now is. A failure is added to the count of consecutive
+// failures and a nil error clears them.
+func (s Store) RecordResult(ctx context.Context, subscriptionID string, result error, now time.Time) (Subscription, error) {
+	data := struct {
+		SubscriptionID string    `db:"subscription_id"`
+		LastError      *string   `db:"last_error"`
+		Failed         bool      `db:"failed"`
+		DateUpdated    time.Time `db:"date_updated"`
+	}{
+		SubscriptionID: subscriptionID,
+		DateUpdated:    now,
+	}
+	if result != nil {
+		msg := result.Error()
+		data.LastError = &msg
+		data.Failed = true
+	}
+
+	const q = `
+	UPDATE
+		subscriptions
+	SET
+		"last_error" = :last_error,
+		"failures" = CASE WHEN :failed THEN failures + 1 ELSE 0 END,
+		"date_updated" = :date_updated
+	WHERE
+		subscription_id = :subscription_id
+	RETURNING
+		*`
+
+	var sub Subscription
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &sub); err != nil {
+		if err == database.ErrNotFound {
+			return Subscription{}, database.ErrNotFound
+		}
+		return Subscription{}, fmt.Errorf("recording result subscriptionID[%s]: %w", subscriptionID, err)
+	}
+
+	return sub, nil
+}
+
+// Retry brings the next run of a subscription forward to retryAt so a failed
+// delivery is attempted again. A subscription already due before retryAt is
+// left as it is.
+func (s Store) Retry(ctx context.Context, subscriptionID string, retryAt time.Time) error {
+	data := struct {
+		SubscriptionID string    `db:"subscription_id"`
+		RetryAt        time.Time `db:"retry_at"`
+	}{
+		SubscriptionID: subscriptionID,
+		RetryAt:        retryAt,
+	}
+
+	const q = `
+	UPDATE
+		subscriptions
+	SET
+		"next_run" = :retry_at
+	WHERE
+		subscription_id = :subscription_id AND
+		next_run > :retry_at`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("retrying subscriptionID[%s]: %w", subscriptionID, err)
+	}
+
+	return nil
+}
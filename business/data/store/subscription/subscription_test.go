@@ -0,0 +1,144 @@
+package subscription_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/subscription"
+	"github.com/deliveranceTechSolutions/erp/business/data/tests"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var dbc = tests.DBContainer{
+	Image: "postgres:13-alpine",
+	Port:  "5432",
+	Args:  []string{"-e", "POSTGRES_PASSWORD=postgres"},
+}
+
+// Seeded users the subscriptions are created for.
+const (
+	adminID = "5cf37266-3473-4006-984f-9325122678b7"
+	userID  = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+)
+
+func TestSubscription(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	store := subscription.NewStore(log, db)
+
+	t.Log("Given the need to work with Subscription records.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen handling a single Subscription.", testID)
+		{
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
+			now := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+			nextRun := now.Add(8 * time.Hour)
+
+			admin := claims(adminID, auth.RoleAdmin)
+			user := claims(userID, auth.RoleUser)
+
+			ns := subscription.NewSubscription{
+				Name:       "Daily Revenue",
+				SourceType: subscription.SourceReport,
+				Source:     "revenue",
+				Format:     subscription.FormatCSV,
+				Schedule:   "0 8 * * *",
+			}
+
+			sub, err := store.Create(ctx, admin, ns, nextRun, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create subscription : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create subscription.", tests.Success, testID)
+
+			if _, err := store.QueryByID(ctx, user, sub.ID); !errors.Is(err, database.ErrForbidden) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to view another user's subscription : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to view another user's subscription.", tests.Success, testID)
+
+			due, err := store.QueryDue(ctx, now)
+			if err != nil || len(due) != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould have nothing due before the next run : %v %+v.", tests.Failed, testID, err, due)
+			}
+			t.Logf("\t%s\tTest %d:\tShould have nothing due before the next run.", tests.Success, testID)
+
+			due, err = store.QueryDue(ctx, nextRun)
+			if err != nil || len(due) != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould have the subscription due at the next run : %v %+v.", tests.Failed, testID, err, due)
+			}
+			t.Logf("\t%s\tTest %d:\tShould have the subscription due at the next run.", tests.Success, testID)
+
+			following := nextRun.Add(24 * time.Hour)
+			claimed, err := store.Claim(ctx, due[0], following, nextRun)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to claim the due run : %s.", tests.Failed, testID, err)
+			}
+			if !claimed.NextRun.Equal(following) || claimed.LastRun == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould move the subscription to its following run : %+v.", tests.Failed, testID, claimed)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to claim the due run.", tests.Success, testID)
+
+			if _, err := store.Claim(ctx, due[0], following, nextRun); !errors.Is(err, database.ErrNotFound) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to claim the same run twice : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to claim the same run twice.", tests.Success, testID)
+
+			failed, err := store.RecordResult(ctx, sub.ID, errors.New("connection refused"), nextRun)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to record a failed delivery : %s.", tests.Failed, testID, err)
+			}
+			if failed.Failures != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould count the failed delivery : %+v.", tests.Failed, testID, failed)
+			}
+			saved, err := store.QueryByID(ctx, admin, sub.ID)
+			if err != nil || saved.LastError == nil || *saved.LastError != "connection refused" {
+				t.Fatalf("\t%s\tTest %d:\tShould get back the recorded failure : %v %+v.", tests.Failed, testID, err, saved)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to record a failed delivery.", tests.Success, testID)
+
+			retryAt := nextRun.Add(time.Minute)
+			if err := store.Retry(ctx, sub.ID, retryAt); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to retry the failed delivery : %s.", tests.Failed, testID, err)
+			}
+			due, err = store.QueryDue(ctx, retryAt)
+			if err != nil || len(due) != 1 || !due[0].NextRun.Equal(retryAt) {
+				t.Fatalf("\t%s\tTest %d:\tShould have the subscription due again at the retry : %v %+v.", tests.Failed, testID, err, due)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to retry the failed delivery.", tests.Success, testID)
+
+			recovered, err := store.RecordResult(ctx, sub.ID, nil, retryAt)
+			if err != nil || recovered.Failures != 0 || recovered.LastError != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould clear the failures after a delivery : %v %+v.", tests.Failed, testID, err, recovered)
+			}
+			t.Logf("\t%s\tTest %d:\tShould clear the failures after a delivery.", tests.Success, testID)
+
+			if err := store.Delete(ctx, admin, sub.ID); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to delete subscription : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to delete subscription.", tests.Success, testID)
+
+			if _, err := store.QueryByID(ctx, admin, sub.ID); !errors.Is(err, database.ErrNotFound) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to retrieve subscription : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to retrieve subscription.", tests.Success, testID)
+		}
+	}
+}
+
+func claims(subject string, roles ...string) auth.Claims {
+	return auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    "service project",
+			Subject:   subject,
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			IssuedAt:  time.Now().UTC().Unix(),
+		},
+		Roles: roles,
+	}
+}
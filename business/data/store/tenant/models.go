@@ -0,0 +1,19 @@
+package tenant
+
+import (
+	"time"
+)
+
+// Tenant is a company the ERP is run for. Every business record belongs to
+// exactly one tenant and is only visible within it.
+type Tenant struct {
+	ID          string    `db:"tenant_id" json:"id"`
+	Name        string    `db:"name" json:"name"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+	DateUpdated time.Time `db:"date_updated" json:"date_updated"`
+}
+
+// NewTenant contains information needed to create a new Tenant.
+type NewTenant struct {
+	Name string `json:"name" validate:"required"`
+}
@@ -0,0 +1,129 @@
+// Package tenant contains tenant related CRUD functionality.
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// Store manages the set of API's for tenant access.
+type Store struct {
+	log *zap.SugaredLogger
+	db  *sqlx.DB
+}
+
+// NewStore constructs a tenant store for api access.
+func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
+	return Store{
+		log: log,
+		db:  db,
+	}
+}
+
+// Create inserts a new tenant into the database. Tenants are provisioned
+// outside of any tenant so this requires a system context.
+func (s Store) Create(ctx context.Context, nt NewTenant, now time.Time) (Tenant, error) {
+	if err := validate.Check(nt); err != nil {
+		return Tenant{}, fmt.Errorf("validating data: %w", err)
+	}
+
+	tnt := Tenant{
+		ID:          validate.GenerateID(),
+		Name:        nt.Name,
+		DateCreated: now,
+		DateUpdated: now,
+	}
+
+	const q = `
+	INSERT INTO tenants
+		(tenant_id, name, date_created, date_updated)
+	VALUES
+		(:tenant_id, :name, :date_created, :date_updated)`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, tnt); err != nil {
+		return Tenant{}, fmt.Errorf("inserting tenant: %w", err)
+	}
+
+	return tnt, nil
+}
+
+// Delete removes a tenant and, with it, every record that belongs to it.
+func (s Store) Delete(ctx context.Context, tenantID string) error {
+	if err := validate.CheckID(tenantID); err != nil {
+		return database.ErrInvalidID
+	}
+
+	data := struct {
+		TenantID string `db:"tenant_id"`
+	}{
+		TenantID: tenantID,
+	}
+
+	const q = `
+	DELETE FROM
+		tenants
+	WHERE
+		tenant_id = :tenant_id`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("deleting tenantID[%s]: %w", tenantID, err)
+	}
+
+	return nil
+}
+
+// Query retrieves the tenants the context can reach, which is every tenant
+// for a system context.
+func (s Store) Query(ctx context.Context) ([]Tenant, error) {
+	const q = `
+	SELECT
+		*
+	FROM
+		tenants
+	ORDER BY
+		name`
+
+	var tenants []Tenant
+	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, struct{}{}, &tenants); err != nil {
+		return nil, fmt.Errorf("selecting tenants: %w", err)
+	}
+
+	return tenants, nil
+}
+
+// QueryByID gets the specified tenant from the database.
+func (s Store) QueryByID(ctx context.Context, tenantID string) (Tenant, error) {
+	if err := validate.CheckID(tenantID); err != nil {
+		return Tenant{}, database.ErrInvalidID
+	}
+
+	data := struct {
+		TenantID string `db:"tenant_id"`
+	}{
+		TenantID: tenantID,
+	}
+
+	const q = `
+	SELECT
+		*
+	FROM
+		tenants
+	WHERE
+		tenant_id = :tenant_id`
+
+	var tnt Tenant
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &tnt); err != nil {
+		if err == database.ErrNotFound {
+			return Tenant{}, database.ErrNotFound
+		}
+		return Tenant{}, fmt.Errorf("selecting tenantID[%q]: %w", tenantID, err)
+	}
+
+	return tnt, nil
+}
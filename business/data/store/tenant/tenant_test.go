@@ -0,0 +1,157 @@
+package tenant_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/data/store/project"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/search"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/tenant"
+	"github.com/deliveranceTechSolutions/erp/business/data/store/user"
+	"github.com/deliveranceTechSolutions/erp/business/data/tests"
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var dbc = tests.DBContainer{
+	Image: "postgres:13-alpine",
+	Port:  "5432",
+	Args:  []string{"-e", "POSTGRES_PASSWORD=postgres"},
+}
+
+// Seeded admin of the default tenant.
+const adminID = "5cf37266-3473-4006-984f-9325122678b7"
+
+func TestIsolation(t *testing.T) {
+	log, db, teardown := tests.NewUnit(t, dbc)
+	t.Cleanup(teardown)
+
+	tenantStore := tenant.NewStore(log, db)
+	userStore := user.NewStore(log, db)
+	projectStore := project.NewStore(log, db)
+	searchStore := search.NewStore(log, db)
+
+	t.Log("Given the need to keep every tenant's records isolated.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a second tenant has records of its own.", testID)
+		{
+			ctx := context.Background()
+			now := time.Date(2019, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+			tnt, err := tenantStore.Create(database.WithSystem(ctx), tenant.NewTenant{Name: "Acme"}, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create tenant : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create tenant.", tests.Success, testID)
+
+			ctxA := database.WithTenant(ctx, tests.TenantID)
+			ctxB := database.WithTenant(ctx, tnt.ID)
+
+			nu := user.NewUser{
+				Name:            "Wile Coyote",
+				Email:           "wile@acme.com",
+				Roles:           []string{auth.RoleAdmin},
+				Password:        "gophers",
+				PasswordConfirm: "gophers",
+			}
+			usrB, err := userStore.Create(ctxB, nu, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create user in the tenant : %s.", tests.Failed, testID, err)
+			}
+			prjB, err := projectStore.Create(ctxB, claims(usrB.ID, tnt.ID, auth.RoleAdmin), project.NewProject{Name: "Rocket Skates", StartDate: now}, now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create project in the tenant : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create records in the tenant.", tests.Success, testID)
+
+			adminA := claims(adminID, tests.TenantID, auth.RoleAdmin)
+			if _, err := userStore.QueryByID(ctxA, adminA, usrB.ID); !errors.Is(err, database.ErrNotFound) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to read another tenant's user : %v.", tests.Failed, testID, err)
+			}
+			if _, err := projectStore.QueryByID(ctxA, prjB.ID); !errors.Is(err, database.ErrNotFound) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to read another tenant's project : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to read another tenant's records by ID.", tests.Success, testID)
+
+			users, err := userStore.Query(ctxA, user.QueryFilter{}, nil, database.Keyset{Limit: 100, Total: true})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to query users : %s.", tests.Failed, testID, err)
+			}
+			for _, usr := range users.Items {
+				if usr.ID == usrB.ID {
+					t.Fatalf("\t%s\tTest %d:\tShould NOT list another tenant's users.", tests.Failed, testID)
+				}
+			}
+			if *users.Total != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould only count the tenant's own users : %d.", tests.Failed, testID, *users.Total)
+			}
+			results, err := searchStore.Search(ctxA, adminA, search.Query{Text: "rocket", Limit: 10})
+			if err != nil || len(results) != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT find another tenant's records : %v %+v.", tests.Failed, testID, err, results)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT list, count or find another tenant's records.", tests.Success, testID)
+
+			name := "Stolen"
+			if _, err := projectStore.Update(ctxA, prjB.ID, project.UpdateProject{Name: &name}, now); !errors.Is(err, database.ErrNotFound) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to update another tenant's project : %v.", tests.Failed, testID, err)
+			}
+			if err := projectStore.Delete(ctxA, prjB.ID); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to run the delete : %s.", tests.Failed, testID, err)
+			}
+			if prj, err := projectStore.QueryByID(ctxB, prjB.ID); err != nil || prj.Name != prjB.Name {
+				t.Fatalf("\t%s\tTest %d:\tShould leave another tenant's project untouched : %v %+v.", tests.Failed, testID, err, prj)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to change another tenant's records.", tests.Success, testID)
+
+			data := struct {
+				ProjectID string `db:"project_id"`
+				TenantID  string `db:"tenant_id"`
+			}{
+				ProjectID: validate.GenerateID(),
+				TenantID:  tnt.ID,
+			}
+			const q = `INSERT INTO projects (project_id, tenant_id, name) VALUES (:project_id, :tenant_id, 'Planted')`
+			if err := database.NamedExecContext(ctxA, log, db, q, data); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to write into another tenant.", tests.Failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to write into another tenant.", tests.Success, testID)
+
+			if _, err := projectStore.QueryByID(ctx, prjB.ID); !errors.Is(err, database.ErrNoTenant) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to query without a tenant : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to query without a tenant.", tests.Success, testID)
+
+			clm, err := userStore.Authenticate(ctx, now, "wile@acme.com", "gophers")
+			if err != nil || clm.TenantID != tnt.ID {
+				t.Fatalf("\t%s\tTest %d:\tShould issue claims for the user's tenant : %v %+v.", tests.Failed, testID, err, clm)
+			}
+			t.Logf("\t%s\tTest %d:\tShould issue claims for the user's tenant.", tests.Success, testID)
+
+			if err := tenantStore.Delete(database.WithSystem(ctx), tnt.ID); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to delete tenant : %s.", tests.Failed, testID, err)
+			}
+			if _, err := projectStore.QueryByID(ctxB, prjB.ID); !errors.Is(err, database.ErrNotFound) {
+				t.Fatalf("\t%s\tTest %d:\tShould remove the tenant's records with it : %v.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould remove the tenant's records with it.", tests.Success, testID)
+		}
+	}
+}
+
+func claims(subject string, tenantID string, roles ...string) auth.Claims {
+	return auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    "service project",
+			Subject:   subject,
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			IssuedAt:  time.Now().UTC().Unix(),
+		},
+		TenantID: tenantID,
+		Roles:    roles,
+	}
+}
@@ -12,12 +12,14 @@ import (
 // User represents an individual user.
 type User struct {
 	ID           string         `db:"user_id" json:"id"`
+	TenantID     string         `db:"tenant_id" json:"-"`
 	Name         string         `db:"name" json:"name"`
 	Email        string         `db:"email" json:"email"`
 	Roles        pq.StringArray `db:"roles" json:"roles"`
 	PasswordHash []byte         `db:"password_hash" json:"-"`
 	DateCreated  time.Time      `db:"date_created" json:"date_created"`
 	DateUpdated  time.Time      `db:"date_updated" json:"date_updated"`
+	DateVerified *time.Time     `db:"date_verified" json:"date_verified,omitempty"`
 }
 
 // using a New{CoreType} idiom allows you to circumvent
@@ -44,4 +46,37 @@ type UpdateUser struct {
 	Roles           []string `json:"roles"`
 	Password        *string  `json:"password"`
 	PasswordConfirm *string  `json:"password_confirm" validate:"omitempty,eqfield=Password"`
-}
\ No newline at end of file
+}
+
+// UpdateProfile defines what users may change about themselves. Roles are
+// only changed by those allowed to assign them, and passwords only by giving
+// the current one.
+type UpdateProfile struct {
+	Name  *string `json:"name" validate:"omitempty,min=1"`
+	Email *string `json:"email" validate:"omitempty,email"`
+}
+
+// ChangePassword contains what a user gives to change their own password.
+type ChangePassword struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	Password        string `json:"password" validate:"required"`
+	PasswordConfirm string `json:"password_confirm" validate:"eqfield=Password"`
+}
+
+// QueryFilter holds the available fields a query can be filtered on. Every
+// field is optional and only the provided ones narrow the results.
+type QueryFilter struct {
+	Name             *string    `json:"name" validate:"omitempty,min=1"`
+	Email            *string    `json:"email" validate:"omitempty,email"`
+	Role             *string    `json:"role" validate:"omitempty,min=1"`
+	StartCreatedDate *time.Time `json:"created_after"`
+	EndCreatedDate   *time.Time `json:"created_before"`
+}
+
+// OrderByFields maps the fields users can be sorted by to their columns.
+var OrderByFields = map[string]string{
+	"id":           "user_id",
+	"name":         "name",
+	"email":        "email",
+	"date_created": "date_created",
+}
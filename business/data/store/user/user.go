@@ -3,22 +3,25 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
 	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/password"
 	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Store manages the set of API's for user access.
 type Store struct {
-	log *zap.SugaredLogger
-	db  *sqlx.DB
+	log    *zap.SugaredLogger
+	db     *sqlx.DB
+	hasher password.Hasher
 }
 
 // NewStore constructs a user store for api access.
@@ -29,6 +32,13 @@ func NewStore(log *zap.SugaredLogger, db *sqlx.DB) Store {
 	}
 }
 
+// WithHasher returns a copy of the store that hashes passwords with the
+// hasher. Stores hash with argon2id and its default parameters otherwise.
+func (s Store) WithHasher(hasher password.Hasher) Store {
+	s.hasher = hasher
+	return s
+}
+
 // Let the client tell you the time, so we can test it correctly.
 
 // Create inserts a new user into the database.
@@ -37,7 +47,7 @@ func (s Store) Create(ctx context.Context, nu NewUser, now time.Time) (User, err
 		return User{}, fmt.Errorf("validating data: %w", err)
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(nu.Password), bcrypt.DefaultCost)
+	hash, err := s.hasher.Hash(nu.Password)
 	if err != nil {
 		return User{}, fmt.Errorf("generating password hash: %w", err)
 	}
@@ -66,7 +76,8 @@ func (s Store) Create(ctx context.Context, nu NewUser, now time.Time) (User, err
 	return usr, nil
 }
 
-// Update replaces a user document in the database.
+// Update replaces a user document in the database. Changing the email clears
+// its verification, since the new address hasn't been shown to be the user's.
 func (s Store) Update(ctx context.Context, claims auth.Claims, userID string, uu UpdateUser, now time.Time) error {
 	if err := validate.CheckID(userID); err != nil {
 		return database.ErrInvalidID
@@ -75,6 +86,11 @@ func (s Store) Update(ctx context.Context, claims auth.Claims, userID string, uu
 		return fmt.Errorf("validating data: %w", err)
 	}
 
+	// If you can't write users and are looking to update someone other than yourself.
+	if !claims.HasPermission(auth.PermUsersWrite) && claims.Subject != userID {
+		return database.ErrForbidden
+	}
+
 	// we query for the user to verify if the state is full
 	// doesn't matter is race conditions win, we need
 	// to verify the state is qualified before we UPDATE.
@@ -87,13 +103,16 @@ func (s Store) Update(ctx context.Context, claims auth.Claims, userID string, uu
 		usr.Name = *uu.Name
 	}
 	if uu.Email != nil {
+		if !strings.EqualFold(*uu.Email, usr.Email) {
+			usr.DateVerified = nil
+		}
 		usr.Email = *uu.Email
 	}
 	if uu.Roles != nil {
 		usr.Roles = uu.Roles
 	}
 	if uu.Password != nil {
-		pw, err := bcrypt.GenerateFromPassword([]byte(*uu.Password), bcrypt.DefaultCost)
+		pw, err := s.hasher.Hash(*uu.Password)
 		if err != nil {
 			return fmt.Errorf("generating password hash: %w", err)
 		}
@@ -109,6 +128,7 @@ func (s Store) Update(ctx context.Context, claims auth.Claims, userID string, uu
 		"email" = :email,
 		"roles" = :roles,
 		"password_hash" = :password_hash,
+		"date_verified" = :date_verified,
 		"date_updated" = :date_updated
 	WHERE
 		user_id = :user_id`
@@ -126,8 +146,8 @@ func (s Store) Delete(ctx context.Context, claims auth.Claims, userID string) er
 		return database.ErrInvalidID
 	}
 
-	// If you are not an admin and looking to delete someone other than yourself.
-	if !claims.Authorized(auth.RoleAdmin) && claims.Subject != userID {
+	// If you can't write users and are looking to delete someone other than yourself.
+	if !claims.HasPermission(auth.PermUsersWrite) && claims.Subject != userID {
 		return database.ErrForbidden
 	}
 
@@ -150,34 +170,73 @@ func (s Store) Delete(ctx context.Context, claims auth.Claims, userID string) er
 	return nil
 }
 
-// Query retrieves a list of existing users from the database.
-func (s Store) Query(ctx context.Context, pageNumber int, rowsPerPage int) ([]User, error) {
-	data := struct {
-		Offset      int `db:"offset"`
-		RowsPerPage int `db:"rows_per_page"`
-	}{
-		Offset:      (pageNumber - 1) * rowsPerPage,
-		RowsPerPage: rowsPerPage,
+// Query retrieves a page of existing users from the database.
+func (s Store) Query(ctx context.Context, filter QueryFilter, orderBy []database.OrderBy, ks database.Keyset) (database.Page[User], error) {
+	if err := validate.Check(filter); err != nil {
+		return database.Page[User]{}, fmt.Errorf("validating filter: %w", err)
 	}
 
-	const q = `
+	b := database.NewBuilder()
+	if filter.Name != nil {
+		b.Contains("name", *filter.Name)
+	}
+	if filter.Email != nil {
+		b.Equal("email", *filter.Email)
+	}
+	if filter.Role != nil {
+		b.ArrayContains("roles", []string{*filter.Role})
+	}
+	if filter.StartCreatedDate != nil {
+		b.GreaterOrEqual("date_created", *filter.StartCreatedDate)
+	}
+	if filter.EndCreatedDate != nil {
+		b.Less("date_created", *filter.EndCreatedDate)
+	}
+	b.Seek(orderBy, "user_id", ks)
+
+	const base = `
 	SELECT
 		*
 	FROM
-		users
-	ORDER BY
-		user_id
-	OFFSET :offset ROWS FETCH NEXT :rows_per_page ROWS ONLY`
+		users`
+
+	q, data, err := b.Build(base)
+	if err != nil {
+		return database.Page[User]{}, fmt.Errorf("building query: %w", err)
+	}
 
 	var users []User
 	if err := database.NamedQuerySlice(ctx, s.log, s.db, q, data, &users); err != nil {
-		if err == database.ErrNotFound {
-			return nil, database.ErrNotFound
+		return database.Page[User]{}, fmt.Errorf("selecting users: %w", err)
+	}
+
+	page, err := database.NewPage(b, users, keysetValue)
+	if err != nil {
+		return database.Page[User]{}, err
+	}
+
+	if ks.Total {
+		const base = `
+		SELECT
+			count(*) AS count
+		FROM
+			users`
+
+		q, data, err := b.BuildCount(base)
+		if err != nil {
+			return database.Page[User]{}, fmt.Errorf("building count: %w", err)
 		}
-		return nil, fmt.Errorf("selecting users: %w", err)
+
+		var result struct {
+			Count int `db:"count"`
+		}
+		if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &result); err != nil {
+			return database.Page[User]{}, fmt.Errorf("counting users: %w", err)
+		}
+		page.Total = &result.Count
 	}
 
-	return users, nil
+	return page, nil
 }
 
 // QueryByID gets the specified user from the database.
@@ -186,8 +245,8 @@ func (s Store) QueryByID(ctx context.Context, claims auth.Claims, userID string)
 		return User{}, database.ErrInvalidID
 	}
 
-	// If you are not an admin and looking to retrieve someone other than yourself.
-	if !claims.Authorized(auth.RoleAdmin) && claims.Subject != userID {
+	// If you can't read users and are looking to retrieve someone other than yourself.
+	if !claims.HasPermission(auth.PermUsersRead) && claims.Subject != userID {
 		return User{}, database.ErrForbidden
 	}
 
@@ -246,18 +305,18 @@ func (s Store) QueryByEmail(ctx context.Context, claims auth.Claims, email strin
 		return User{}, fmt.Errorf("selecting email[%q]: %w", email, err)
 	}
 
-	// If you are not an admin and looking to retrieve someone other than yourself.
-	if !claims.Authorized(auth.RoleAdmin) && claims.Subject != usr.ID {
+	// If you can't read users and are looking to retrieve someone other than yourself.
+	if !claims.HasPermission(auth.PermUsersRead) && claims.Subject != usr.ID {
 		return User{}, database.ErrForbidden
 	}
 
 	return usr, nil
 }
 
-// Authenticate finds a user by their email and verifies their password. On
-// success it returns a Claims User representing this user. The claims can be
-// used to generate a token for future authentication.
-func (s Store) Authenticate(ctx context.Context, now time.Time, email, password string) (auth.Claims, error) {
+// QueryByEmailAnyTenant gets the user with the email from whichever tenant
+// they belong to. It is for requests made before the user signs in, when
+// their tenant isn't known, and is run with a system context.
+func (s Store) QueryByEmailAnyTenant(ctx context.Context, email string) (User, error) {
 	data := struct {
 		Email string `db:"email"`
 	}{
@@ -273,39 +332,51 @@ func (s Store) Authenticate(ctx context.Context, now time.Time, email, password
 		email = :email`
 
 	var usr User
-	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &usr); err != nil {
+	if err := database.NamedQueryStruct(database.WithSystem(ctx), s.log, s.db, q, data, &usr); err != nil {
 		if err == database.ErrNotFound {
-			return auth.Claims{}, database.ErrNotFound
+			return User{}, database.ErrNotFound
 		}
-		return auth.Claims{}, fmt.Errorf("selecting user[%q]: %w", email, err)
+		return User{}, fmt.Errorf("selecting email[%q]: %w", email, err)
 	}
 
-	// Compare the provided password with the saved hash. Use the bcrypt
-	// comparison function so it is cryptographically secure.
-	if err := bcrypt.CompareHashAndPassword(usr.PasswordHash, []byte(password)); err != nil {
-		return auth.Claims{}, database.ErrAuthenticationFailure
+	return usr, nil
+}
+
+// Verify records that the user proved they own their email address.
+func (s Store) Verify(ctx context.Context, userID string, now time.Time) error {
+	if err := validate.CheckID(userID); err != nil {
+		return database.ErrInvalidID
 	}
 
-	// If we are this far the request is valid. Create some claims for the user
-	// and generate their token.
-	claims := auth.Claims{
-		StandardClaims: jwt.StandardClaims{
-			Issuer:    "service project",
-			Subject:   usr.ID,
-			ExpiresAt: time.Now().Add(time.Hour).Unix(),
-			IssuedAt:  time.Now().UTC().Unix(),
-		},
-		Roles: usr.Roles,
+	data := struct {
+		UserID string    `db:"user_id"`
+		Now    time.Time `db:"now"`
+	}{
+		UserID: userID,
+		Now:    now,
 	}
 
-	return claims, nil
-}
+	const q = `
+	UPDATE
+		users
+	SET
+		date_verified = :now
+	WHERE
+		user_id = :user_id AND
+		date_verified IS NULL`
+
+	if err := database.NamedExecContext(ctx, s.log, s.db, q, data); err != nil {
+		return fmt.Errorf("verifying userID[%s]: %w", userID, err)
+	}
 
+	return nil
+}
 
-// Dashboard find the user by email and hydrates the view with a config JSON. On
-// success it returns a JSON object representing the user's conf. The claims can be
-// used to generate a token for future authentication.
-func (s Store) Dashboard(ctx context.Context, now time.Time, email, password string) (auth.Claims, error) {
+// Authenticate finds a user by their email and verifies their password. On
+// success it returns a Claims User representing this user. The claims can be
+// used to generate a token for future authentication once the token policy
+// has stamped them.
+func (s Store) Authenticate(ctx context.Context, now time.Time, email, pass string) (auth.Claims, error) {
 	data := struct {
 		Email string `db:"email"`
 	}{
@@ -314,37 +385,139 @@ func (s Store) Dashboard(ctx context.Context, now time.Time, email, password str
 
 	const q = `
 	SELECT
-		dash
+		*
 	FROM
 		users
 	WHERE
 		email = :email`
 
+	// The user's tenant isn't known until they are found, so the lookup has to
+	// reach every tenant's users. Emails are unique across tenants.
 	var usr User
-	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &usr); err != nil {
+	if err := database.NamedQueryStruct(database.WithSystem(ctx), s.log, s.db, q, data, &usr); err != nil {
 		if err == database.ErrNotFound {
+
+			// Verify against no one so the time taken doesn't tell an
+			// unknown email from a wrong password.
+			s.hasher.VerifyNone(pass)
 			return auth.Claims{}, database.ErrNotFound
 		}
 		return auth.Claims{}, fmt.Errorf("selecting user[%q]: %w", email, err)
 	}
 
-	// Compare the provided password with the saved hash. Use the bcrypt
-	// comparison function so it is cryptographically secure.
-	if err := bcrypt.CompareHashAndPassword(usr.PasswordHash, []byte(password)); err != nil {
+	// Compare the provided password with the saved hash using the scheme the
+	// hash was made with, so hashes of older schemes keep working.
+	rehash, err := s.hasher.Verify(usr.PasswordHash, pass)
+	switch {
+	case errors.Is(err, password.ErrMismatch):
 		return auth.Claims{}, database.ErrAuthenticationFailure
+	case err != nil:
+		return auth.Claims{}, fmt.Errorf("verifying password of user[%q]: %w", email, err)
+	}
+
+	// The password is only known now, so this is the one chance to move an
+	// outdated hash to the current scheme. The user is signed in either way.
+	if rehash {
+		if err := s.rehash(ctx, usr, pass); err != nil {
+			s.log.Errorw("user", "status", "rehashing password", "userID", usr.ID, "ERROR", err)
+		}
 	}
 
 	// If we are this far the request is valid. Create some claims for the user
 	// and generate their token.
 	claims := auth.Claims{
 		StandardClaims: jwt.StandardClaims{
-			Issuer:    "service project",
-			Subject:   usr.ID,
-			ExpiresAt: time.Now().Add(time.Hour).Unix(),
-			IssuedAt:  time.Now().UTC().Unix(),
+			Id:      validate.GenerateID(),
+			Subject: usr.ID,
 		},
-		Roles: usr.Roles,
+		TenantID: usr.TenantID,
+		Roles:    usr.Roles,
+		AMR:      []string{auth.AMRPassword},
 	}
 
 	return claims, nil
-}
\ No newline at end of file
+}
+
+// rehash replaces the user's outdated password hash with one made by the
+// store's hasher. The update only applies while the hash is the one the
+// password was verified against, so a password changed meanwhile is kept.
+// It is made as the user so the row level security policies let it through,
+// and it fails when no row was updated.
+func (s Store) rehash(ctx context.Context, usr User, pass string) error {
+	hash, err := s.hasher.Hash(pass)
+	if err != nil {
+		return err
+	}
+
+	data := struct {
+		UserID  string `db:"user_id"`
+		OldHash []byte `db:"old_hash"`
+		NewHash []byte `db:"new_hash"`
+	}{
+		UserID:  usr.ID,
+		OldHash: usr.PasswordHash,
+		NewHash: hash,
+	}
+
+	const q = `
+	UPDATE
+		users
+	SET
+		password_hash = :new_hash
+	WHERE
+		user_id = :user_id AND
+		password_hash = :old_hash
+	RETURNING
+		user_id`
+
+	ctx = database.WithTenant(ctx, usr.TenantID)
+	ctx = database.WithUser(ctx, usr.ID, usr.Roles)
+
+	var updated struct {
+		UserID string `db:"user_id"`
+	}
+	if err := database.NamedQueryStruct(ctx, s.log, s.db, q, data, &updated); err != nil {
+		return fmt.Errorf("rehashing userID[%s]: %w", usr.ID, err)
+	}
+
+	return nil
+}
+
+// QueryPasswordHashes returns the password hash of every user of every
+// tenant. It is run with a system context for reporting on the hashes.
+func (s Store) QueryPasswordHashes(ctx context.Context) ([][]byte, error) {
+	const q = `
+	SELECT
+		password_hash
+	FROM
+		users`
+
+	var rows []struct {
+		PasswordHash []byte `db:"password_hash"`
+	}
+	if err := database.NamedQuerySlice(database.WithSystem(ctx), s.log, s.db, q, struct{}{}, &rows); err != nil {
+		return nil, fmt.Errorf("selecting password hashes: %w", err)
+	}
+
+	hashes := make([][]byte, len(rows))
+	for i, row := range rows {
+		hashes[i] = row.PasswordHash
+	}
+
+	return hashes, nil
+}
+
+// keysetValue returns the user's value for a column users can be sorted by.
+func keysetValue(usr User, column string) any {
+	switch column {
+	case "user_id":
+		return usr.ID
+	case "name":
+		return usr.Name
+	case "email":
+		return usr.Email
+	case "date_created":
+		return usr.DateCreated
+	}
+	return nil
+}
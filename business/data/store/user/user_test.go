@@ -11,6 +11,7 @@ import (
 	"github.com/deliveranceTechSolutions/erp/business/data/tests"
 	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
 	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+	"github.com/deliveranceTechSolutions/erp/business/sys/password"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/go-cmp/cmp"
 	
@@ -33,7 +34,7 @@ func TestUser(t *testing.T) {
 		testID := 0
 		t.Logf("\tTest %d:\tWhen handling a single User.", testID)
 		{
-			ctx := context.Background()
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
 			now := time.Date(2018, time.October, 1, 0, 0, 0, 0, time.UTC)
 
 			nu := user.NewUser{
@@ -142,36 +143,51 @@ func TestPagingUser(t *testing.T) {
 		testID := 0
 		t.Logf("\tTest %d:\tWhen paging through 2 users.", testID)
 		{
-			ctx := context.Background()
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
 
-			users1, err := store.Query(ctx, 1, 1)
+			ks := database.Keyset{Limit: 1, Total: true}
+			page1, err := store.Query(ctx, user.QueryFilter{}, nil, ks)
 			if err != nil {
 				t.Fatalf("\t%s\tTest %d:\tShould be able to retrieve users for page 1 : %s.", tests.Failed, testID, err)
 			}
 			t.Logf("\t%s\tTest %d:\tShould be able to retrieve users for page 1.", tests.Success, testID)
 
-			if len(users1) != 1 {
-				t.Fatalf("\t%s\tTest %d:\tShould have a single user : %s.", tests.Failed, testID, err)
+			if len(page1.Items) != 1 || page1.Next == "" || page1.Prev != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould have a single user and a next cursor : %+v.", tests.Failed, testID, page1)
+			}
+			t.Logf("\t%s\tTest %d:\tShould have a single user and a next cursor.", tests.Success, testID)
+
+			if page1.Total == nil || *page1.Total != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould count 2 users : %v.", tests.Failed, testID, page1.Total)
 			}
-			t.Logf("\t%s\tTest %d:\tShould have a single user.", tests.Success, testID)
+			t.Logf("\t%s\tTest %d:\tShould count 2 users.", tests.Success, testID)
 
-			users2, err := store.Query(ctx, 2, 1)
+			page2, err := store.Query(ctx, user.QueryFilter{}, nil, database.Keyset{After: page1.Next, Limit: 1})
 			if err != nil {
 				t.Fatalf("\t%s\tTest %d:\tShould be able to retrieve users for page 2 : %s.", tests.Failed, testID, err)
 			}
 			t.Logf("\t%s\tTest %d:\tShould be able to retrieve users for page 2.", tests.Success, testID)
 
-			if len(users2) != 1 {
-				t.Fatalf("\t%s\tTest %d:\tShould have a single user : %s.", tests.Failed, testID, err)
+			if len(page2.Items) != 1 || page2.Next != "" || page2.Prev == "" {
+				t.Fatalf("\t%s\tTest %d:\tShould have a single user and only a prev cursor : %+v.", tests.Failed, testID, page2)
 			}
-			t.Logf("\t%s\tTest %d:\tShould have a single user.", tests.Success, testID)
+			t.Logf("\t%s\tTest %d:\tShould have a single user and only a prev cursor.", tests.Success, testID)
 
-			if users1[0].ID == users2[0].ID {
-				t.Logf("\t\tTest %d:\tUser1: %v", testID, users1[0].ID)
-				t.Logf("\t\tTest %d:\tUser2: %v", testID, users2[0].ID)
-				t.Fatalf("\t%s\tTest %d:\tShould have different users : %s.", tests.Failed, testID, err)
+			if page1.Items[0].ID == page2.Items[0].ID {
+				t.Logf("\t\tTest %d:\tUser1: %v", testID, page1.Items[0].ID)
+				t.Logf("\t\tTest %d:\tUser2: %v", testID, page2.Items[0].ID)
+				t.Fatalf("\t%s\tTest %d:\tShould have different users.", tests.Failed, testID)
 			}
 			t.Logf("\t%s\tTest %d:\tShould have different users.", tests.Success, testID)
+
+			back, err := store.Query(ctx, user.QueryFilter{}, nil, database.Keyset{Before: page2.Prev, Limit: 1})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to page back : %s.", tests.Failed, testID, err)
+			}
+			if len(back.Items) != 1 || back.Items[0].ID != page1.Items[0].ID {
+				t.Fatalf("\t%s\tTest %d:\tShould page back to the first user : %+v.", tests.Failed, testID, back)
+			}
+			t.Logf("\t%s\tTest %d:\tShould page back to the first user.", tests.Success, testID)
 		}
 	}
 }
@@ -187,7 +203,7 @@ func TestAuthenticate(t *testing.T) {
 		testID := 0
 		t.Logf("\tTest %d:\tWhen handling a single User.", testID)
 		{
-			ctx := context.Background()
+			ctx := database.WithTenant(context.Background(), tests.TenantID)
 			now := time.Date(2018, time.October, 1, 0, 0, 0, 0, time.UTC)
 
 			nu := user.NewUser{
@@ -211,12 +227,12 @@ func TestAuthenticate(t *testing.T) {
 			t.Logf("\t%s\tTest %d:\tShould be able to generate claims.", tests.Success, testID)
 
 			want := auth.Claims{
-				Roles: usr.Roles,
+				TenantID: tests.TenantID,
+				Roles:    usr.Roles,
+				AMR:      []string{auth.AMRPassword},
 				StandardClaims: jwt.StandardClaims{
-					Issuer:    "service project",
-					Subject:   usr.ID,
-					ExpiresAt: time.Now().Add(time.Hour).Unix(),
-					IssuedAt:  time.Now().UTC().Unix(),
+					Id:      claims.Id,
+					Subject: usr.ID,
 				},
 			}
 
@@ -224,6 +240,67 @@ func TestAuthenticate(t *testing.T) {
 				t.Fatalf("\t%s\tTest %d:\tShould get back the expected claims. Diff:\n%s", tests.Failed, testID, diff)
 			}
 			t.Logf("\t%s\tTest %d:\tShould get back the expected claims.", tests.Success, testID)
+
+			// The seeded admin's password was hashed with bcrypt, so signing in
+			// moves it to argon2id.
+			admin, err := store.Authenticate(ctx, now, "admin@example.com", "gophers")
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to authenticate with a bcrypt hash : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to authenticate with a bcrypt hash.", tests.Success, testID)
+
+			saved, err := store.QueryByID(ctx, admin, admin.Subject)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to retrieve user by ID: %s.", tests.Failed, testID, err)
+			}
+			if password.Scheme(saved.PasswordHash) != password.SchemeArgon2id {
+				t.Fatalf("\t%s\tTest %d:\tShould rehash the password with argon2id : %s.", tests.Failed, testID, saved.PasswordHash)
+			}
+			t.Logf("\t%s\tTest %d:\tShould rehash the password with argon2id.", tests.Success, testID)
+
+			if _, err := store.Authenticate(ctx, now, "admin@example.com", "gophers"); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to authenticate with the new hash : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to authenticate with the new hash.", tests.Success, testID)
+		}
+	}
+}
+
+func TestRehashRowLevelSecurity(t *testing.T) {
+	rls := dbc
+	rls.RowLevelSecurity = true
+
+	log, db, teardown := tests.NewUnit(t, rls)
+	t.Cleanup(teardown)
+
+	store := user.NewStore(log, db)
+
+	t.Log("Given the need to rehash passwords with row level security enforced.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a user signs in with an outdated hash.", testID)
+		{
+			ctx := context.Background()
+			now := time.Date(2018, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+			// The seeded user's password was hashed with bcrypt.
+			claims, err := store.Authenticate(ctx, now, "user@example.com", "gophers")
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to authenticate with a bcrypt hash : %s.", tests.Failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to authenticate with a bcrypt hash.", tests.Success, testID)
+
+			ctx = database.WithTenant(ctx, claims.TenantID)
+			ctx = database.WithUser(ctx, claims.Subject, claims.Roles)
+
+			saved, err := store.QueryByID(ctx, claims, claims.Subject)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to retrieve user by ID: %s.", tests.Failed, testID, err)
+			}
+			if password.Scheme(saved.PasswordHash) != password.SchemeArgon2id {
+				t.Fatalf("\t%s\tTest %d:\tShould save the argon2id hash : %s.", tests.Failed, testID, saved.PasswordHash)
+			}
+			t.Logf("\t%s\tTest %d:\tShould save the argon2id hash.", tests.Success, testID)
 		}
 	}
 }
@@ -4,6 +4,7 @@ package tests
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"fmt"
@@ -29,11 +30,16 @@ const (
 	Failed  = "\u2717"
 )
 
-// DBContainer provides configuration for a container to run.
+// TenantID is the tenant the seed data belongs to.
+const TenantID = "0a4dc3f4-43c4-4c0b-9a0f-6e3b1a0c9d7e"
+
+// DBContainer provides configuration for a container to run and for the
+// connection made to it.
 type DBContainer struct {
-	Image string
-	Port  string
-	Args  []string
+	Image            string
+	Port             string
+	Args             []string
+	RowLevelSecurity bool
 }
 
 // NewUnit creates a test database inside a Docker container. It creates the
@@ -52,6 +58,8 @@ func NewUnit(t *testing.T, dbc DBContainer) (*zap.SugaredLogger, *sqlx.DB, func(
 		Host:       c.Host,
 		Name:       "postgres",
 		DisableTLS: true,
+
+		RowLevelSecurity: dbc.RowLevelSecurity,
 	})
 	if err != nil {
 		t.Fatalf("Opening database connection: %v", err)
@@ -122,7 +130,7 @@ func NewIntegration(t *testing.T, dbc DBContainer) *Test {
 	}
 
 	// Build an authenticator using this private key and id for the key store.
-	auth, err := auth.New(keyID, keystore.NewMap(map[string]*rsa.PrivateKey{keyID: privateKey}))
+	auth, err := auth.New(keyID, keystore.NewMap(map[string]crypto.Signer{keyID: privateKey}))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -147,6 +155,7 @@ func (test *Test) Token(email, pass string) string {
 	if err != nil {
 		test.t.Fatal(err)
 	}
+	claims = test.Auth.Policy().Stamp(claims, time.Now())
 
 	token, err := test.Auth.GenerateToken(claims)
 	if err != nil {
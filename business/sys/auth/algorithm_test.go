@@ -0,0 +1,154 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/foundation/keystore"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating P-256 key: %v", err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating P-384 key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating Ed25519 key: %v", err)
+	}
+
+	ks := keystore.NewMap(map[string]crypto.Signer{
+		"rsa":  rsaKey,
+		"p256": p256Key,
+		"p384": p384Key,
+		"ed":   edKey,
+	})
+
+	tt := []struct {
+		kid string
+		alg string
+		kty string
+		crv string
+	}{
+		{"rsa", auth.AlgRS256, "RSA", ""},
+		{"p256", auth.AlgES256, "EC", "P-256"},
+		{"p384", auth.AlgES384, "EC", "P-384"},
+		{"ed", auth.AlgEdDSA, "OKP", "Ed25519"},
+	}
+
+	claims := auth.Claims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    "service project",
+			Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			IssuedAt:  time.Now().UTC().Unix(),
+		},
+		Roles: []string{auth.RoleUser},
+	}
+
+	t.Log("Given the need to sign tokens with the algorithm of each key.")
+	{
+		for testID, tc := range tt {
+			t.Logf("\tTest %d:\tWhen signing with the %s key.", testID, tc.alg)
+			{
+				a, err := auth.New(tc.kid, ks)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+				}
+
+				token, err := a.GenerateToken(claims)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to generate a JWT.", success, testID)
+
+				tkn, _, err := new(jwt.Parser).ParseUnverified(token, &auth.Claims{})
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to read the token header: %v", failed, testID, err)
+				}
+				if exp, got := tc.alg, tkn.Method.Alg(); exp != got {
+					t.Logf("\t\tTest %d:\texp: %s", testID, exp)
+					t.Logf("\t\tTest %d:\tgot: %s", testID, got)
+					t.Fatalf("\t%s\tTest %d:\tShould sign with the key's algorithm.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould sign with the key's algorithm.", success, testID)
+
+				if _, err := a.ValidateToken(token); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to validate the JWT: %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to validate the JWT.", success, testID)
+
+				jwks, err := a.JWKS()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to build the key set: %v", failed, testID, err)
+				}
+				var found bool
+				for _, jwk := range jwks.Keys {
+					if jwk.KeyID != tc.kid {
+						continue
+					}
+					found = true
+					if jwk.Algorithm != tc.alg || jwk.KeyType != tc.kty || jwk.Curve != tc.crv {
+						t.Fatalf("\t%s\tTest %d:\tShould describe the key: %+v", failed, testID, jwk)
+					}
+				}
+				if !found {
+					t.Fatalf("\t%s\tTest %d:\tShould publish the key.", failed, testID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould publish the key.", success, testID)
+			}
+		}
+
+		testID := len(tt)
+		t.Logf("\tTest %d:\tWhen a token names a key of another algorithm.", testID)
+		{
+			a, err := auth.New("rsa", ks)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+
+			tkn := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+			tkn.Header["kid"] = "rsa"
+			token, err := tkn.SignedString(p256Key)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to sign the token: %v", failed, testID, err)
+			}
+
+			if _, err := a.ValidateToken(token); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to validate the token.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to validate the token.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the active key uses an unsupported curve.", testID)
+		{
+			p224Key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a private key: %v", failed, testID, err)
+			}
+
+			ks := keystore.NewMap(map[string]crypto.Signer{"p224": p224Key})
+			if _, err := auth.New("p224", ks); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to create an authenticator.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to create an authenticator.", success, testID)
+		}
+	}
+}
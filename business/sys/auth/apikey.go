@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// APIKeyHeader is the header integrations send their API key in, in place of
+// a bearer token.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyLookup declares a method set of behavior for finding the claims an
+// API key acts with. Keys that are unknown, revoked or expired are rejected.
+type APIKeyLookup interface {
+	Authenticate(ctx context.Context, key string, now time.Time) (Claims, error)
+}
+
+// SetAPIKeyLookup has API keys accepted by ValidateAPIKey.
+func (a *Auth) SetAPIKeyLookup(lookup APIKeyLookup) {
+	a.apiKeys = lookup
+}
+
+// ValidateAPIKey returns the claims the API key acts with. The claims carry
+// the key's permissions rather than roles, so they are never granted more
+// than the key was.
+func (a *Auth) ValidateAPIKey(ctx context.Context, key string) (Claims, error) {
+	if a.apiKeys == nil {
+		return Claims{}, errors.New("api keys are not accepted")
+	}
+
+	return a.apiKeys.Authenticate(ctx, key, time.Now())
+}
@@ -2,45 +2,58 @@
 package auth
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
 // KeyLookup declares a method set of behavior for looking up
-// private and public keys for JWT use.
+// private and public keys for JWT use. RSA, ECDSA (P-256 and P-384) and
+// Ed25519 keys are supported and the algorithm used is selected by the type
+// of each key.
 type KeyLookup interface {
-	PrivateKey(kid string) (*rsa.PrivateKey, error)
-	PublicKey(kid string) (*rsa.PublicKey, error)
+	PrivateKey(kid string) (crypto.PrivateKey, error)
+	PublicKey(kid string) (crypto.PublicKey, error)
 }
 
+// Set of algorithms tokens can be signed with.
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgES384 = "ES384"
+	AlgEdDSA = "EdDSA"
+)
+
 // Auth is used to authenticate clients. It can generate a token for a
 // set of user claims and recreate the claims by parsing the token.
 // activeKID is going to determine the key the jwt is signed with
 type Auth struct {
-	activeKID string
-	keyLookup KeyLookup
-	method    jwt.SigningMethod
-	keyFunc   func(t *jwt.Token) (interface{}, error)
-	parser    jwt.Parser
+	mu          sync.RWMutex
+	activeKID   string
+	keyLookup   KeyLookup
+	keyFunc     func(t *jwt.Token) (interface{}, error)
+	parser      jwt.Parser
+	policy      Policy
+	revocations *revocations
+	permissions *permissions
+	apiKeys     APIKeyLookup
 }
 
 // New creates an Auth to support authentication/authorization.
 func New(activeKID string, keyLookup KeyLookup) (*Auth, error) {
 	// The activeKID represents the private key used to signed new tokens.
-	_, err := keyLookup.PrivateKey(activeKID)
-	if err != nil {
-		return nil, errors.New("active KID does not exist in store")
+	if _, err := signingKey(keyLookup, activeKID); err != nil {
+		return nil, err
 	}
 
-
-	method := jwt.GetSigningMethod("RS256")
-	if method == nil {
-		return nil, errors.New("configuring algorithm RS256")
-	}
-	
 	keyFunc := func(t *jwt.Token) (any, error) {
 		kid, ok := t.Header["kid"]
 		if !ok {
@@ -50,21 +63,41 @@ func New(activeKID string, keyLookup KeyLookup) (*Auth, error) {
 		if !ok {
 			return nil, errors.New("user token key id (kid) must be string")
 		}
-		return keyLookup.PublicKey(kidID)
+
+		publicKey, err := keyLookup.PublicKey(kidID)
+		if err != nil {
+			return nil, err
+		}
+
+		// The algorithm is decided by the key, never by the token, so a
+		// token can't have its signature checked in a way the key wasn't
+		// meant for.
+		method, err := signingMethod(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != method.Alg() {
+			return nil, fmt.Errorf("token algorithm %s does not match key algorithm %s", t.Method.Alg(), method.Alg())
+		}
+
+		return publicKey, nil
 	}
 
 	// Create the token parser to use. The algorithm used to sign the JWT must be
 	// validated to avoid a critical vulnerability:
 	// https://auth0.com/blog/critical-vulnerabilities-in-json-web-token-libraries/
 	parser := jwt.Parser{
-		ValidMethods: []string{"RS256"},
+		ValidMethods: []string{AlgRS256, AlgES256, AlgES384, AlgEdDSA},
+
+		// The claims are checked against the policy once the signature is
+		// known to be good.
+		SkipClaimsValidation: true,
 	}
 
 	// Always construct something completely, gather everything then construct the concrete type
-	a := Auth {
+	a := Auth{
 		activeKID: activeKID,
 		keyLookup: keyLookup,
-		method:    method,
 		keyFunc:   keyFunc,
 		parser:    parser,
 	}
@@ -72,17 +105,70 @@ func New(activeKID string, keyLookup KeyLookup) (*Auth, error) {
 	return &a, nil
 }
 
-// GenerateToken generates a signed JWT token string representing the user Claims.
+// ActiveKID returns the kid of the key new tokens are signed with.
+func (a *Auth) ActiveKID() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.activeKID
+}
+
+// SetActiveKID switches the key new tokens are signed with. Tokens signed
+// with the previous key remain valid for as long as the key lookup still
+// has its public key.
+func (a *Auth) SetActiveKID(activeKID string) error {
+	if _, err := signingKey(a.keyLookup, activeKID); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.activeKID = activeKID
+	return nil
+}
+
+// Policy returns the policy tokens are issued and accepted under.
+func (a *Auth) Policy() Policy {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.policy
+}
+
+// SetPolicy sets the policy tokens are issued and accepted under. Without a
+// policy tokens of any issuer and audience are accepted as long as they were
+// signed with our keys and haven't expired.
+func (a *Auth) SetPolicy(policy Policy) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.policy = policy
+}
+
+// GenerateToken generates a signed JWT token string representing the user
+// Claims. Tokens must expire.
 func (a *Auth) GenerateToken(claims Claims) (string, error) {
-	token := jwt.NewWithClaims(a.method, claims)
-	token.Header["kid"] = a.activeKID
+	if claims.ExpiresAt == 0 {
+		return "", errors.New("token must expire")
+	}
 
-	privateKey, err := a.keyLookup.PrivateKey(a.activeKID)
+	activeKID := a.ActiveKID()
+
+	signer, err := signingKey(a.keyLookup, activeKID)
 	if err != nil {
 		return "", errors.New("kid lookup failed")
 	}
 
-	str, err := token.SignedString(privateKey)
+	method, err := signingMethod(signer.Public())
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = activeKID
+
+	str, err := token.SignedString(signer)
 	if err != nil {
 		return "", fmt.Errorf("signing token: %w", err)
 	}
@@ -91,18 +177,71 @@ func (a *Auth) GenerateToken(claims Claims) (string, error) {
 }
 
 // ValidateToken recreates the Claims that were used to generate a token. It
-// verifies that the token was signed using our key.
+// verifies that the token was signed using our key and is accepted by the
+// policy. The error wraps one of the reasons tokens are refused.
 func (a *Auth) ValidateToken(tokenStr string) (Claims, error) {
 	var claims Claims
 	token, err := a.parser.ParseWithClaims(tokenStr, &claims, a.keyFunc)
 	if err != nil {
-		return Claims{}, fmt.Errorf("parsing token: %w", err)
+		var verr *jwt.ValidationError
+		if errors.As(err, &verr) && verr.Errors&jwt.ValidationErrorMalformed != 0 {
+			return Claims{}, fmt.Errorf("parsing token: %v: %w", err, ErrMalformed)
+		}
+		return Claims{}, fmt.Errorf("parsing token: %v: %w", err, ErrSignature)
 	}
 
 	if !token.Valid {
-		return Claims{}, errors.New("invalid token")
+		return Claims{}, ErrSignature
+	}
+
+	if err := a.Policy().Check(claims, time.Now()); err != nil {
+		return Claims{}, err
 	}
 
 	return claims, nil
 }
 
+// =============================================================================
+
+// signingKey looks up the private key for the kid and checks it can sign
+// tokens with one of the supported algorithms.
+func signingKey(keyLookup KeyLookup, kid string) (crypto.Signer, error) {
+	privateKey, err := keyLookup.PrivateKey(kid)
+	if err != nil {
+		return nil, errors.New("active KID does not exist in store")
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", privateKey)
+	}
+
+	if _, err := signingMethod(signer.Public()); err != nil {
+		return nil, err
+	}
+
+	return signer, nil
+}
+
+// signingMethod returns the method tokens are signed with for the type of
+// the public key.
+func signingMethod(publicKey crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		}
+		return nil, fmt.Errorf("unsupported curve %s", key.Curve.Params().Name)
+
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %T", publicKey)
+}
@@ -1,6 +1,7 @@
 package auth_test
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"testing"
@@ -89,10 +90,10 @@ type keyStore struct {
 	pk *rsa.PrivateKey
 }
 
-func (ks *keyStore) PrivateKey(kid string) (*rsa.PrivateKey, error) {
+func (ks *keyStore) PrivateKey(kid string) (crypto.PrivateKey, error) {
 	return ks.pk, nil
 }
 
-func (ks *keyStore) PublicKey(kid string) (*rsa.PublicKey, error) {
+func (ks *keyStore) PublicKey(kid string) (crypto.PublicKey, error) {
 	return &ks.pk.PublicKey, nil
 }
\ No newline at end of file
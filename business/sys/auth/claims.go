@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/golang-jwt/jwt/v4"
 )
@@ -13,11 +14,36 @@ const (
 	RoleUser  = "USER"
 )
 
+// These are the scopes OAuth clients can be granted.
+const (
+	ScopeReportsRead   = "reports:read"
+	ScopeProjectsRead  = "projects:read"
+	ScopeProjectsWrite = "projects:write"
+)
+
+// These are the expected values for Claims.AMR, the methods a user
+// authenticated with as named by RFC 8176.
+const (
+	AMRPassword = "pwd"
+	AMROTP      = "otp"
+	AMRMFA      = "mfa"
+)
+
 // Claims represents the authorization claims transmitted via a JWT.
-// implement jwt.StandardClaims interface from that package
+// implement jwt.StandardClaims interface from that package. TenantID names
+// the tenant the user belongs to, which every request is scoped to. ClientID
+// and Scope are set on tokens issued to OAuth clients, Scope being the space
+// separated list of scopes the client was granted. AMR lists the methods the
+// user authenticated with when the token was issued. Permissions are
+// resolved from the roles for each request and never carried in the token.
 type Claims struct {
 	jwt.StandardClaims
-	Roles []string `json:"roles"`
+	TenantID    string   `json:"tenant"`
+	Roles       []string `json:"roles"`
+	ClientID    string   `json:"client_id,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	AMR         []string `json:"amr,omitempty"`
+	Permissions []string `json:"-"`
 }
 
 // Authorized returns true if the claims has at least one of the provided roles.
@@ -32,6 +58,57 @@ func (c Claims) Authorized(roles ...string) bool {
 	return false
 }
 
+// HasScope returns true if the claims were granted every one of the scopes.
+func (c Claims) HasScope(scopes ...string) bool {
+	granted := strings.Fields(c.Scope)
+	for _, want := range scopes {
+		found := false
+		for _, has := range granted {
+			if has == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// MFA returns true if the token was issued after the user authenticated
+// with more than one factor.
+func (c Claims) MFA() bool {
+	for _, method := range c.AMR {
+		if method == AMRMFA {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission returns true if the claims were granted every one of the
+// permissions. ADMIN is granted every permission.
+func (c Claims) HasPermission(permissions ...string) bool {
+	if c.Authorized(RoleAdmin) {
+		return true
+	}
+
+	for _, want := range permissions {
+		found := false
+		for _, has := range c.Permissions {
+			if has == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 // ctxKey represents the type of value for the context key.
 type ctxKey int
 
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// KeyLister is implemented by key lookups that can list every public key
+// they hold so the keys can be published for other services to verify our
+// tokens with.
+type KeyLister interface {
+	PublicKeys() map[string]crypto.PublicKey
+}
+
+// JWK is a public key in the JSON Web Key format of RFC 7517. RSA keys set
+// N and E, elliptic curve keys set Curve, X and Y and Ed25519 keys set Curve
+// and X as described in RFC 8037.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKS is a set of public keys in the JSON Web Key Set format.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Algorithms returns the names of the algorithms tokens are signed with,
+// one for each type of key that is published.
+func (a *Auth) Algorithms() ([]string, error) {
+	jwks, err := a.JWKS()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var algs []string
+	for _, jwk := range jwks.Keys {
+		if !seen[jwk.Algorithm] {
+			seen[jwk.Algorithm] = true
+			algs = append(algs, jwk.Algorithm)
+		}
+	}
+	sort.Strings(algs)
+
+	return algs, nil
+}
+
+// JWKS returns the public keys tokens are verified with. Only the public
+// parts of the keys are ever included.
+func (a *Auth) JWKS() (JWKS, error) {
+	lister, ok := a.keyLookup.(KeyLister)
+	if !ok {
+		return JWKS{}, errors.New("key lookup can't list its keys")
+	}
+
+	keys := lister.PublicKeys()
+
+	kids := make([]string, 0, len(keys))
+	for kid := range keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := JWKS{
+		Keys: make([]JWK, 0, len(keys)),
+	}
+	for _, kid := range kids {
+		jwk, err := newJWK(kid, keys[kid])
+		if err != nil {
+			return JWKS{}, fmt.Errorf("kid[%s]: %w", kid, err)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks, nil
+}
+
+// newJWK returns the JWK for a public key.
+func newJWK(kid string, publicKey crypto.PublicKey) (JWK, error) {
+	method, err := signingMethod(publicKey)
+	if err != nil {
+		return JWK{}, err
+	}
+
+	jwk := JWK{
+		Use:       "sig",
+		Algorithm: method.Alg(),
+		KeyID:     kid,
+	}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+
+	case *ecdsa.PublicKey:
+		// The coordinates are always the full size of the curve.
+		size := (key.Curve.Params().BitSize + 7) / 8
+		jwk.KeyType = "EC"
+		jwk.Curve = key.Curve.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size)))
+
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(key)
+	}
+
+	return jwk, nil
+}
+
+// PublicKey returns the public key the JWK describes. It is the inverse of
+// publishing a key so the keys of other issuers can be used to verify the
+// tokens they sign.
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch j.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("decoding modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("decoding exponent: %w", err)
+		}
+		exp := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", j.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("decoding x: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decoding y: %w", err)
+		}
+		key := ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &key, nil
+
+	case "OKP":
+		if j.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", j.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("decoding x: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q", j.KeyType)
+}
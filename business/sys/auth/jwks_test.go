@@ -0,0 +1,134 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/foundation/keystore"
+)
+
+func TestJWKS(t *testing.T) {
+	t.Log("Given the need to publish the keys tokens are verified with.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen listing the keys of the key store.", testID)
+		{
+			const keyID = "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a private key: %v", failed, testID, err)
+			}
+
+			a, err := auth.New(keyID, keystore.NewMap(map[string]crypto.Signer{keyID: privateKey}))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+
+			jwks, err := a.JWKS()
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to build the key set: %v", failed, testID, err)
+			}
+			if len(jwks.Keys) != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould have one key: %+v", failed, testID, jwks)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to build the key set.", success, testID)
+
+			jwk := jwks.Keys[0]
+			if jwk.KeyID != keyID || jwk.Algorithm != "RS256" || jwk.Use != "sig" || jwk.KeyType != "RSA" {
+				t.Fatalf("\t%s\tTest %d:\tShould describe the key: %+v", failed, testID, jwk)
+			}
+			t.Logf("\t%s\tTest %d:\tShould describe the key.", success, testID)
+
+			n, _ := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, _ := base64.RawURLEncoding.DecodeString(jwk.E)
+			if new(big.Int).SetBytes(n).Cmp(privateKey.N) != 0 || int(new(big.Int).SetBytes(e).Int64()) != privateKey.E {
+				t.Fatalf("\t%s\tTest %d:\tShould hold the public key: %+v", failed, testID, jwk)
+			}
+			t.Logf("\t%s\tTest %d:\tShould hold the public key.", success, testID)
+
+			data, err := json.Marshal(jwks)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to marshal the key set: %v", failed, testID, err)
+			}
+			for _, private := range []string{`"d"`, `"p"`, `"q"`, `"dp"`, `"dq"`, `"qi"`} {
+				if strings.Contains(string(data), private) {
+					t.Fatalf("\t%s\tTest %d:\tShould NOT expose private material %s: %s", failed, testID, private, data)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT expose private material.", success, testID)
+		}
+	}
+}
+
+func TestJWKPublicKey(t *testing.T) {
+	t.Log("Given the need to verify tokens with keys published by other issuers.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen reading back the keys of a published key set.", testID)
+		{
+			rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an RSA key: %v", failed, testID, err)
+			}
+			p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a P-256 key: %v", failed, testID, err)
+			}
+			p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a P-384 key: %v", failed, testID, err)
+			}
+			_, edKey, err := ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an Ed25519 key: %v", failed, testID, err)
+			}
+
+			signers := map[string]crypto.Signer{
+				"rsa":  rsaKey,
+				"p256": p256Key,
+				"p384": p384Key,
+				"ed":   edKey,
+			}
+			a, err := auth.New("rsa", keystore.NewMap(signers))
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+
+			jwks, err := a.JWKS()
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to build the key set: %v", failed, testID, err)
+			}
+
+			type equaler interface {
+				Equal(x crypto.PublicKey) bool
+			}
+			for _, jwk := range jwks.Keys {
+				key, err := jwk.PublicKey()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to read the %s key: %v", failed, testID, jwk.KeyID, err)
+				}
+				if !key.(equaler).Equal(signers[jwk.KeyID].Public()) {
+					t.Fatalf("\t%s\tTest %d:\tShould read back the same %s key.", failed, testID, jwk.KeyID)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould read back the same keys.", success, testID)
+
+			bad := jwks.Keys[0]
+			bad.KeyType = "oct"
+			if _, err := bad.PublicKey(); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT read a key of an unsupported type.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT read a key of an unsupported type.", success, testID)
+		}
+	}
+}
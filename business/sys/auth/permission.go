@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// These are the permissions the service checks. Roles defined by a tenant
+// are sets of permissions, and ADMIN is granted every permission.
+const (
+	PermUsersRead     = "users:read"
+	PermUsersWrite    = "users:write"
+	PermRolesManage   = "roles:manage"
+	PermReportsRead   = "reports:read"
+	PermProjectsWrite = "projects:write"
+	PermAPIKeysManage = "apikeys:manage"
+)
+
+// Permissions lists every permission the service checks.
+var Permissions = []string{
+	PermUsersRead,
+	PermUsersWrite,
+	PermRolesManage,
+	PermReportsRead,
+	PermProjectsWrite,
+	PermAPIKeysManage,
+}
+
+// PermissionLookup declares a method set of behavior for looking up the
+// permissions granted by a set of roles in the tenant the context is scoped
+// to.
+type PermissionLookup interface {
+	Permissions(ctx context.Context, roles []string) ([]string, error)
+}
+
+// permissionTTL is how long the permissions of a set of roles are trusted
+// before the lookup is asked again. Changes made to a role through another
+// instance of the service take at most this long to apply.
+const permissionTTL = 30 * time.Second
+
+// permissionEntry is the cached answer for a set of roles.
+type permissionEntry struct {
+	permissions []string
+	until       time.Time
+}
+
+// permissions caches the answers of a PermissionLookup in process so every
+// request doesn't have to go to the database.
+type permissions struct {
+	lookup  PermissionLookup
+	mu      sync.Mutex
+	entries map[string]permissionEntry
+}
+
+// SetPermissionLookup has the permissions granted by the roles of a token
+// resolved with the lookup in ResolvePermissions.
+func (a *Auth) SetPermissionLookup(lookup PermissionLookup) {
+	a.permissions = &permissions{
+		lookup:  lookup,
+		entries: make(map[string]permissionEntry),
+	}
+}
+
+// ResolvePermissions returns the claims with the permissions granted by
+// their roles set. Permissions are never carried in a token so changes to a
+// role apply to tokens already issued.
+func (a *Auth) ResolvePermissions(ctx context.Context, claims Claims) (Claims, error) {
+	if a.permissions == nil || len(claims.Roles) == 0 {
+		return claims, nil
+	}
+
+	p := a.permissions
+	now := time.Now()
+	key := permissionKey(claims.TenantID, claims.Roles)
+
+	p.mu.Lock()
+	entry, exists := p.entries[key]
+	p.mu.Unlock()
+
+	if !exists || !now.Before(entry.until) {
+		perms, err := p.lookup.Permissions(ctx, claims.Roles)
+		if err != nil {
+			return Claims{}, err
+		}
+
+		entry = permissionEntry{permissions: perms, until: now.Add(permissionTTL)}
+		p.store(key, entry, now)
+	}
+
+	claims.Permissions = entry.permissions
+	return claims, nil
+}
+
+// ForgetPermissions drops the cached permissions of the tenant so changes
+// made to its roles apply on this instance right away.
+func (a *Auth) ForgetPermissions(tenantID string) {
+	if a.permissions == nil {
+		return
+	}
+
+	p := a.permissions
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for k := range p.entries {
+		if strings.HasPrefix(k, tenantID+"|") {
+			delete(p.entries, k)
+		}
+	}
+}
+
+// store caches the entry, dropping the entries that are out of date.
+func (p *permissions) store(key string, entry permissionEntry, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for k, v := range p.entries {
+		if !now.Before(v.until) {
+			delete(p.entries, k)
+		}
+	}
+
+	p.entries[key] = entry
+}
+
+// permissionKey returns the cache key for the roles in the tenant. The
+// order the roles are listed in doesn't matter.
+func permissionKey(tenantID string, roles []string) string {
+	sorted := append([]string(nil), roles...)
+	sort.Strings(sorted)
+
+	return tenantID + "|" + strings.Join(sorted, ",")
+}
@@ -0,0 +1,88 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+)
+
+func TestPermissions(t *testing.T) {
+	t.Log("Given the need to authorize requests with the permissions of their roles.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen resolving the permissions of a token's roles.", testID)
+		{
+			const keyID = "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a private key: %v", failed, testID, err)
+			}
+
+			a, err := auth.New(keyID, &keyStore{pk: privateKey})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+
+			lookup := &roleList{
+				roles: map[string][]string{"AUDITOR": {auth.PermUsersRead}},
+			}
+			a.SetPermissionLookup(lookup)
+
+			claims := auth.Claims{
+				TenantID: "0a4dc3f4-43c4-4c0b-9a0f-6e3b1a0c9d7e",
+				Roles:    []string{auth.RoleUser, "AUDITOR"},
+			}
+
+			for i := 0; i < 2; i++ {
+				claims, err = a.ResolvePermissions(context.Background(), claims)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to resolve permissions: %v", failed, testID, err)
+				}
+			}
+			if lookup.calls != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould cache the permissions: %d lookups.", failed, testID, lookup.calls)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to resolve and cache permissions.", success, testID)
+
+			if !claims.HasPermission(auth.PermUsersRead) || claims.HasPermission(auth.PermUsersRead, auth.PermUsersWrite) {
+				t.Fatalf("\t%s\tTest %d:\tShould be granted only the role's permissions: %v", failed, testID, claims.Permissions)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be granted only the role's permissions.", success, testID)
+
+			lookup.roles["AUDITOR"] = append(lookup.roles["AUDITOR"], auth.PermUsersWrite)
+			a.ForgetPermissions(claims.TenantID)
+
+			claims, err = a.ResolvePermissions(context.Background(), claims)
+			if err != nil || !claims.HasPermission(auth.PermUsersWrite) {
+				t.Fatalf("\t%s\tTest %d:\tShould see a role change once the tenant is forgotten: %v %v", failed, testID, err, claims.Permissions)
+			}
+			t.Logf("\t%s\tTest %d:\tShould see a role change once the tenant is forgotten.", success, testID)
+
+			admin := auth.Claims{Roles: []string{auth.RoleAdmin}}
+			if !admin.HasPermission(auth.PermRolesManage, auth.PermProjectsWrite) {
+				t.Fatalf("\t%s\tTest %d:\tShould grant ADMIN every permission.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould grant ADMIN every permission.", success, testID)
+		}
+	}
+}
+
+// =============================================================================
+
+type roleList struct {
+	roles map[string][]string
+	calls int
+}
+
+func (rl *roleList) Permissions(ctx context.Context, roles []string) ([]string, error) {
+	rl.calls++
+
+	var perms []string
+	for _, r := range roles {
+		perms = append(perms, rl.roles[r]...)
+	}
+	return perms, nil
+}
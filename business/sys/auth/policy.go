@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Set of error variables for the reasons a token is refused.
+var (
+	ErrMalformed   = errors.New("token is malformed")
+	ErrSignature   = errors.New("token signature is invalid")
+	ErrExpired     = errors.New("token is expired")
+	ErrNotYetValid = errors.New("token is not valid yet")
+	ErrIssuer      = errors.New("token issuer is not accepted")
+	ErrAudience    = errors.New("token audience is not accepted")
+)
+
+// defaultTTL is how long tokens last when the policy doesn't say.
+const defaultTTL = time.Hour
+
+// Policy decides the registered claims of the tokens issued and which tokens
+// are accepted. Tokens are issued by Issuer for Audience and, when those are
+// set, only tokens issued by and for them are accepted so a token meant for
+// another service sharing our keys is refused. Tokens last for the shortest
+// RoleTTL of the roles they carry, or TTL when none of them has one. The
+// clocks of the services issuing and accepting tokens can be Leeway apart.
+type Policy struct {
+	Issuer   string
+	Audience string
+	TTL      time.Duration
+	RoleTTL  map[string]time.Duration
+	Leeway   time.Duration
+}
+
+// Stamp sets the issuer, audience and the times the claims are valid
+// between for a token issued at now.
+func (p Policy) Stamp(claims Claims, now time.Time) Claims {
+	claims.Issuer = p.Issuer
+	claims.Audience = p.Audience
+	claims.IssuedAt = now.Unix()
+	claims.NotBefore = now.Unix()
+	claims.ExpiresAt = now.Add(p.ttl(claims.Roles)).Unix()
+
+	return claims
+}
+
+// Check verifies the claims are accepted at now.
+func (p Policy) Check(claims Claims, now time.Time) error {
+	if claims.ExpiresAt == 0 {
+		return fmt.Errorf("missing expiry: %w", ErrMalformed)
+	}
+
+	switch {
+	case !claims.VerifyExpiresAt(now.Add(-p.Leeway).Unix(), true):
+		return ErrExpired
+	case !claims.VerifyNotBefore(now.Add(p.Leeway).Unix(), false):
+		return ErrNotYetValid
+	case !claims.VerifyIssuedAt(now.Add(p.Leeway).Unix(), false):
+		return ErrNotYetValid
+	case p.Issuer != "" && !claims.VerifyIssuer(p.Issuer, true):
+		return ErrIssuer
+	case p.Audience != "" && !claims.VerifyAudience(p.Audience, true):
+		return ErrAudience
+	}
+
+	return nil
+}
+
+// ttl returns how long a token carrying the roles lasts. The shortest TTL
+// of the roles wins so a token lasts no longer than its most privileged role
+// allows.
+func (p Policy) ttl(roles []string) time.Duration {
+	var ttl time.Duration
+	for _, role := range roles {
+		if d := p.RoleTTL[role]; d > 0 && (ttl == 0 || d < ttl) {
+			ttl = d
+		}
+	}
+
+	switch {
+	case ttl > 0:
+		return ttl
+	case p.TTL > 0:
+		return p.TTL
+	}
+
+	return defaultTTL
+}
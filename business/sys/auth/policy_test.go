@@ -0,0 +1,124 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestPolicy(t *testing.T) {
+	t.Log("Given the need to only accept tokens issued by and for this service.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen validating tokens under a policy.", testID)
+		{
+			const keyID = "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a private key: %v", failed, testID, err)
+			}
+
+			a, err := auth.New(keyID, &keyStore{pk: privateKey})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+
+			policy := auth.Policy{
+				Issuer:   "https://erp.example.com",
+				Audience: "sales-api",
+				TTL:      time.Hour,
+				RoleTTL:  map[string]time.Duration{auth.RoleAdmin: 15 * time.Minute},
+				Leeway:   30 * time.Second,
+			}
+			a.SetPolicy(policy)
+
+			now := time.Now()
+			claims := auth.Claims{
+				StandardClaims: jwt.StandardClaims{Subject: "5cf37266-3473-4006-984f-9325122678b7"},
+				Roles:          []string{auth.RoleUser},
+			}
+
+			user := policy.Stamp(claims, now)
+			if user.Issuer != policy.Issuer || user.Audience != policy.Audience || user.ExpiresAt != now.Add(time.Hour).Unix() {
+				t.Fatalf("\t%s\tTest %d:\tShould stamp the claims with the policy: %+v", failed, testID, user.StandardClaims)
+			}
+			t.Logf("\t%s\tTest %d:\tShould stamp the claims with the policy.", success, testID)
+
+			claims.Roles = []string{auth.RoleAdmin, auth.RoleUser}
+			admin := policy.Stamp(claims, now)
+			if admin.ExpiresAt != now.Add(15*time.Minute).Unix() {
+				t.Fatalf("\t%s\tTest %d:\tShould give the shortest lifetime of the roles: %v", failed, testID, time.Unix(admin.ExpiresAt, 0).Sub(now))
+			}
+			t.Logf("\t%s\tTest %d:\tShould give the shortest lifetime of the roles.", success, testID)
+
+			token, err := a.GenerateToken(user)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a token: %v", failed, testID, err)
+			}
+			if _, err := a.ValidateToken(token); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept a token issued under the policy: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept a token issued under the policy.", success, testID)
+
+			skewed := user
+			skewed.NotBefore = now.Add(10 * time.Second).Unix()
+			skewed.IssuedAt = skewed.NotBefore
+			token, err = a.GenerateToken(skewed)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a token: %v", failed, testID, err)
+			}
+			if _, err := a.ValidateToken(token); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept a token from a clock within the leeway: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept a token from a clock within the leeway.", success, testID)
+
+			tests := []struct {
+				name   string
+				modify func(c *auth.Claims)
+				err    error
+			}{
+				{"another issuer", func(c *auth.Claims) { c.Issuer = "service project" }, auth.ErrIssuer},
+				{"another audience", func(c *auth.Claims) { c.Audience = "billing-api" }, auth.ErrAudience},
+				{"an expired token", func(c *auth.Claims) { c.ExpiresAt = now.Add(-time.Minute).Unix() }, auth.ErrExpired},
+				{"a token that isn't valid yet", func(c *auth.Claims) { c.NotBefore = now.Add(time.Minute).Unix() }, auth.ErrNotYetValid},
+			}
+			for _, tt := range tests {
+				bad := user
+				tt.modify(&bad)
+
+				token, err := a.GenerateToken(bad)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to generate a token: %v", failed, testID, err)
+				}
+				if _, err := a.ValidateToken(token); !errors.Is(err, tt.err) {
+					t.Fatalf("\t%s\tTest %d:\tShould NOT accept %s: %v", failed, testID, tt.name, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould NOT accept %s.", success, testID, tt.name)
+			}
+
+			token, err = a.GenerateToken(user)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a token: %v", failed, testID, err)
+			}
+			if _, err := a.ValidateToken(token[:len(token)-4] + "AAAA"); !errors.Is(err, auth.ErrSignature) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept a token with a bad signature: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT accept a token with a bad signature.", success, testID)
+
+			if _, err := a.ValidateToken("not.a.token"); !errors.Is(err, auth.ErrMalformed) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept a malformed token: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT accept a malformed token.", success, testID)
+
+			if _, err := a.GenerateToken(claims); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT generate a token that never expires.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT generate a token that never expires.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RevocationLookup declares a method set of behavior for looking up whether
+// a token was revoked before it expired.
+type RevocationLookup interface {
+	Revoked(ctx context.Context, jti string) (bool, error)
+}
+
+// revocationTTL is how long a token found not to be revoked is trusted
+// before the lookup is asked again. Tokens revoked by another instance of
+// the service are accepted for at most this long.
+const revocationTTL = 30 * time.Second
+
+// revocationEntry is the cached answer for a token.
+type revocationEntry struct {
+	revoked bool
+	until   time.Time
+}
+
+// revocations caches the answers of a RevocationLookup in process so every
+// request doesn't have to go to the database. Revoked tokens are remembered
+// until they expire since they can never become valid again.
+type revocations struct {
+	lookup  RevocationLookup
+	mu      sync.Mutex
+	entries map[string]revocationEntry
+	swept   time.Time
+}
+
+// SetRevocationLookup has tokens checked against the lookup when they are
+// validated with Revoked.
+func (a *Auth) SetRevocationLookup(lookup RevocationLookup) {
+	a.revocations = &revocations{
+		lookup:  lookup,
+		entries: make(map[string]revocationEntry),
+	}
+}
+
+// Revoked reports whether the token the claims were read from was revoked.
+// Tokens without an id can't be revoked.
+func (a *Auth) Revoked(ctx context.Context, claims Claims) (bool, error) {
+	if a.revocations == nil || claims.Id == "" {
+		return false, nil
+	}
+
+	r := a.revocations
+	now := time.Now()
+
+	r.mu.Lock()
+	entry, exists := r.entries[claims.Id]
+	r.mu.Unlock()
+
+	if exists && now.Before(entry.until) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := r.lookup.Revoked(ctx, claims.Id)
+	if err != nil {
+		return false, err
+	}
+
+	until := now.Add(revocationTTL)
+	if revoked {
+		until = time.Unix(claims.ExpiresAt, 0)
+	}
+	r.store(claims.Id, revocationEntry{revoked: revoked, until: until}, now)
+
+	return revoked, nil
+}
+
+// Revoke records in process that the token was revoked so this instance
+// stops accepting it right away. Expires is when the token expires.
+func (a *Auth) Revoke(jti string, expires time.Time) {
+	if a.revocations == nil {
+		return
+	}
+
+	a.revocations.store(jti, revocationEntry{revoked: true, until: expires}, time.Now())
+}
+
+// store caches the entry, dropping the entries that are out of date at most
+// once per TTL so the cache doesn't grow without bound.
+func (r *revocations) store(jti string, entry revocationEntry, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.swept) > revocationTTL {
+		for k, v := range r.entries {
+			if !now.Before(v.until) {
+				delete(r.entries, k)
+			}
+		}
+		r.swept = now
+	}
+
+	r.entries[jti] = entry
+}
@@ -0,0 +1,84 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestRevoked(t *testing.T) {
+	t.Log("Given the need to reject tokens revoked before they expire.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen checking tokens against a revocation list.", testID)
+		{
+			const keyID = "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"
+			privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a private key: %v", failed, testID, err)
+			}
+
+			a, err := auth.New(keyID, &keyStore{pk: privateKey})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+
+			claims := auth.Claims{
+				StandardClaims: jwt.StandardClaims{
+					Id:        "e7b1d1f0-1a52-4c0e-8c43-2d9d3f1b5a10",
+					ExpiresAt: time.Now().Add(time.Hour).Unix(),
+				},
+			}
+
+			if revoked, err := a.Revoked(context.Background(), claims); err != nil || revoked {
+				t.Fatalf("\t%s\tTest %d:\tShould accept tokens without a revocation list: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept tokens without a revocation list.", success, testID)
+
+			lookup := revocationList{revoked: make(map[string]bool), calls: make(map[string]int)}
+			a.SetRevocationLookup(lookup)
+
+			for i := 0; i < 2; i++ {
+				if revoked, err := a.Revoked(context.Background(), claims); err != nil || revoked {
+					t.Fatalf("\t%s\tTest %d:\tShould accept a token that isn't revoked: %v", failed, testID, err)
+				}
+			}
+			if lookup.calls[claims.Id] != 1 {
+				t.Fatalf("\t%s\tTest %d:\tShould cache the answer: %d lookups.", failed, testID, lookup.calls[claims.Id])
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept a token that isn't revoked and cache the answer.", success, testID)
+
+			a.Revoke(claims.Id, time.Unix(claims.ExpiresAt, 0))
+			if revoked, err := a.Revoked(context.Background(), claims); err != nil || !revoked {
+				t.Fatalf("\t%s\tTest %d:\tShould reject a token revoked in process right away: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject a token revoked in process right away.", success, testID)
+
+			other := claims
+			other.Id = "0b8f6c6e-4a0a-4f58-9f5e-6f4f3b2f1c0d"
+			lookup.revoked[other.Id] = true
+			if revoked, err := a.Revoked(context.Background(), other); err != nil || !revoked {
+				t.Fatalf("\t%s\tTest %d:\tShould reject a token on the revocation list: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject a token on the revocation list.", success, testID)
+		}
+	}
+}
+
+// =============================================================================
+
+// revocationList is a RevocationLookup that counts the lookups made.
+type revocationList struct {
+	revoked map[string]bool
+	calls   map[string]int
+}
+
+func (rl revocationList) Revoked(ctx context.Context, jti string) (bool, error) {
+	rl.calls[jti]++
+	return rl.revoked[jti], nil
+}
@@ -0,0 +1,133 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/foundation/keystore"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestKeyRotation(t *testing.T) {
+	t.Log("Given the need to rotate signing keys without a restart.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a new key is added to the keys folder and activated.", testID)
+		{
+			const oldKID = "54bb2165-71e1-41a6-af3e-7da4a0e1e2c1"
+			const newKID = "8f6c2a3e-2f0e-4a8e-9c4b-6c1f3d2b7a90"
+
+			fsys := fstest.MapFS{
+				oldKID + ".pem": {Data: genPEM(t)},
+			}
+
+			ks, err := keystore.NewFS(fsys)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to load the keys folder: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to load the keys folder.", success, testID)
+
+			a, err := auth.New(oldKID, ks)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create an authenticator: %v", failed, testID, err)
+			}
+
+			claims := auth.Claims{
+				StandardClaims: jwt.StandardClaims{
+					Issuer:    "service project",
+					Subject:   "5cf37266-3473-4006-984f-9325122678b7",
+					ExpiresAt: time.Now().Add(time.Hour).Unix(),
+					IssuedAt:  time.Now().UTC().Unix(),
+				},
+				Roles: []string{auth.RoleUser},
+			}
+
+			oldToken, err := a.GenerateToken(claims)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT: %v", failed, testID, err)
+			}
+
+			if err := a.SetActiveKID(newKID); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not be able to activate a key that isn't loaded.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not be able to activate a key that isn't loaded.", success, testID)
+
+			// The old key is removed along with the new key being added so
+			// it has to be retired to keep verifying the tokens it signed.
+			fsys = fstest.MapFS{
+				newKID + ".pem":     {Data: genPEM(t)},
+				keystore.ActiveFile: {Data: []byte(newKID + "\n")},
+			}
+
+			if err := ks.Reload(fsys, time.Hour); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to reload the keys folder: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to reload the keys folder.", success, testID)
+
+			if exp, got := newKID, ks.ActiveKID(); exp != got {
+				t.Logf("\t\tTest %d:\texp: %s", testID, exp)
+				t.Logf("\t\tTest %d:\tgot: %s", testID, got)
+				t.Fatalf("\t%s\tTest %d:\tShould read the active kid from the folder.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould read the active kid from the folder.", success, testID)
+
+			if err := a.SetActiveKID(ks.ActiveKID()); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to activate the new key: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to activate the new key.", success, testID)
+
+			newToken, err := a.GenerateToken(claims)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a JWT with the new key: %v", failed, testID, err)
+			}
+
+			var parsed auth.Claims
+			tkn, _, err := new(jwt.Parser).ParseUnverified(newToken, &parsed)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to read the token header: %v", failed, testID, err)
+			}
+			if exp, got := newKID, tkn.Header["kid"]; exp != got {
+				t.Logf("\t\tTest %d:\texp: %v", testID, exp)
+				t.Logf("\t\tTest %d:\tgot: %v", testID, got)
+				t.Fatalf("\t%s\tTest %d:\tShould sign new tokens with the new key.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould sign new tokens with the new key.", success, testID)
+
+			if _, err := a.ValidateToken(oldToken); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould still validate tokens signed with the retired key: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould still validate tokens signed with the retired key.", success, testID)
+
+			if _, err := ks.PrivateKey(oldKID); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould not be able to sign with the retired key.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not be able to sign with the retired key.", success, testID)
+
+			if _, exists := ks.PublicKeys()[oldKID]; !exists {
+				t.Fatalf("\t%s\tTest %d:\tShould keep publishing the retired key.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep publishing the retired key.", success, testID)
+		}
+	}
+}
+
+// genPEM generates a private key encoded the way the keys folder holds them.
+func genPEM(t *testing.T) []byte {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	block := pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	}
+
+	return pem.EncodeToMemory(&block)
+}
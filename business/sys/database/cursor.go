@@ -0,0 +1,219 @@
+package database
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidCursor occurs when a cursor can't be decoded or was issued for a
+// different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Keyset describes the page of rows to read relative to a cursor. At most one
+// of After and Before is set; with neither the first page is read. Total asks
+// for the number of rows matching the filter to be counted as well.
+type Keyset struct {
+	After  string
+	Before string
+	Limit  int
+	Total  bool
+}
+
+// Page is the envelope every list is returned in. Next and Prev are opaque
+// cursors for the pages either side and are omitted at either end of the
+// list. Total is only set when it was asked for.
+type Page[T any] struct {
+	Items []T    `json:"items"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Total *int   `json:"total,omitempty"`
+}
+
+// cursor is the decoded form of a cursor. It holds the sort order it was
+// issued for along with the values of the row it points at.
+type cursor struct {
+	Order  []string `json:"o"`
+	Values []any    `json:"v"`
+}
+
+// Seek sets the sort order like OrderBy and restricts the rows to the page
+// the keyset describes. Rather than skipping rows with an OFFSET it seeks
+// past the row the cursor points at, so reading any page costs the same and
+// rows inserted or deleted between requests don't shift the pages.
+func (b *Builder) Seek(orders []OrderBy, tiebreak string, ks Keyset) *Builder {
+	b.OrderBy(orders, tiebreak)
+	if b.err != nil {
+		return b
+	}
+
+	if ks.Limit < 1 {
+		b.fail(fmt.Errorf("invalid limit[%d]", ks.Limit))
+		return b
+	}
+	if ks.After != "" && ks.Before != "" {
+		b.fail(fmt.Errorf("%w: only one of after and before may be provided", ErrInvalidCursor))
+		return b
+	}
+	b.keyset = ks
+
+	// Reading backwards walks the order in reverse and the rows are put back
+	// in order by NewPage.
+	token, backward := ks.After, false
+	if ks.Before != "" {
+		token, backward = ks.Before, true
+
+		for i, o := range b.orders {
+			b.order[i] = o.Column + " " + reverse(o.Direction)
+		}
+	}
+
+	if token != "" {
+		values, err := decodeCursor(token, b.orders)
+		if err != nil {
+			b.fail(err)
+			return b
+		}
+
+		// Rows past the cursor differ from it on some column, in the direction
+		// of that column, and match it on every column before.
+		// (a > :k0) OR (a = :k0 AND b > :k1) OR ...
+		var or []string
+		for i, o := range b.orders {
+			direction := o.Direction
+			if backward {
+				direction = reverse(direction)
+			}
+			op := ">"
+			if direction == DESC {
+				op = "<"
+			}
+
+			var and []string
+			for j := 0; j < i; j++ {
+				and = append(and, fmt.Sprintf("%s = :k%d", b.orders[j].Column, j))
+			}
+			and = append(and, fmt.Sprintf("%s %s :k%d", o.Column, op, i))
+			or = append(or, "("+strings.Join(and, " AND ")+")")
+
+			b.args[fmt.Sprintf("k%d", i)] = values[i]
+		}
+		b.seek = "(" + strings.Join(or, " OR ") + ")"
+	}
+
+	// One extra row is read to know if there is another page.
+	b.args["limit"] = ks.Limit + 1
+	b.page = "FETCH FIRST :limit ROWS ONLY"
+
+	return b
+}
+
+// NewPage wraps the rows read with a builder set up by Seek into a Page. The
+// value function returns a row's value for a column so the cursors for the
+// pages either side can be issued.
+func NewPage[T any](b *Builder, rows []T, value func(row T, column string) any) (Page[T], error) {
+	ks := b.keyset
+
+	more := len(rows) > ks.Limit
+	if more {
+		rows = rows[:ks.Limit]
+	}
+
+	hasNext, hasPrev := more, ks.After != ""
+	if ks.Before != "" {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+		hasNext, hasPrev = true, more
+	}
+
+	page := Page[T]{
+		Items: rows,
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if len(rows) == 0 {
+		return page, nil
+	}
+
+	var err error
+	if hasNext {
+		if page.Next, err = encodeCursor(b.orders, rows[len(rows)-1], value); err != nil {
+			return Page[T]{}, err
+		}
+	}
+	if hasPrev {
+		if page.Prev, err = encodeCursor(b.orders, rows[0], value); err != nil {
+			return Page[T]{}, err
+		}
+	}
+
+	return page, nil
+}
+
+// encodeCursor issues the cursor pointing at the row.
+func encodeCursor[T any](orders []OrderBy, row T, value func(row T, column string) any) (string, error) {
+	c := cursor{
+		Order:  order(orders),
+		Values: make([]any, len(orders)),
+	}
+	for i, o := range orders {
+		c.Values[i] = value(row, o.Column)
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("encoding cursor: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor returns the values held by the cursor, checking it was issued
+// for the same sort order.
+func decodeCursor(token string, orders []OrderBy) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	exp := order(orders)
+	if len(c.Order) != len(exp) || len(c.Values) != len(exp) {
+		return nil, fmt.Errorf("%w: issued for a different order", ErrInvalidCursor)
+	}
+	for i := range exp {
+		if c.Order[i] != exp[i] {
+			return nil, fmt.Errorf("%w: issued for a different order", ErrInvalidCursor)
+		}
+	}
+
+	return c.Values, nil
+}
+
+// order describes the sort order a cursor is issued for.
+func order(orders []OrderBy) []string {
+	desc := make([]string, len(orders))
+	for i, o := range orders {
+		desc[i] = o.Column + " " + o.Direction
+	}
+	return desc
+}
+
+// reverse returns the opposite direction.
+func reverse(direction string) string {
+	if direction == DESC {
+		return ASC
+	}
+	return DESC
+}
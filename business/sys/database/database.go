@@ -35,6 +35,10 @@ type Config struct {
 	MaxIdleConns int
 	MaxOpenConns int
 	DisableTLS   bool
+
+	// RowLevelSecurity turns on the row level security policies that enforce
+	// the caller's ownership and roles, as set with WithUser, in the database.
+	RowLevelSecurity bool
 }
 
 // Open knows how to open a database connection based on the configuration
@@ -47,6 +51,9 @@ func Open(cfg Config) (*sqlx.DB, error) {
 	q := make(url.Values)
 	q.Set("sslmode", sslMode)
 	q.Set("timezone", "utc")
+	if cfg.RowLevelSecurity {
+		q.Set("app.row_level_security", "on")
+	}
 
 	u := url.URL{
 		Scheme:   "postgres",
@@ -105,11 +112,10 @@ func NamedExecContext(ctx context.Context, log *zap.SugaredLogger, db *sqlx.DB,
 	span.SetAttributes(attribute.String("query", q))
 	defer span.End()
 
-	if _, err := db.NamedExecContext(ctx, query, data); err != nil {
+	return scoped(ctx, db, func(ext sqlx.ExtContext) error {
+		_, err := sqlx.NamedExecContext(ctx, ext, query, data)
 		return err
-	}
-
-	return nil
+	})
 }
 
 // NamedQueryStruct is a helper function for executing queries that return a
@@ -122,19 +128,18 @@ func NamedQueryStruct(ctx context.Context, log *zap.SugaredLogger, db *sqlx.DB,
 	span.SetAttributes(attribute.String("query", q))
 	defer span.End()
 
-	rows, err := db.NamedQueryContext(ctx, query, data)
-	if err != nil {
-		return err
-	}
-	if !rows.Next() {
-		return ErrNotFound
-	}
-
-	if err := rows.StructScan(dest); err != nil {
-		return err
-	}
+	return scoped(ctx, db, func(ext sqlx.ExtContext) error {
+		rows, err := sqlx.NamedQueryContext(ctx, ext, query, data)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		if !rows.Next() {
+			return ErrNotFound
+		}
 
-	return nil
+		return rows.StructScan(dest)
+	})
 }
 
 // NamedQuerySlice is a helper function for executing queries that return a
@@ -152,21 +157,24 @@ func NamedQuerySlice(ctx context.Context, log *zap.SugaredLogger, db *sqlx.DB, q
 		return errors.New("must provide a pointer to a slice")
 	}
 
-	rows, err := db.NamedQueryContext(ctx, query, data)
-	if err != nil {
-		return err
-	}
-
-	slice := val.Elem()
-	for rows.Next() {
-		v := reflect.New(slice.Type().Elem())
-		if err := rows.StructScan(v.Interface()); err != nil {
+	return scoped(ctx, db, func(ext sqlx.ExtContext) error {
+		rows, err := sqlx.NamedQueryContext(ctx, ext, query, data)
+		if err != nil {
 			return err
 		}
-		slice.Set(reflect.Append(slice, v.Elem()))
-	}
+		defer rows.Close()
+
+		slice := val.Elem()
+		for rows.Next() {
+			v := reflect.New(slice.Type().Elem())
+			if err := rows.StructScan(v.Interface()); err != nil {
+				return err
+			}
+			slice.Set(reflect.Append(slice, v.Elem()))
+		}
 
-	return nil
+		return rows.Err()
+	})
 }
 
 // queryString provides a pretty print version of the query and parameters.
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Set of directions rows can be ordered in.
+const (
+	ASC  = "ASC"
+	DESC = "DESC"
+)
+
+// ErrInvalidOrderBy occurs when a requested sort field or direction is not
+// allowed.
+var ErrInvalidOrderBy = errors.New("invalid order by")
+
+// column restricts the column names the builder will write into a query.
+var column = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+
+// OrderBy is a column and the direction to sort it in.
+type OrderBy struct {
+	Column    string
+	Direction string
+}
+
+// ParseOrderBy parses a comma separated list of fields to sort by. A field
+// may be prefixed with - or suffixed with :desc to sort in descending order,
+// e.g. "name,-date_created" or "name:asc,date_created:desc". Only the fields
+// in allowed may be used and each is translated to the column it maps to.
+// An empty value returns the defaults.
+func ParseOrderBy(value string, allowed map[string]string, defaults ...OrderBy) ([]OrderBy, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return defaults, nil
+	}
+
+	var orders []OrderBy
+	for _, part := range strings.Split(value, ",") {
+		field := strings.TrimSpace(part)
+		direction := ASC
+
+		switch {
+		case strings.HasPrefix(field, "-"):
+			field = field[1:]
+			direction = DESC
+		case strings.Contains(field, ":"):
+			var dir string
+			field, dir, _ = strings.Cut(field, ":")
+			switch strings.ToUpper(dir) {
+			case ASC:
+			case DESC:
+				direction = DESC
+			default:
+				return nil, fmt.Errorf("%w: unknown direction %q", ErrInvalidOrderBy, dir)
+			}
+		}
+
+		col, exists := allowed[field]
+		if !exists {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidOrderBy, field)
+		}
+
+		orders = append(orders, OrderBy{Column: col, Direction: direction})
+	}
+
+	return orders, nil
+}
+
+// =============================================================================
+
+// Builder assembles the WHERE, ORDER BY and paging clauses of a SELECT from
+// typed conditions. Values are always passed as named parameters so nothing
+// provided by a client is ever written into the SQL, and column names are
+// checked so only plain identifiers can be used.
+type Builder struct {
+	where  []string
+	seek   string
+	orders []OrderBy
+	order  []string
+	page   string
+	keyset Keyset
+	args   map[string]any
+	err    error
+}
+
+// NewBuilder constructs an empty builder.
+func NewBuilder() *Builder {
+	return &Builder{
+		args: make(map[string]any),
+	}
+}
+
+// Equal adds a condition that the column equals the value.
+func (b *Builder) Equal(col string, value any) *Builder {
+	return b.condition(col, "%s = :%s", value)
+}
+
+// Contains adds a case insensitive condition that the column contains the
+// text. Wildcard characters in the text are matched literally.
+func (b *Builder) Contains(col string, text string) *Builder {
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return b.condition(col, "%s ILIKE :%s", "%"+escaper.Replace(text)+"%")
+}
+
+// GreaterOrEqual adds a condition that the column is at or above the value.
+func (b *Builder) GreaterOrEqual(col string, value any) *Builder {
+	return b.condition(col, "%s >= :%s", value)
+}
+
+// Less adds a condition that the column is below the value.
+func (b *Builder) Less(col string, value any) *Builder {
+	return b.condition(col, "%s < :%s", value)
+}
+
+// ArrayContains adds a condition that the array column holds every value.
+func (b *Builder) ArrayContains(col string, values []string) *Builder {
+	return b.condition(col, "%s @> :%s", pq.StringArray(values))
+}
+
+// OrderBy sets the sort order. Rows are always ordered by tiebreak last so
+// paging is stable when the sort columns hold duplicate values.
+func (b *Builder) OrderBy(orders []OrderBy, tiebreak string) *Builder {
+	b.orders = b.orders[:0]
+	b.order = b.order[:0]
+
+	seen := make(map[string]bool)
+	for _, o := range append(orders, OrderBy{Column: tiebreak, Direction: ASC}) {
+		if !column.MatchString(o.Column) {
+			b.fail(fmt.Errorf("%w: column %q", ErrInvalidOrderBy, o.Column))
+			return b
+		}
+		if o.Direction != ASC && o.Direction != DESC {
+			b.fail(fmt.Errorf("%w: direction %q", ErrInvalidOrderBy, o.Direction))
+			return b
+		}
+		if seen[o.Column] {
+			continue
+		}
+		seen[o.Column] = true
+		b.orders = append(b.orders, o)
+		b.order = append(b.order, o.Column+" "+o.Direction)
+	}
+
+	return b
+}
+
+// Build appends the clauses to the base query and returns it with the named
+// parameters to execute it with.
+func (b *Builder) Build(base string) (string, map[string]any, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+
+	var q strings.Builder
+	q.WriteString(strings.TrimRight(base, " \t\n"))
+
+	where := b.where
+	if b.seek != "" {
+		where = append(where[:len(where):len(where)], b.seek)
+	}
+	if len(where) > 0 {
+		q.WriteString("\n\tWHERE\n\t\t")
+		q.WriteString(strings.Join(where, " AND\n\t\t"))
+	}
+	if len(b.order) > 0 {
+		q.WriteString("\n\tORDER BY\n\t\t")
+		q.WriteString(strings.Join(b.order, ", "))
+	}
+	if b.page != "" {
+		q.WriteString("\n\t")
+		q.WriteString(b.page)
+	}
+
+	return q.String(), b.args, nil
+}
+
+// BuildCount appends only the WHERE conditions to the base query, ignoring
+// any cursor, order and limit, so it can count every row matching the filter.
+func (b *Builder) BuildCount(base string) (string, map[string]any, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+
+	var q strings.Builder
+	q.WriteString(strings.TrimRight(base, " \t\n"))
+
+	if len(b.where) > 0 {
+		q.WriteString("\n\tWHERE\n\t\t")
+		q.WriteString(strings.Join(b.where, " AND\n\t\t"))
+	}
+
+	return q.String(), b.args, nil
+}
+
+// condition adds a WHERE condition formatted with the column and the name
+// of the parameter holding the value.
+func (b *Builder) condition(col string, format string, value any) *Builder {
+	if !column.MatchString(col) {
+		b.fail(fmt.Errorf("invalid column %q", col))
+		return b
+	}
+
+	name := fmt.Sprintf("w%d", len(b.where))
+	b.args[name] = value
+	b.where = append(b.where, fmt.Sprintf(format, col, name))
+
+	return b
+}
+
+// fail records the first error so it can be returned by Build.
+func (b *Builder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
@@ -0,0 +1,139 @@
+package database_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestQuery(t *testing.T) {
+	allowed := map[string]string{
+		"id":   "user_id",
+		"name": "name",
+	}
+
+	t.Log("Given the need to build list queries from client provided values.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen parsing the fields to sort by.", testID)
+		{
+			orders, err := database.ParseOrderBy("name,-id", allowed)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to parse the fields: %v", failed, testID, err)
+			}
+			exp := []database.OrderBy{{Column: "name", Direction: database.ASC}, {Column: "user_id", Direction: database.DESC}}
+			if len(orders) != len(exp) || orders[0] != exp[0] || orders[1] != exp[1] {
+				t.Fatalf("\t%s\tTest %d:\tShould map the fields to their columns: %+v", failed, testID, orders)
+			}
+			t.Logf("\t%s\tTest %d:\tShould map the fields to their columns.", success, testID)
+
+			if orders, _ := database.ParseOrderBy("name:desc", allowed); len(orders) != 1 || orders[0].Direction != database.DESC {
+				t.Fatalf("\t%s\tTest %d:\tShould accept a direction suffix: %+v", failed, testID, orders)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept a direction suffix.", success, testID)
+
+			for _, v := range []string{"password_hash", "name;drop table users", "name:sideways"} {
+				if _, err := database.ParseOrderBy(v, allowed); !errors.Is(err, database.ErrInvalidOrderBy) {
+					t.Fatalf("\t%s\tTest %d:\tShould NOT be able to sort by %q: %v", failed, testID, v, err)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to sort by fields outside the allow list.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen building a filtered and sorted page.", testID)
+		{
+			orders := []database.OrderBy{{Column: "name", Direction: database.DESC}}
+			q, args, err := database.NewBuilder().
+				Contains("name", "50%_off").
+				ArrayContains("roles", []string{"ADMIN"}).
+				Seek(orders, "user_id", database.Keyset{Limit: 10}).
+				Build("SELECT * FROM users")
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to build the query: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to build the query.", success, testID)
+
+			for _, want := range []string{"name ILIKE :w0", "roles @> :w1", "ORDER BY\n\t\tname DESC, user_id ASC", "FETCH FIRST :limit ROWS ONLY"} {
+				if !strings.Contains(q, want) {
+					t.Fatalf("\t%s\tTest %d:\tShould contain %q in:\n%s", failed, testID, want, q)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould write the conditions, order and page.", success, testID)
+
+			if args["w0"] != `%50\%\_off%` || args["limit"] != 11 {
+				t.Fatalf("\t%s\tTest %d:\tShould pass the values as parameters: %v", failed, testID, args)
+			}
+			t.Logf("\t%s\tTest %d:\tShould pass the values as escaped parameters.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen paging with cursors.", testID)
+		{
+			type row struct {
+				id   string
+				name string
+			}
+			value := func(r row, column string) any {
+				if column == "name" {
+					return r.name
+				}
+				return r.id
+			}
+			orders := []database.OrderBy{{Column: "name", Direction: database.DESC}}
+
+			b := database.NewBuilder().Seek(orders, "user_id", database.Keyset{Limit: 2})
+			page, err := database.NewPage(b, []row{{"3", "c"}, {"2", "b"}, {"1", "a"}}, value)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to build the first page: %v", failed, testID, err)
+			}
+			if len(page.Items) != 2 || page.Next == "" || page.Prev != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould trim the extra row and only have a next cursor: %+v", failed, testID, page)
+			}
+			t.Logf("\t%s\tTest %d:\tShould trim the extra row and only have a next cursor.", success, testID)
+
+			q, args, err := database.NewBuilder().Seek(orders, "user_id", database.Keyset{After: page.Next, Limit: 2}).Build("SELECT * FROM users")
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to seek past the cursor: %v", failed, testID, err)
+			}
+			if !strings.Contains(q, "((name < :k0) OR (name = :k0 AND user_id > :k1))") || args["k0"] != "b" || args["k1"] != "2" {
+				t.Fatalf("\t%s\tTest %d:\tShould seek past the last row:\n%s\n%v", failed, testID, q, args)
+			}
+			t.Logf("\t%s\tTest %d:\tShould seek past the last row.", success, testID)
+
+			b = database.NewBuilder().Seek(orders, "user_id", database.Keyset{Before: page.Next, Limit: 2})
+			q, _, _ = b.Build("SELECT * FROM users")
+			if !strings.Contains(q, "(name > :k0)") || !strings.Contains(q, "name ASC, user_id DESC") {
+				t.Fatalf("\t%s\tTest %d:\tShould walk backwards from the cursor:\n%s", failed, testID, q)
+			}
+			page, _ = database.NewPage(b, []row{{"3", "c"}}, value)
+			if page.Items[0].id != "3" || page.Next == "" || page.Prev != "" {
+				t.Fatalf("\t%s\tTest %d:\tShould have only a next cursor on the first page: %+v", failed, testID, page)
+			}
+			t.Logf("\t%s\tTest %d:\tShould walk backwards from the cursor.", success, testID)
+
+			other := []database.OrderBy{{Column: "name", Direction: database.ASC}}
+			if _, _, err := database.NewBuilder().Seek(other, "user_id", database.Keyset{After: page.Next, Limit: 2}).Build("SELECT * FROM users"); !errors.Is(err, database.ErrInvalidCursor) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept a cursor for another order: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT accept a cursor for another order.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen given an unsafe column.", testID)
+		{
+			if _, _, err := database.NewBuilder().Equal("name = name OR 1", 1).Build("SELECT * FROM users"); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT be able to build the query.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT be able to build the query.", success, testID)
+		}
+	}
+}
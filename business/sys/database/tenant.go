@@ -0,0 +1,117 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrNoTenant occurs when a query is run with a context that names neither a
+// tenant nor the system.
+var ErrNoTenant = errors.New("tenant missing from context")
+
+// tenantRole is the database role tenant queries run as. The row level
+// security policies on every business table only let it see and write the
+// rows of the tenant named by the app.tenant_id setting.
+const tenantRole = "erp_tenant"
+
+// ctxKey represents the type of value for the context key.
+type ctxKey int
+
+// scopeKey is used to store/retrieve a scope value from a context.Context.
+const scopeKey ctxKey = 1
+
+// scope describes whose rows the queries run with a context can reach.
+type scope struct {
+	tenantID    string
+	userID      string
+	roles       []string
+	permissions []string
+	system      bool
+}
+
+// WithTenant returns a context whose queries only see and create the rows
+// that belong to the tenant.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, scopeKey, scope{tenantID: tenantID})
+}
+
+// WithSystem returns a context whose queries reach the rows of every tenant.
+// It is for work done on behalf of every tenant, such as authenticating a
+// user before their tenant is known or delivering subscriptions, and must
+// never be used to serve a tenant's request.
+func WithSystem(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scopeKey, scope{system: true})
+}
+
+// WithUser returns a context whose queries are made on behalf of the user.
+// The tenant the context is scoped to is kept. When the connection was opened
+// with RowLevelSecurity the policies only let the user reach the rows their
+// ownership and roles allow.
+func WithUser(ctx context.Context, userID string, roles []string) context.Context {
+	s, _ := ctx.Value(scopeKey).(scope)
+	s.userID = userID
+	s.roles = roles
+	s.permissions = nil
+	return context.WithValue(ctx, scopeKey, s)
+}
+
+// WithPermissions returns a context whose queries are made with the
+// permissions the roles of the user set with WithUser grant. The policies
+// check these for the rows the user doesn't own.
+func WithPermissions(ctx context.Context, permissions []string) context.Context {
+	s, _ := ctx.Value(scopeKey).(scope)
+	s.permissions = permissions
+	return context.WithValue(ctx, scopeKey, s)
+}
+
+// GetTenant returns the tenant the context is scoped to.
+func GetTenant(ctx context.Context) (string, error) {
+	s, ok := ctx.Value(scopeKey).(scope)
+	if !ok || s.tenantID == "" {
+		return "", ErrNoTenant
+	}
+	return s.tenantID, nil
+}
+
+// scoped runs fn against the database scoped by the context. Tenant queries
+// run in a transaction that switches to the tenant role and sets the tenant,
+// so every statement is filtered by the row level security policies without
+// the query having to name the tenant. The user, their roles and permissions
+// are set too for the policies that enforce ownership and permissions. System
+// queries run as is.
+func scoped(ctx context.Context, db *sqlx.DB, fn func(sqlx.ExtContext) error) error {
+	s, ok := ctx.Value(scopeKey).(scope)
+	switch {
+	case !ok, !s.system && s.tenantID == "":
+		return ErrNoTenant
+	case s.system:
+		return fn(db)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const q = `
+	SELECT
+		set_config('role', $1, true),
+		set_config('app.tenant_id', $2, true),
+		set_config('app.user_id', $3, true),
+		set_config('app.roles', $4, true),
+		set_config('app.permissions', $5, true)`
+
+	if _, err := tx.ExecContext(ctx, q, tenantRole, s.tenantID, s.userID, strings.Join(s.roles, ","), strings.Join(s.permissions, ",")); err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
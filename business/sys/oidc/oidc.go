@@ -0,0 +1,393 @@
+// Package oidc validates the ID tokens of trusted external OpenID Connect
+// issuers, such as a company single sign on, so their users can sign in
+// without a password of ours.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Set of error variables for tokens that aren't accepted.
+var (
+	ErrUnknownIssuer = errors.New("token issuer is not trusted")
+	ErrInvalidToken  = errors.New("invalid token")
+)
+
+// leeway is how far the clocks of an issuer and ours can drift apart before
+// its tokens are refused as expired or not yet valid.
+const leeway = time.Minute
+
+// refetchAfter is how long after fetching an issuer's keys a token signed
+// with a key that isn't known causes them to be fetched again. Issuers
+// publish new keys before using them, so this is enough to follow rotations
+// without letting bad tokens hammer the issuer.
+const refetchAfter = time.Minute
+
+// Issuer is an external OpenID Connect provider whose ID tokens are trusted.
+// Tokens must be issued by URL for Audience and are verified with the keys
+// found at JWKS, which is either a URL or the path to a local file. The
+// users of the issuer belong to TenantID. The groups listed in GroupsClaim,
+// "groups" when not set, are mapped to our roles with Roles and every user
+// is given DefaultRoles.
+type Issuer struct {
+	URL          string              `json:"issuer"`
+	Audience     string              `json:"audience"`
+	JWKS         string              `json:"jwks"`
+	TenantID     string              `json:"tenant_id"`
+	GroupsClaim  string              `json:"groups_claim"`
+	Roles        map[string][]string `json:"roles"`
+	DefaultRoles []string            `json:"default_roles"`
+}
+
+// Identity is the user an external token was issued to.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	TenantID      string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+	Roles         []string
+	AMR           []string
+}
+
+// LoadIssuers reads the list of trusted issuers from a JSON file.
+func LoadIssuers(path string) ([]Issuer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading issuers: %w", err)
+	}
+
+	var issuers []Issuer
+	if err := json.Unmarshal(data, &issuers); err != nil {
+		return nil, fmt.Errorf("decoding issuers: %w", err)
+	}
+
+	return issuers, nil
+}
+
+// Verifier validates the tokens of a set of trusted issuers.
+type Verifier struct {
+	issuers map[string]*issuer
+	client  *http.Client
+	parser  jwt.Parser
+}
+
+// NewVerifier constructs a Verifier that trusts the issuers. Keys published
+// at a URL are fetched with the client, or a client with a short timeout
+// when it is nil.
+func NewVerifier(issuers []Issuer, client *http.Client) (*Verifier, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	v := Verifier{
+		issuers: make(map[string]*issuer, len(issuers)),
+		client:  client,
+		parser: jwt.Parser{
+			ValidMethods: []string{
+				"RS256", "RS384", "RS512",
+				"PS256", "PS384", "PS512",
+				"ES256", "ES384", "EdDSA",
+			},
+
+			// The claims are checked against the time of the request, along
+			// with the issuer and audience, once the signature is good.
+			SkipClaimsValidation: true,
+		},
+	}
+
+	for _, iss := range issuers {
+		switch {
+		case iss.URL == "":
+			return nil, errors.New("issuer URL must be set")
+		case iss.Audience == "":
+			return nil, fmt.Errorf("issuer[%s]: audience must be set", iss.URL)
+		case iss.JWKS == "":
+			return nil, fmt.Errorf("issuer[%s]: jwks must be set", iss.URL)
+		case iss.TenantID == "":
+			return nil, fmt.Errorf("issuer[%s]: tenant must be set", iss.URL)
+		}
+		if _, exists := v.issuers[iss.URL]; exists {
+			return nil, fmt.Errorf("issuer[%s]: listed more than once", iss.URL)
+		}
+		if iss.GroupsClaim == "" {
+			iss.GroupsClaim = "groups"
+		}
+		v.issuers[iss.URL] = &issuer{Issuer: iss}
+	}
+
+	return &v, nil
+}
+
+// Verify checks the token was signed by one of the trusted issuers for its
+// audience and is valid at now. It returns who the token was issued to
+// along with the roles their groups map to.
+func (v *Verifier) Verify(ctx context.Context, tokenStr string, now time.Time) (Identity, error) {
+	var unverified jwt.MapClaims
+	if _, _, err := v.parser.ParseUnverified(tokenStr, &unverified); err != nil {
+		return Identity{}, fmt.Errorf("parsing token: %v: %w", err, ErrInvalidToken)
+	}
+
+	issURL, _ := unverified["iss"].(string)
+	iss, ok := v.issuers[issURL]
+	if !ok {
+		return Identity{}, fmt.Errorf("issuer[%s]: %w", issURL, ErrUnknownIssuer)
+	}
+
+	keyFunc := func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, alg, err := iss.key(ctx, v.client, kid, now)
+		if err != nil {
+			return nil, err
+		}
+
+		// The algorithm is decided by the key, never by the token.
+		if !compatible(key, alg, t.Method.Alg()) {
+			return nil, fmt.Errorf("token algorithm %s does not match key", t.Method.Alg())
+		}
+
+		return key, nil
+	}
+
+	var claims jwt.MapClaims
+	if _, err := v.parser.ParseWithClaims(tokenStr, &claims, keyFunc); err != nil {
+		return Identity{}, fmt.Errorf("issuer[%s]: %v: %w", iss.URL, err, ErrInvalidToken)
+	}
+
+	if err := iss.validate(claims, now); err != nil {
+		return Identity{}, fmt.Errorf("issuer[%s]: %v: %w", iss.URL, err, ErrInvalidToken)
+	}
+
+	id := Identity{
+		Issuer:        iss.URL,
+		TenantID:      iss.TenantID,
+		Groups:        strs(claims[iss.GroupsClaim]),
+		AMR:           strs(claims["amr"]),
+		EmailVerified: verified(claims["email_verified"]),
+	}
+	id.Subject, _ = claims["sub"].(string)
+	id.Email, _ = claims["email"].(string)
+	id.Name, _ = claims["name"].(string)
+	id.Roles = iss.roles(id.Groups)
+
+	return id, nil
+}
+
+// =============================================================================
+
+// issuer is a trusted issuer along with the keys last fetched for it.
+type issuer struct {
+	Issuer
+
+	mu      sync.Mutex
+	keys    map[string]auth.JWK
+	fetched time.Time
+}
+
+// key returns the key with the kid along with the algorithm it was published
+// for. The keys are fetched when they haven't been yet and again when the
+// kid isn't known, so keys the issuer rotates to are picked up. A token
+// without a kid can only be verified when the issuer has a single key.
+func (iss *issuer) key(ctx context.Context, client *http.Client, kid string, now time.Time) (crypto.PublicKey, string, error) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+
+	jwk, ok := iss.lookup(kid)
+	if !ok && now.Sub(iss.fetched) >= refetchAfter {
+		// A failed fetch waits just as long before it's tried again.
+		iss.fetched = now
+		keys, err := fetch(ctx, client, iss.JWKS)
+		if err != nil {
+			return nil, "", fmt.Errorf("fetching keys: %w", err)
+		}
+		iss.keys = keys
+
+		jwk, ok = iss.lookup(kid)
+	}
+	if !ok {
+		return nil, "", fmt.Errorf("key %q not found", kid)
+	}
+
+	key, err := jwk.PublicKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("kid[%s]: %w", kid, err)
+	}
+
+	return key, jwk.Algorithm, nil
+}
+
+// lookup finds the key with the kid in the keys last fetched.
+func (iss *issuer) lookup(kid string) (auth.JWK, bool) {
+	if kid == "" {
+		if len(iss.keys) != 1 {
+			return auth.JWK{}, false
+		}
+		for _, jwk := range iss.keys {
+			return jwk, true
+		}
+	}
+
+	jwk, ok := iss.keys[kid]
+	return jwk, ok
+}
+
+// validate checks the claims were issued by the issuer for its audience to
+// a subject and are valid at now.
+func (iss *issuer) validate(claims jwt.MapClaims, now time.Time) error {
+	switch {
+	case !claims.VerifyIssuer(iss.URL, true):
+		return errors.New("wrong issuer")
+	case !claims.VerifyAudience(iss.Audience, true):
+		return errors.New("wrong audience")
+	case !claims.VerifyExpiresAt(now.Add(-leeway).Unix(), true):
+		return errors.New("token is expired")
+	case !claims.VerifyNotBefore(now.Add(leeway).Unix(), false):
+		return errors.New("token is not valid yet")
+	case !claims.VerifyIssuedAt(now.Add(leeway).Unix(), false):
+		return errors.New("token used before issued")
+	}
+
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return errors.New("missing subject")
+	}
+
+	return nil
+}
+
+// roles returns the roles the groups map to along with the default roles,
+// sorted and without duplicates.
+func (iss *issuer) roles(groups []string) []string {
+	seen := make(map[string]bool)
+	roles := []string{}
+	add := func(rs []string) {
+		for _, r := range rs {
+			if !seen[r] {
+				seen[r] = true
+				roles = append(roles, r)
+			}
+		}
+	}
+
+	add(iss.DefaultRoles)
+	for _, group := range groups {
+		add(iss.Roles[group])
+	}
+	sort.Strings(roles)
+
+	return roles
+}
+
+// fetch reads the signing keys of a key set from a URL or a local file.
+func fetch(ctx context.Context, client *http.Client, source string) (map[string]auth.JWK, error) {
+	var data []byte
+	switch {
+	case strings.HasPrefix(source, "https://"), strings.HasPrefix(source, "http://"):
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("status %d", resp.StatusCode)
+		}
+		if data, err = io.ReadAll(io.LimitReader(resp.Body, 1<<20)); err != nil {
+			return nil, err
+		}
+
+	default:
+		var err error
+		if data, err = os.ReadFile(source); err != nil {
+			return nil, err
+		}
+	}
+
+	var jwks auth.JWKS
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("decoding keys: %w", err)
+	}
+
+	keys := make(map[string]auth.JWK, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use == "" || jwk.Use == "sig" {
+			keys[jwk.KeyID] = jwk
+		}
+	}
+
+	return keys, nil
+}
+
+// compatible reports whether a token signed with the algorithm can be
+// verified with the key. When the key was published for an algorithm only
+// that algorithm is allowed.
+func compatible(key crypto.PublicKey, keyAlg string, tokenAlg string) bool {
+	if keyAlg != "" && keyAlg != tokenAlg {
+		return false
+	}
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(tokenAlg, "RS") || strings.HasPrefix(tokenAlg, "PS")
+	case *ecdsa.PublicKey:
+		return tokenAlg == "ES"+strings.TrimPrefix(key.Curve.Params().Name, "P-")
+	case ed25519.PublicKey:
+		return tokenAlg == "EdDSA"
+	}
+
+	return false
+}
+
+// strs reads a claim that holds a list of strings. A single string is
+// treated as a list of one.
+func strs(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		list := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+
+	return nil
+}
+
+// verified reads the email_verified claim, which some issuers send as a
+// string.
+func verified(claim any) bool {
+	switch v := claim.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+
+	return false
+}
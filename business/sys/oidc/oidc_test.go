@@ -0,0 +1,225 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/oidc"
+	"github.com/deliveranceTechSolutions/erp/foundation/keystore"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+const (
+	issuerURL = "https://sso.example.com"
+	audience  = "erp"
+	tenantID  = "45b5fbd3-755f-4379-8f07-a58d4a30fa2f"
+)
+
+func TestVerify(t *testing.T) {
+	t.Log("Given the need to accept the tokens of a trusted external issuer.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the issuer's keys are read from a local file.", testID)
+		{
+			ctx := context.Background()
+			now := time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a key: %v", failed, testID, err)
+			}
+			jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+			writeJWKS(t, jwksFile, map[string]crypto.Signer{"k1": key})
+
+			v, err := oidc.NewVerifier([]oidc.Issuer{
+				{
+					URL:          issuerURL,
+					Audience:     audience,
+					JWKS:         jwksFile,
+					TenantID:     tenantID,
+					Roles:        map[string][]string{"erp-admins": {auth.RoleAdmin}},
+					DefaultRoles: []string{auth.RoleUser},
+				},
+			}, nil)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a verifier: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to create a verifier.", success, testID)
+
+			claims := jwt.MapClaims{
+				"iss":            issuerURL,
+				"aud":            []string{audience, "other"},
+				"sub":            "248289761001",
+				"email":          "jane@example.com",
+				"email_verified": true,
+				"name":           "Jane Doe",
+				"groups":         []string{"erp-admins", "engineering"},
+				"amr":            []string{"pwd", "mfa"},
+				"iat":            now.Unix(),
+				"exp":            now.Add(time.Hour).Unix(),
+			}
+
+			id, err := v.Verify(ctx, sign(t, key, "k1", claims), now)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept a token of the issuer: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept a token of the issuer.", success, testID)
+
+			exp := oidc.Identity{
+				Issuer:        issuerURL,
+				Subject:       "248289761001",
+				TenantID:      tenantID,
+				Email:         "jane@example.com",
+				EmailVerified: true,
+				Name:          "Jane Doe",
+				Groups:        []string{"erp-admins", "engineering"},
+				Roles:         []string{auth.RoleAdmin, auth.RoleUser},
+				AMR:           []string{"pwd", "mfa"},
+			}
+			if !reflect.DeepEqual(id, exp) {
+				t.Fatalf("\t%s\tTest %d:\tShould map the token to an identity: got %+v, exp %+v", failed, testID, id, exp)
+			}
+			t.Logf("\t%s\tTest %d:\tShould map the token to an identity.", success, testID)
+
+			tests := []struct {
+				name  string
+				claim string
+				value any
+				err   error
+			}{
+				{"another audience", "aud", "other", oidc.ErrInvalidToken},
+				{"an expired token", "exp", now.Add(-2 * time.Minute).Unix(), oidc.ErrInvalidToken},
+				{"a token that isn't valid yet", "nbf", now.Add(2 * time.Minute).Unix(), oidc.ErrInvalidToken},
+				{"a token without a subject", "sub", "", oidc.ErrInvalidToken},
+				{"an issuer that isn't trusted", "iss", "https://evil.example.com", oidc.ErrUnknownIssuer},
+			}
+			for _, tt := range tests {
+				bad := jwt.MapClaims{}
+				for k, v := range claims {
+					bad[k] = v
+				}
+				bad[tt.claim] = tt.value
+
+				if _, err := v.Verify(ctx, sign(t, key, "k1", bad), now); !errors.Is(err, tt.err) {
+					t.Fatalf("\t%s\tTest %d:\tShould NOT accept %s: %v", failed, testID, tt.name, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould NOT accept %s.", success, testID, tt.name)
+			}
+
+			other, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a key: %v", failed, testID, err)
+			}
+			if _, err := v.Verify(ctx, sign(t, other, "k1", claims), now); !errors.Is(err, oidc.ErrInvalidToken) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept a token signed with another key: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT accept a token signed with another key.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the issuer rotates its keys.", testID)
+		{
+			ctx := context.Background()
+			now := time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+			oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a key: %v", failed, testID, err)
+			}
+			newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a key: %v", failed, testID, err)
+			}
+			jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+			writeJWKS(t, jwksFile, map[string]crypto.Signer{"old": oldKey})
+
+			v, err := oidc.NewVerifier([]oidc.Issuer{{URL: issuerURL, Audience: audience, JWKS: jwksFile, TenantID: tenantID}}, nil)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to create a verifier: %v", failed, testID, err)
+			}
+
+			claims := jwt.MapClaims{
+				"iss": issuerURL,
+				"aud": audience,
+				"sub": "248289761001",
+				"exp": now.Add(time.Hour).Unix(),
+			}
+			if _, err := v.Verify(ctx, sign(t, oldKey, "old", claims), now); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept a token signed with the old key: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept a token signed with the old key.", success, testID)
+
+			writeJWKS(t, jwksFile, map[string]crypto.Signer{"old": oldKey, "new": newKey})
+
+			if _, err := v.Verify(ctx, sign(t, newKey, "new", claims), now.Add(time.Second)); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT fetch the keys again right away.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT fetch the keys again right away.", success, testID)
+
+			if _, err := v.Verify(ctx, sign(t, newKey, "new", claims), now.Add(2*time.Minute)); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept a token signed with the new key: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept a token signed with the new key.", success, testID)
+		}
+	}
+}
+
+// writeJWKS publishes the public keys of the signers to the file.
+func writeJWKS(t *testing.T, path string, signers map[string]crypto.Signer) {
+	var kid string
+	for kid = range signers {
+		break
+	}
+
+	a, err := auth.New(kid, keystore.NewMap(signers))
+	if err != nil {
+		t.Fatalf("creating authenticator: %v", err)
+	}
+	jwks, err := a.JWKS()
+	if err != nil {
+		t.Fatalf("building key set: %v", err)
+	}
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatalf("marshaling key set: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("writing key set: %v", err)
+	}
+}
+
+// sign signs the claims with the key the way an external issuer would.
+func sign(t *testing.T, key crypto.Signer, kid string, claims jwt.MapClaims) string {
+	method := jwt.SigningMethod(jwt.SigningMethodRS256)
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		method = jwt.SigningMethodES256
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	str, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+
+	return str
+}
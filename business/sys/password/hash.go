@@ -0,0 +1,254 @@
+package password
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Set of error variables for verifying a password against a hash.
+var (
+	ErrMismatch    = errors.New("password does not match")
+	ErrUnknownHash = errors.New("unknown password hash")
+)
+
+// These are the schemes password hashes can be made with.
+const (
+	SchemeArgon2id = "argon2id"
+	SchemeBcrypt   = "bcrypt"
+)
+
+// Algorithm is a way of hashing passwords. Hashes record the parameters
+// they were made with so an algorithm can tell whether a hash is current.
+type Algorithm interface {
+	Hash(password []byte) ([]byte, error)
+	Current(hash []byte) bool
+}
+
+// Hasher hashes new passwords with its Algorithm and verifies passwords
+// against the hashes of any scheme, whatever parameters they were made
+// with. The zero value hashes with DefaultArgon2id.
+type Hasher struct {
+	Algorithm Algorithm
+}
+
+// Hash hashes the password with a new random salt.
+func (h Hasher) Hash(password string) ([]byte, error) {
+	hash, err := h.algorithm().Hash([]byte(password))
+	if err != nil {
+		return nil, fmt.Errorf("hashing password: %w", err)
+	}
+
+	return hash, nil
+}
+
+// Verify checks the password matches the hash. It returns ErrMismatch when
+// it doesn't. Once the password is known to match, it reports whether the
+// hash should be replaced with a new one because it is outdated.
+func (h Hasher) Verify(hash []byte, password string) (rehash bool, err error) {
+	switch Scheme(hash) {
+	case SchemeArgon2id:
+		err = verifyArgon2id(hash, []byte(password))
+
+	case SchemeBcrypt:
+		if err = bcrypt.CompareHashAndPassword(hash, []byte(password)); errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			err = ErrMismatch
+		}
+
+	default:
+		err = ErrUnknownHash
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return h.Outdated(hash), nil
+}
+
+// Outdated reports whether the hash was made with another algorithm or
+// other parameters than new hashes are.
+func (h Hasher) Outdated(hash []byte) bool {
+	return !h.algorithm().Current(hash)
+}
+
+// dummies holds a hash per algorithm that no password matches, keyed by the
+// algorithm's parameters.
+var dummies sync.Map
+
+// VerifyNone does the work of verifying the password against a hash made
+// with the Hasher's algorithm when there is no hash to verify it against,
+// such as for an unknown user. Signing in as no one then takes as long as
+// with a wrong password, so the time taken doesn't tell whether the user
+// exists. The password never matches.
+func (h Hasher) VerifyNone(password string) {
+	key := fmt.Sprintf("%#v", h.algorithm())
+
+	hash, exists := dummies.Load(key)
+	if !exists {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return
+		}
+		dummy, err := h.Hash(base64.RawStdEncoding.EncodeToString(secret))
+		if err != nil {
+			return
+		}
+		hash, _ = dummies.LoadOrStore(key, dummy)
+	}
+
+	h.Verify(hash.([]byte), password)
+}
+
+// algorithm returns the algorithm new hashes are made with.
+func (h Hasher) algorithm() Algorithm {
+	if h.Algorithm == nil {
+		return DefaultArgon2id
+	}
+	return h.Algorithm
+}
+
+// Scheme returns the scheme the hash was made with, or an empty string when
+// the scheme isn't known.
+func Scheme(hash []byte) string {
+	switch {
+	case bytes.HasPrefix(hash, []byte("$argon2id$")):
+		return SchemeArgon2id
+	case bytes.HasPrefix(hash, []byte("$2a$")), bytes.HasPrefix(hash, []byte("$2b$")), bytes.HasPrefix(hash, []byte("$2y$")):
+		return SchemeBcrypt
+	}
+
+	return ""
+}
+
+// =============================================================================
+
+// Argon2id hashes passwords with Argon2id. Time is the number of passes
+// over Memory KiB of memory made by Threads threads. Hashes are encoded in
+// the PHC string format, which records the parameters alongside the salt.
+type Argon2id struct {
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// DefaultArgon2id holds the parameters RFC 9106 recommends when memory is
+// constrained.
+var DefaultArgon2id = Argon2id{
+	Time:    3,
+	Memory:  64 * 1024,
+	Threads: 4,
+	SaltLen: 16,
+	KeyLen:  32,
+}
+
+// Hash hashes the password with a new random salt.
+func (a Argon2id) Hash(password []byte) ([]byte, error) {
+	salt := make([]byte, a.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+
+	key := argon2.IDKey(password, salt, a.Time, a.Memory, a.Threads, a.KeyLen)
+
+	enc := base64.RawStdEncoding
+	hash := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, a.Memory, a.Time, a.Threads, enc.EncodeToString(salt), enc.EncodeToString(key))
+
+	return []byte(hash), nil
+}
+
+// Current reports whether the hash was made with Argon2id and these
+// parameters.
+func (a Argon2id) Current(hash []byte) bool {
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false
+	}
+
+	return params.Time == a.Time &&
+		params.Memory == a.Memory &&
+		params.Threads == a.Threads &&
+		len(salt) == int(a.SaltLen) &&
+		len(key) == int(a.KeyLen)
+}
+
+// verifyArgon2id checks the password matches the Argon2id hash, using the
+// parameters the hash was made with.
+func verifyArgon2id(hash []byte, password []byte) error {
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return err
+	}
+
+	other := argon2.IDKey(password, salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrMismatch
+	}
+
+	return nil
+}
+
+// parseArgon2id reads the parameters, salt and key of an Argon2id hash.
+func parseArgon2id(hash []byte) (Argon2id, []byte, []byte, error) {
+	var version int
+	var params Argon2id
+	var encSalt, encKey string
+
+	parts := bytes.Split(hash, []byte("$"))
+	if len(parts) != 6 || string(parts[1]) != SchemeArgon2id {
+		return Argon2id{}, nil, nil, ErrUnknownHash
+	}
+	if _, err := fmt.Sscanf(string(parts[2]), "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2id{}, nil, nil, ErrUnknownHash
+	}
+	if _, err := fmt.Sscanf(string(parts[3]), "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return Argon2id{}, nil, nil, ErrUnknownHash
+	}
+	if params.Time == 0 || params.Threads == 0 {
+		return Argon2id{}, nil, nil, ErrUnknownHash
+	}
+	encSalt, encKey = string(parts[4]), string(parts[5])
+
+	salt, err := base64.RawStdEncoding.DecodeString(encSalt)
+	if err != nil {
+		return Argon2id{}, nil, nil, ErrUnknownHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(encKey)
+	if err != nil || len(key) == 0 {
+		return Argon2id{}, nil, nil, ErrUnknownHash
+	}
+	params.SaltLen = uint32(len(salt))
+	params.KeyLen = uint32(len(key))
+
+	return params, salt, key, nil
+}
+
+// =============================================================================
+
+// Bcrypt hashes passwords with bcrypt at Cost. It is kept for the hashes
+// made before argon2id and for deployments that have to stay on bcrypt.
+type Bcrypt struct {
+	Cost int
+}
+
+// DefaultBcrypt hashes with the default cost of the bcrypt package.
+var DefaultBcrypt = Bcrypt{Cost: bcrypt.DefaultCost}
+
+// Hash hashes the password with a new random salt.
+func (b Bcrypt) Hash(password []byte) ([]byte, error) {
+	return bcrypt.GenerateFromPassword(password, b.Cost)
+}
+
+// Current reports whether the hash was made with bcrypt at this cost.
+func (b Bcrypt) Current(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	return err == nil && cost == b.Cost
+}
@@ -0,0 +1,74 @@
+package password_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/password"
+)
+
+func TestHasher(t *testing.T) {
+	t.Log("Given the need to hash passwords and move old hashes forward.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen verifying passwords against hashes.", testID)
+		{
+			// Cheap parameters keep the test fast.
+			argon := password.Argon2id{Time: 1, Memory: 1024, Threads: 1, SaltLen: 16, KeyLen: 32}
+			hasher := password.Hasher{Algorithm: argon}
+
+			hash, err := hasher.Hash("gophers")
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to hash a password: %v", failed, testID, err)
+			}
+			if password.Scheme(hash) != password.SchemeArgon2id {
+				t.Fatalf("\t%s\tTest %d:\tShould hash with argon2id: %s", failed, testID, hash)
+			}
+			t.Logf("\t%s\tTest %d:\tShould hash with argon2id.", success, testID)
+
+			rehash, err := hasher.Verify(hash, "gophers")
+			if err != nil || rehash {
+				t.Fatalf("\t%s\tTest %d:\tShould verify a current hash without rehashing: %v, %v", failed, testID, err, rehash)
+			}
+			t.Logf("\t%s\tTest %d:\tShould verify a current hash without rehashing.", success, testID)
+
+			if _, err := hasher.Verify(hash, "gopher"); !errors.Is(err, password.ErrMismatch) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT verify the wrong password: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT verify the wrong password.", success, testID)
+
+			stronger := argon
+			stronger.Time = 2
+			rehash, err = password.Hasher{Algorithm: stronger}.Verify(hash, "gophers")
+			if err != nil || !rehash {
+				t.Fatalf("\t%s\tTest %d:\tShould rehash when the parameters changed: %v, %v", failed, testID, err, rehash)
+			}
+			t.Logf("\t%s\tTest %d:\tShould rehash when the parameters changed.", success, testID)
+
+			legacy, err := password.Hasher{Algorithm: password.Bcrypt{Cost: 4}}.Hash("gophers")
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to hash with bcrypt: %v", failed, testID, err)
+			}
+			rehash, err = hasher.Verify(legacy, "gophers")
+			if err != nil || !rehash {
+				t.Fatalf("\t%s\tTest %d:\tShould verify a bcrypt hash and rehash it: %v, %v", failed, testID, err, rehash)
+			}
+			t.Logf("\t%s\tTest %d:\tShould verify a bcrypt hash and rehash it.", success, testID)
+
+			if _, err := hasher.Verify(legacy, "gopher"); !errors.Is(err, password.ErrMismatch) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT verify the wrong password against bcrypt: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT verify the wrong password against bcrypt.", success, testID)
+
+			if _, err := hasher.Verify([]byte("$md5$abc"), "gophers"); !errors.Is(err, password.ErrUnknownHash) {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT verify a hash of an unknown scheme: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT verify a hash of an unknown scheme.", success, testID)
+
+			if !(password.Hasher{}).Outdated(hash) {
+				t.Fatalf("\t%s\tTest %d:\tShould report hashes made without the default parameters as outdated.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould report hashes made without the default parameters as outdated.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package password provides support for the policy passwords have to meet
+// and for hashing them.
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
+)
+
+// Policy describes the passwords users can choose. The zero value accepts
+// any password that isn't the user's email.
+type Policy struct {
+	MinLength int
+	breached  map[string]struct{}
+}
+
+// LoadBreached reads a list of breached passwords, one per line, that the
+// policy will reject. Lines can hold the password itself or the hex SHA-1
+// hash of it, optionally followed by a colon and a count, as in the
+// downloadable Pwned Passwords lists.
+func (p *Policy) LoadBreached(r io.Reader) error {
+	breached := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if hash, _, found := strings.Cut(line, ":"); found && isSHA1(hash) {
+			line = hash
+		}
+		if isSHA1(line) {
+			line = strings.ToUpper(line)
+		}
+
+		breached[line] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading breached passwords: %w", err)
+	}
+
+	p.breached = breached
+	return nil
+}
+
+// Check verifies the password meets the policy for the user with the email.
+// It returns field errors for the field named.
+func (p Policy) Check(field, password, email string) error {
+	fail := func(msg string) error {
+		return validate.FieldErrors{{Field: field, Err: msg}}
+	}
+
+	if len([]rune(password)) < p.MinLength {
+		return fail(fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+
+	if email != "" && strings.EqualFold(password, email) {
+		return fail("must not be the email address")
+	}
+
+	if p.breached != nil {
+		sum := sha1.Sum([]byte(password))
+		if _, found := p.breached[password]; found {
+			return fail("has appeared in a data breach")
+		}
+		if _, found := p.breached[strings.ToUpper(hex.EncodeToString(sum[:]))]; found {
+			return fail("has appeared in a data breach")
+		}
+	}
+
+	return nil
+}
+
+// isSHA1 reports whether the value is a hex encoded SHA-1 hash.
+func isSHA1(v string) bool {
+	if len(v) != 2*sha1.Size {
+		return false
+	}
+	_, err := hex.DecodeString(v)
+	return err == nil
+}
@@ -0,0 +1,54 @@
+package password_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/password"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestPolicy(t *testing.T) {
+	t.Log("Given the need to keep users from choosing weak passwords.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen checking passwords against the policy.", testID)
+		{
+			// The second line is the SHA-1 hash of "correct horse battery".
+			list := "letmein123456\n98decc62ece399a22ed30d490ef333be7fde7385:42\n"
+
+			p := password.Policy{MinLength: 12}
+			if err := p.LoadBreached(strings.NewReader(list)); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to load the breached list: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to load the breached list.", success, testID)
+
+			if err := p.Check("password", "tuna salad sandwich", "bill@example.com"); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould accept a password meeting the policy: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept a password meeting the policy.", success, testID)
+
+			if err := p.Check("password", "short", "bill@example.com"); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept a short password.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT accept a short password.", success, testID)
+
+			if err := p.Check("password", "Bill@Example.com", "bill@example.com"); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept the email as the password.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT accept the email as the password.", success, testID)
+
+			for _, pass := range []string{"letmein123456", "correct horse battery"} {
+				if err := p.Check("password", pass, "bill@example.com"); err == nil {
+					t.Fatalf("\t%s\tTest %d:\tShould NOT accept the breached password %q.", failed, testID, pass)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT accept breached passwords, listed plainly or hashed.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Package totp provides support for the time based one time passwords of
+// RFC 6238 that authenticator apps generate.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// These are the parameters of the codes. They are the defaults of RFC 6238
+// and the only ones every authenticator app supports.
+const (
+	Digits = 6
+	Period = 30 * time.Second
+)
+
+// skew is the number of periods before and after the current one whose
+// codes are accepted, allowing for clocks that drift.
+const skew = 1
+
+// encoding is how secrets are written for people and authenticator apps.
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret generates a random secret for a user to add to their
+// authenticator app.
+func NewSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generating secret: %w", err)
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+// URI returns the otpauth URI for the secret. Authenticator apps add the
+// account from it, usually by scanning it as a QR code.
+func URI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digits))
+	v.Set("period", fmt.Sprint(int(Period/time.Second)))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: v.Encode(),
+	}
+	return u.String()
+}
+
+// Code returns the code for the secret at the time.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decode(secret)
+	if err != nil {
+		return "", err
+	}
+	return code(key, step(t)), nil
+}
+
+// Validate checks the code against the secret at the time. It returns the
+// time step the code belongs to so callers can refuse a code that was
+// already used.
+func Validate(secret, candidate string, t time.Time) (int64, bool) {
+	key, err := decode(secret)
+	if err != nil || len(candidate) != Digits {
+		return 0, false
+	}
+
+	now := step(t)
+	for s := now - skew; s <= now+skew; s++ {
+		if hmac.Equal([]byte(code(key, s)), []byte(candidate)) {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// =============================================================================
+
+// decode returns the key of the secret.
+func decode(secret string) ([]byte, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return nil, fmt.Errorf("decoding secret: %w", err)
+	}
+	return key, nil
+}
+
+// step returns the time step the time falls in.
+func step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// code computes the code for the time step as described by RFC 4226.
+func code(key []byte, s int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(s))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%mod)
+}
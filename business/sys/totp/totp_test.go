@@ -0,0 +1,71 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/business/sys/totp"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestTOTP(t *testing.T) {
+	t.Log("Given the need to verify the codes of authenticator apps.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen using the RFC 6238 test secret.", testID)
+		{
+			// The SHA1 test vectors of RFC 6238, truncated to six digits.
+			secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+			vectors := map[int64]string{
+				59:         "287082",
+				1111111109: "081804",
+				1234567890: "005924",
+				2000000000: "279037",
+			}
+
+			for unix, exp := range vectors {
+				code, err := totp.Code(secret, time.Unix(unix, 0))
+				if err != nil || code != exp {
+					t.Fatalf("\t%s\tTest %d:\tShould generate %s at %d: %s %v", failed, testID, exp, unix, code, err)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould generate the codes of the test vectors.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen validating codes.", testID)
+		{
+			secret, err := totp.NewSecret()
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to generate a secret: %v", failed, testID, err)
+			}
+			now := time.Now()
+
+			code, _ := totp.Code(secret, now.Add(-totp.Period))
+			step, ok := totp.Validate(secret, code, now)
+			if !ok || step != now.Add(-totp.Period).Unix()/30 {
+				t.Fatalf("\t%s\tTest %d:\tShould accept the code of the previous period: %d %v", failed, testID, step, ok)
+			}
+			t.Logf("\t%s\tTest %d:\tShould accept the code of the previous period.", success, testID)
+
+			code, _ = totp.Code(secret, now.Add(-3*totp.Period))
+			if _, ok := totp.Validate(secret, code, now); ok {
+				t.Fatalf("\t%s\tTest %d:\tShould NOT accept an old code.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould NOT accept an old code.", success, testID)
+
+			uri := totp.URI("ERP", "bill@example.com", secret)
+			if !strings.HasPrefix(uri, "otpauth://totp/ERP:bill@example.com?") || !strings.Contains(uri, "secret="+secret) {
+				t.Fatalf("\t%s\tTest %d:\tShould build an otpauth URI: %s", failed, testID, uri)
+			}
+			t.Logf("\t%s\tTest %d:\tShould build an otpauth URI.", success, testID)
+		}
+	}
+}
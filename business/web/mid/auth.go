@@ -8,11 +8,13 @@ import (
 	"strings"
 
 	"github.com/deliveranceTechSolutions/erp/business/sys/auth"
+	"github.com/deliveranceTechSolutions/erp/business/sys/database"
 	"github.com/deliveranceTechSolutions/erp/business/sys/validate"
 	"github.com/deliveranceTechSolutions/erp/foundation/web"
 )
 
-// Authenticate validates a JWT from the `Authorization` header.
+// Authenticate validates a JWT from the `Authorization` header, or an API
+// key from the `X-API-Key` header.
 func Authenticate(a *auth.Auth) web.Middleware {
 
 	// This is the actual middleware function to be executed.
@@ -20,23 +22,70 @@ func Authenticate(a *auth.Auth) web.Middleware {
 
 		// Create the handler that will be attached in the middleware chain.
 		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			var claims auth.Claims
+			var err error
+
+			if key := r.Header.Get(auth.APIKeyHeader); key != "" {
+
+				// Validate the key is one we issued and is still valid.
+				claims, err = a.ValidateAPIKey(ctx, key)
+				if err != nil {
+					if validate.Cause(err) != database.ErrAuthenticationFailure {
+						return fmt.Errorf("validating api key: %w", err)
+					}
+					return validate.NewRequestError(errors.New("invalid api key"), http.StatusUnauthorized)
+				}
+			} else {
+
+				// Expecting: bearer <token>
+				authStr := r.Header.Get("authorization")
+
+				// Parse the authorization header.
+				parts := strings.Split(authStr, " ")
+				if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+					err := errors.New("expected authorization header format: bearer <token>")
+					return validate.NewRequestError(err, http.StatusUnauthorized)
+				}
+
+				// Validate the token is signed by us and meant for us. The
+				// reason it was refused is passed back as described in
+				// RFC 6750.
+				claims, err = a.ValidateToken(parts[1])
+				if err != nil {
+					reason := validate.Cause(err)
+					w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, reason))
+					return validate.NewRequestError(reason, http.StatusUnauthorized)
+				}
+			}
 
-			// Expecting: bearer <token>
-			authStr := r.Header.Get("authorization")
-
-			// Parse the authorization header.
-			parts := strings.Split(authStr, " ")
-			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-				err := errors.New("expected authorization header format: bearer <token>")
+			// Every request is scoped to the tenant the token was issued for.
+			if claims.TenantID == "" {
+				err := errors.New("token is not issued for a tenant")
 				return validate.NewRequestError(err, http.StatusUnauthorized)
 			}
 
-			// Validate the token is signed by us.
-			claims, err := a.ValidateToken(parts[1])
+			// Scope every query made for the request to the tenant and user.
+			ctx = database.WithTenant(ctx, claims.TenantID)
+			ctx = database.WithUser(ctx, claims.Subject, claims.Roles)
+
+			// Reject tokens that were revoked before they expired.
+			revoked, err := a.Revoked(ctx, claims)
 			if err != nil {
+				return fmt.Errorf("checking revocation: %w", err)
+			}
+			if revoked {
+				err := errors.New("token has been revoked")
 				return validate.NewRequestError(err, http.StatusUnauthorized)
 			}
 
+			// Resolve what the roles of the token permit as of now. API keys
+			// carry no roles, only the permissions they were given.
+			claims, err = a.ResolvePermissions(ctx, claims)
+			if err != nil {
+				return fmt.Errorf("resolving permissions: %w", err)
+			}
+			ctx = database.WithPermissions(ctx, claims.Permissions)
+
 			// Add claims to the context so they can be retrieved later.
 			ctx = auth.SetClaims(ctx, claims)
 
@@ -83,4 +132,111 @@ func Authorize(roles ...string) web.Middleware {
 	}
 
 	return m
-}
\ No newline at end of file
+}
+
+// RequirePermission validates that an authenticated user was granted every
+// one of the permissions through their roles.
+func RequirePermission(permissions ...string) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+			// If the context is missing this value return failure.
+			claims, err := auth.GetClaims(ctx)
+			if err != nil {
+				return validate.NewRequestError(
+					fmt.Errorf("you are not authorized for that action, no claims"),
+					http.StatusForbidden,
+				)
+			}
+
+			if !claims.HasPermission(permissions...) {
+				return validate.NewRequestError(
+					fmt.Errorf("you are not authorized for that action, roles[%v] required[%v]", claims.Roles, permissions),
+					http.StatusForbidden,
+				)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// RequireMFA validates that the token was issued with multi-factor
+// authentication when the user holds any of the roles. Multi-factor
+// authentication stays optional for users holding none of them.
+func RequireMFA(roles ...string) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+			// If the context is missing this value return failure.
+			claims, err := auth.GetClaims(ctx)
+			if err != nil {
+				return validate.NewRequestError(
+					fmt.Errorf("you are not authorized for that action, no claims"),
+					http.StatusForbidden,
+				)
+			}
+
+			if !claims.MFA() && claims.Authorized(roles...) {
+				return validate.NewRequestError(
+					fmt.Errorf("you are not authorized for that action, multi-factor authentication required for roles[%v]", claims.Roles),
+					http.StatusForbidden,
+				)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// RequireScope validates that an authenticated OAuth client was granted every
+// one of the scopes. Tokens issued to users directly rather than through a
+// client carry no scopes and are limited by their roles alone.
+func RequireScope(scopes ...string) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+			// If the context is missing this value return failure.
+			claims, err := auth.GetClaims(ctx)
+			if err != nil {
+				return validate.NewRequestError(
+					fmt.Errorf("you are not authorized for that action, no claims"),
+					http.StatusForbidden,
+				)
+			}
+
+			if claims.ClientID != "" && !claims.HasScope(scopes...) {
+				return validate.NewRequestError(
+					fmt.Errorf("you are not authorized for that action, scope[%s] required[%v]", claims.Scope, scopes),
+					http.StatusForbidden,
+				)
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -25,6 +25,12 @@ func Panics() web.Middleware {
 			defer func() {
 				if rec := recover(); rec != nil {
 
+					// A handler aborting the connection has already
+					// dealt with the error, so let the server drop it.
+					if rec == http.ErrAbortHandler {
+						panic(rec)
+					}
+
 					// Stack trace will be provided.
 					trace := debug.Stack()
 					err = fmt.Errorf("PANIC [%v] TRACE[%s]", rec, string(trace))
@@ -0,0 +1,88 @@
+// Package canvas provides a small drawing surface that can be encoded as SVG
+// or PNG. Shapes are drawn once against the Canvas interface and the chosen
+// implementation takes care of the output format.
+package canvas
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+)
+
+// Anchor defines how text is aligned against its x coordinate.
+type Anchor int
+
+// Set of text anchors.
+const (
+	AnchorStart Anchor = iota
+	AnchorMiddle
+	AnchorEnd
+)
+
+// Point is a coordinate on the canvas. The origin is the top left corner.
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Canvas declares the set of drawing operations needed to render a chart.
+type Canvas interface {
+	Size() (width int, height int)
+	Line(from Point, to Point, stroke color.Color, width float64)
+	Polyline(points []Point, stroke color.Color, width float64)
+	Rect(x float64, y float64, width float64, height float64, fill color.Color)
+	Circle(center Point, radius float64, fill color.Color)
+	Wedge(center Point, radius float64, start float64, end float64, fill color.Color)
+	Text(at Point, text string, size float64, fill color.Color, anchor Anchor)
+	Encode(w io.Writer) error
+}
+
+// Set of formats a canvas can be encoded to.
+const (
+	FormatSVG = "svg"
+	FormatPNG = "png"
+)
+
+// New constructs a canvas of the specified size that encodes to format.
+func New(format string, width int, height int) (Canvas, error) {
+	switch format {
+	case FormatSVG:
+		return NewSVG(width, height), nil
+	case FormatPNG:
+		return NewRaster(width, height), nil
+	}
+
+	return nil, fmt.Errorf("unsupported canvas format[%s]", format)
+}
+
+// ContentType returns the media type for the specified format.
+func ContentType(format string) string {
+	switch format {
+	case FormatSVG:
+		return "image/svg+xml"
+	case FormatPNG:
+		return "image/png"
+	}
+	return "application/octet-stream"
+}
+
+// TextWidth estimates the width of text drawn at the specified size. Both
+// implementations use a fixed advance so layout is identical between them.
+func TextWidth(text string, size float64) float64 {
+	return float64(len([]rune(text))) * advance(size)
+}
+
+// advance is the horizontal space used by a single character.
+func advance(size float64) float64 {
+	return size * 6 / 7 * 0.75
+}
+
+// polar converts an angle in radians, measured clockwise from twelve o'clock,
+// into a point on the circle.
+func polar(center Point, radius float64, angle float64) Point {
+	return Point{
+		X: center.X + radius*math.Sin(angle),
+		Y: center.Y - radius*math.Cos(angle),
+	}
+}
@@ -0,0 +1,163 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"unicode"
+)
+
+// Raster is a Canvas that draws into an in-memory image and encodes it as PNG.
+type Raster struct {
+	img *image.RGBA
+}
+
+// NewRaster constructs a white raster canvas of the specified size.
+func NewRaster(width int, height int) *Raster {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	return &Raster{
+		img: img,
+	}
+}
+
+// Size returns the dimensions of the canvas.
+func (r *Raster) Size() (int, int) {
+	b := r.img.Bounds()
+	return b.Dx(), b.Dy()
+}
+
+// Image returns the image drawn so far.
+func (r *Raster) Image() image.Image {
+	return r.img
+}
+
+// Line draws a straight line between two points.
+func (r *Raster) Line(from Point, to Point, stroke color.Color, width float64) {
+	half := math.Max(width, 1) / 2
+	minX, maxX := math.Min(from.X, to.X)-half, math.Max(from.X, to.X)+half
+	minY, maxY := math.Min(from.Y, to.Y)-half, math.Max(from.Y, to.Y)+half
+
+	r.fill(minX, minY, maxX, maxY, stroke, func(x, y float64) bool {
+		return distance(Point{x, y}, from, to) <= half
+	})
+}
+
+// Polyline draws connected line segments through every point.
+func (r *Raster) Polyline(points []Point, stroke color.Color, width float64) {
+	for i := 1; i < len(points); i++ {
+		r.Line(points[i-1], points[i], stroke, width)
+	}
+}
+
+// Rect draws a filled rectangle with its top left corner at x, y.
+func (r *Raster) Rect(x float64, y float64, width float64, height float64, fill color.Color) {
+	rect := image.Rect(round(x), round(y), round(x+width), round(y+height))
+	draw.Draw(r.img, rect, image.NewUniform(fill), image.Point{}, draw.Over)
+}
+
+// Circle draws a filled circle.
+func (r *Raster) Circle(center Point, radius float64, fill color.Color) {
+	r.fill(center.X-radius, center.Y-radius, center.X+radius, center.Y+radius, fill, func(x, y float64) bool {
+		return math.Hypot(x-center.X, y-center.Y) <= radius
+	})
+}
+
+// Wedge draws a filled slice of a circle between two angles in radians,
+// measured clockwise from twelve o'clock.
+func (r *Raster) Wedge(center Point, radius float64, start float64, end float64, fill color.Color) {
+	r.fill(center.X-radius, center.Y-radius, center.X+radius, center.Y+radius, fill, func(x, y float64) bool {
+		if math.Hypot(x-center.X, y-center.Y) > radius {
+			return false
+		}
+
+		angle := math.Atan2(x-center.X, center.Y-y)
+		if angle < 0 {
+			angle += 2 * math.Pi
+		}
+		return angle >= start && angle < end
+	})
+}
+
+// Text draws text with its baseline at the specified point using the
+// built-in bitmap font. Lower case letters are drawn as upper case.
+func (r *Raster) Text(at Point, text string, size float64, fill color.Color, anchor Anchor) {
+	switch anchor {
+	case AnchorMiddle:
+		at.X -= TextWidth(text, size) / 2
+	case AnchorEnd:
+		at.X -= TextWidth(text, size)
+	}
+
+	scale := size * 0.75 / glyphHeight
+	top := at.Y - glyphHeight*scale
+	src := image.NewUniform(fill)
+
+	for i, ch := range []rune(text) {
+		glyph, exists := glyphs[unicode.ToUpper(ch)]
+		if !exists {
+			continue
+		}
+
+		left := at.X + float64(i)*advance(size)
+		for row, bits := range glyph {
+			for col := 0; col < glyphWidth; col++ {
+				if bits&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+
+				x := left + float64(col)*scale
+				y := top + float64(row)*scale
+				rect := image.Rect(round(x), round(y), round(x+scale), round(y+scale))
+				if rect.Empty() {
+					rect.Max = rect.Min.Add(image.Point{1, 1})
+				}
+				draw.Draw(r.img, rect, src, image.Point{}, draw.Over)
+			}
+		}
+	}
+}
+
+// Encode writes the image as a PNG.
+func (r *Raster) Encode(w io.Writer) error {
+	return png.Encode(w, r.img)
+}
+
+// =============================================================================
+
+// fill sets every pixel inside the bounding box whose center satisfies inside.
+func (r *Raster) fill(minX, minY, maxX, maxY float64, c color.Color, inside func(x, y float64) bool) {
+	bounds := r.img.Bounds()
+	box := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX))+1, int(math.Ceil(maxY))+1).Intersect(bounds)
+
+	for py := box.Min.Y; py < box.Max.Y; py++ {
+		for px := box.Min.X; px < box.Max.X; px++ {
+			if inside(float64(px)+0.5, float64(py)+0.5) {
+				r.img.Set(px, py, c)
+			}
+		}
+	}
+}
+
+// distance returns the distance from p to the segment between a and b.
+func distance(p Point, a Point, b Point) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	length := dx*dx + dy*dy
+	if length == 0 {
+		return math.Hypot(p.X-a.X, p.Y-a.Y)
+	}
+
+	t := ((p.X-a.X)*dx + (p.Y-a.Y)*dy) / length
+	t = math.Max(0, math.Min(1, t))
+
+	return math.Hypot(p.X-(a.X+t*dx), p.Y-(a.Y+t*dy))
+}
+
+// round converts a coordinate to the nearest pixel.
+func round(v float64) int {
+	return int(math.Round(v))
+}
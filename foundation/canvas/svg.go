@@ -0,0 +1,148 @@
+package canvas
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+)
+
+// SVG is a Canvas that records shapes as SVG elements.
+type SVG struct {
+	width  int
+	height int
+	body   strings.Builder
+}
+
+// NewSVG constructs an empty SVG canvas of the specified size.
+func NewSVG(width int, height int) *SVG {
+	return &SVG{
+		width:  width,
+		height: height,
+	}
+}
+
+// Size returns the dimensions of the canvas.
+func (s *SVG) Size() (int, int) {
+	return s.width, s.height
+}
+
+// Line draws a straight line between two points.
+func (s *SVG) Line(from Point, to Point, stroke color.Color, width float64) {
+	fmt.Fprintf(&s.body, `<line x1="%s" y1="%s" x2="%s" y2="%s" stroke="%s" stroke-width="%s"/>`+"\n",
+		num(from.X), num(from.Y), num(to.X), num(to.Y), hex(stroke), num(width))
+}
+
+// Polyline draws connected line segments through every point.
+func (s *SVG) Polyline(points []Point, stroke color.Color, width float64) {
+	if len(points) == 0 {
+		return
+	}
+
+	coords := make([]string, len(points))
+	for i, p := range points {
+		coords[i] = num(p.X) + "," + num(p.Y)
+	}
+
+	fmt.Fprintf(&s.body, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%s" stroke-linejoin="round"/>`+"\n",
+		strings.Join(coords, " "), hex(stroke), num(width))
+}
+
+// Rect draws a filled rectangle with its top left corner at x, y.
+func (s *SVG) Rect(x float64, y float64, width float64, height float64, fill color.Color) {
+	fmt.Fprintf(&s.body, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`+"\n",
+		num(x), num(y), num(width), num(height), hex(fill))
+}
+
+// Circle draws a filled circle.
+func (s *SVG) Circle(center Point, radius float64, fill color.Color) {
+	fmt.Fprintf(&s.body, `<circle cx="%s" cy="%s" r="%s" fill="%s"/>`+"\n",
+		num(center.X), num(center.Y), num(radius), hex(fill))
+}
+
+// Wedge draws a filled slice of a circle between two angles in radians,
+// measured clockwise from twelve o'clock.
+func (s *SVG) Wedge(center Point, radius float64, start float64, end float64, fill color.Color) {
+	if end-start >= 2*math.Pi {
+		s.Circle(center, radius, fill)
+		return
+	}
+
+	from := polar(center, radius, start)
+	to := polar(center, radius, end)
+
+	large := 0
+	if end-start > math.Pi {
+		large = 1
+	}
+
+	fmt.Fprintf(&s.body, `<path d="M%s,%s L%s,%s A%s,%s 0 %d 1 %s,%s Z" fill="%s"/>`+"\n",
+		num(center.X), num(center.Y), num(from.X), num(from.Y), num(radius), num(radius), large, num(to.X), num(to.Y), hex(fill))
+}
+
+// Text draws text with its baseline at the specified point.
+func (s *SVG) Text(at Point, text string, size float64, fill color.Color, anchor Anchor) {
+	fmt.Fprintf(&s.body, `<text x="%s" y="%s" font-size="%s" fill="%s" text-anchor="%s">%s</text>`+"\n",
+		num(at.X), num(at.Y), num(size), hex(fill), anchorName(anchor), escape(text))
+}
+
+// Encode writes the complete SVG document.
+func (s *SVG) Encode(w io.Writer) error {
+	const header = `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace">` + "\n"
+
+	if _, err := fmt.Fprintf(w, header, s.width, s.height, s.width, s.height); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, s.body.String()); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, "</svg>\n"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// num formats a coordinate with at most two decimal places.
+func num(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// hex formats a color as an SVG color value.
+func hex(c color.Color) string {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	if rgba.A == 0xff {
+		return fmt.Sprintf("#%02x%02x%02x", rgba.R, rgba.G, rgba.B)
+	}
+	return fmt.Sprintf("rgba(%d,%d,%d,%.2f)", rgba.R, rgba.G, rgba.B, float64(rgba.A)/0xff)
+}
+
+// anchorName returns the SVG text-anchor value for the anchor.
+func anchorName(a Anchor) string {
+	switch a {
+	case AnchorMiddle:
+		return "middle"
+	case AnchorEnd:
+		return "end"
+	}
+	return "start"
+}
+
+// escaper replaces the characters that are not allowed in SVG text.
+var escaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&#39;",
+)
+
+// escape makes text safe to embed in an SVG document.
+func escape(text string) string {
+	return escaper.Replace(text)
+}
@@ -0,0 +1,245 @@
+// Package cron parses cron style schedules and calculates when they next run.
+//
+// A schedule is five space separated fields: minute, hour, day of month,
+// month and day of week. Each field accepts *, single values, ranges (1-5),
+// lists (1,15) and steps (*/15 or 0-30/10). Months and week days may also be
+// written by their three letter names (JAN, MON). The descriptors @hourly,
+// @daily, @weekly, @monthly and @yearly are accepted as shorthand.
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// descriptors maps the shorthand schedules to their five field form.
+var descriptors = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var dayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+// field describes the allowed values for one position in a schedule.
+type field struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: monthNames},
+	{name: "day of week", min: 0, max: 7, names: dayNames},
+}
+
+// maxSearch bounds how far ahead Next looks for a matching time so schedules
+// that can never match, like the 31st of February, do not loop forever.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Schedule is a parsed cron schedule. Each field is a bit set of the values
+// it matches.
+type Schedule struct {
+	spec    string
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	domStar bool
+	dowStar bool
+}
+
+// Parse parses the cron expression into a schedule.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	expr := spec
+	if strings.HasPrefix(expr, "@") {
+		var exists bool
+		if expr, exists = descriptors[strings.ToLower(expr)]; !exists {
+			return Schedule{}, fmt.Errorf("unknown descriptor %q", spec)
+		}
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return Schedule{}, fmt.Errorf("expected %d fields, got %d in %q", len(fields), len(parts), spec)
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, f := range fields {
+		var err error
+		if bits[i], err = parseField(parts[i], f); err != nil {
+			return Schedule{}, fmt.Errorf("%s: %w", f.name, err)
+		}
+	}
+
+	// Sunday may be written as 0 or 7.
+	dow := bits[4]
+	if dow&(1<<7) != 0 {
+		dow = dow&^(1<<7) | 1
+	}
+
+	s := Schedule{
+		spec:    spec,
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     dow,
+		domStar: strings.HasPrefix(parts[2], "*"),
+		dowStar: strings.HasPrefix(parts[4], "*"),
+	}
+
+	return s, nil
+}
+
+// String returns the expression the schedule was parsed from.
+func (s Schedule) String() string {
+	return s.spec
+}
+
+// Next returns the first time after the specified time that matches the
+// schedule. Times are matched in the location of after. The zero time is
+// returned if nothing matches within the next five years.
+func (s Schedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// matchDay follows the cron rule that when both the day of month and day of
+// week are restricted a day matching either one is enough.
+func (s Schedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// =============================================================================
+
+// parseField converts one comma separated field into its bit set.
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		b, err := parseRange(part, f)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+// parseRange converts a single *, value or range with an optional step into
+// its bit set.
+func parseRange(expr string, f field) (uint64, error) {
+	rng, step, hasStep := strings.Cut(expr, "/")
+
+	var lo, hi int
+	switch {
+	case rng == "*":
+		lo, hi = f.min, f.max
+		if f.max == 7 {
+			hi = 6
+		}
+
+	default:
+		from, to, isRange := strings.Cut(rng, "-")
+
+		var err error
+		if lo, err = parseValue(from, f); err != nil {
+			return 0, err
+		}
+		hi = lo
+		if isRange {
+			if hi, err = parseValue(to, f); err != nil {
+				return 0, err
+			}
+		} else if hasStep {
+			hi = f.max
+		}
+	}
+
+	if lo > hi {
+		return 0, fmt.Errorf("range %q is backwards", expr)
+	}
+
+	every := 1
+	if hasStep {
+		var err error
+		if every, err = strconv.Atoi(step); err != nil || every <= 0 {
+			return 0, fmt.Errorf("invalid step %q", step)
+		}
+	}
+
+	var bits uint64
+	for v := lo; v <= hi; v += every {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+// parseValue converts a number or name into a value within the field's bounds.
+func parseValue(expr string, f field) (int, error) {
+	if expr == "" {
+		return 0, errors.New("missing value")
+	}
+
+	if v, exists := f.names[strings.ToUpper(expr)]; exists {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(expr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", expr)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, f.min, f.max)
+	}
+	return v, nil
+}
@@ -0,0 +1,127 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/deliveranceTechSolutions/erp/foundation/cron"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestParse(t *testing.T) {
+	tt := []struct {
+		name  string
+		spec  string
+		valid bool
+	}{
+		{"every minute", "* * * * *", true},
+		{"values", "30 8 1 6 2", true},
+		{"lists, ranges and steps", "0,30 9-17 */2 1-11/2 MON-FRI", true},
+		{"names in any case", "0 8 * jan,Feb sun", true},
+		{"sunday as seven", "0 8 * * 7", true},
+		{"descriptor", "@daily", true},
+		{"descriptor in upper case", "@WEEKLY", true},
+		{"empty", "", false},
+		{"too few fields", "* * * *", false},
+		{"too many fields", "* * * * * *", false},
+		{"unknown descriptor", "@every", false},
+		{"minute out of range", "60 * * * *", false},
+		{"hour out of range", "0 24 * * *", false},
+		{"day of month zero", "0 0 0 * *", false},
+		{"month out of range", "0 0 1 13 *", false},
+		{"day of week out of range", "0 0 * * 8", false},
+		{"backwards range", "5-1 * * * *", false},
+		{"zero step", "*/0 * * * *", false},
+		{"missing value", "1- * * * *", false},
+		{"unknown name", "0 0 * FOO *", false},
+	}
+
+	t.Log("Given the need to parse cron schedules.")
+	{
+		for testID, tst := range tt {
+			t.Logf("\tTest %d:\tWhen parsing %q (%s).", testID, tst.spec, tst.name)
+			{
+				s, err := cron.Parse(tst.spec)
+				switch {
+				case tst.valid && err != nil:
+					t.Fatalf("\t%s\tTest %d:\tShould be able to parse the schedule: %v", failed, testID, err)
+				case !tst.valid && err == nil:
+					t.Fatalf("\t%s\tTest %d:\tShould NOT be able to parse the schedule.", failed, testID)
+				}
+				if tst.valid && s.String() != tst.spec {
+					t.Fatalf("\t%s\tTest %d:\tShould keep the expression: %q", failed, testID, s.String())
+				}
+				t.Logf("\t%s\tTest %d:\tShould parse only valid schedules.", success, testID)
+			}
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	date := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	// The 1st of January 2019 is a Tuesday.
+	start := date(2019, time.January, 1, 0, 0)
+
+	tt := []struct {
+		name  string
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{"every minute", "* * * * *", start, date(2019, time.January, 1, 0, 1)},
+		{"seconds are ignored", "* * * * *", start.Add(30 * time.Second), date(2019, time.January, 1, 0, 1)},
+		{"step", "*/15 * * * *", date(2019, time.January, 1, 0, 20), date(2019, time.January, 1, 0, 30)},
+		{"stepped range", "0-30/10 9 * * *", date(2019, time.January, 1, 9, 25), date(2019, time.January, 1, 9, 30)},
+		{"stepped range wraps to next hour", "0-30/10 9 * * *", date(2019, time.January, 1, 9, 30), date(2019, time.January, 2, 9, 0)},
+		{"hour range", "0 9-17 * * *", date(2019, time.January, 1, 17, 30), date(2019, time.January, 2, 9, 0)},
+		{"list", "0 8,20 * * *", date(2019, time.January, 1, 8, 0), date(2019, time.January, 1, 20, 0)},
+		{"week day names", "0 8 * * MON", start, date(2019, time.January, 7, 8, 0)},
+		{"week day range", "0 8 * * 1-5", date(2019, time.January, 4, 9, 0), date(2019, time.January, 7, 8, 0)},
+		{"sunday as zero", "0 8 * * 0", start, date(2019, time.January, 6, 8, 0)},
+		{"sunday as seven", "0 8 * * 7", start, date(2019, time.January, 6, 8, 0)},
+		{"month name", "0 0 1 FEB *", start, date(2019, time.February, 1, 0, 0)},
+		{"day of month only", "0 0 13 * *", start, date(2019, time.January, 13, 0, 0)},
+		{"day of week only", "0 0 * * FRI", start, date(2019, time.January, 4, 0, 0)},
+		{"day of month or week matches week", "0 0 15 * FRI", start, date(2019, time.January, 4, 0, 0)},
+		{"day of month or week matches month", "0 0 15 * FRI", date(2019, time.January, 11, 0, 0), date(2019, time.January, 15, 0, 0)},
+		{"day of month and starred week", "0 0 15 * *", date(2019, time.January, 11, 0, 0), date(2019, time.January, 15, 0, 0)},
+		{"stepped star needs both days", "0 0 13 * */2", start, date(2019, time.January, 13, 0, 0)},
+		{"skips short months", "0 0 31 * *", date(2019, time.January, 31, 0, 0), date(2019, time.March, 31, 0, 0)},
+		{"leap day", "0 0 29 2 *", start, date(2020, time.February, 29, 0, 0)},
+		{"@hourly", "@hourly", start, date(2019, time.January, 1, 1, 0)},
+		{"@daily", "@daily", start, date(2019, time.January, 2, 0, 0)},
+		{"@midnight", "@midnight", start, date(2019, time.January, 2, 0, 0)},
+		{"@weekly", "@weekly", start, date(2019, time.January, 6, 0, 0)},
+		{"@monthly", "@monthly", start, date(2019, time.February, 1, 0, 0)},
+		{"@yearly", "@yearly", start, date(2020, time.January, 1, 0, 0)},
+		{"never fires on the 31st of february", "0 0 31 2 *", start, time.Time{}},
+		{"never fires on the 30th of february", "0 0 30 FEB *", start, time.Time{}},
+	}
+
+	t.Log("Given the need to know when a cron schedule next runs.")
+	{
+		for testID, tst := range tt {
+			t.Logf("\tTest %d:\tWhen running %q after %s (%s).", testID, tst.spec, tst.after.Format(time.RFC3339), tst.name)
+			{
+				s, err := cron.Parse(tst.spec)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to parse the schedule: %v", failed, testID, err)
+				}
+
+				got := s.Next(tst.after)
+				if !got.Equal(tst.want) {
+					t.Fatalf("\t%s\tTest %d:\tShould next run at %s: got %s", failed, testID, tst.want.Format(time.RFC3339), got.Format(time.RFC3339))
+				}
+				t.Logf("\t%s\tTest %d:\tShould next run at the expected time.", success, testID)
+			}
+		}
+	}
+}
@@ -3,7 +3,12 @@
 package keystore
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
@@ -11,40 +16,108 @@ import (
 	"path"
 	"strings"
 	"sync"
-
-	"github.com/golang-jwt/jwt/v4"
+	"time"
 )
 
+// ActiveFile is the name of the file in a keys folder that holds the kid of
+// the key new tokens are signed with. It lets the active key be switched for
+// every instance by changing the folder they all read.
+const ActiveFile = "active"
+
 // KeyStore represents an in memory store implementation of the
-// KeyStorer interface for use with the auth package.
+// KeyStorer interface for use with the auth package. It holds RSA, ECDSA
+// and Ed25519 keys, all of which are a crypto.Signer.
 type KeyStore struct {
-	mu    sync.RWMutex
-	store map[string]*rsa.PrivateKey
+	mu      sync.RWMutex
+	store   map[string]crypto.Signer
+	retired map[string]retiredKey
+	active  string
+}
+
+// retiredKey is a key that was removed from the folder. It can no longer
+// sign tokens but still verifies the tokens it signed until they expire.
+type retiredKey struct {
+	privateKey crypto.Signer
+	until      time.Time
 }
 
 // New constructs an empty KeyStore ready for use.
 func New() *KeyStore {
 	return &KeyStore{
-		store: make(map[string]*rsa.PrivateKey),
+		store:   make(map[string]crypto.Signer),
+		retired: make(map[string]retiredKey),
 	}
 }
 
 // NewMap constructs a KeyStore with an initial set of keys.
-func NewMap(store map[string]*rsa.PrivateKey) *KeyStore {
+func NewMap(store map[string]crypto.Signer) *KeyStore {
 	return &KeyStore{
-		store: store,
+		store:   store,
+		retired: make(map[string]retiredKey),
 	}
 }
 
 // NewFS constructs a KeyStore based on a set of PEM files rooted inside
 // of a directory. The name of each PEM file will be used as the key id.
+// RSA keys can be PKCS #1 or PKCS #8, ECDSA keys SEC 1 or PKCS #8 and
+// Ed25519 keys PKCS #8.
 // Example: keystore.NewFS(os.DirFS("/zarf/keys/"))
 // Example: /zarf/keys/54bb2165-71e1-41a6-af3e-7da4a0e1e2c1.pem
 func NewFS(fsys fs.FS) (*KeyStore, error) {
-	ks := KeyStore{
-		store: make(map[string]*rsa.PrivateKey),
+	ks := New()
+	if err := ks.Reload(fsys, 0); err != nil {
+		return nil, err
 	}
 
+	return ks, nil
+}
+
+// Reload reads the set of PEM files again so keys added to the directory are
+// picked up without a restart. Keys removed from the directory are retired:
+// they can't be used to sign any more but remain available for verification
+// for the retain duration, which should be at least the lifetime of a token.
+func (ks *KeyStore) Reload(fsys fs.FS, retain time.Duration) error {
+	store, active, err := load(fsys)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	for kid, privateKey := range ks.store {
+		if _, exists := store[kid]; !exists && retain > 0 {
+			ks.retired[kid] = retiredKey{privateKey: privateKey, until: now.Add(retain)}
+		}
+	}
+	for kid, rk := range ks.retired {
+		if _, exists := store[kid]; exists || !now.Before(rk.until) {
+			delete(ks.retired, kid)
+		}
+	}
+
+	ks.store = store
+	ks.active = active
+
+	return nil
+}
+
+// ActiveKID returns the kid named by the active file when the store was last
+// loaded from a directory, or an empty string if there was none.
+func (ks *KeyStore) ActiveKID() string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	return ks.active
+}
+
+// load reads every PEM file in the directory along with the active file.
+func load(fsys fs.FS) (map[string]crypto.Signer, string, error) {
+	store := make(map[string]crypto.Signer)
+	var active string
+
 	fn := func(fileName string, dirEntry fs.DirEntry, err error) error {
 		if err != nil {
 			return fmt.Errorf("walkdir failure: %w", err)
@@ -54,6 +127,15 @@ func NewFS(fsys fs.FS) (*KeyStore, error) {
 			return nil
 		}
 
+		if fileName == ActiveFile {
+			data, err := fs.ReadFile(fsys, fileName)
+			if err != nil {
+				return fmt.Errorf("reading active file: %w", err)
+			}
+			active = strings.TrimSpace(string(data))
+			return nil
+		}
+
 		if path.Ext(fileName) != ".pem" {
 			return nil
 		}
@@ -72,24 +154,59 @@ func NewFS(fsys fs.FS) (*KeyStore, error) {
 			return fmt.Errorf("reading auth private key: %w", err)
 		}
 
-		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+		privateKey, err := parsePrivateKey(privatePEM)
 		if err != nil {
 			return fmt.Errorf("parsing auth private key: %w", err)
 		}
 
-		ks.store[strings.TrimSuffix(dirEntry.Name(), ".pem")] = privateKey
+		store[strings.TrimSuffix(dirEntry.Name(), ".pem")] = privateKey
 		return nil
 	}
 
 	if err := fs.WalkDir(fsys, ".", fn); err != nil {
-		return nil, fmt.Errorf("walking directory: %w", err)
+		return nil, "", fmt.Errorf("walking directory: %w", err)
+	}
+
+	return store, active, nil
+}
+
+// parsePrivateKey parses the first PEM block of the data as a private key.
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("key must be PEM encoded")
+	}
+
+	var privateKey any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case *ecdsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
 	}
 
-	return &ks, nil
+	return nil, fmt.Errorf("unsupported key type %T", privateKey)
 }
 
 // Add adds a private key and combination kid to the store.
-func (ks *KeyStore) Add(privateKey *rsa.PrivateKey, kid string) {
+func (ks *KeyStore) Add(privateKey crypto.Signer, kid string) {
 	ks.mu.Lock()
 	defer ks.mu.Unlock()
 
@@ -115,7 +232,7 @@ func (ks *KeyStore) Remove(kid string) {
 
 // PrivateKey searches the key store for a given kid and returns
 // the private key.
-func (ks *KeyStore) PrivateKey(kid string) (*rsa.PrivateKey, error) {
+func (ks *KeyStore) PrivateKey(kid string) (crypto.PrivateKey, error) {
 	ks.mu.RLock()
 	defer ks.mu.RUnlock()
 
@@ -128,14 +245,39 @@ func (ks *KeyStore) PrivateKey(kid string) (*rsa.PrivateKey, error) {
 
 // PublicKey searches the key store for a given kid and returns
 // the public key.
-func (ks *KeyStore) PublicKey(kid string) (*rsa.PublicKey, error) {
+func (ks *KeyStore) PublicKey(kid string) (crypto.PublicKey, error) {
 	ks.mu.RLock()
 	defer ks.mu.RUnlock()
 
 	privateKey, found := ks.store[kid]
 	if !found {
-		return nil, errors.New("kid lookup failed")
+		rk, retired := ks.retired[kid]
+		if !retired || !time.Now().Before(rk.until) {
+			return nil, errors.New("kid lookup failed")
+		}
+		privateKey = rk.privateKey
 	}
-	return &privateKey.PublicKey, nil
+	return privateKey.Public(), nil
 }
 
+
+// PublicKeys returns the public key of every key in the store, including the
+// retired keys still valid for verification, indexed by kid. The private keys
+// never leave the store.
+func (ks *KeyStore) PublicKeys() map[string]crypto.PublicKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	now := time.Now()
+
+	keys := make(map[string]crypto.PublicKey, len(ks.store)+len(ks.retired))
+	for kid, rk := range ks.retired {
+		if now.Before(rk.until) {
+			keys[kid] = rk.privateKey.Public()
+		}
+	}
+	for kid, privateKey := range ks.store {
+		keys[kid] = privateKey.Public()
+	}
+	return keys
+}
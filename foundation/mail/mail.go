@@ -0,0 +1,207 @@
+// Package mail provides support for sending email with attachments. Senders
+// implement the Mailer interface so the delivery mechanism can be swapped,
+// an SMTP implementation is provided.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Attachment is a file sent along with a message.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Message is a single email to deliver.
+type Message struct {
+	From        string
+	To          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Mailer declares the behavior for delivering email.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// =============================================================================
+
+// Config defines the information needed to reach an SMTP server. Username
+// and Password are optional so local test servers that do not authenticate
+// can be used.
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTP delivers messages through an SMTP server.
+type SMTP struct {
+	cfg Config
+}
+
+// NewSMTP constructs a mailer for the configured SMTP server.
+func NewSMTP(cfg Config) *SMTP {
+	return &SMTP{
+		cfg: cfg,
+	}
+}
+
+// Send delivers the message. STARTTLS is used when the server offers it and
+// messages without a From address are sent from the configured address.
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = s.cfg.From
+	}
+	if msg.From == "" || len(msg.To) == 0 {
+		return errors.New("message requires a sender and at least one recipient")
+	}
+
+	data, err := encode(msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("encoding message: %w", err)
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("dialing %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("greeting %s: %w", addr, err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.cfg.Host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	if s.cfg.Username != "" {
+		auth := smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+
+	if err := c.Mail(msg.From); err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	for _, to := range msg.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("recipient[%s]: %w", to, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("completing message: %w", err)
+	}
+
+	return c.Quit()
+}
+
+// =============================================================================
+
+// encode formats the message as a MIME multipart document with the body as
+// the first part followed by every attachment.
+func encode(msg Message, now time.Time) ([]byte, error) {
+	boundary, err := newBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", key, value)
+	}
+
+	header("From", msg.From)
+	header("To", strings.Join(msg.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": boundary}))
+	b.WriteString("\r\n")
+
+	fmt.Fprintf(&b, "--%s\r\n", boundary)
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "base64")
+	b.WriteString("\r\n")
+	writeBase64(&b, []byte(msg.Body))
+
+	for _, a := range msg.Attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		header("Content-Type", contentType)
+		header("Content-Transfer-Encoding", "base64")
+		header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+		b.WriteString("\r\n")
+		writeBase64(&b, a.Data)
+	}
+
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+
+	return b.Bytes(), nil
+}
+
+// writeBase64 writes data base64 encoded in lines of 76 characters.
+func writeBase64(b *bytes.Buffer, data []byte) {
+	const lineLength = 76
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > lineLength {
+		b.WriteString(encoded[:lineLength])
+		b.WriteString("\r\n")
+		encoded = encoded[lineLength:]
+	}
+	b.WriteString(encoded)
+	b.WriteString("\r\n")
+}
+
+// newBoundary generates a random multipart boundary.
+func newBoundary() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generating boundary: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
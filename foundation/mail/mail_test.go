@@ -0,0 +1,65 @@
+package mail_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/deliveranceTechSolutions/erp/foundation/mail"
+	"github.com/deliveranceTechSolutions/erp/foundation/mail/mailtest"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestSMTP(t *testing.T) {
+	srv, err := mailtest.NewServer()
+	if err != nil {
+		t.Fatalf("starting smtp server: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+
+	mailer := mail.NewSMTP(mail.Config{
+		Host: srv.Host,
+		Port: srv.Port,
+		From: "noreply@sales-api.local",
+	})
+
+	t.Log("Given the need to deliver email through an SMTP server.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen sending a message with an attachment.", testID)
+		{
+			msg := mail.Message{
+				To:      []string{"bill@example.com"},
+				Subject: "Weekly Sales",
+				Body:    "The report is attached.",
+				Attachments: []mail.Attachment{
+					{Name: "sales.csv", ContentType: "text/csv", Data: []byte("month,total\n")},
+				},
+			}
+			if err := mailer.Send(context.Background(), msg); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to send the message: %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to send the message.", success, testID)
+
+			got := srv.Messages()
+			if len(got) != 1 || got[0].From != "noreply@sales-api.local" || len(got[0].To) != 1 || got[0].To[0] != "bill@example.com" {
+				t.Fatalf("\t%s\tTest %d:\tShould deliver from the configured sender: %+v", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould deliver from the configured sender.", success, testID)
+
+			text, err := got[0].Text()
+			if err != nil || text != msg.Body {
+				t.Fatalf("\t%s\tTest %d:\tShould deliver the body: %q %v", failed, testID, text, err)
+			}
+			if !strings.Contains(got[0].Data, "Subject: Weekly Sales") || !strings.Contains(got[0].Data, "filename=sales.csv") {
+				t.Fatalf("\t%s\tTest %d:\tShould deliver the subject and attachment: %s", failed, testID, got[0].Data)
+			}
+			t.Logf("\t%s\tTest %d:\tShould deliver the body, subject and attachment.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Package mailtest provides a local SMTP server that records the messages
+// delivered to it, standing in for a real server in tests.
+package mailtest
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Received is a message delivered to the server.
+type Received struct {
+	From string
+	To   []string
+	Data string
+}
+
+// Text returns the decoded plain text body of the message.
+func (r Received) Text() (string, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(r.Data))
+	if err != nil {
+		return "", err
+	}
+
+	part := textproto.MIMEHeader(msg.Header)
+	body := msg.Body
+
+	mediaType, params, err := mime.ParseMediaType(part.Get("Content-Type"))
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return "", errors.New("message has no text part")
+				}
+				return "", err
+			}
+			if strings.HasPrefix(p.Header.Get("Content-Type"), "text/plain") {
+				part, body = p.Header, p
+				break
+			}
+		}
+	}
+
+	if strings.EqualFold(part.Get("Content-Transfer-Encoding"), "base64") {
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Server is an SMTP server that accepts every message and keeps it.
+type Server struct {
+	Host string
+	Port int
+
+	ln       net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	messages []Received
+}
+
+// NewServer starts a server listening on a free port of the loopback
+// interface.
+func NewServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	addr := ln.Addr().(*net.TCPAddr)
+	s := Server{
+		Host: addr.IP.String(),
+		Port: addr.Port,
+		ln:   ln,
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.serve()
+	}()
+
+	return &s, nil
+}
+
+// Addr returns the address the server is listening on.
+func (s *Server) Addr() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// Messages returns the messages delivered so far.
+func (s *Server) Messages() []Received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Received(nil), s.messages...)
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.wg.Wait()
+	return err
+}
+
+// =============================================================================
+
+// serve accepts connections until the server is closed.
+func (s *Server) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.session(conn)
+		}()
+	}
+}
+
+// session speaks just enough SMTP to take delivery of messages.
+func (s *Server) session(conn net.Conn) {
+	tp := textproto.NewConn(conn)
+	reply := func(code int, msg string) bool {
+		return tp.PrintfLine("%d %s", code, msg) == nil
+	}
+
+	if !reply(220, "mailtest ready") {
+		return
+	}
+
+	var msg Received
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply(250, "mailtest")
+
+		case "MAIL":
+			msg = Received{From: address(arg)}
+			reply(250, "OK")
+
+		case "RCPT":
+			msg.To = append(msg.To, address(arg))
+			reply(250, "OK")
+
+		case "DATA":
+			if !reply(354, "end data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+
+			reply(250, "OK")
+
+		case "RSET", "NOOP":
+			reply(250, "OK")
+
+		case "QUIT":
+			reply(221, "bye")
+			return
+
+		default:
+			reply(502, "command not implemented")
+		}
+	}
+}
+
+// address returns the address of a MAIL FROM or RCPT TO argument.
+func address(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr = strings.TrimSpace(addr)
+	if i := strings.IndexByte(addr, ' '); i >= 0 {
+		addr = addr[:i]
+	}
+	return strings.Trim(addr, "<>")
+}
@@ -0,0 +1,80 @@
+package sheet
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// dateLayout is how dates are written in CSV cells.
+const dateLayout = "2006-01-02T15:04:05Z07:00"
+
+// CSV streams a sheet as comma separated values.
+type CSV struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSV constructs a CSV writer.
+func NewCSV(w io.Writer) *CSV {
+	return &CSV{
+		w: csv.NewWriter(w),
+	}
+}
+
+// WriteHeader writes the column names as the first record.
+func (c *CSV) WriteHeader(columns []string) error {
+	if c.header {
+		return errors.New("header already written")
+	}
+	c.header = true
+
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = text(column)
+	}
+
+	return c.w.Write(record)
+}
+
+// WriteRow writes a single record.
+func (c *CSV) WriteRow(cells []Cell) error {
+	if !c.header {
+		return errors.New("header must be written before rows")
+	}
+
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		switch cell.Kind {
+		case KindString:
+			record[i] = text(cell.String)
+		case KindNumber:
+			record[i] = strconv.FormatFloat(cell.Number, 'f', -1, 64)
+		case KindDate:
+			record[i] = cell.Date.Format(dateLayout)
+		}
+	}
+
+	return c.w.Write(record)
+}
+
+// Close flushes any buffered records to the underlying writer.
+func (c *CSV) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// =============================================================================
+
+// text returns the text of a cell made safe to open in a spreadsheet. CSV
+// text starting with one of = + - @ or a tab or carriage return is run as a
+// formula by spreadsheet applications, so it is prefixed with a quote to
+// keep it plain text.
+func text(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+// Package sheet provides streaming writers for tabular data in CSV and XLSX
+// form. Rows are written to the underlying writer as they are provided so
+// large exports never need to be held in memory. CSV text that a spreadsheet
+// would run as a formula is written quoted.
+package sheet
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Set of formats a sheet can be written in.
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Kind identifies the type of value held by a cell.
+type Kind int
+
+// Set of cell kinds.
+const (
+	KindEmpty Kind = iota
+	KindString
+	KindNumber
+	KindDate
+)
+
+// Cell is a single typed value in a row.
+type Cell struct {
+	Kind   Kind
+	String string
+	Number float64
+	Date   time.Time
+}
+
+// String constructs a text cell.
+func String(s string) Cell {
+	return Cell{Kind: KindString, String: s}
+}
+
+// Number constructs a numeric cell.
+func Number(n float64) Cell {
+	return Cell{Kind: KindNumber, Number: n}
+}
+
+// Date constructs a date cell.
+func Date(t time.Time) Cell {
+	return Cell{Kind: KindDate, Date: t}
+}
+
+// Empty constructs a cell with no value.
+func Empty() Cell {
+	return Cell{}
+}
+
+// Writer declares the behavior for streaming a sheet. WriteHeader must be
+// called once before any rows and Close must be called to complete the file.
+type Writer interface {
+	WriteHeader(columns []string) error
+	WriteRow(cells []Cell) error
+	Close() error
+}
+
+// New constructs a writer for the specified format.
+func New(w io.Writer, format string, name string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSV(w), nil
+	case FormatXLSX:
+		return NewXLSX(w, name)
+	}
+
+	return nil, fmt.Errorf("unsupported sheet format[%s]", format)
+}
+
+// ContentType returns the media type for the specified format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,237 @@
+package sheet
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Style indexes defined in styles.xml.
+const (
+	styleDefault = 0
+	styleDate    = 1
+	styleHeader  = 2
+)
+
+// epoch is the day spreadsheets count dates from.
+var epoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// XLSX streams a single worksheet Office Open XML workbook. Strings are
+// written inline so no shared string table has to be collected in memory.
+// Inline strings are never run as formulas so text is written as it is.
+type XLSX struct {
+	zw     *zip.Writer
+	sheet  *bufio.Writer
+	row    int
+	header bool
+}
+
+// NewXLSX constructs an XLSX writer whose worksheet carries the specified
+// name. The fixed parts of the workbook are written immediately.
+func NewXLSX(w io.Writer, name string) (*XLSX, error) {
+	zw := zip.NewWriter(w)
+
+	parts := []struct {
+		name string
+		body string
+	}{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", relsXML},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXML, escapeXML(sheetName(name)))},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+		{"xl/styles.xml", stylesXML},
+	}
+
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("creating part[%s]: %w", part.name, err)
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, fmt.Errorf("writing part[%s]: %w", part.name, err)
+		}
+	}
+
+	// The worksheet is the last part so it can be streamed until Close.
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("creating worksheet: %w", err)
+	}
+
+	x := XLSX{
+		zw:    zw,
+		sheet: bufio.NewWriter(f),
+	}
+
+	return &x, nil
+}
+
+// WriteHeader writes the column names as a bold first row.
+func (x *XLSX) WriteHeader(columns []string) error {
+	if x.header {
+		return errors.New("header already written")
+	}
+	x.header = true
+
+	if _, err := io.WriteString(x.sheet, worksheetOpenXML); err != nil {
+		return err
+	}
+
+	cells := make([]Cell, len(columns))
+	for i, column := range columns {
+		cells[i] = String(column)
+	}
+
+	return x.writeRow(cells, styleHeader)
+}
+
+// WriteRow writes a single row of typed cells.
+func (x *XLSX) WriteRow(cells []Cell) error {
+	if !x.header {
+		return errors.New("header must be written before rows")
+	}
+
+	return x.writeRow(cells, styleDefault)
+}
+
+// Close completes the worksheet and the workbook.
+func (x *XLSX) Close() error {
+	if !x.header {
+		if _, err := io.WriteString(x.sheet, worksheetOpenXML); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.WriteString(x.sheet, worksheetCloseXML); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+
+	return x.zw.Close()
+}
+
+// writeRow writes the cells as the next row using style for text and numbers.
+func (x *XLSX) writeRow(cells []Cell, style int) error {
+	x.row++
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, x.row)
+
+	for i, cell := range cells {
+		ref := columnName(i) + strconv.Itoa(x.row)
+
+		switch cell.Kind {
+		case KindString:
+			fmt.Fprintf(&b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escapeXML(cell.String))
+		case KindNumber:
+			fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(cell.Number, 'f', -1, 64))
+		case KindDate:
+			fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, ref, styleDate, strconv.FormatFloat(serial(cell.Date), 'f', -1, 64))
+		}
+	}
+
+	b.WriteString("</row>")
+
+	_, err := x.sheet.WriteString(b.String())
+	return err
+}
+
+// =============================================================================
+
+// columnName converts a zero based column index into its letters: A, B ... Z,
+// AA, AB and so on.
+func columnName(i int) string {
+	var name []byte
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = append([]byte{byte('A' + (i-1)%26)}, name...)
+	}
+	return string(name)
+}
+
+// serial converts a time into the spreadsheet's fractional day count.
+func serial(t time.Time) float64 {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return t.Sub(epoch).Hours() / 24
+}
+
+// sheetName makes name acceptable as a worksheet name which cannot be empty,
+// longer than 31 characters or contain any of []:*?/\
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '-'
+		}
+		return r
+	}, name)
+
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name == "" {
+		name = "Sheet1"
+	}
+
+	return name
+}
+
+// escapeXML makes text safe to embed in an XML document.
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// =============================================================================
+
+const contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`
+
+const relsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const workbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+const stylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="3">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>
+<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+</cellXfs>
+<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>
+</styleSheet>`
+
+const worksheetOpenXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+
+const worksheetCloseXML = `</sheetData></worksheet>`
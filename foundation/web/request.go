@@ -2,6 +2,7 @@ package web
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 
 	"github.com/dimfeld/httptreemux/v5"
@@ -13,6 +14,16 @@ func Param(r *http.Request, key string) string {
 	return m[key]
 }
 
+// RemoteIP returns the address of the client making the request without the
+// port. Forwarding headers are ignored since any client can set them.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // Decode reads the body of an HTTP request looking for a JSON document. The
 // body is decoded into the provided value.
 //
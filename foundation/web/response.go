@@ -3,6 +3,8 @@ package web
 import (
 	"context"
 	"encoding/json"
+	"io"
+	"mime"
 	"net/http"
 )
 
@@ -38,4 +40,39 @@ func Respond(ctx context.Context, w http.ResponseWriter, data any, statusCode in
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// RespondRaw sends pre-encoded data to the client with the specified content
+// type. It is used for responses that are not JSON such as images.
+func RespondRaw(ctx context.Context, w http.ResponseWriter, data []byte, contentType string, statusCode int) error {
+
+	// Set the status code for the request logger middleware.
+	SetStatusCode(ctx, statusCode)
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RespondStream sends a file to the client as it is produced by fn instead of
+// building it in memory first. The filename is offered as the name to save
+// the download under. The status has been sent by the time fn runs, so
+// anything that can fail should be checked before calling RespondStream. An
+// error from fn can no longer be reported to the client and is returned for
+// the caller to log before aborting the connection.
+func RespondStream(ctx context.Context, w http.ResponseWriter, contentType string, filename string, statusCode int, fn func(w io.Writer) error) error {
+
+	// Set the status code for the request logger middleware.
+	SetStatusCode(ctx, statusCode)
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(statusCode)
+
+	return fn(w)
+}